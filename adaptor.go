@@ -0,0 +1,264 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ed25519
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+const (
+	// AdaptorSecretSize is the size, in bytes, of adaptor secrets.
+	AdaptorSecretSize = 32
+
+	// AdaptorPointSize is the size, in bytes, of adaptor points.
+	AdaptorPointSize = 32
+
+	// PreSignatureSize is the size, in bytes, of adaptor pre-signatures.
+	PreSignatureSize = SignatureSize
+
+	// adaptorPrefix domain separates the nonce derivation for
+	// pre-signatures from that of ordinary signatures, so that the same
+	// nonce is never used with two different challenges.
+	adaptorPrefix = "Ed25519 adaptor signature"
+)
+
+var (
+	errInvalidAdaptorPoint  = errors.New("ed25519: invalid adaptor point")
+	errInvalidAdaptorSecret = errors.New("ed25519: invalid adaptor secret")
+	errInvalidPreSignature  = errors.New("ed25519: invalid pre-signature")
+	errAdaptorMismatch      = errors.New("ed25519: signature does not match pre-signature")
+)
+
+type adaptorPoint struct {
+	point  ge25519.Ge25519
+	packed [AdaptorPointSize]byte
+}
+
+func (T *adaptorPoint) unpack(b []byte) bool {
+	if len(b) != AdaptorPointSize || !ge25519.UnpackVartime(&T.point, b) {
+		return false
+	}
+
+	// Require the canonical encoding of a point in the prime order
+	// subgroup, that is not the identity.
+	ge25519.Pack(T.packed[:], &T.point)
+	if !bytes.Equal(T.packed[:], b) {
+		return false
+	}
+	return !ge25519.IsNeutralVartime(&T.point) && ge25519.IsTorsionFreeVartime(&T.point)
+}
+
+// GenerateAdaptor generates an adaptor point/secret pair using entropy
+// from rand.  If rand is nil, crypto/rand.Reader will be used.
+func GenerateAdaptor(rand io.Reader) ([]byte, []byte, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var (
+		wide [64]byte
+		t    modm.Bignum256
+	)
+	for {
+		if _, err := io.ReadFull(rand, wide[:]); err != nil {
+			return nil, nil, err
+		}
+		modm.Expand(&t, wide[:])
+		if !modm.IsZeroVartime(&t) {
+			break
+		}
+	}
+	for i := range wide {
+		wide[i] = 0
+	}
+
+	secret := make([]byte, AdaptorSecretSize)
+	modm.Contract(secret, &t)
+	t.Reset()
+
+	point, err := AdaptorPoint(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return point, secret, nil
+}
+
+// AdaptorPoint returns the adaptor point T = tB corresponding to the
+// adaptor secret t.
+func AdaptorPoint(secret []byte) ([]byte, error) {
+	var (
+		t modm.Bignum256
+		T ge25519.Ge25519
+	)
+	if err := unpackAdaptorSecret(&t, secret); err != nil {
+		return nil, err
+	}
+
+	point := make([]byte, AdaptorPointSize)
+	ge25519.ScalarmultBaseNiels(&T, &ge25519.NielsBaseMultiples, &t)
+	ge25519.Pack(point, &T)
+	t.Reset()
+
+	return point, nil
+}
+
+// PreSign produces a pre-signature of message with privateKey, under the
+// adaptor point T.  The pre-signature can be turned into an ordinary
+// Ed25519 signature with Adapt, by anyone that knows the adaptor secret,
+// and the adaptor secret can be recovered from the pair with
+// ExtractAdaptorSecret.  It will panic if len(privateKey) is not
+// PrivateKeySize.
+func PreSign(privateKey PrivateKey, message, point []byte) ([]byte, error) {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+
+	var T adaptorPoint
+	if !T.unpack(point) {
+		return nil, errInvalidAdaptorPoint
+	}
+
+	return sign(privateKey, message, fPure, nil, &T), nil
+}
+
+// PreVerify reports whether preSig is a valid pre-signature of message by
+// publicKey, under the adaptor point T.  If it is, the signature produced by
+// Adapt with the corresponding adaptor secret will be accepted by Verify.
+// It will panic if len(publicKey) is not PublicKeySize.
+func PreVerify(publicKey PublicKey, message, point, preSig []byte) bool {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+
+	var (
+		hash                [64]byte
+		T                   adaptorPoint
+		Rproj, R, A, checkR ge25519.Ge25519
+		hram, S             modm.Bignum256
+	)
+
+	if len(preSig) != PreSignatureSize || !scMinimal(preSig[32:]) || !ge25519.UnpackNegativeVartime(&A, publicKey) {
+		return false
+	}
+	if isSmallOrderVartime(publicKey) || isSmallOrderVartime(preSig[:32]) {
+		return false
+	}
+	if !T.unpack(point) || !ge25519.UnpackVartime(&checkR, preSig[:32]) {
+		return false
+	}
+
+	// hram = H(R',A,m)
+	h := sha512.New()
+	_, _ = h.Write(preSig[:32])
+	_, _ = h.Write(publicKey[:])
+	_, _ = h.Write(message)
+	h.Sum(hash[:0])
+	modm.Expand(&hram, hash[:])
+
+	// S'B - H(R',A,m)A + T
+	modm.Expand(&S, preSig[32:])
+	ge25519.DoubleScalarmultVartime(&Rproj, &A, &hram, &S)
+	ge25519.ProjectiveToExtended(&R, &Rproj)
+	ge25519.Add(&R, &R, &T.point)
+
+	// check that [8](R' - (S'B - H(R',A,m)A + T)) == 0, matching Verify.
+	return ge25519.CofactorEqual(&R, &checkR)
+}
+
+// Adapt completes the pre-signature with the adaptor secret, producing an
+// ordinary Ed25519 signature.
+func Adapt(preSig, adaptorSecret []byte) ([]byte, error) {
+	if len(preSig) != PreSignatureSize || !scMinimal(preSig[32:]) {
+		return nil, errInvalidPreSignature
+	}
+
+	var S, t modm.Bignum256
+	if err := unpackAdaptorSecret(&t, adaptorSecret); err != nil {
+		return nil, err
+	}
+
+	// S = S' + t
+	modm.Expand(&S, preSig[32:])
+	modm.Add(&S, &S, &t)
+	t.Reset()
+
+	sig := make([]byte, SignatureSize)
+	copy(sig, preSig[:32])
+	modm.Contract(sig[32:], &S)
+
+	return sig, nil
+}
+
+// ExtractAdaptorSecret recovers the adaptor secret for the adaptor point
+// T, from a pre-signature and the signature that was adapted from it.
+func ExtractAdaptorSecret(sig, preSig, point []byte) ([]byte, error) {
+	if len(preSig) != PreSignatureSize || !scMinimal(preSig[32:]) {
+		return nil, errInvalidPreSignature
+	}
+	if len(sig) != SignatureSize || !scMinimal(sig[32:]) || !bytes.Equal(sig[:32], preSig[:32]) {
+		return nil, errAdaptorMismatch
+	}
+
+	// t = S - S'
+	var S, SPrime, t modm.Bignum256
+	modm.Expand(&S, sig[32:])
+	modm.Expand(&SPrime, preSig[32:])
+	modm.Sub(&t, &S, &SPrime)
+
+	secret := make([]byte, AdaptorSecretSize)
+	modm.Contract(secret, &t)
+	t.Reset()
+
+	checkPoint, err := AdaptorPoint(secret)
+	if err != nil || !bytes.Equal(checkPoint, point) {
+		return nil, errAdaptorMismatch
+	}
+
+	return secret, nil
+}
+
+func unpackAdaptorSecret(t *modm.Bignum256, secret []byte) error {
+	if len(secret) != AdaptorSecretSize || !scMinimal(secret) {
+		return errInvalidAdaptorSecret
+	}
+	modm.Expand(t, secret)
+	if modm.IsZeroVartime(t) {
+		return errInvalidAdaptorSecret
+	}
+	return nil
+}
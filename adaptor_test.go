@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ed25519
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAdaptorSignature(t *testing.T) {
+	publicKey, privateKey, _ := GenerateKey(nil)
+	point, secret, err := GenerateAdaptor(nil)
+	if err != nil {
+		t.Fatalf("GenerateAdaptor: %v", err)
+	}
+
+	message := []byte("test message")
+	preSig, err := PreSign(privateKey, message, point)
+	if err != nil {
+		t.Fatalf("PreSign: %v", err)
+	}
+	if !PreVerify(publicKey, message, point, preSig) {
+		t.Fatalf("PreVerify failed")
+	}
+	if PreVerify(publicKey, []byte("wrong message"), point, preSig) {
+		t.Fatalf("PreVerify succeeded on wrong message")
+	}
+	if Verify(publicKey, message, preSig) {
+		t.Fatalf("pre-signature is a valid signature")
+	}
+
+	otherPoint, otherSecret, _ := GenerateAdaptor(nil)
+	if PreVerify(publicKey, message, otherPoint, preSig) {
+		t.Fatalf("PreVerify succeeded with wrong adaptor point")
+	}
+
+	// Pre-signing is deterministic, but differs from ordinary signing
+	// and between adaptor points.
+	preSig2, _ := PreSign(privateKey, message, point)
+	if !bytes.Equal(preSig, preSig2) {
+		t.Fatalf("PreSign is not deterministic")
+	}
+	otherPreSig, _ := PreSign(privateKey, message, otherPoint)
+	if bytes.Equal(preSig[32:], otherPreSig[32:]) {
+		t.Fatalf("PreSign reused a nonce across adaptor points")
+	}
+
+	sig, err := Adapt(preSig, secret)
+	if err != nil {
+		t.Fatalf("Adapt: %v", err)
+	}
+	if !Verify(publicKey, message, sig) {
+		t.Fatalf("adapted signature failed to verify")
+	}
+	if !VerifyWithOptions(publicKey, message, sig, &Options{}) {
+		t.Fatalf("adapted signature failed to verify with options")
+	}
+
+	badSig, _ := Adapt(preSig, otherSecret)
+	if Verify(publicKey, message, badSig) {
+		t.Fatalf("signature adapted with wrong secret verified")
+	}
+
+	extracted, err := ExtractAdaptorSecret(sig, preSig, point)
+	if err != nil {
+		t.Fatalf("ExtractAdaptorSecret: %v", err)
+	}
+	if !bytes.Equal(extracted, secret) {
+		t.Fatalf("extracted secret mismatch: %x != %x", extracted, secret)
+	}
+	if _, err = ExtractAdaptorSecret(sig, preSig, otherPoint); err == nil {
+		t.Fatalf("ExtractAdaptorSecret succeeded with wrong adaptor point")
+	}
+	if _, err = ExtractAdaptorSecret(Sign(privateKey, message), preSig, point); err == nil {
+		t.Fatalf("ExtractAdaptorSecret succeeded with unrelated signature")
+	}
+}
+
+func TestAdaptorBadInputs(t *testing.T) {
+	_, privateKey, _ := GenerateKey(nil)
+	message := []byte("test message")
+
+	identity := make([]byte, AdaptorPointSize)
+	identity[0] = 1
+	nonCanonical := bytes.Repeat([]byte{0xff}, AdaptorPointSize)
+	nonCanonical[31] = 0x7f
+	for _, v := range [][]byte{
+		identity,
+		nonCanonical,
+		smallOrderPoints[4][:],
+		make([]byte, AdaptorPointSize-1),
+	} {
+		if _, err := PreSign(privateKey, message, v); err == nil {
+			t.Fatalf("PreSign accepted invalid adaptor point: %x", v)
+		}
+	}
+
+	if _, err := AdaptorPoint(make([]byte, AdaptorSecretSize)); err == nil {
+		t.Fatalf("AdaptorPoint accepted zero secret")
+	}
+	if _, err := AdaptorPoint(bytes.Repeat([]byte{0xff}, AdaptorSecretSize)); err == nil {
+		t.Fatalf("AdaptorPoint accepted non-canonical secret")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ed25519
+
+import (
+	"crypto/sha512"
+	"errors"
+	"strconv"
+
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+// BlindingKeySize is the size, in bytes, of key blinding keys.
+const BlindingKeySize = 32
+
+var (
+	errInvalidBlindingKey = errors.New("ed25519: invalid blinding key")
+	errInvalidPublicKey   = errors.New("ed25519: invalid public key")
+)
+
+// deriveBlindingScalar derives the blinding scalar s and the nonce prefix
+// from the blinding key and context, per
+// draft-irtf-cfrg-signature-key-blinding:
+//
+//	hd1 = SHA-512(bk || ctx)
+//	s = hd1[0..32] mod L (without pruning)
+//	prefix = hd1[32..64]
+func deriveBlindingScalar(s *modm.Bignum256, prefix *[32]byte, blindingKey, context []byte) error {
+	if len(blindingKey) != BlindingKeySize {
+		return errInvalidBlindingKey
+	}
+
+	var hd1 [64]byte
+	h := sha512.New()
+	_, _ = h.Write(blindingKey)
+	_, _ = h.Write(context)
+	h.Sum(hd1[:0])
+	h.Reset()
+
+	modm.Expand(s, hd1[:32])
+	if prefix != nil {
+		copy(prefix[:], hd1[32:])
+	}
+	for i := range hd1 {
+		hd1[i] = 0
+	}
+
+	if modm.IsZeroVartime(s) {
+		return errInvalidBlindingKey
+	}
+
+	return nil
+}
+
+func unpackPublicKey(A *ge25519.Ge25519, publicKey PublicKey) error {
+	if len(publicKey) != PublicKeySize || !ge25519.UnpackVartime(A, publicKey) || isSmallOrderVartime(publicKey) {
+		return errInvalidPublicKey
+	}
+	return nil
+}
+
+// BlindPublicKey blinds publicKey with the blinding key and context,
+// producing a public key that can not be linked to publicKey without
+// knowledge of the blinding key.  Signatures under the blinded public key
+// can be produced with BlindKeySign, and verified with Verify.
+func BlindPublicKey(publicKey PublicKey, blindingKey, context []byte) (PublicKey, error) {
+	var (
+		s modm.Bignum256
+		A ge25519.Ge25519
+	)
+	if err := unpackPublicKey(&A, publicKey); err != nil {
+		return nil, err
+	}
+	if err := deriveBlindingScalar(&s, nil, blindingKey, context); err != nil {
+		return nil, err
+	}
+
+	// pkR = [s]pkS
+	blinded := make(PublicKey, PublicKeySize)
+	ge25519.Scalarmult(&A, &A, &s)
+	ge25519.Pack(blinded, &A)
+	s.Reset()
+
+	return blinded, nil
+}
+
+// UnblindPublicKey recovers the public key that was blinded with the
+// blinding key and context to produce blindedPublicKey.
+func UnblindPublicKey(blindedPublicKey PublicKey, blindingKey, context []byte) (PublicKey, error) {
+	var (
+		s, sInv modm.Bignum256
+		A       ge25519.Ge25519
+	)
+	if err := unpackPublicKey(&A, blindedPublicKey); err != nil {
+		return nil, err
+	}
+	if err := deriveBlindingScalar(&s, nil, blindingKey, context); err != nil {
+		return nil, err
+	}
+
+	// pkS = [s^-1]pkR
+	unblinded := make(PublicKey, PublicKeySize)
+	modm.Invert(&sInv, &s)
+	ge25519.Scalarmult(&A, &A, &sInv)
+	ge25519.Pack(unblinded, &A)
+	s.Reset()
+	sInv.Reset()
+
+	return unblinded, nil
+}
+
+// BlindKeySign signs the message with privateKey blinded by the blinding
+// key and context, producing a signature that can be verified with Verify,
+// using the public key returned by BlindPublicKey.  It will panic if
+// len(privateKey) is not PrivateKeySize.
+func BlindKeySign(privateKey PrivateKey, blindingKey, context, message []byte) ([]byte, error) {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+
+	var (
+		extsk, prefix [64]byte
+		blindPrefix   [32]byte
+		s0, s1, s     modm.Bignum256
+		A             ge25519.Ge25519
+		publicKey     [PublicKeySize]byte
+	)
+	if err := deriveBlindingScalar(&s1, &blindPrefix, blindingKey, context); err != nil {
+		return nil, err
+	}
+
+	expandSeed(&extsk, privateKey[:32])
+
+	// s = s0 * s1
+	modm.Expand(&s0, extsk[:32])
+	modm.Mul(&s, &s0, &s1)
+
+	// prefix = H(d0[32..64] || hd1[32..64])
+	h := sha512.New()
+	_, _ = h.Write(extsk[32:])
+	_, _ = h.Write(blindPrefix[:])
+	h.Sum(prefix[:0])
+	h.Reset()
+
+	// pkR = [s]B
+	ge25519.ScalarmultBaseNiels(&A, &ge25519.NielsBaseMultiples, &s)
+	ge25519.Pack(publicKey[:], &A)
+
+	sig := signWithScalar(&s, prefix[:32], publicKey[:], message, fPure, nil, nil)
+
+	s0.Reset()
+	s1.Reset()
+	s.Reset()
+	for i := range extsk {
+		extsk[i] = 0
+	}
+	for i := range prefix {
+		prefix[i] = 0
+	}
+	for i := range blindPrefix {
+		blindPrefix[i] = 0
+	}
+
+	return sig, nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeyBlinding(t *testing.T) {
+	publicKey, privateKey, _ := GenerateKey(nil)
+	blindingKey := make([]byte, BlindingKeySize)
+	if _, err := rand.Read(blindingKey); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	context := []byte("epoch 1")
+	message := []byte("test message")
+
+	blinded, err := BlindPublicKey(publicKey, blindingKey, context)
+	if err != nil {
+		t.Fatalf("BlindPublicKey: %v", err)
+	}
+	if bytes.Equal(blinded, publicKey) {
+		t.Fatalf("blinded public key equals public key")
+	}
+
+	unblinded, err := UnblindPublicKey(blinded, blindingKey, context)
+	if err != nil {
+		t.Fatalf("UnblindPublicKey: %v", err)
+	}
+	if !bytes.Equal(unblinded, publicKey) {
+		t.Fatalf("unblinded public key mismatch: %x != %x", unblinded, publicKey)
+	}
+
+	sig, err := BlindKeySign(privateKey, blindingKey, context, message)
+	if err != nil {
+		t.Fatalf("BlindKeySign: %v", err)
+	}
+	if !Verify(blinded, message, sig) {
+		t.Fatalf("blinded signature failed to verify")
+	}
+	if Verify(publicKey, message, sig) {
+		t.Fatalf("blinded signature verified with unblinded key")
+	}
+	sig2, _ := BlindKeySign(privateKey, blindingKey, context, message)
+	if !bytes.Equal(sig, sig2) {
+		t.Fatalf("BlindKeySign is not deterministic")
+	}
+	if bytes.Equal(sig, Sign(privateKey, message)) {
+		t.Fatalf("blinded signature equals unblinded signature")
+	}
+
+	// A different context results in an unlinkable key.
+	otherBlinded, err := BlindPublicKey(publicKey, blindingKey, []byte("epoch 2"))
+	if err != nil {
+		t.Fatalf("BlindPublicKey: %v", err)
+	}
+	if bytes.Equal(blinded, otherBlinded) {
+		t.Fatalf("blinded public key is independent of context")
+	}
+	if Verify(otherBlinded, message, sig) {
+		t.Fatalf("blinded signature verified under a different context")
+	}
+}
+
+func TestKeyBlindingVectors(t *testing.T) {
+	// Ed25519 test vector from draft-irtf-cfrg-signature-key-blinding,
+	// Appendix A.
+	skS, _ := hex.DecodeString("875532ab039b0a154161c284e19c74afa28d5bf5454e99284bbcffaa71eebf45")
+	pkS, _ := hex.DecodeString("3b5983605b277cd44918410eb246bb52d83adfc806ccaa91a60b5b2011bc5973")
+	bk, _ := hex.DecodeString("c461e8595f0ac41d374f878613206704978115a226f60470ffd566e9e6ae73bf")
+	pkR, _ := hex.DecodeString("e52bbb204e72a816854ac82c7e244e13a8fcc3217cfdeb90c8a5a927e741a20f")
+	message, _ := hex.DecodeString("68656c6c6f20776f726c64")
+
+	privateKey := NewKeyFromSeed(skS)
+	publicKey := privateKey.Public().(PublicKey)
+	if !bytes.Equal(publicKey, pkS) {
+		t.Fatalf("public key mismatch: %x", publicKey)
+	}
+
+	blinded, err := BlindPublicKey(publicKey, bk, nil)
+	if err != nil {
+		t.Fatalf("BlindPublicKey: %v", err)
+	}
+	if !bytes.Equal(blinded, pkR) {
+		t.Fatalf("BlindPublicKey: got %x", blinded)
+	}
+
+	unblinded, err := UnblindPublicKey(pkR, bk, nil)
+	if err != nil {
+		t.Fatalf("UnblindPublicKey: %v", err)
+	}
+	if !bytes.Equal(unblinded, pkS) {
+		t.Fatalf("UnblindPublicKey: got %x", unblinded)
+	}
+
+	sig, err := BlindKeySign(privateKey, bk, nil, message)
+	if err != nil {
+		t.Fatalf("BlindKeySign: %v", err)
+	}
+	if !Verify(pkR, message, sig) {
+		t.Fatalf("BlindKeySign: signature does not verify under pkR")
+	}
+}
+
+func TestKeyBlindingBadInputs(t *testing.T) {
+	publicKey, _, _ := GenerateKey(nil)
+	blindingKey := make([]byte, BlindingKeySize)
+
+	if _, err := BlindPublicKey(publicKey, blindingKey[:31], nil); err == nil {
+		t.Fatalf("BlindPublicKey accepted a short blinding key")
+	}
+	if _, err := BlindPublicKey(smallOrderPoints[4][:], blindingKey, nil); err == nil {
+		t.Fatalf("BlindPublicKey accepted a small order public key")
+	}
+	if _, err := UnblindPublicKey(publicKey[:31], blindingKey, nil); err == nil {
+		t.Fatalf("UnblindPublicKey accepted a short public key")
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Command ed25519-conformance runs the test vectors shipped under testdata/
+// through every verification mode, and reports which cases are accepted
+// or rejected, so that the behavior can be documented and compared
+// between releases.
+//
+// Usage:
+//
+//	ed25519-conformance [-testdata dir] [-suites list] [-stdlib] [-differences] [-format text|json]
+//
+// The modes are the default (RFC 8032 with the additional checks
+// documented in the package), ZIP-215, and batch verification with
+// either.  With -stdlib, crypto/ed25519 is also included for comparison.
+//
+// The exit status is 1 if a case contradicts the result mandated by its
+// test suite (in the default mode, or the ZIP-215 mode for the ZIP-215
+// vectors), and 2 on errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// result is the outcome of a test case across the verification modes.
+type result struct {
+	Suite        string            `json:"suite"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description,omitempty"`
+	Expected     string            `json:"expected,omitempty"`
+	ExpectedMode string            `json:"expected_mode,omitempty"`
+	Results      map[string]string `json:"results"`
+}
+
+// agrees returns true iff every verification mode produced the same
+// result.
+func (r *result) agrees() bool {
+	var prev string
+	for _, v := range r.Results {
+		if v == resultUnsupported {
+			continue
+		}
+		if prev != "" && v != prev {
+			return false
+		}
+		prev = v
+	}
+	return true
+}
+
+// conforms returns true iff the verification mode that the test suite
+// applies to produced the mandated result, if any.
+func (r *result) conforms() bool {
+	switch r.Expected {
+	case expectedValid:
+		return r.Results[r.ExpectedMode] == resultAccept
+	case expectedInvalid:
+		return r.Results[r.ExpectedMode] == resultReject
+	default:
+		return true
+	}
+}
+
+// report is the output of a conformance run.
+type report struct {
+	GoVersion string    `json:"go_version"`
+	Modes     []string  `json:"modes"`
+	Cases     []*result `json:"cases"`
+}
+
+func run(cases []*testCase, modes []mode) (*report, error) {
+	rep := &report{
+		GoVersion: runtime.Version(),
+	}
+	for _, m := range modes {
+		rep.Modes = append(rep.Modes, m.name)
+	}
+
+	for _, c := range cases {
+		r := &result{
+			Suite:        c.suite,
+			Name:         c.name,
+			Description:  c.description,
+			Expected:     c.expected,
+			ExpectedMode: c.expectedMode,
+			Results:      make(map[string]string),
+		}
+		for _, m := range modes {
+			v, err := m.verify(c)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s: %s: %v", c.suite, c.name, m.name, err)
+			}
+			r.Results[m.name] = v
+		}
+		rep.Cases = append(rep.Cases, r)
+	}
+
+	return rep, nil
+}
+
+// filter returns a report with only the cases where the verification
+// modes disagree, or that do not conform to their test suite.
+func (rep *report) filter() *report {
+	filtered := &report{
+		GoVersion: rep.GoVersion,
+		Modes:     rep.Modes,
+	}
+	for _, r := range rep.Cases {
+		if !r.agrees() || !r.conforms() {
+			filtered.Cases = append(filtered.Cases, r)
+		}
+	}
+	return filtered
+}
+
+func (rep *report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "SUITE\tCASE\tEXPECTED")
+	for _, m := range rep.Modes {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(m))
+	}
+	fmt.Fprintf(tw, "\tDESCRIPTION\n")
+	for _, r := range rep.Cases {
+		expected := r.Expected
+		switch {
+		case expected == "":
+			expected = "-"
+		case r.ExpectedMode != modeDefault:
+			expected += " (" + r.ExpectedMode + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s", r.Suite, r.Name, expected)
+		for _, m := range rep.Modes {
+			fmt.Fprintf(tw, "\t%s", r.Results[m])
+		}
+		fmt.Fprintf(tw, "\t%s\n", r.Description)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Summarize the number of accepted cases per suite.
+	fmt.Fprintf(w, "\nAccepted cases (%s):\n", rep.GoVersion)
+	fmt.Fprintf(tw, "SUITE\tCASES")
+	for _, m := range rep.Modes {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(m))
+	}
+	fmt.Fprintf(tw, "\n")
+	for _, s := range suites {
+		var (
+			total    int
+			accepted = make(map[string]int)
+		)
+		for _, r := range rep.Cases {
+			if r.Suite != s.name {
+				continue
+			}
+			total++
+			for m, v := range r.Results {
+				if v == resultAccept {
+					accepted[m]++
+				}
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d", s.name, total)
+		for _, m := range rep.Modes {
+			fmt.Fprintf(tw, "\t%d", accepted[m])
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	return tw.Flush()
+}
+
+func (rep *report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+func main() {
+	var (
+		testdata    = flag.String("testdata", "testdata", "test vector `directory`")
+		suiteList   = flag.String("suites", "", "comma separated `list` of test suites to run (default all)")
+		withStdlib  = flag.Bool("stdlib", false, "also verify with crypto/ed25519")
+		differences = flag.Bool("differences", false, "only report cases where the modes disagree, or that contradict their test suite")
+		format      = flag.String("format", "text", "output `format`: text or json")
+	)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: ed25519-conformance [flags]\n\nSuites:")
+		for _, s := range suites {
+			fmt.Fprintf(os.Stderr, " %s", s.name)
+		}
+		fmt.Fprintf(os.Stderr, "\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := conformance(os.Stdout, *testdata, *suiteList, *withStdlib, *differences, *format); err != nil {
+		if err == errNonConformant {
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "ed25519-conformance: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+var errNonConformant = fmt.Errorf("non-conformant results")
+
+func conformance(w io.Writer, testdata, suiteList string, withStdlib, differences bool, format string) error {
+	var write func(*report, io.Writer) error
+	switch format {
+	case "text":
+		write = (*report).writeText
+	case "json":
+		write = (*report).writeJSON
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	var names []string
+	if suiteList != "" {
+		names = strings.Split(suiteList, ",")
+	}
+	cases, err := loadSuites(testdata, names)
+	if err != nil {
+		return err
+	}
+	modes, err := activeModes(withStdlib)
+	if err != nil {
+		return err
+	}
+
+	rep, err := run(cases, modes)
+	if err != nil {
+		return err
+	}
+	conforms := true
+	for _, r := range rep.Cases {
+		conforms = conforms && r.conforms()
+	}
+	if differences {
+		rep = rep.filter()
+	}
+
+	if err = write(rep, w); err != nil {
+		return err
+	}
+	if !conforms {
+		return errNonConformant
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testdataDir = "../../testdata"
+
+func TestConformance(t *testing.T) {
+	cases, err := loadSuites(testdataDir, nil)
+	if err != nil {
+		t.Fatalf("loadSuites: %v", err)
+	}
+	modes, err := activeModes(stdlibMode != nil)
+	if err != nil {
+		t.Fatalf("activeModes: %v", err)
+	}
+	rep, err := run(cases, modes)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	numCases := make(map[string]int)
+	for _, r := range rep.Cases {
+		numCases[r.Suite]++
+		if !r.conforms() {
+			t.Errorf("%s/%s: non-conformant: %v", r.Suite, r.Name, r.Results)
+		}
+
+		// Batch verification must match individual verification.
+		if r.Results["batch"] != r.Results[modeDefault] || r.Results["batch-zip215"] != r.Results[modeZIP215] {
+			t.Errorf("%s/%s: batch verification mismatch: %v", r.Suite, r.Name, r.Results)
+		}
+	}
+	for _, s := range suites {
+		if numCases[s.name] == 0 {
+			t.Errorf("%s: no test cases", s.name)
+		}
+	}
+
+	// Matches speccheckExpectedResults and speccheckExpectedResultsZIP215
+	// in the package tests.
+	var speccheckDefault, speccheckZIP215 []string
+	for _, r := range rep.Cases {
+		if r.Suite == "speccheck" {
+			speccheckDefault = append(speccheckDefault, r.Results[modeDefault][:1])
+			speccheckZIP215 = append(speccheckZIP215, r.Results[modeZIP215][:1])
+		}
+	}
+	if s := strings.Join(speccheckDefault, ""); s != "rrraaarrrrrr" {
+		t.Errorf("speccheck: unexpected default results: %s", s)
+	}
+	if s := strings.Join(speccheckZIP215, ""); s != "aaaaaarrraaa" {
+		t.Errorf("speccheck: unexpected ZIP-215 results: %s", s)
+	}
+
+	filtered := rep.filter()
+	for _, r := range filtered.Cases {
+		if r.agrees() && r.conforms() {
+			t.Errorf("%s/%s: unexpectedly reported as a difference", r.Suite, r.Name)
+		}
+	}
+	if len(filtered.Cases) == 0 || len(filtered.Cases) == len(rep.Cases) {
+		t.Errorf("filter: unexpected number of cases: %d", len(filtered.Cases))
+	}
+}
+
+func TestConformanceOutput(t *testing.T) {
+	var buf bytes.Buffer
+	if err := conformance(&buf, testdataDir, "speccheck,rfc8032", false, false, "json"); err != nil {
+		t.Fatalf("conformance: %v", err)
+	}
+	var rep report
+	if err := json.Unmarshal(buf.Bytes(), &rep); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if len(rep.Cases) != 16 || len(rep.Modes) != len(modes) {
+		t.Fatalf("unexpected report: %d cases, modes %v", len(rep.Cases), rep.Modes)
+	}
+	if rep.Cases[0].Suite != "rfc8032" || rep.Cases[0].Results[modeDefault] != resultAccept {
+		t.Fatalf("unexpected first case: %+v", rep.Cases[0])
+	}
+
+	buf.Reset()
+	if err := conformance(&buf, testdataDir, "zip215", false, true, "text"); err != nil {
+		t.Fatalf("conformance: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if summary := strings.Join(strings.Fields(lines[len(lines)-1]), " "); summary != "zip215 196 0 196 0 196" {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+
+	for _, tc := range []struct {
+		suites, format string
+	}{
+		{"bogus", "text"},
+		{"zip215", "bogus"},
+	} {
+		if err := conformance(&buf, testdataDir, tc.suites, false, false, tc.format); err == nil {
+			t.Errorf("conformance(%s, %s): expected an error", tc.suites, tc.format)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	modeDefault = "default"
+	modeZIP215  = "zip215"
+
+	resultAccept      = "accept"
+	resultReject      = "reject"
+	resultUnsupported = "n/a"
+
+	// batchSize is the number of copies of a test case that are batch
+	// verified, which is large enough for VerifyBatch to use the batch
+	// verification equation rather than verifying each signature
+	// individually.
+	batchSize = 8
+)
+
+// mode is a verification mode.
+type mode struct {
+	name   string
+	verify func(c *testCase) (string, error)
+}
+
+var modes = []mode{
+	{modeDefault, func(c *testCase) (string, error) { return verify(c, false) }},
+	{modeZIP215, func(c *testCase) (string, error) { return verify(c, true) }},
+	{"batch", func(c *testCase) (string, error) { return verifyBatch(c, false) }},
+	{"batch-zip215", func(c *testCase) (string, error) { return verifyBatch(c, true) }},
+}
+
+// stdlibMode verifies with crypto/ed25519, and is only available if the
+// runtime library provides it.
+var stdlibMode *mode
+
+func toResult(ok bool) string {
+	if ok {
+		return resultAccept
+	}
+	return resultReject
+}
+
+func verify(c *testCase, zip215 bool) (string, error) {
+	// VerifyWithOptions panics on malformed public keys.
+	if len(c.publicKey) != ed25519.PublicKeySize {
+		return resultReject, nil
+	}
+
+	opts := &ed25519.Options{
+		Context:      c.context,
+		ZIP215Verify: zip215,
+	}
+	return toResult(ed25519.VerifyWithOptions(c.publicKey, c.message, c.sig, opts)), nil
+}
+
+func verifyBatch(c *testCase, zip215 bool) (string, error) {
+	var (
+		publicKeys = make([]ed25519.PublicKey, 0, batchSize)
+		messages   = make([][]byte, 0, batchSize)
+		sigs       = make([][]byte, 0, batchSize)
+	)
+	for i := 0; i < batchSize; i++ {
+		publicKeys = append(publicKeys, c.publicKey)
+		messages = append(messages, c.message)
+		sigs = append(sigs, c.sig)
+	}
+
+	opts := &ed25519.Options{
+		Context:      c.context,
+		ZIP215Verify: zip215,
+	}
+	ok, valid, err := ed25519.VerifyBatch(nil, publicKeys, messages, sigs, opts)
+	if err != nil {
+		return "", err
+	}
+	for i, v := range valid {
+		if v != ok {
+			return "", fmt.Errorf("inconsistent batch verification result for signature %d", i)
+		}
+	}
+	return toResult(ok), nil
+}
+
+// activeModes returns the verification modes to run.
+func activeModes(withStdlib bool) ([]mode, error) {
+	if !withStdlib {
+		return modes, nil
+	}
+	if stdlibMode == nil {
+		return nil, fmt.Errorf("crypto/ed25519 requires Go 1.13 or later")
+	}
+	return append(append([]mode{}, modes...), *stdlibMode), nil
+}
@@ -0,0 +1,405 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	expectedValid   = "valid"
+	expectedInvalid = "invalid"
+)
+
+// testCase is a single signature verification test case.
+type testCase struct {
+	suite       string
+	name        string
+	description string
+
+	// expected is the result mandated by the test suite (expectedValid
+	// or expectedInvalid) for the expectedMode verification mode, or ""
+	// if the suite exists to document differences in behavior.
+	expected     string
+	expectedMode string
+
+	publicKey []byte
+	message   []byte
+	sig       []byte
+	context   string
+}
+
+// suite is a set of test vectors under testdata/.
+type suite struct {
+	name string
+	file string
+	load func(r io.Reader) ([]*testCase, error)
+}
+
+var suites = []suite{
+	{"golden", "sign.input.gz", loadGolden},
+	{"rfc8032", "rfc8032_ctx.json.gz", loadRFC8032},
+	{"wycheproof", "eddsa_test.json.gz", loadWycheproof},
+	{"speccheck", "speccheck_cases.json.gz", loadSpeccheck},
+	{"zip215", "zip215.json.gz", loadZIP215},
+}
+
+// loadSuites loads the named test suites (or all of them, if names is
+// empty) from the test vectors in dir.
+func loadSuites(dir string, names []string) ([]*testCase, error) {
+	var cases []*testCase
+
+	for _, name := range names {
+		if !hasSuite(name) {
+			return nil, fmt.Errorf("unknown suite %q", name)
+		}
+	}
+	for _, s := range suites {
+		if len(names) > 0 && !contains(names, s.name) {
+			continue
+		}
+
+		suiteCases, err := loadSuite(dir, &s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.name, err)
+		}
+		cases = append(cases, suiteCases...)
+	}
+
+	return cases, nil
+}
+
+func loadSuite(dir string, s *suite) ([]*testCase, error) {
+	f, err := os.Open(filepath.Join(dir, s.file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	cases, err := s.load(rd)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cases {
+		c.suite = s.name
+		if c.expected != "" && c.expectedMode == "" {
+			c.expectedMode = modeDefault
+		}
+	}
+	return cases, nil
+}
+
+func hasSuite(name string) bool {
+	for _, s := range suites {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeHex(what, s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", what, err)
+	}
+	return b, nil
+}
+
+// loadGolden loads a selection of test cases from
+// https://ed25519.cr.yp.to/python/sign.input, which are all valid.
+func loadGolden(r io.Reader) ([]*testCase, error) {
+	var cases []*testCase
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		parts := strings.Split(scanner.Text(), ":")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("bad number of parts on line %d", lineNo)
+		}
+
+		publicKey, err := decodeHex("public key", parts[1])
+		if err != nil {
+			return nil, err
+		}
+		msg, err := decodeHex("message", parts[2])
+		if err != nil {
+			return nil, err
+		}
+		sig, err := decodeHex("signature", parts[3])
+		if err != nil {
+			return nil, err
+		}
+		if len(sig) < 64 {
+			return nil, fmt.Errorf("bad signature length on line %d", lineNo)
+		}
+
+		// The signatures in the test vectors also include the message
+		// at the end, but only R and S are needed.
+		cases = append(cases, &testCase{
+			name:      strconv.Itoa(lineNo),
+			expected:  expectedValid,
+			publicKey: publicKey,
+			message:   msg,
+			sig:       sig[:64],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cases, nil
+}
+
+// loadRFC8032 loads the RFC 8032 Ed25519ctx test vectors.
+func loadRFC8032(r io.Reader) ([]*testCase, error) {
+	var vectors []struct {
+		Name      string `json:"name"`
+		PublicKey string `json:"public_key"`
+		Message   string `json:"message"`
+		Context   string `json:"context"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		return nil, err
+	}
+
+	var cases []*testCase
+	for _, v := range vectors {
+		publicKey, err := decodeHex("public key", v.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := decodeHex("message", v.Message)
+		if err != nil {
+			return nil, err
+		}
+		context, err := decodeHex("context", v.Context)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := decodeHex("signature", v.Signature)
+		if err != nil {
+			return nil, err
+		}
+
+		cases = append(cases, &testCase{
+			name:        v.Name,
+			description: "Ed25519ctx, context " + strconv.Quote(string(context)),
+			expected:    expectedValid,
+			publicKey:   publicKey,
+			message:     msg,
+			sig:         sig,
+			context:     string(context),
+		})
+	}
+
+	return cases, nil
+}
+
+// loadWycheproof loads the Project Wycheproof EdDSA test vectors.
+func loadWycheproof(r io.Reader) ([]*testCase, error) {
+	var vectors struct {
+		NumTests   int `json:"numberOfTests"`
+		TestGroups []struct {
+			Key struct {
+				PublicKey string `json:"pk"`
+			} `json:"key"`
+			Tests []struct {
+				ID        int    `json:"tcId"`
+				Comment   string `json:"comment"`
+				Message   string `json:"msg"`
+				Signature string `json:"sig"`
+				Result    string `json:"result"`
+			} `json:"tests"`
+		} `json:"testGroups"`
+	}
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		return nil, err
+	}
+
+	var cases []*testCase
+	for _, group := range vectors.TestGroups {
+		publicKey, err := decodeHex("public key", group.Key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range group.Tests {
+			msg, err := decodeHex("message", v.Message)
+			if err != nil {
+				return nil, err
+			}
+			sig, err := decodeHex("signature", v.Signature)
+			if err != nil {
+				return nil, err
+			}
+
+			var expected string
+			switch strings.ToLower(v.Result) {
+			case "valid":
+				expected = expectedValid
+			case "invalid":
+				expected = expectedInvalid
+			default:
+				return nil, fmt.Errorf("test case %d: unknown result %q", v.ID, v.Result)
+			}
+
+			cases = append(cases, &testCase{
+				name:        strconv.Itoa(v.ID),
+				description: v.Comment,
+				expected:    expected,
+				publicKey:   publicKey,
+				message:     msg,
+				sig:         sig,
+			})
+		}
+	}
+	if len(cases) != vectors.NumTests {
+		return nil, fmt.Errorf("unexpected number of test cases: %d (expected %d)", len(cases), vectors.NumTests)
+	}
+
+	return cases, nil
+}
+
+// speccheckDescriptions describes the test cases presented in the paper
+// "Taming the many EdDSAs" by Chalkias, Garillot, and Nikolaenko.  The
+// cases exist to document differences between implementations, so none
+// of them have an expected result.
+var speccheckDescriptions = []string{
+	"small order A, small order R",
+	"small order A, mixed order R",
+	"mixed order A, small order R",
+	"mixed order A, mixed order R",
+	"cofactored verify",
+	"cofactored verify computes 8(hA) instead of (8h mod L)A",
+	"non-canonical S (S > L)",
+	"non-canonical S (S >> L)",
+	"mixed order A, non-canonical small order R (accepted if R reduced before hashing)",
+	"mixed order A, non-canonical small order R (accepted if R not reduced before hashing)",
+	"non-canonical small order A, mixed order R (accepted if cofactored or A reduced before hashing)",
+	"non-canonical small order A, mixed order R (accepted if cofactored or A not reduced before hashing)",
+}
+
+// loadSpeccheck loads the ed25519-speccheck test cases.
+func loadSpeccheck(r io.Reader) ([]*testCase, error) {
+	var vectors []struct {
+		Message   string `json:"message"`
+		PublicKey string `json:"pub_key"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		return nil, err
+	}
+
+	var cases []*testCase
+	for i, v := range vectors {
+		publicKey, err := decodeHex("public key", v.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := decodeHex("message", v.Message)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := decodeHex("signature", v.Signature)
+		if err != nil {
+			return nil, err
+		}
+
+		var description string
+		if i < len(speccheckDescriptions) {
+			description = speccheckDescriptions[i]
+		}
+		cases = append(cases, &testCase{
+			name:        strconv.Itoa(i),
+			description: description,
+			publicKey:   publicKey,
+			message:     msg,
+			sig:         sig,
+		})
+	}
+
+	return cases, nil
+}
+
+// loadZIP215 loads the ZIP-215 test vectors, which are signatures of
+// "Zcash" that a ZIP-215 verifier must accept, and that RFC 8032
+// verifiers are expected to disagree on.
+func loadZIP215(r io.Reader) ([]*testCase, error) {
+	var vectors [][2]string
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		return nil, err
+	}
+
+	var cases []*testCase
+	for i, v := range vectors {
+		publicKey, err := decodeHex("public key", v[0])
+		if err != nil {
+			return nil, err
+		}
+		sig, err := decodeHex("signature", v[1])
+		if err != nil {
+			return nil, err
+		}
+
+		cases = append(cases, &testCase{
+			name:         strconv.Itoa(i),
+			expected:     expectedValid,
+			expectedMode: modeZIP215,
+			publicKey:    publicKey,
+			message:      []byte("Zcash"),
+			sig:          sig,
+		})
+	}
+
+	return cases, nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+// manifestEntry is a line of a batch verification manifest.
+type manifestEntry struct {
+	line      int
+	publicKey ed25519.PublicKey
+	sig       []byte
+	path      string
+}
+
+// parseManifest parses a batch verification manifest, with one
+// "<public key> <signature> <file>" entry per line.  The public keys and
+// signatures are hex or base64 encoded, and relative file names are
+// relative to dir.  Blank lines and lines starting with '#' are ignored.
+func parseManifest(r io.Reader, dir string) ([]manifestEntry, error) {
+	var entries []manifestEntry
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("manifest line %d: expected \"<public key> <signature> <file>\"", lineNum)
+		}
+		publicKey, err := parsePublicKey([]byte(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %v", lineNum, err)
+		}
+		sig, err := parseSignature([]byte(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %v", lineNum, err)
+		}
+
+		// The file name is the remainder of the line, and may contain
+		// spaces.
+		rest := strings.TrimSpace(line[len(fields[0]):])
+		path := strings.TrimSpace(rest[len(fields[1]):])
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		entries = append(entries, manifestEntry{
+			line:      lineNum,
+			publicKey: publicKey,
+			sig:       sig,
+			path:      path,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func runBatchVerify(args []string) error {
+	fs := newFlagSet("batch-verify")
+	manifestFile := fs.String("manifest", "", "manifest `file`, with one \"<public key> <signature> <file>\" entry per line")
+	mode := addModeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *manifestFile == "" {
+		return fmt.Errorf("missing -manifest")
+	}
+
+	opts, err := mode.options()
+	if err != nil {
+		return err
+	}
+	manifest, err := readFile(*manifestFile)
+	if err != nil {
+		return err
+	}
+	dir := "."
+	if *manifestFile != "-" {
+		dir = filepath.Dir(*manifestFile)
+	}
+	entries, err := parseManifest(bytes.NewReader(manifest), dir)
+	if err != nil {
+		return err
+	}
+
+	failed, err := batchVerify(os.Stdout, entries, opts)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d signatures failed verification", failed, len(entries))
+	}
+	return nil
+}
+
+// batchVerify verifies the manifest entries, reports the result of each
+// to w, and returns the number of failures.
+func batchVerify(w io.Writer, entries []manifestEntry, opts *ed25519.Options) (int, error) {
+	var (
+		publicKeys = make([]ed25519.PublicKey, 0, len(entries))
+		messages   = make([][]byte, 0, len(entries))
+		sigs       = make([][]byte, 0, len(entries))
+	)
+	for _, entry := range entries {
+		message, err := readMessageFile(entry.path, opts)
+		if err != nil {
+			return 0, fmt.Errorf("manifest line %d: %v", entry.line, err)
+		}
+		publicKeys = append(publicKeys, entry.publicKey)
+		messages = append(messages, message)
+		sigs = append(sigs, entry.sig)
+	}
+
+	_, valid, err := ed25519.VerifyBatch(nil, publicKeys, messages, sigs, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	var failed int
+	for i, entry := range entries {
+		status := "OK"
+		if !valid[i] {
+			status = "FAILED"
+			failed++
+		}
+		fmt.Fprintf(w, "%s: %s\n", entry.path, status)
+	}
+	return failed, nil
+}
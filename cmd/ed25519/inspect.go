@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+)
+
+// keyReport is the result of inspecting a public key.
+type keyReport struct {
+	canonical   bool
+	onCurve     bool
+	smallOrder  bool
+	torsionFree bool
+}
+
+// inspectPublicKey examines the encoding and the group properties of a
+// public key.
+func inspectPublicKey(publicKey ed25519.PublicKey) *keyReport {
+	var (
+		r    keyReport
+		p, q ge25519.Ge25519
+	)
+	if r.onCurve = ge25519.UnpackVartime(&p, publicKey); !r.onCurve {
+		return &r
+	}
+
+	// Non-canonical encodings (y >= p, or x = 0 with the sign bit set)
+	// do not survive a round trip.
+	var packed [ed25519.PublicKeySize]byte
+	ge25519.Pack(packed[:], &p)
+	r.canonical = bytes.Equal(packed[:], publicKey)
+
+	ge25519.CofactorMultiply(&q, &p)
+	r.smallOrder = ge25519.IsNeutralVartime(&q)
+	r.torsionFree = ge25519.IsTorsionFreeVartime(&p)
+
+	return &r
+}
+
+// ok returns true iff the public key is suitable for use.
+func (r *keyReport) ok() bool {
+	return r.onCurve && r.canonical && !r.smallOrder && r.torsionFree
+}
+
+func (r *keyReport) write(w io.Writer, publicKey ed25519.PublicKey) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	fmt.Fprintf(w, "public key:   %s\n", hex.EncodeToString(publicKey))
+	fmt.Fprintf(w, "on curve:     %s\n", yesNo(r.onCurve))
+	if r.onCurve {
+		fmt.Fprintf(w, "canonical:    %s\n", yesNo(r.canonical))
+		fmt.Fprintf(w, "small order:  %s\n", yesNo(r.smallOrder))
+		fmt.Fprintf(w, "torsion-free: %s\n", yesNo(r.torsionFree))
+	}
+
+	var status string
+	switch {
+	case !r.onCurve:
+		status = "invalid (not a point on the curve)"
+	case r.smallOrder:
+		status = "weak (small order, signatures are not bound to a signer)"
+	case !r.canonical:
+		status = "weak (non-canonical encoding)"
+	case !r.torsionFree:
+		status = "weak (has a torsion component, verifiers may disagree)"
+	default:
+		status = "ok"
+	}
+	fmt.Fprintf(w, "status:       %s\n", status)
+}
+
+func runInspect(args []string) error {
+	fs := newFlagSet("inspect")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: ed25519 inspect [file]\n\nInspects a public key, read from the file or the standard input.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	name := "-"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		name = fs.Arg(0)
+	default:
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	publicKey, err := loadPublicKey(name)
+	if err != nil {
+		return err
+	}
+	r := inspectPublicKey(publicKey)
+	r.write(os.Stdout, publicKey)
+	if !r.ok() {
+		return errors.New("public key rejected")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/pkix"
+)
+
+const (
+	formatSeed    = "seed"
+	formatPrivate = "private"
+)
+
+func runKeygen(args []string) error {
+	fs := newFlagSet("keygen")
+	format := fs.String("format", formatSeed, "private key format: `seed` (32 bytes) or private (64 bytes, seed and public key)")
+	encoding := fs.String("encoding", encodingHex, "key encoding: `hex`, base64, raw, or pem (PKCS #8 and PKIX, seed only)")
+	out := fs.String("out", "-", "private key output `file`, which must not exist")
+	pub := fs.String("pub", "", "public key output `file`, which must not exist (optional)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+	privateKeyBytes, publicKeyBytes, err := marshalKeyPair(privateKey, publicKey, *format, *encoding)
+	if err != nil {
+		return err
+	}
+
+	if err = writeFile(*out, privateKeyBytes, 0600); err != nil {
+		return err
+	}
+	if *pub != "" {
+		if err = writeFile(*pub, publicKeyBytes, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalKeyPair(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey, format, encoding string) ([]byte, []byte, error) {
+	if encoding == encodingPEM {
+		if format != formatSeed {
+			return nil, nil, errors.New("the pem encoding only supports the seed format")
+		}
+		privateKeyBytes, err := pkix.MarshalPKCS8PrivateKeyPEM(privateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		publicKeyBytes, err := pkix.MarshalPKIXPublicKeyPEM(publicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return privateKeyBytes, publicKeyBytes, nil
+	}
+
+	var b []byte
+	switch format {
+	case formatSeed:
+		b = privateKey.Seed()
+	case formatPrivate:
+		b = privateKey
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q", format)
+	}
+	privateKeyBytes, err := encode(b, encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKeyBytes, err := encode(publicKey, encoding)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKeyBytes, publicKeyBytes, nil
+}
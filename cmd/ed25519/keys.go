@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/pkix"
+)
+
+const (
+	encodingHex    = "hex"
+	encodingBase64 = "base64"
+	encodingRaw    = "raw"
+	encodingPEM    = "pem"
+)
+
+var errKeyMismatch = errors.New("private key does not match its public key")
+
+// decode decodes hex, base64 or raw binary data, of one of the expected
+// sizes.
+func decode(data []byte, sizes ...int) ([]byte, error) {
+	isExpectedSize := func(b []byte) bool {
+		for _, size := range sizes {
+			if len(b) == size {
+				return true
+			}
+		}
+		return false
+	}
+
+	text := strings.TrimSpace(string(data))
+	if b, err := hex.DecodeString(text); err == nil && isExpectedSize(b) {
+		return b, nil
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	} {
+		if b, err := enc.DecodeString(text); err == nil && isExpectedSize(b) {
+			return b, nil
+		}
+	}
+	if isExpectedSize(data) {
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("expected %v bytes, encoded as hex, base64 or raw binary", sizes)
+}
+
+// encode encodes data as hex, base64 or raw binary.  Text encodings are
+// terminated by a newline.
+func encode(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case encodingHex:
+		return []byte(hex.EncodeToString(data) + "\n"), nil
+	case encodingBase64:
+		return []byte(base64.StdEncoding.EncodeToString(data) + "\n"), nil
+	case encodingRaw:
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+func isPEM(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN "))
+}
+
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if isPEM(data) {
+		return pkix.ParsePKCS8PrivateKeyPEM(data)
+	}
+
+	b, err := decode(data, ed25519.SeedSize, ed25519.PrivateKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("malformed private key: %v", err)
+	}
+	privateKey := ed25519.NewKeyFromSeed(b[:ed25519.SeedSize])
+	if len(b) == ed25519.PrivateKeySize && subtle.ConstantTimeCompare(privateKey, b) != 1 {
+		return nil, errKeyMismatch
+	}
+	return privateKey, nil
+}
+
+func parsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if isPEM(data) {
+		return pkix.ParsePKIXPublicKeyPEM(data)
+	}
+
+	b, err := decode(data, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("malformed public key: %v", err)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+func parseSignature(data []byte) ([]byte, error) {
+	b, err := decode(data, ed25519.SignatureSize)
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %v", err)
+	}
+	return b, nil
+}
+
+func loadPrivateKey(name string) (ed25519.PrivateKey, error) {
+	data, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKey(data)
+}
+
+func loadPublicKey(name string) (ed25519.PublicKey, error) {
+	data, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return parsePublicKey(data)
+}
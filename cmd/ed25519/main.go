@@ -0,0 +1,163 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Command ed25519 generates Ed25519 keys, and creates and verifies
+// Ed25519, Ed25519ctx and Ed25519ph signatures.
+//
+// Usage:
+//
+//	ed25519 keygen [-format seed|private] [-encoding hex|base64|raw|pem] [-out file] [-pub file]
+//	ed25519 sign -key file [-mode pure|ctx|ph] [-context string] [-encoding hex|base64|raw] [-in file] [-out file]
+//	ed25519 verify -pub file -sig file [-mode pure|ctx|ph] [-context string] [-in file]
+//	ed25519 batch-verify -manifest file [-mode pure|ctx|ph] [-context string]
+//	ed25519 inspect [file]
+//
+// Keys and signatures are read as hex, base64, or raw binary, which is
+// detected automatically, and keys may also be PEM encoded PKCS #8 private
+// keys or PKIX (SubjectPublicKeyInfo) public keys.  Private keys may be
+// either the 32 byte seed, or the 64 byte seed and public key.  A file name
+// of "-" refers to the standard input or output.
+//
+// The exit status is 0 on success, 1 if a signature is invalid, a public
+// key is rejected by inspect, or another error occurs, and 2 for usage
+// errors.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"keygen", "generate a key pair", runKeygen},
+	{"sign", "sign a message", runSign},
+	{"verify", "verify a signature", runVerify},
+	{"batch-verify", "verify the signatures listed in a manifest", runBatchVerify},
+	{"inspect", "inspect a public key", runInspect},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != os.Args[1] {
+			continue
+		}
+		if err := cmd.run(os.Args[2:]); err != nil {
+			if err == flag.ErrHelp {
+				os.Exit(2)
+			}
+			fmt.Fprintf(os.Stderr, "ed25519 %s: %v\n", cmd.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if os.Args[1] != "help" && os.Args[1] != "-h" && os.Args[1] != "-help" {
+		fmt.Fprintf(os.Stderr, "ed25519: unknown command %q\n", os.Args[1])
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: ed25519 <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'ed25519 <command> -h' for the flags of a command.\n")
+}
+
+// newFlagSet returns a flag set for a command, which returns errors
+// instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("ed25519 "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseFlags parses the flags of a command that takes no positional
+// arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	return nil
+}
+
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+func openFile(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// writeFile writes data to the file, which must not already exist, with
+// the given permissions.
+func writeFile(name string, data []byte, perm os.FileMode) error {
+	if name == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+func TestDecode(t *testing.T) {
+	b := make([]byte, ed25519.PublicKeySize)
+	for i := range b {
+		b[i] = byte(i)
+	}
+
+	for _, encoding := range []string{encodingHex, encodingBase64, encodingRaw} {
+		encoded, err := encode(b, encoding)
+		if err != nil {
+			t.Fatalf("encode(%s): %v", encoding, err)
+		}
+		decoded, err := decode(encoded, ed25519.PublicKeySize)
+		if err != nil {
+			t.Fatalf("decode(%s): %v", encoding, err)
+		}
+		if !bytes.Equal(decoded, b) {
+			t.Fatalf("decode(%s): got %x", encoding, decoded)
+		}
+	}
+
+	for _, encoded := range []string{
+		base64.RawStdEncoding.EncodeToString(b),
+		base64.URLEncoding.EncodeToString(b),
+		"  " + hex.EncodeToString(b) + "\r\n",
+	} {
+		decoded, err := decode([]byte(encoded), ed25519.PublicKeySize)
+		if err != nil || !bytes.Equal(decoded, b) {
+			t.Fatalf("decode(%q): %x, %v", encoded, decoded, err)
+		}
+	}
+
+	if _, err := decode([]byte(hex.EncodeToString(b[1:])), ed25519.PublicKeySize); err == nil {
+		t.Fatalf("decode: accepted a truncated key")
+	}
+	if _, err := encode(b, encodingPEM); err == nil {
+		t.Fatalf("encode: accepted the pem encoding")
+	}
+}
+
+func TestKeyPair(t *testing.T) {
+	for _, tc := range []struct {
+		format, encoding string
+	}{
+		{formatSeed, encodingHex},
+		{formatSeed, encodingBase64},
+		{formatSeed, encodingRaw},
+		{formatSeed, encodingPEM},
+		{formatPrivate, encodingHex},
+		{formatPrivate, encodingBase64},
+		{formatPrivate, encodingRaw},
+	} {
+		publicKey, privateKey, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		privateKeyBytes, publicKeyBytes, err := marshalKeyPair(privateKey, publicKey, tc.format, tc.encoding)
+		if err != nil {
+			t.Fatalf("marshalKeyPair(%s, %s): %v", tc.format, tc.encoding, err)
+		}
+
+		parsedPrivateKey, err := parsePrivateKey(privateKeyBytes)
+		if err != nil {
+			t.Fatalf("parsePrivateKey(%s, %s): %v", tc.format, tc.encoding, err)
+		}
+		if !parsedPrivateKey.Equal(privateKey) {
+			t.Fatalf("parsePrivateKey(%s, %s): private key mismatch", tc.format, tc.encoding)
+		}
+		parsedPublicKey, err := parsePublicKey(publicKeyBytes)
+		if err != nil {
+			t.Fatalf("parsePublicKey(%s, %s): %v", tc.format, tc.encoding, err)
+		}
+		if !parsedPublicKey.Equal(publicKey) {
+			t.Fatalf("parsePublicKey(%s, %s): public key mismatch", tc.format, tc.encoding)
+		}
+	}
+
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	if _, _, err := marshalKeyPair(privateKey, publicKey, formatPrivate, encodingPEM); err == nil {
+		t.Fatalf("marshalKeyPair: accepted the private format with pem")
+	}
+
+	// A 64 byte private key must contain the matching public key.
+	b := append([]byte{}, privateKey...)
+	b[ed25519.PrivateKeySize-1] ^= 1
+	if _, err := parsePrivateKey(b); err != errKeyMismatch {
+		t.Fatalf("parsePrivateKey: accepted a mismatched public key: %v", err)
+	}
+}
+
+func TestModeOptions(t *testing.T) {
+	for _, tc := range []struct {
+		mode, context string
+		ok            bool
+	}{
+		{modePure, "", true},
+		{modePure, "foo", false},
+		{modeCtx, "foo", true},
+		{modeCtx, "", false},
+		{modeCtx, strings.Repeat("a", ed25519.ContextMaxSize+1), false},
+		{modePh, "", true},
+		{modePh, "foo", true},
+		{"bogus", "", false},
+	} {
+		m := &modeFlags{mode: &tc.mode, context: &tc.context}
+		opts, err := m.options()
+		if (err == nil) != tc.ok {
+			t.Fatalf("options(%s, %q): unexpected error: %v", tc.mode, tc.context, err)
+		}
+		if err != nil {
+			continue
+		}
+		if opts.Context != tc.context {
+			t.Fatalf("options(%s, %q): unexpected context: %q", tc.mode, tc.context, opts.Context)
+		}
+	}
+
+	// Ed25519ph signatures of the streamed digest must match signing the
+	// digest directly.
+	mode, context := modePh, "foo"
+	opts, _ := (&modeFlags{mode: &mode, context: &context}).options()
+	msg := []byte("test message")
+	digest, err := readMessage(bytes.NewReader(msg), opts)
+	if err != nil {
+		t.Fatalf("readMessage: %v", err)
+	}
+	expected := sha512.Sum512(msg)
+	if !bytes.Equal(digest, expected[:]) {
+		t.Fatalf("readMessage: got %x", digest)
+	}
+}
+
+func TestBatchVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ed25519-batch-verify")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	mode, context := modePure, ""
+	opts, _ := (&modeFlags{mode: &mode, context: &context}).options()
+
+	var manifest bytes.Buffer
+	manifest.WriteString("# name with spaces, bad signature, good signature\n\n")
+	for i, name := range []string{"msg 0", "msg1", "msg2"} {
+		msg := []byte(name)
+		if err = ioutil.WriteFile(filepath.Join(dir, name), msg, 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+		sig, _ := privateKey.Sign(nil, msg, opts)
+		if i == 1 {
+			sig[0] ^= 1
+		}
+		manifest.WriteString(hex.EncodeToString(publicKey) + " " + base64.StdEncoding.EncodeToString(sig) + "  " + name + "\n")
+	}
+
+	entries, err := parseManifest(&manifest, dir)
+	if err != nil {
+		t.Fatalf("parseManifest: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("parseManifest: got %d entries", len(entries))
+	}
+	if entries[0].path != filepath.Join(dir, "msg 0") || entries[0].line != 3 {
+		t.Fatalf("parseManifest: unexpected entry: %s (line %d)", entries[0].path, entries[0].line)
+	}
+
+	var out bytes.Buffer
+	failed, err := batchVerify(&out, entries, opts)
+	if err != nil {
+		t.Fatalf("batchVerify: %v", err)
+	}
+	if failed != 1 {
+		t.Fatalf("batchVerify: got %d failures", failed)
+	}
+	if !strings.Contains(out.String(), "msg1: FAILED\n") || strings.Count(out.String(), ": OK\n") != 2 {
+		t.Fatalf("batchVerify: unexpected output: %s", out.String())
+	}
+
+	for _, line := range []string{
+		"00 00 foo\n",
+		strings.Repeat("00", 32) + " " + strings.Repeat("00", 64) + "\n",
+	} {
+		if _, err = parseManifest(strings.NewReader(line), dir); err == nil {
+			t.Fatalf("parseManifest(%q): accepted a malformed line", line)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+
+	mustDecode := func(s string) ed25519.PublicKey {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			panic(err)
+		}
+		return b
+	}
+
+	for _, tc := range []struct {
+		name     string
+		key      ed25519.PublicKey
+		expected keyReport
+	}{
+		{
+			"random",
+			publicKey,
+			keyReport{canonical: true, onCurve: true, torsionFree: true},
+		},
+		{
+			"identity",
+			mustDecode("0100000000000000000000000000000000000000000000000000000000000000"),
+			keyReport{canonical: true, onCurve: true, smallOrder: true, torsionFree: true},
+		},
+		{
+			"order 4, non-canonical",
+			mustDecode("edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f"),
+			keyReport{onCurve: true, smallOrder: true},
+		},
+		{
+			"order 8",
+			mustDecode("c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a"),
+			keyReport{canonical: true, onCurve: true, smallOrder: true},
+		},
+		{
+			"not on curve",
+			mustDecode("0200000000000000000000000000000000000000000000000000000000000000"),
+			keyReport{},
+		},
+	} {
+		r := inspectPublicKey(tc.key)
+		if *r != tc.expected {
+			t.Fatalf("inspectPublicKey(%s): got %+v", tc.name, *r)
+		}
+		if r.ok() != (tc.name == "random") {
+			t.Fatalf("inspectPublicKey(%s): unexpected ok: %v", tc.name, r.ok())
+		}
+	}
+}
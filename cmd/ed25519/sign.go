@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"crypto"
+	"crypto/sha512"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	modePure = "pure"
+	modeCtx  = "ctx"
+	modePh   = "ph"
+)
+
+// modeFlags are the flags that select the Ed25519 variant.
+type modeFlags struct {
+	mode    *string
+	context *string
+}
+
+func addModeFlags(fs *flag.FlagSet) *modeFlags {
+	return &modeFlags{
+		mode:    fs.String("mode", modePure, "signature scheme: `pure` (Ed25519), ctx (Ed25519ctx), or ph (Ed25519ph, for large files)"),
+		context: fs.String("context", "", "domain separation context `string`, required for ctx, and optional for ph"),
+	}
+}
+
+// options returns the Options of the selected Ed25519 variant.
+func (m *modeFlags) options() (*ed25519.Options, error) {
+	if len(*m.context) > ed25519.ContextMaxSize {
+		return nil, fmt.Errorf("context longer than %d bytes", ed25519.ContextMaxSize)
+	}
+
+	switch *m.mode {
+	case modePure:
+		if *m.context != "" {
+			return nil, errors.New("a context requires the ctx or ph mode")
+		}
+		return &ed25519.Options{}, nil
+	case modeCtx:
+		if *m.context == "" {
+			return nil, errors.New("the ctx mode requires a non-empty context")
+		}
+		return &ed25519.Options{Context: *m.context}, nil
+	case modePh:
+		return &ed25519.Options{Hash: crypto.SHA512, Context: *m.context}, nil
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", *m.mode)
+	}
+}
+
+// readMessage reads the message to be signed or verified from r, which is
+// hashed with SHA-512 for Ed25519ph.
+func readMessage(r io.Reader, opts *ed25519.Options) ([]byte, error) {
+	if opts.Hash != crypto.SHA512 {
+		return ioutil.ReadAll(r)
+	}
+
+	h := sha512.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func readMessageFile(name string, opts *ed25519.Options) ([]byte, error) {
+	f, err := openFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readMessage(f, opts)
+}
+
+func runSign(args []string) error {
+	fs := newFlagSet("sign")
+	keyFile := fs.String("key", "", "private key `file`")
+	mode := addModeFlags(fs)
+	encoding := fs.String("encoding", encodingHex, "signature encoding: `hex`, base64, or raw")
+	in := fs.String("in", "-", "message `file`")
+	out := fs.String("out", "-", "signature output `file`, which must not exist")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return errors.New("missing -key")
+	}
+
+	opts, err := mode.options()
+	if err != nil {
+		return err
+	}
+	privateKey, err := loadPrivateKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	message, err := readMessageFile(*in, opts)
+	if err != nil {
+		return err
+	}
+
+	sig, err := privateKey.Sign(nil, message, opts)
+	if err != nil {
+		return err
+	}
+	b, err := encode(sig, *encoding)
+	if err != nil {
+		return err
+	}
+	return writeFile(*out, b, 0644)
+}
+
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	pubFile := fs.String("pub", "", "public key `file`")
+	sigFile := fs.String("sig", "", "signature `file`")
+	mode := addModeFlags(fs)
+	in := fs.String("in", "-", "message `file`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *pubFile == "" || *sigFile == "" {
+		return errors.New("missing -pub or -sig")
+	}
+
+	opts, err := mode.options()
+	if err != nil {
+		return err
+	}
+	publicKey, err := loadPublicKey(*pubFile)
+	if err != nil {
+		return err
+	}
+	sigBytes, err := readFile(*sigFile)
+	if err != nil {
+		return err
+	}
+	sig, err := parseSignature(sigBytes)
+	if err != nil {
+		return err
+	}
+	message, err := readMessageFile(*in, opts)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.VerifyWithOptions(publicKey, message, sig, opts) {
+		return errInvalidSignature
+	}
+	fmt.Fprintln(os.Stdout, "Signature OK")
+	return nil
+}
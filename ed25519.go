@@ -66,6 +66,10 @@ const (
 
 	// ContextMaxSize is the maximum allowed context length for Ed25519ctx.
 	ContextMaxSize = 255
+
+	// ExpandedPrivateKeySize is the size, in bytes, of expanded private keys
+	// (the secret scalar followed by the nonce prefix).
+	ExpandedPrivateKeySize = 64
 )
 
 var _ crypto.Signer = (PrivateKey)(nil)
@@ -167,13 +171,18 @@ func (priv PrivateKey) Seed() []byte {
 // is crypto.SHA512, the pre-hashed variant Ed25519ph is used and message is
 // expected to be a SHA-512 hash, otherwise opts.HashFunc() must be
 // crypto.Hash(0) and the message must not be hashed, as Ed25519 performs two
-// passes over messages to be signed.
+// passes over messages to be signed.  opts may be *Options, or with Go 1.20
+// and later, the standard library's *crypto/ed25519.Options.
 func (priv PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
 	var (
 		context []byte
 		f       dom2Flag = fPure
 	)
-	if o, ok := opts.(*Options); ok {
+	o, ok := opts.(*Options)
+	if !ok {
+		o = fromStdSignerOpts(opts)
+	}
+	if o != nil {
 		f, context, err = o.unwrap()
 		if err != nil {
 			return nil, err
@@ -185,7 +194,7 @@ func (priv PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOp
 		return nil, err
 	}
 
-	return sign(priv, message, f, context), nil
+	return sign(priv, message, f, context, nil), nil
 }
 
 // PublicKey is the type of Ed25519 public keys.
@@ -206,41 +215,130 @@ func (pub PublicKey) Equal(x crypto.PublicKey) bool {
 // Sign signs the message with privateKey and returns a signature. It will
 // panic if len(privateKey) is not PrivateKeySize.
 func Sign(privateKey PrivateKey, message []byte) []byte {
-	return sign(privateKey, message, fPure, nil)
+	return sign(privateKey, message, fPure, nil, nil)
 }
 
-func sign(privateKey PrivateKey, message []byte, f dom2Flag, c []byte) []byte {
+// SignExpanded signs the message with an expanded private key, consisting of
+// the little-endian secret scalar followed by the nonce prefix, and returns a
+// signature.  This is intended for keys that are not derived from a seed
+// (eg: BIP32-Ed25519).  It will panic if len(expandedPrivateKey) is not
+// ExpandedPrivateKeySize.
+func SignExpanded(expandedPrivateKey, message []byte) []byte {
+	if l := len(expandedPrivateKey); l != ExpandedPrivateKeySize {
+		panic("ed25519: bad expanded private key length: " + strconv.Itoa(l))
+	}
+
+	var (
+		a         modm.Bignum256
+		A         ge25519.Ge25519
+		publicKey [PublicKeySize]byte
+	)
+
+	modm.Expand(&a, expandedPrivateKey[:32])
+	ge25519.ScalarmultBaseNiels(&A, &ge25519.NielsBaseMultiples, &a)
+	ge25519.Pack(publicKey[:], &A)
+
+	sig := signWithScalar(&a, expandedPrivateKey[32:], publicKey[:], message, fPure, nil, nil)
+	a.Reset()
+
+	return sig
+}
+
+func sign(privateKey PrivateKey, message []byte, f dom2Flag, c []byte, adaptor *adaptorPoint) []byte {
 	if l := len(privateKey); l != PrivateKeySize {
 		panic("ed25519: bad private key length: " + strconv.Itoa(l))
 	}
 
 	var (
-		extsk, hashr, hram [64]byte
-		r, S, a            modm.Bignum256
-		R                  ge25519.Ge25519
-
-		RS [SignatureSize]byte
+		extsk [64]byte
+		a     modm.Bignum256
 	)
 
+	expandSeed(&extsk, privateKey[:32])
+
+	modm.Expand(&a, extsk[:32])
+	sig := signWithScalar(&a, extsk[32:], privateKey[32:], message, f, c, adaptor)
+
+	a.Reset()
+	for i := range extsk {
+		extsk[i] = 0
+	}
+
+	return sig
+}
+
+// ExpandPrivateKey returns the expanded private key corresponding to
+// privateKey, consisting of the clamped little-endian secret scalar
+// followed by the nonce prefix, as used by SignExpanded.  It will panic if
+// len(privateKey) is not PrivateKeySize.
+func ExpandPrivateKey(privateKey PrivateKey) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+
+	var extsk [ExpandedPrivateKeySize]byte
+	expandSeed(&extsk, privateKey[:SeedSize])
+
+	expandedPrivateKey := make([]byte, ExpandedPrivateKeySize)
+	copy(expandedPrivateKey, extsk[:])
+	for i := range extsk {
+		extsk[i] = 0
+	}
+
+	return expandedPrivateKey
+}
+
+// expandSeed derives the expanded private key (the clamped secret scalar
+// followed by the nonce prefix) from seed, as specified in RFC 8032.
+func expandSeed(extsk *[ExpandedPrivateKeySize]byte, seed []byte) {
+	// `sha512.Sum512` does not call d.Reset(), but it's somewhat of a
+	// moot point because the runtime library's SHA-512 implementation's
+	// `Reset()` method doesn't actually clear the buffer currently.
 	h := sha512.New()
-	_, _ = h.Write(privateKey[:32])
+	_, _ = h.Write(seed)
 	h.Sum(extsk[:0])
+	h.Reset()
+
 	extsk[0] &= 248
 	extsk[31] &= 127
 	extsk[31] |= 64
+}
+
+// signWithScalar signs the message with the secret scalar a, the nonce
+// derivation prefix, and the public key A = aB.  This allows signing with
+// keys that are not derived from a seed (eg: blinded keys).
+func signWithScalar(a *modm.Bignum256, prefix, publicKey, message []byte, f dom2Flag, c []byte, adaptor *adaptorPoint) []byte {
+	var (
+		hashr, hram [64]byte
+		r, S        modm.Bignum256
+		R           ge25519.Ge25519
+
+		RS [SignatureSize]byte
+	)
 
 	// r = H(aExt[32..64], m)
-	h.Reset()
+	h := sha512.New()
 	if f != fPure {
 		writeDom2(h, f, c)
 	}
-	_, _ = h.Write(extsk[32:])
+	if adaptor != nil {
+		// r = H(adaptorPrefix, aExt[32..64], T, m)
+		_, _ = h.Write([]byte(adaptorPrefix))
+	}
+	_, _ = h.Write(prefix)
+	if adaptor != nil {
+		_, _ = h.Write(adaptor.packed[:])
+	}
 	_, _ = h.Write(message)
 	h.Sum(hashr[:0])
 	modm.Expand(&r, hashr[:])
 
 	// R = rB
 	ge25519.ScalarmultBaseNiels(&R, &ge25519.NielsBaseMultiples, &r)
+	if adaptor != nil {
+		// R = rB + T
+		ge25519.Add(&R, &R, &adaptor.point)
+	}
 	ge25519.Pack(RS[:], &R)
 
 	// S = H(R,A,m)..
@@ -249,14 +347,13 @@ func sign(privateKey PrivateKey, message []byte, f dom2Flag, c []byte) []byte {
 		writeDom2(h, f, c)
 	}
 	_, _ = h.Write(RS[:32])
-	_, _ = h.Write(privateKey[32:])
+	_, _ = h.Write(publicKey)
 	_, _ = h.Write(message)
 	h.Sum(hram[:0])
 	modm.Expand(&S, hram[:])
 
 	// S = H(R,A,m)a
-	modm.Expand(&a, extsk[:32])
-	modm.Mul(&S, &S, &a)
+	modm.Mul(&S, &S, a)
 
 	// S = (r + H(R,A,m)a)
 	modm.Add(&S, &S, &r)
@@ -265,10 +362,7 @@ func sign(privateKey PrivateKey, message []byte, f dom2Flag, c []byte) []byte {
 	modm.Contract(RS[32:], &S)
 
 	h.Reset()
-	a.Reset()
-	for i := range extsk {
-		extsk[i] = 0
-	}
+	r.Reset()
 
 	return RS[:]
 }
@@ -380,18 +474,8 @@ func NewKeyFromSeed(seed []byte) PrivateKey {
 		panic("ed25519: bad seed length: " + strconv.Itoa(l))
 	}
 
-	// `sha512.Sum512` does not call d.Reset(), but it's somewhat of a
-	// moot point because the runtime library's SHA-512 implementation's
-	// `Reset()` method doesn't actually clear the buffer currently.
 	var digest [64]byte
-	h := sha512.New()
-	_, _ = h.Write(seed)
-	h.Sum(digest[:0])
-	h.Reset()
-
-	digest[0] &= 248
-	digest[31] &= 127
-	digest[31] |= 64
+	expandSeed(&digest, seed)
 
 	var (
 		a              modm.Bignum256
@@ -94,6 +94,28 @@ func TestSignVerify(t *testing.T) {
 	}
 }
 
+func TestSignExpanded(t *testing.T) {
+	var zero zeroReader
+	public, private, _ := GenerateKey(zero)
+
+	expanded := sha512.Sum512(private.Seed())
+	expanded[0] &= 248
+	expanded[31] &= 127
+	expanded[31] |= 64
+	if !bytes.Equal(ExpandPrivateKey(private), expanded[:]) {
+		t.Errorf("ExpandPrivateKey mismatch")
+	}
+
+	message := []byte("test message")
+	sig := SignExpanded(expanded[:], message)
+	if !bytes.Equal(sig, Sign(private, message)) {
+		t.Errorf("expanded key signature mismatch")
+	}
+	if !Verify(public, message, sig) {
+		t.Errorf("valid signature rejected")
+	}
+}
+
 func TestSignVerifyHashed(t *testing.T) {
 	key, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42ec172b93ad5e563bf4932c70e1245034c35467ef2efd4d64ebf819683467e2bf")
 	expectedSig, _ := hex.DecodeString("98a70222f0b8121aa9d30f813d683f809e462b469c7ff87639499bb94e6dae4131f85042463c2a355a2003d062adf5aaa10b8c61e636062aaad11c2a26083406")
@@ -0,0 +1,379 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package bip32ed25519 implements BIP32-Ed25519 hierarchical deterministic
+// keys, as specified in "BIP32-Ed25519 Hierarchical Deterministic Keys over
+// a Non-linear Keyspace" by Khovratovich and Law, with the (V2) derivation
+// used by Cardano.
+//
+// Unlike SLIP-0010, non-hardened child public keys can be derived from the
+// parent public key and chain code alone, which allows watch-only wallets.
+// As the private keys are not derived from a seed, signing is done from
+// the extended private key directly, and the resulting signatures can be
+// verified with ed25519.Verify.
+package bip32ed25519
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+const (
+	// HardenedOffset is the index of the first hardened child key.
+	HardenedOffset uint32 = 0x80000000
+
+	// ChainCodeSize is the size, in bytes, of chain codes.
+	ChainCodeSize = 32
+
+	// ExtendedPrivateKeySize is the size, in bytes, of serialized extended
+	// private keys (kL || kR || c).
+	ExtendedPrivateKeySize = ed25519.ExpandedPrivateKeySize + ChainCodeSize
+
+	// ExtendedPublicKeySize is the size, in bytes, of serialized extended
+	// public keys (A || c).
+	ExtendedPublicKeySize = ed25519.PublicKeySize + ChainCodeSize
+
+	tagPrivateZ     = 0x00
+	tagPrivateChain = 0x01
+	tagPublicZ      = 0x02
+	tagPublicChain  = 0x03
+)
+
+var (
+	errInvalidPrivateKey = errors.New("bip32ed25519: invalid extended private key")
+	errInvalidPublicKey  = errors.New("bip32ed25519: invalid extended public key")
+	errInvalidSeed       = errors.New("bip32ed25519: seed produces an invalid master key")
+	errHardenedPublic    = errors.New("bip32ed25519: hardened derivation requires the private key")
+	errInvalidChild      = errors.New("bip32ed25519: child key is invalid")
+)
+
+// ExtendedPrivateKey is a BIP32-Ed25519 extended private key.
+type ExtendedPrivateKey struct {
+	k         [ed25519.ExpandedPrivateKeySize]byte // kL || kR
+	chainCode [ChainCodeSize]byte
+	publicKey [ed25519.PublicKeySize]byte
+}
+
+// ExtendedPublicKey is a BIP32-Ed25519 extended public key.
+type ExtendedPublicKey struct {
+	publicKey [ed25519.PublicKeySize]byte
+	chainCode [ChainCodeSize]byte
+}
+
+// NewMasterKey derives the master extended private key from a seed, as in
+// the original paper.  Approximately half of all seeds are rejected, as the
+// third highest bit of kL must be clear, and the caller is expected to
+// retry with a different seed.
+func NewMasterKey(seed []byte) (*ExtendedPrivateKey, error) {
+	k := sha512.Sum512(seed)
+	if k[31]&0x20 != 0 {
+		for i := range k {
+			k[i] = 0
+		}
+		return nil, errInvalidSeed
+	}
+	k[0] &= 248
+	k[31] &= 127
+	k[31] |= 64
+
+	// c = SHA-256(0x01 || seed)
+	h := sha256.New()
+	_, _ = h.Write([]byte{0x01})
+	_, _ = h.Write(seed)
+	chainCode := h.Sum(nil)
+
+	xprv := newExtendedPrivateKey(k[:], chainCode)
+	for i := range k {
+		k[i] = 0
+	}
+
+	return xprv, nil
+}
+
+// NewExtendedPrivateKey deserializes an extended private key (kL || kR || c).
+func NewExtendedPrivateKey(b []byte) (*ExtendedPrivateKey, error) {
+	if len(b) != ExtendedPrivateKeySize {
+		return nil, errInvalidPrivateKey
+	}
+
+	// kL must be a multiple of the cofactor, with the highest bit clear.
+	// The derivation scheme additionally requires the third highest bit
+	// of the master key be clear, but that does not hold for descendant
+	// keys.
+	if b[0]&7 != 0 || b[31]&0x80 != 0 {
+		return nil, errInvalidPrivateKey
+	}
+
+	return newExtendedPrivateKey(b[:ed25519.ExpandedPrivateKeySize], b[ed25519.ExpandedPrivateKeySize:]), nil
+}
+
+func newExtendedPrivateKey(k, chainCode []byte) *ExtendedPrivateKey {
+	var (
+		a modm.Bignum256
+		A ge25519.Ge25519
+	)
+
+	xprv := new(ExtendedPrivateKey)
+	copy(xprv.k[:], k)
+	copy(xprv.chainCode[:], chainCode)
+
+	// A = [kL]B
+	modm.Expand(&a, xprv.k[:32])
+	ge25519.ScalarmultBaseNiels(&A, &ge25519.NielsBaseMultiples, &a)
+	ge25519.Pack(xprv.publicKey[:], &A)
+	a.Reset()
+
+	return xprv
+}
+
+// Bytes returns the serialized extended private key (kL || kR || c).
+func (xprv *ExtendedPrivateKey) Bytes() []byte {
+	b := make([]byte, 0, ExtendedPrivateKeySize)
+	b = append(b, xprv.k[:]...)
+	return append(b, xprv.chainCode[:]...)
+}
+
+// ChainCode returns the chain code.
+func (xprv *ExtendedPrivateKey) ChainCode() []byte {
+	return append([]byte{}, xprv.chainCode[:]...)
+}
+
+// PublicKey returns the Ed25519 public key.
+func (xprv *ExtendedPrivateKey) PublicKey() ed25519.PublicKey {
+	return append(ed25519.PublicKey{}, xprv.publicKey[:]...)
+}
+
+// ExtendedPublicKey returns the extended public key.
+func (xprv *ExtendedPrivateKey) ExtendedPublicKey() *ExtendedPublicKey {
+	return &ExtendedPublicKey{
+		publicKey: xprv.publicKey,
+		chainCode: xprv.chainCode,
+	}
+}
+
+// Sign signs the message with the extended private key, and returns a
+// signature that can be verified with ed25519.Verify.
+func (xprv *ExtendedPrivateKey) Sign(message []byte) []byte {
+	return ed25519.SignExpanded(xprv.k[:], message)
+}
+
+// Derive derives the child extended private key at index.  Indexes at or
+// above HardenedOffset produce hardened children.
+func (xprv *ExtendedPrivateKey) Derive(index uint32) (*ExtendedPrivateKey, error) {
+	var (
+		z, c [sha512.Size]byte
+		k    [ed25519.ExpandedPrivateKeySize]byte
+		idx  [4]byte
+	)
+	binary.LittleEndian.PutUint32(idx[:], index)
+
+	// Z = HMAC-SHA512(c, 0x00 || kL || kR || i) (hardened)
+	// Z = HMAC-SHA512(c, 0x02 || A || i)
+	if index >= HardenedOffset {
+		hmacSHA512(&z, xprv.chainCode[:], tagPrivateZ, xprv.k[:], idx[:])
+		hmacSHA512(&c, xprv.chainCode[:], tagPrivateChain, xprv.k[:], idx[:])
+	} else {
+		hmacSHA512(&z, xprv.chainCode[:], tagPublicZ, xprv.publicKey[:], idx[:])
+		hmacSHA512(&c, xprv.chainCode[:], tagPublicChain, xprv.publicKey[:], idx[:])
+	}
+
+	// kL_i = 8 * ZL[0..28] + kL
+	var zl8 [32]byte
+	mul8(&zl8, z[:28])
+	add256(k[:32], zl8[:], xprv.k[:32])
+
+	// kR_i = ZR + kR mod 2^256
+	add256(k[32:], z[32:], xprv.k[32:])
+
+	child := newExtendedPrivateKey(k[:], c[32:])
+
+	zeroBytes(z[:])
+	zeroBytes(c[:])
+	zeroBytes(k[:])
+	zeroBytes(zl8[:])
+
+	// The child is unusable if kL_i is a multiple of the group order,
+	// which happens with negligible probability.
+	if isIdentity(child.publicKey[:]) {
+		return nil, errInvalidChild
+	}
+
+	return child, nil
+}
+
+// DeriveIndexes derives the descendant extended private key along the
+// indexes.
+func (xprv *ExtendedPrivateKey) DeriveIndexes(indexes []uint32) (*ExtendedPrivateKey, error) {
+	var err error
+	for _, index := range indexes {
+		if xprv, err = xprv.Derive(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return xprv, nil
+}
+
+// Reset clears the extended private key.
+func (xprv *ExtendedPrivateKey) Reset() {
+	zeroBytes(xprv.k[:])
+	zeroBytes(xprv.chainCode[:])
+}
+
+// NewExtendedPublicKey deserializes an extended public key (A || c).
+func NewExtendedPublicKey(b []byte) (*ExtendedPublicKey, error) {
+	var A ge25519.Ge25519
+	if len(b) != ExtendedPublicKeySize || !ge25519.UnpackVartime(&A, b[:ed25519.PublicKeySize]) {
+		return nil, errInvalidPublicKey
+	}
+
+	xpub := new(ExtendedPublicKey)
+	copy(xpub.publicKey[:], b)
+	copy(xpub.chainCode[:], b[ed25519.PublicKeySize:])
+
+	return xpub, nil
+}
+
+// Bytes returns the serialized extended public key (A || c).
+func (xpub *ExtendedPublicKey) Bytes() []byte {
+	b := make([]byte, 0, ExtendedPublicKeySize)
+	b = append(b, xpub.publicKey[:]...)
+	return append(b, xpub.chainCode[:]...)
+}
+
+// ChainCode returns the chain code.
+func (xpub *ExtendedPublicKey) ChainCode() []byte {
+	return append([]byte{}, xpub.chainCode[:]...)
+}
+
+// PublicKey returns the Ed25519 public key.
+func (xpub *ExtendedPublicKey) PublicKey() ed25519.PublicKey {
+	return append(ed25519.PublicKey{}, xpub.publicKey[:]...)
+}
+
+// Derive derives the non-hardened child extended public key at index.
+func (xpub *ExtendedPublicKey) Derive(index uint32) (*ExtendedPublicKey, error) {
+	if index >= HardenedOffset {
+		return nil, errHardenedPublic
+	}
+
+	var (
+		z, c [sha512.Size]byte
+		idx  [4]byte
+		zl8  [32]byte
+		s    modm.Bignum256
+		A, P ge25519.Ge25519
+	)
+	binary.LittleEndian.PutUint32(idx[:], index)
+
+	// Z = HMAC-SHA512(c, 0x02 || A || i)
+	hmacSHA512(&z, xpub.chainCode[:], tagPublicZ, xpub.publicKey[:], idx[:])
+	hmacSHA512(&c, xpub.chainCode[:], tagPublicChain, xpub.publicKey[:], idx[:])
+
+	if !ge25519.UnpackVartime(&A, xpub.publicKey[:]) {
+		return nil, errInvalidPublicKey
+	}
+
+	// A_i = A + [8 * ZL]B
+	mul8(&zl8, z[:28])
+	modm.Expand(&s, zl8[:])
+	ge25519.ScalarmultBaseNiels(&P, &ge25519.NielsBaseMultiples, &s)
+	ge25519.Add(&A, &A, &P)
+
+	child := new(ExtendedPublicKey)
+	ge25519.Pack(child.publicKey[:], &A)
+	copy(child.chainCode[:], c[32:])
+	if isIdentity(child.publicKey[:]) {
+		return nil, errInvalidChild
+	}
+
+	return child, nil
+}
+
+// DeriveIndexes derives the descendant extended public key along the
+// (non-hardened) indexes.
+func (xpub *ExtendedPublicKey) DeriveIndexes(indexes []uint32) (*ExtendedPublicKey, error) {
+	var err error
+	for _, index := range indexes {
+		if xpub, err = xpub.Derive(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return xpub, nil
+}
+
+func hmacSHA512(out *[sha512.Size]byte, key []byte, tag byte, data ...[]byte) {
+	mac := hmac.New(sha512.New, key)
+	_, _ = mac.Write([]byte{tag})
+	for _, v := range data {
+		_, _ = mac.Write(v)
+	}
+	mac.Sum(out[:0])
+}
+
+// mul8 sets out = 8 * x, where x is a 28 byte little-endian integer.
+func mul8(out *[32]byte, x []byte) {
+	var carry byte
+	for i := 0; i < 28; i++ {
+		out[i] = (x[i] << 3) | carry
+		carry = x[i] >> 5
+	}
+	out[28] = carry
+	out[29], out[30], out[31] = 0, 0, 0
+}
+
+// add256 sets out = x + y mod 2^256, where x and y are 32 byte
+// little-endian integers.
+func add256(out, x, y []byte) {
+	var carry uint16
+	for i := 0; i < 32; i++ {
+		v := uint16(x[i]) + uint16(y[i]) + carry
+		out[i] = byte(v)
+		carry = v >> 8
+	}
+}
+
+func isIdentity(publicKey []byte) bool {
+	var identity [ed25519.PublicKeySize]byte
+	identity[0] = 1
+	return string(publicKey) == string(identity[:])
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
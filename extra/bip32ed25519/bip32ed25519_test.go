@@ -0,0 +1,241 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package bip32ed25519
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+func newTestMasterKey(t *testing.T) *ExtendedPrivateKey {
+	var seed [32]byte
+	for i := 0; i < 256; i++ {
+		seed[0] = byte(i)
+		xprv, err := NewMasterKey(seed[:])
+		if err == nil {
+			return xprv
+		}
+		if err != errInvalidSeed {
+			t.Fatalf("NewMasterKey: %v", err)
+		}
+	}
+	t.Fatalf("failed to find a valid seed")
+	return nil
+}
+
+func TestDerivation(t *testing.T) {
+	master := newTestMasterKey(t)
+	message := []byte("test message")
+
+	// m/1852'/1815'/0'
+	account, err := master.DeriveIndexes([]uint32{HardenedOffset + 1852, HardenedOffset + 1815, HardenedOffset + 0})
+	if err != nil {
+		t.Fatalf("DeriveIndexes: %v", err)
+	}
+	accountPub := account.ExtendedPublicKey()
+
+	for _, path := range [][]uint32{
+		{0},
+		{0, 1},
+		{1, 0x7fffffff},
+		{0, 2, 4, 8},
+	} {
+		xprv, err := account.DeriveIndexes(path)
+		if err != nil {
+			t.Fatalf("%v: DeriveIndexes: %v", path, err)
+		}
+		xpub, err := accountPub.DeriveIndexes(path)
+		if err != nil {
+			t.Fatalf("%v: public DeriveIndexes: %v", path, err)
+		}
+
+		if !bytes.Equal(xprv.PublicKey(), xpub.PublicKey()) {
+			t.Fatalf("%v: public key mismatch: %x != %x", path, xprv.PublicKey(), xpub.PublicKey())
+		}
+		if !bytes.Equal(xprv.ChainCode(), xpub.ChainCode()) {
+			t.Fatalf("%v: chain code mismatch", path)
+		}
+
+		sig := xprv.Sign(message)
+		if !ed25519.Verify(xpub.PublicKey(), message, sig) {
+			t.Fatalf("%v: signature failed to verify", path)
+		}
+		if ed25519.Verify(accountPub.PublicKey(), message, sig) {
+			t.Fatalf("%v: signature verified with parent key", path)
+		}
+	}
+
+	// Hardened and non-hardened children are distinct.
+	normal, _ := account.Derive(0)
+	hardened, _ := account.Derive(HardenedOffset)
+	if bytes.Equal(normal.PublicKey(), hardened.PublicKey()) {
+		t.Fatalf("hardened child equals non-hardened child")
+	}
+	if _, err = accountPub.Derive(HardenedOffset); err != errHardenedPublic {
+		t.Fatalf("public derivation of hardened child: %v", err)
+	}
+}
+
+// icarusMasterKey derives a Cardano (Icarus) master key from BIP-39
+// entropy, as specified in CIP-0003.
+func icarusMasterKey(entropy, passphrase []byte) []byte {
+	k := pbkdf2.Key(passphrase, entropy, 4096, ExtendedPrivateKeySize, sha512.New)
+	k[0] &= 0xf8
+	k[31] &= 0x1f
+	k[31] |= 0x40
+	return k
+}
+
+func TestVectors(t *testing.T) {
+	// CIP-0003 Icarus master key test vectors, for the entropy of "eight
+	// country switch draw meat scout mystery blade tip drift useless good
+	// keep usage title".
+	entropy, _ := hex.DecodeString("46e62370a138a182a498b8e2885bc032379ddf38")
+	for _, v := range []struct {
+		passphrase string
+		masterKey  string
+	}{
+		{"", "c065afd2832cd8b087c4d9ab7011f481ee1e0721e78ea5dd609f3ab3f156d245d176bd8fd4ec60b4731c3918a2a72a0226c0cd119ec35b47e4d55884667f552a23f7fdcd4a10c6cd2c7393ac61d877873e248f417634aa3d812af327ffe9d620"},
+		{"foo", "70531039904019351e1afb361cd1b312a4d0565d4ff9f8062d38acf4b15cce41d7b5738d9c893feea55512a3004acb0d222c35d3e3d5cde943a15a9824cbac59443cf67e589614076ba01e354b1a432e0e6db3b59e37fc56b5fb0222970a010e"},
+	} {
+		b := icarusMasterKey(entropy, []byte(v.passphrase))
+		if hex.EncodeToString(b) != v.masterKey {
+			t.Fatalf("%q: master key mismatch: %x", v.passphrase, b)
+		}
+		xprv, err := NewExtendedPrivateKey(b)
+		if err != nil {
+			t.Fatalf("%q: NewExtendedPrivateKey: %v", v.passphrase, err)
+		}
+		if !bytes.Equal(xprv.Bytes(), b) {
+			t.Fatalf("%q: serialization mismatch", v.passphrase)
+		}
+	}
+
+	// CIP-0019 test vector, the payment verification key at
+	// m/1852'/1815'/0'/0/0 for "test walk nut penalty hip pave soap entry
+	// language right filter choice" (addr_vk1w0l2sr2zgfm26ztc6nl9xy8ghsk5
+	// sh6ldwemlpmp9xylzy4dtf7st80zhd).
+	entropy, _ = hex.DecodeString("df9ed25ed146bf43336a5d7cf7395994")
+	expectedPublicKey, _ := hex.DecodeString("73fea80d424276ad0978d4fe5310e8bc2d485f5f6bb3bf87612989f112ad5a7d")
+
+	master, err := NewExtendedPrivateKey(icarusMasterKey(entropy, nil))
+	if err != nil {
+		t.Fatalf("NewExtendedPrivateKey: %v", err)
+	}
+	account, err := master.DeriveIndexes([]uint32{HardenedOffset + 1852, HardenedOffset + 1815, HardenedOffset + 0})
+	if err != nil {
+		t.Fatalf("DeriveIndexes: %v", err)
+	}
+	xprv, err := account.DeriveIndexes([]uint32{0, 0})
+	if err != nil {
+		t.Fatalf("DeriveIndexes: %v", err)
+	}
+	if !bytes.Equal(xprv.PublicKey(), expectedPublicKey) {
+		t.Fatalf("private derivation: public key mismatch: %x", xprv.PublicKey())
+	}
+	xpub, err := account.ExtendedPublicKey().DeriveIndexes([]uint32{0, 0})
+	if err != nil {
+		t.Fatalf("public DeriveIndexes: %v", err)
+	}
+	if !bytes.Equal(xpub.Bytes(), xprv.ExtendedPublicKey().Bytes()) {
+		t.Fatalf("public derivation: extended public key mismatch: %x", xpub.Bytes())
+	}
+}
+
+func TestMasterKey(t *testing.T) {
+	master := newTestMasterKey(t)
+	b := master.Bytes()
+	if b[0]&7 != 0 || b[31]&0x80 != 0 || b[31]&0x40 == 0 || b[31]&0x20 != 0 {
+		t.Fatalf("master key is not clamped: %x", b[:32])
+	}
+
+	// With a SHA-512 derived kL/kR, signatures are identical to
+	// those of the equivalent seed based private key.
+	var seed [32]byte
+	for i := 0; i < 256; i++ {
+		seed[0] = byte(i)
+		if k := sha512.Sum512(seed[:]); k[31]&0x20 == 0 {
+			break
+		}
+	}
+	xprv, err := NewMasterKey(seed[:])
+	if err != nil {
+		t.Fatalf("NewMasterKey: %v", err)
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed[:])
+	message := []byte("test message")
+	if !bytes.Equal(xprv.Sign(message), ed25519.Sign(privateKey, message)) {
+		t.Fatalf("master key signature mismatch")
+	}
+	if !bytes.Equal(xprv.PublicKey(), privateKey.Public().(ed25519.PublicKey)) {
+		t.Fatalf("master key public key mismatch")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	master := newTestMasterKey(t)
+	child, err := master.Derive(HardenedOffset + 42)
+	if err != nil {
+		t.Fatalf("Derive: %v", err)
+	}
+
+	xprv, err := NewExtendedPrivateKey(child.Bytes())
+	if err != nil {
+		t.Fatalf("NewExtendedPrivateKey: %v", err)
+	}
+	if !bytes.Equal(xprv.Bytes(), child.Bytes()) || !bytes.Equal(xprv.PublicKey(), child.PublicKey()) {
+		t.Fatalf("extended private key round trip mismatch")
+	}
+
+	xpub, err := NewExtendedPublicKey(child.ExtendedPublicKey().Bytes())
+	if err != nil {
+		t.Fatalf("NewExtendedPublicKey: %v", err)
+	}
+	if !bytes.Equal(xpub.Bytes(), child.ExtendedPublicKey().Bytes()) {
+		t.Fatalf("extended public key round trip mismatch")
+	}
+
+	bad := child.Bytes()
+	bad[0] |= 1
+	if _, err = NewExtendedPrivateKey(bad); err == nil {
+		t.Fatalf("NewExtendedPrivateKey accepted an unclamped key")
+	}
+	if _, err = NewExtendedPrivateKey(bad[:ExtendedPrivateKeySize-1]); err == nil {
+		t.Fatalf("NewExtendedPrivateKey accepted a truncated key")
+	}
+	if _, err = NewExtendedPublicKey(xpub.Bytes()[:ExtendedPublicKeySize-1]); err == nil {
+		t.Fatalf("NewExtendedPublicKey accepted a truncated key")
+	}
+}
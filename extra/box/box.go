@@ -0,0 +1,215 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package box implements libsodium compatible authenticated public-key
+// encryption (crypto_box, X25519 + XSalsa20-Poly1305), and anonymous
+// sealed boxes (crypto_box_seal).
+//
+// Keys are this module's X25519 keys, so boxes can be sent to, and opened
+// by, Ed25519 key holders with x25519.NewPublicKeyFromEd25519 and
+// x25519.NewPrivateKeyFromEd25519, which match libsodium's
+// crypto_sign_ed25519_pk_to_curve25519 and crypto_sign_ed25519_sk_to_curve25519.
+//
+// Unlike golang.org/x/crypto/nacl/box, a low-order public key results in
+// an error, matching libsodium, rather than encryption under a known key.
+package box
+
+import (
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/salsa20/salsa"
+
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+const (
+	// Overhead is the number of bytes of overhead when boxing a message.
+	Overhead = secretbox.Overhead
+
+	// AnonymousOverhead is the number of bytes of overhead when using
+	// SealAnonymous.
+	AnonymousOverhead = x25519.PointSize + Overhead
+
+	// NonceSize is the size, in bytes, of the nonce used by Seal and Open.
+	NonceSize = 24
+
+	// SharedKeySize is the size, in bytes, of a precomputed shared key.
+	SharedKeySize = 32
+)
+
+var (
+	errInvalidPublicKey = errors.New("box: invalid public key")
+	errOpen             = errors.New("box: message authentication failed")
+)
+
+// Precompute calculates the shared key between peersPublicKey and
+// privateKey, for use with SealAfterPrecomputation and
+// OpenAfterPrecomputation.
+func Precompute(peersPublicKey *x25519.PublicKey, privateKey *x25519.PrivateKey) (*[SharedKeySize]byte, error) {
+	dh, err := privateKey.ECDH(peersPublicKey)
+	if err != nil {
+		return nil, errInvalidPublicKey
+	}
+
+	var k [32]byte
+	copy(k[:], dh)
+	sharedKey := new([SharedKeySize]byte)
+	salsa.HSalsa20(sharedKey, new([16]byte), &k, &salsa.Sigma)
+
+	for i := range k {
+		k[i] = 0
+		dh[i] = 0
+	}
+
+	return sharedKey, nil
+}
+
+// Seal appends an encrypted and authenticated copy of message to out,
+// which must not overlap message.  The nonce must be unique for each
+// distinct message, and the output will be Overhead bytes longer than
+// message.
+func Seal(out, message []byte, nonce *[NonceSize]byte, peersPublicKey *x25519.PublicKey, privateKey *x25519.PrivateKey) ([]byte, error) {
+	sharedKey, err := Precompute(peersPublicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(sharedKey)
+
+	return SealAfterPrecomputation(out, message, nonce, sharedKey), nil
+}
+
+// SealAfterPrecomputation performs the same actions as Seal, but takes a
+// shared key as generated by Precompute.
+func SealAfterPrecomputation(out, message []byte, nonce *[NonceSize]byte, sharedKey *[SharedKeySize]byte) []byte {
+	return secretbox.Seal(out, message, nonce, sharedKey)
+}
+
+// Open authenticates and decrypts a box produced by Seal and appends the
+// message to out, which must not overlap box.  The output will be
+// Overhead bytes smaller than box.
+func Open(out, box []byte, nonce *[NonceSize]byte, peersPublicKey *x25519.PublicKey, privateKey *x25519.PrivateKey) ([]byte, error) {
+	sharedKey, err := Precompute(peersPublicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(sharedKey)
+
+	return OpenAfterPrecomputation(out, box, nonce, sharedKey)
+}
+
+// OpenAfterPrecomputation performs the same actions as Open, but takes a
+// shared key as generated by Precompute.
+func OpenAfterPrecomputation(out, box []byte, nonce *[NonceSize]byte, sharedKey *[SharedKeySize]byte) ([]byte, error) {
+	message, ok := secretbox.Open(out, box, nonce, sharedKey)
+	if !ok {
+		return nil, errOpen
+	}
+	return message, nil
+}
+
+// SealAnonymous appends an encrypted and authenticated copy of message to
+// out, which will be AnonymousOverhead bytes longer than the original and
+// must not overlap it.  This box can only be opened by the holder of the
+// recipient's private key, and the sender can not be identified.
+//
+// An ephemeral key is generated using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+func SealAnonymous(out, message []byte, recipient *x25519.PublicKey, rand io.Reader) ([]byte, error) {
+	ephemeral, err := x25519.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	defer ephemeral.Reset()
+
+	ephemeralPublicKey := ephemeral.PublicKey()
+	nonce := sealNonce(ephemeralPublicKey, recipient)
+
+	ret, body := sliceForAppend(out, AnonymousOverhead+len(message))
+	copy(body, ephemeralPublicKey.Bytes())
+
+	if _, err = Seal(body[:x25519.PointSize], message, nonce, recipient, ephemeral); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// OpenAnonymous authenticates and decrypts a box produced by SealAnonymous
+// and appends the message to out, which must not overlap box.  The output
+// will be AnonymousOverhead bytes smaller than box.
+func OpenAnonymous(out, box []byte, privateKey *x25519.PrivateKey) ([]byte, error) {
+	if len(box) < AnonymousOverhead {
+		return nil, errOpen
+	}
+
+	ephemeralPublicKey, err := x25519.NewPublicKey(box[:x25519.PointSize])
+	if err != nil {
+		return nil, errOpen
+	}
+	nonce := sealNonce(ephemeralPublicKey, privateKey.PublicKey())
+
+	return Open(out, box[x25519.PointSize:], nonce, ephemeralPublicKey, privateKey)
+}
+
+// sealNonce derives the nonce as BLAKE2b-192(ephemeralPublicKey || publicKey).
+func sealNonce(ephemeralPublicKey, publicKey *x25519.PublicKey) *[NonceSize]byte {
+	h, err := blake2b.New(NonceSize, nil)
+	if err != nil {
+		panic("box: failed to initialize BLAKE2b: " + err.Error())
+	}
+	_, _ = h.Write(ephemeralPublicKey.Bytes())
+	_, _ = h.Write(publicKey.Bytes())
+
+	var nonce [NonceSize]byte
+	copy(nonce[:], h.Sum(nil))
+
+	return &nonce
+}
+
+// sliceForAppend takes a slice and a requested number of bytes.  It returns
+// a slice with the contents of the given slice followed by that many bytes
+// and a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
+
+func zeroKey(k *[SharedKeySize]byte) {
+	for i := range k {
+		k[i] = 0
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package box
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	naclbox "golang.org/x/crypto/nacl/box"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+func mustNewPrivateKey(t *testing.T, b byte) *x25519.PrivateKey {
+	k, err := x25519.NewPrivateKey(bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatalf("x25519.NewPrivateKey: %v", err)
+	}
+	return k
+}
+
+func TestBox(t *testing.T) {
+	privateKey1, privateKey2 := mustNewPrivateKey(t, 1), mustNewPrivateKey(t, 2)
+	message := bytes.Repeat([]byte{3}, 64)
+	var nonce [NonceSize]byte
+	for i := range nonce {
+		nonce[i] = 4
+	}
+
+	box, err := Seal(nil, message, &nonce, privateKey1.PublicKey(), privateKey2)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// expected was generated using the C implementation of NaCl.
+	expected, _ := hex.DecodeString("78ea30b19d2341ebbdba54180f821eec265cf86312549bea8a37652a8bb94f07b78a73ed1708085e6ddd0e943bbdeb8755079a37eb31d86163ce241164a47629c0539f330b4914cd135b3855bc2a2dfc")
+	if !bytes.Equal(box, expected) {
+		t.Fatalf("box didn't match, got\n%x\n, expected\n%x", box, expected)
+	}
+
+	opened, err := Open(nil, box, &nonce, privateKey2.PublicKey(), privateKey1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, message) {
+		t.Fatalf("got %x, want %x", opened, message)
+	}
+
+	for i := range box {
+		box[i] ^= 0x40
+		if _, err = Open(nil, box, &nonce, privateKey2.PublicKey(), privateKey1); err == nil {
+			t.Fatalf("opened box with byte %d corrupted", i)
+		}
+		box[i] ^= 0x40
+	}
+}
+
+func TestSealedBox(t *testing.T) {
+	privateKey := mustNewPrivateKey(t, 1)
+	message := bytes.Repeat([]byte{3}, 64)
+
+	fakeRand := bytes.NewReader(bytes.Repeat([]byte{5}, 32))
+	box, err := SealAnonymous(nil, message, privateKey.PublicKey(), fakeRand)
+	if err != nil {
+		t.Fatalf("SealAnonymous: %v", err)
+	}
+
+	// expected was generated using the C implementation of libsodium with a
+	// random implementation that always returns 5.
+	expected, _ := hex.DecodeString("50a61409b1ddd0325e9b16b700e719e9772c07000b1bd7786e907c653d20495d2af1697137a53b1b1dfc9befc49b6eeb38f86be720e155eb2be61976d2efb34d67ecd44a6ad634625eb9c288bfc883431a84ab0f5557dfe673aa6f74c19f033e648a947358cfcc606397fa1747d5219a")
+	if !bytes.Equal(box, expected) {
+		t.Fatalf("box didn't match, got\n%x\n, expected\n%x", box, expected)
+	}
+
+	// box was generated using the C implementation of libsodium.
+	box, _ = hex.DecodeString("3462e0640728247a6f581e3812850d6edc3dcad1ea5d8184c072f62fb65cb357e27ffa8b76f41656bc66a0882c4d359568410665746d27462a700f01e314f382edd7aae9064879b0f8ba7b88866f88f5e4fbd7649c850541877f9f33ebd25d46d9cbcce09b69a9ba07f0eb1d105d4264")
+	opened, err := OpenAnonymous(nil, box, privateKey)
+	if err != nil {
+		t.Fatalf("OpenAnonymous: %v", err)
+	}
+	if !bytes.Equal(opened, message) {
+		t.Fatalf("message didn't match, got\n%x\n, expected\n%x", opened, message)
+	}
+
+	for i := range box {
+		box[i] ^= 0x40
+		if _, err = OpenAnonymous(nil, box, privateKey); err == nil {
+			t.Fatalf("opened box with byte %d corrupted", i)
+		}
+		box[i] ^= 0x40
+	}
+	if _, err = OpenAnonymous(nil, box[:AnonymousOverhead-1], privateKey); err == nil {
+		t.Fatalf("opened truncated box")
+	}
+}
+
+func TestSealAnonymousAppend(t *testing.T) {
+	privateKey, _ := x25519.GenerateKey(nil)
+	message := []byte("test message")
+
+	// Allocates a new slice if out isn't long enough.
+	out := []byte("hello")
+	box, err := SealAnonymous(out, message, privateKey.PublicKey(), nil)
+	if err != nil {
+		t.Fatalf("SealAnonymous: %v", err)
+	}
+	if !bytes.Equal(out, []byte("hello")) || !bytes.HasPrefix(box, out) {
+		t.Fatalf("out was not preserved")
+	}
+	if _, err = OpenAnonymous(nil, box[len(out):], privateKey); err != nil {
+		t.Fatalf("OpenAnonymous: %v", err)
+	}
+
+	// Uses the provided slice if it's long enough.
+	out = append(make([]byte, 0, 1000), out...)
+	box, err = SealAnonymous(out, message, privateKey.PublicKey(), nil)
+	if err != nil {
+		t.Fatalf("SealAnonymous: %v", err)
+	}
+	if &out[0] != &box[0] {
+		t.Fatalf("expected box to point to out")
+	}
+	if _, err = OpenAnonymous(nil, box[len(out):], privateKey); err != nil {
+		t.Fatalf("OpenAnonymous: %v", err)
+	}
+}
+
+func TestEd25519Interop(t *testing.T) {
+	edPublic, edPrivate, _ := ed25519.GenerateKey(nil)
+	publicKey, _ := x25519.NewPublicKeyFromEd25519(edPublic)
+	privateKey, _ := x25519.NewPrivateKeyFromEd25519(edPrivate)
+	message := []byte("test message")
+
+	box, err := SealAnonymous(nil, message, publicKey, nil)
+	if err != nil {
+		t.Fatalf("SealAnonymous: %v", err)
+	}
+	opened, err := OpenAnonymous(nil, box, privateKey)
+	if err != nil {
+		t.Fatalf("OpenAnonymous: %v", err)
+	}
+	if !bytes.Equal(opened, message) {
+		t.Fatalf("got %x, want %x", opened, message)
+	}
+
+	// Cross-check against golang.org/x/crypto/nacl/box.
+	sender, _ := x25519.GenerateKey(nil)
+	var (
+		nonce                       [NonceSize]byte
+		senderPriv, senderPub       [32]byte
+		recipientPub, recipientPriv [32]byte
+	)
+	copy(senderPriv[:], sender.Bytes())
+	copy(senderPub[:], sender.PublicKey().Bytes())
+	copy(recipientPub[:], publicKey.Bytes())
+	copy(recipientPriv[:], privateKey.Bytes())
+
+	box = naclbox.Seal(nil, message, &nonce, &recipientPub, &senderPriv)
+	if opened, err = Open(nil, box, &nonce, sender.PublicKey(), privateKey); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, message) {
+		t.Fatalf("got %x, want %x", opened, message)
+	}
+
+	box, _ = Seal(nil, message, &nonce, publicKey, sender)
+	if opened, ok := naclbox.Open(nil, box, &nonce, &senderPub, &recipientPriv); !ok || !bytes.Equal(opened, message) {
+		t.Fatalf("nacl/box failed to open box")
+	}
+
+	sharedKey, err := Precompute(publicKey, sender)
+	if err != nil {
+		t.Fatalf("Precompute: %v", err)
+	}
+	var expectedKey [32]byte
+	naclbox.Precompute(&expectedKey, &recipientPub, &senderPriv)
+	if *sharedKey != expectedKey {
+		t.Fatalf("shared key mismatch: %x != %x", sharedKey[:], expectedKey[:])
+	}
+}
+
+func TestLowOrderPublicKey(t *testing.T) {
+	privateKey, _ := x25519.GenerateKey(nil)
+	lowOrder, _ := x25519.NewPublicKey(make([]byte, x25519.PointSize))
+
+	var nonce [NonceSize]byte
+	if _, err := Seal(nil, nil, &nonce, lowOrder, privateKey); err != errInvalidPublicKey {
+		t.Fatalf("Seal: expected invalid public key error, got %v", err)
+	}
+	if _, err := SealAnonymous(nil, nil, lowOrder, nil); err != errInvalidPublicKey {
+		t.Fatalf("SealAnonymous: expected invalid public key error, got %v", err)
+	}
+
+	box := make([]byte, AnonymousOverhead)
+	if _, err := OpenAnonymous(nil, box, privateKey); err == nil {
+		t.Fatalf("OpenAnonymous: opened box with low order ephemeral key")
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cose
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// This is a minimal CBOR (RFC 8949) implementation, that supports the
+// subset of the data model required by COSE.  Values are represented as:
+//
+//  * integers: int64 (int and uint64 are also accepted by the encoder)
+//  * byte strings: []byte
+//  * text strings: string
+//  * arrays: []interface{}
+//  * maps: map[interface{}]interface{}, with int64 or string keys
+//  * tags: cborTag
+//  * simple values: bool, and nil
+//
+// The encoder always produces the core deterministic encoding (RFC 8949,
+// Section 4.2.1).  The decoder only accepts well-formed, definite length
+// items in the preferred (shortest) serialization, and rejects duplicate
+// map keys.
+
+const (
+	cborMajorUint   = 0
+	cborMajorNegInt = 1
+	cborMajorBytes  = 2
+	cborMajorText   = 3
+	cborMajorArray  = 4
+	cborMajorMap    = 5
+	cborMajorTag    = 6
+	cborMajorSimple = 7
+
+	cborFalse = 20
+	cborTrue  = 21
+	cborNull  = 22
+
+	cborMaxDepth = 16
+)
+
+var (
+	errCBORUnsupported = errors.New("cose: unsupported CBOR value")
+	errCBORMalformed   = errors.New("cose: malformed CBOR")
+	errCBORTrailing    = errors.New("cose: trailing data after CBOR item")
+)
+
+type cborTag struct {
+	number  uint64
+	content interface{}
+}
+
+func cborMarshal(v interface{}) ([]byte, error) {
+	return cborAppend(nil, v)
+}
+
+func cborAppendHead(b []byte, major byte, v uint64) []byte {
+	major <<= 5
+	switch {
+	case v < 24:
+		return append(b, major|byte(v))
+	case v <= math.MaxUint8:
+		return append(b, major|24, byte(v))
+	case v <= math.MaxUint16:
+		b = append(b, major|25, 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(v))
+		return b
+	case v <= math.MaxUint32:
+		b = append(b, major|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], uint32(v))
+		return b
+	default:
+		b = append(b, major|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], v)
+		return b
+	}
+}
+
+func cborAppend(b []byte, v interface{}) ([]byte, error) {
+	switch vv := v.(type) {
+	case int:
+		return cborAppend(b, int64(vv))
+	case int64:
+		if vv < 0 {
+			return cborAppendHead(b, cborMajorNegInt, uint64(-(vv + 1))), nil
+		}
+		return cborAppendHead(b, cborMajorUint, uint64(vv)), nil
+	case uint64:
+		return cborAppendHead(b, cborMajorUint, vv), nil
+	case []byte:
+		b = cborAppendHead(b, cborMajorBytes, uint64(len(vv)))
+		return append(b, vv...), nil
+	case string:
+		b = cborAppendHead(b, cborMajorText, uint64(len(vv)))
+		return append(b, vv...), nil
+	case []interface{}:
+		b = cborAppendHead(b, cborMajorArray, uint64(len(vv)))
+		for _, elem := range vv {
+			var err error
+			if b, err = cborAppend(b, elem); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[interface{}]interface{}:
+		// Sort the entries by the bytewise lexicographic order of the
+		// encoded keys.
+		type entry struct {
+			key   []byte
+			value interface{}
+		}
+		entries := make([]entry, 0, len(vv))
+		for k, v := range vv {
+			switch k.(type) {
+			case int, int64, string:
+			default:
+				return nil, errCBORUnsupported
+			}
+			encodedKey, err := cborMarshal(k)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry{encodedKey, v})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+
+		b = cborAppendHead(b, cborMajorMap, uint64(len(entries)))
+		for i := range entries {
+			var err error
+			b = append(b, entries[i].key...)
+			if b, err = cborAppend(b, entries[i].value); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case cborTag:
+		b = cborAppendHead(b, cborMajorTag, vv.number)
+		return cborAppend(b, vv.content)
+	case bool:
+		if vv {
+			return append(b, cborMajorSimple<<5|cborTrue), nil
+		}
+		return append(b, cborMajorSimple<<5|cborFalse), nil
+	case nil:
+		return append(b, cborMajorSimple<<5|cborNull), nil
+	default:
+		return nil, errCBORUnsupported
+	}
+}
+
+func cborUnmarshal(data []byte) (interface{}, error) {
+	d := cborDecoder{b: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.b) != 0 {
+		return nil, errCBORTrailing
+	}
+	return v, nil
+}
+
+type cborDecoder struct {
+	b []byte
+}
+
+func (d *cborDecoder) readHead() (byte, uint64, error) {
+	if len(d.b) < 1 {
+		return 0, 0, errCBORMalformed
+	}
+	major, info := d.b[0]>>5, d.b[0]&0x1f
+	d.b = d.b[1:]
+
+	var (
+		v       uint64
+		minimum uint64
+	)
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		if len(d.b) < 1 {
+			return 0, 0, errCBORMalformed
+		}
+		v, minimum = uint64(d.b[0]), 24
+		d.b = d.b[1:]
+	case info == 25:
+		if len(d.b) < 2 {
+			return 0, 0, errCBORMalformed
+		}
+		v, minimum = uint64(binary.BigEndian.Uint16(d.b)), math.MaxUint8+1
+		d.b = d.b[2:]
+	case info == 26:
+		if len(d.b) < 4 {
+			return 0, 0, errCBORMalformed
+		}
+		v, minimum = uint64(binary.BigEndian.Uint32(d.b)), math.MaxUint16+1
+		d.b = d.b[4:]
+	case info == 27:
+		if len(d.b) < 8 {
+			return 0, 0, errCBORMalformed
+		}
+		v, minimum = binary.BigEndian.Uint64(d.b), math.MaxUint32+1
+		d.b = d.b[8:]
+	default:
+		// Reserved, and indefinite length items.
+		return 0, 0, errCBORMalformed
+	}
+
+	// Only the preferred serialization is accepted.
+	if v < minimum {
+		return 0, 0, errCBORMalformed
+	}
+
+	return major, v, nil
+}
+
+func (d *cborDecoder) readBytes(n uint64) ([]byte, error) {
+	if uint64(len(d.b)) < n {
+		return nil, errCBORMalformed
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b, nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errCBORUnsupported
+	}
+
+	major, v, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborMajorUint:
+		if v > math.MaxInt64 {
+			return nil, errCBORUnsupported
+		}
+		return int64(v), nil
+	case cborMajorNegInt:
+		if v > math.MaxInt64 {
+			return nil, errCBORUnsupported
+		}
+		return -1 - int64(v), nil
+	case cborMajorBytes:
+		b, err := d.readBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case cborMajorText:
+		b, err := d.readBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case cborMajorArray:
+		// Each element is at least 1 byte.
+		if v > uint64(len(d.b)) {
+			return nil, errCBORMalformed
+		}
+		arr := make([]interface{}, 0, int(v))
+		for i := uint64(0); i < v; i++ {
+			elem, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+		return arr, nil
+	case cborMajorMap:
+		if v > uint64(len(d.b)) {
+			return nil, errCBORMalformed
+		}
+		m := make(map[interface{}]interface{}, int(v))
+		for i := uint64(0); i < v; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errCBORUnsupported
+			}
+			if _, ok := m[k]; ok {
+				return nil, errCBORMalformed
+			}
+			if m[k], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case cborMajorTag:
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{number: v, content: content}, nil
+	default: // cborMajorSimple
+		switch v {
+		case cborFalse:
+			return false, nil
+		case cborTrue:
+			return true, nil
+		case cborNull:
+			return nil, nil
+		default:
+			// Floating point, undefined, and other simple values.
+			return nil, errCBORUnsupported
+		}
+	}
+}
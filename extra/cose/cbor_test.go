@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cose
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCBOREncode(t *testing.T) {
+	// RFC 8949, Appendix A.
+	for _, v := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{100, "1864"},
+		{1000, "1903e8"},
+		{1000000, "1a000f4240"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-10, "29"},
+		{-100, "3863"},
+		{-1000, "3903e7"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{}, "40"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"", "60"},
+		{"a", "6161"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]interface{}{}, "80"},
+		{[]interface{}{1, 2, 3}, "83010203"},
+		{[]interface{}{1, []interface{}{2, 3}, []interface{}{4, 5}}, "8301820203820405"},
+		{map[interface{}]interface{}{}, "a0"},
+		{map[interface{}]interface{}{1: 2, 3: 4}, "a201020304"},
+		{map[interface{}]interface{}{"a": 1, "b": []interface{}{2, 3}}, "a26161016162820203"},
+		{cborTag{number: 1, content: 1363896240}, "c11a514b67b0"},
+
+		// Deterministic map key ordering (RFC 8949, Section 4.2.1).
+		{map[interface{}]interface{}{"a": 0, -1: 0, 10: 0, 100: 0, "z": 0, "aa": 0}, "a60a001864002000616100617a00626161 00"},
+	} {
+		b, err := cborMarshal(v.value)
+		if err != nil {
+			t.Fatalf("cborMarshal(%v): %v", v.value, err)
+		}
+		expected, _ := hex.DecodeString(stripSpaces(v.expected))
+		if !bytes.Equal(b, expected) {
+			t.Fatalf("cborMarshal(%v): got %x, expected %x", v.value, b, expected)
+		}
+
+		// Round trip through the decoder, which only supports values
+		// that fit in an int64.
+		if _, ok := v.value.(uint64); ok {
+			continue
+		}
+		decoded, err := cborUnmarshal(b)
+		if err != nil {
+			t.Fatalf("cborUnmarshal(%x): %v", b, err)
+		}
+		b2, _ := cborMarshal(decoded)
+		if !bytes.Equal(b, b2) {
+			t.Fatalf("cborUnmarshal(%x): round-trip mismatch: %x", b, b2)
+		}
+	}
+
+	if _, err := cborMarshal(1.5); err == nil {
+		t.Fatalf("cborMarshal: accepted a float")
+	}
+}
+
+func TestCBORDecodeStrict(t *testing.T) {
+	for _, s := range []string{
+		"",           // Empty
+		"1817",       // Non-preferred integer
+		"190017",     // Non-preferred integer
+		"5801aa",     // Non-preferred length
+		"5f4101ff",   // Indefinite length byte string
+		"9f01ff",     // Indefinite length array
+		"bf0101ff",   // Indefinite length map
+		"a201010101", // Duplicate map key
+		"a1400101",   // Unsupported map key type
+		"f93c00",     // Half precision float
+		"f7",         // Undefined
+		"1c",         // Reserved
+		"4401",       // Truncated
+		"8301",       // Truncated
+		"0000",       // Trailing data
+		"1bffffffffffffffff",
+		"3bffffffffffffffff",
+		"81818181818181818181818181818181818100", // Too deep
+	} {
+		b, _ := hex.DecodeString(s)
+		if v, err := cborUnmarshal(b); err == nil {
+			t.Errorf("cborUnmarshal(%s): accepted bad input: %v", s, v)
+		}
+	}
+}
+
+func stripSpaces(s string) string {
+	return string(bytes.Replace([]byte(s), []byte(" "), nil, -1))
+}
@@ -0,0 +1,390 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package cose implements CBOR Object Signing and Encryption (COSE, RFC
+// 9052 and RFC 9053) for Ed25519 and X25519 keys: COSE_Key encoding, and
+// COSE_Sign1 and COSE_Sign messages with the EdDSA (-8) algorithm.
+//
+// The algorithm is required to be in the protected header of each
+// signature, and messages with critical header parameters ("crit") are
+// rejected, as no extensions are supported.
+package cose
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+const (
+	// AlgorithmEdDSA is the COSE algorithm identifier of EdDSA.
+	AlgorithmEdDSA = -8
+
+	// KeyTypeOKP is the COSE key type of Octet Key Pair keys.
+	KeyTypeOKP = 1
+
+	// CurveX25519 is the COSE elliptic curve identifier of X25519.
+	CurveX25519 = 4
+
+	// CurveEd25519 is the COSE elliptic curve identifier of Ed25519.
+	CurveEd25519 = 6
+
+	// TagSign1 is the CBOR tag of COSE_Sign1 messages.
+	TagSign1 = 18
+
+	// TagSign is the CBOR tag of COSE_Sign messages.
+	TagSign = 98
+
+	// Header labels.
+	headerAlgorithm = 1
+	headerCritical  = 2
+	headerKeyID     = 4
+
+	// COSE_Key labels.
+	keyLabelKeyType   = 1
+	keyLabelKeyID     = 2
+	keyLabelAlgorithm = 3
+	keyLabelCurve     = -1
+	keyLabelX         = -2
+	keyLabelD         = -4
+
+	contextSignature  = "Signature"
+	contextSignature1 = "Signature1"
+)
+
+var (
+	errInvalidKey           = errors.New("cose: invalid key")
+	errInvalidKeyType       = errors.New("cose: unsupported key type")
+	errInvalidCurve         = errors.New("cose: unsupported curve")
+	errInvalidAlgorithm     = errors.New("cose: unsupported algorithm")
+	errKeyMismatch          = errors.New("cose: private key does not match public key")
+	errInvalidMessage       = errors.New("cose: malformed message")
+	errInvalidHeader        = errors.New("cose: malformed header")
+	errCritical             = errors.New("cose: unsupported critical header parameter")
+	errDuplicateLabel       = errors.New("cose: header label in both protected and unprotected headers")
+	errInvalidSignature     = errors.New("cose: invalid signature")
+	errNoSigners            = errors.New("cose: no signers")
+	errDetachedPayload      = errors.New("cose: detached payloads are not supported")
+	errUnprotectedAlgorithm = errors.New("cose: algorithm in unprotected header")
+)
+
+// Header is a COSE header map.  Only the parameters used by this package
+// are represented, others are ignored when parsing.
+type Header struct {
+	// Algorithm is the "alg" (1) parameter, or 0 if absent.
+	Algorithm int64
+
+	// KeyID is the "kid" (4) parameter, or nil if absent.
+	KeyID []byte
+}
+
+func (h *Header) toMap() map[interface{}]interface{} {
+	m := make(map[interface{}]interface{})
+	if h.Algorithm != 0 {
+		m[int64(headerAlgorithm)] = h.Algorithm
+	}
+	if h.KeyID != nil {
+		m[int64(headerKeyID)] = h.KeyID
+	}
+	return m
+}
+
+// encodeProtected returns the serialized protected header, which is the
+// empty byte string if the header is empty.
+func (h *Header) encodeProtected() ([]byte, error) {
+	m := h.toMap()
+	if len(m) == 0 {
+		return []byte{}, nil
+	}
+	return cborMarshal(m)
+}
+
+func parseHeaderMap(m map[interface{}]interface{}) (*Header, error) {
+	var h Header
+	for label, v := range m {
+		switch label {
+		case int64(headerAlgorithm):
+			alg, ok := v.(int64)
+			if !ok || alg == 0 {
+				return nil, errInvalidHeader
+			}
+			h.Algorithm = alg
+		case int64(headerCritical):
+			return nil, errCritical
+		case int64(headerKeyID):
+			kid, ok := v.([]byte)
+			if !ok {
+				return nil, errInvalidHeader
+			}
+			h.KeyID = kid
+		}
+	}
+	return &h, nil
+}
+
+// parseHeaders parses and validates a protected (serialized) and
+// unprotected header pair, of a signature.
+func parseHeaders(rawProtected, rawUnprotected interface{}) (*Header, *Header, error) {
+	protectedBytes, ok := rawProtected.([]byte)
+	if !ok {
+		return nil, nil, errInvalidHeader
+	}
+	protectedMap := make(map[interface{}]interface{})
+	if len(protectedBytes) != 0 {
+		v, err := cborUnmarshal(protectedBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if protectedMap, ok = v.(map[interface{}]interface{}); !ok {
+			return nil, nil, errInvalidHeader
+		}
+	}
+	unprotectedMap, ok := rawUnprotected.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, errInvalidHeader
+	}
+	for label := range unprotectedMap {
+		if _, ok = protectedMap[label]; ok {
+			return nil, nil, errDuplicateLabel
+		}
+	}
+
+	protected, err := parseHeaderMap(protectedMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	unprotected, err := parseHeaderMap(unprotectedMap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return protected, unprotected, nil
+}
+
+// checkSigningHeaders validates the headers of a signature to be created.
+func checkSigningHeaders(protected, unprotected *Header) (*Header, error) {
+	if protected.Algorithm != 0 && protected.Algorithm != AlgorithmEdDSA {
+		return nil, errInvalidAlgorithm
+	}
+	if unprotected.Algorithm != 0 {
+		return nil, errUnprotectedAlgorithm
+	}
+	if protected.KeyID != nil && unprotected.KeyID != nil {
+		return nil, errDuplicateLabel
+	}
+
+	h := *protected
+	h.Algorithm = AlgorithmEdDSA
+	return &h, nil
+}
+
+// checkVerificationHeaders validates the headers of a signature to be
+// verified, to prevent algorithm confusion.
+func checkVerificationHeaders(protected, unprotected *Header) error {
+	if unprotected.Algorithm != 0 {
+		return errUnprotectedAlgorithm
+	}
+	if protected.Algorithm != AlgorithmEdDSA {
+		return errInvalidAlgorithm
+	}
+	return nil
+}
+
+func sign(privateKey ed25519.PrivateKey, toBeSigned []interface{}) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	sigStructure, err := cborMarshal(toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	return privateKey.Sign(nil, sigStructure, crypto.Hash(0))
+}
+
+func verify(publicKey ed25519.PublicKey, toBeSigned []interface{}, sig interface{}) error {
+	sigBytes, ok := sig.([]byte)
+	if !ok {
+		return errInvalidMessage
+	}
+	sigStructure, err := cborMarshal(toBeSigned)
+	if err != nil {
+		return err
+	}
+	if len(publicKey) != ed25519.PublicKeySize || !ed25519.Verify(publicKey, sigStructure, sigBytes) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// Key is a COSE_Key, holding an Ed25519 or X25519 key.
+type Key struct {
+	// Key is one of ed25519.PublicKey, ed25519.PrivateKey,
+	// *x25519.PublicKey, or *x25519.PrivateKey.
+	Key interface{}
+
+	// KeyID is the optional "kid" (2) parameter.
+	KeyID []byte
+
+	// Algorithm is the optional "alg" (3) parameter, or 0 if absent.
+	Algorithm int64
+}
+
+// MarshalCBOR serializes the key to the deterministic CBOR encoding of
+// a COSE_Key.
+func (k *Key) MarshalCBOR() ([]byte, error) {
+	m := map[interface{}]interface{}{
+		int64(keyLabelKeyType): int64(KeyTypeOKP),
+	}
+	switch kk := k.Key.(type) {
+	case ed25519.PublicKey:
+		if len(kk) != ed25519.PublicKeySize {
+			return nil, errInvalidKey
+		}
+		m[int64(keyLabelCurve)] = int64(CurveEd25519)
+		m[int64(keyLabelX)] = []byte(kk)
+	case ed25519.PrivateKey:
+		if len(kk) != ed25519.PrivateKeySize {
+			return nil, errInvalidKey
+		}
+		m[int64(keyLabelCurve)] = int64(CurveEd25519)
+		m[int64(keyLabelX)] = []byte(kk[32:])
+		m[int64(keyLabelD)] = kk.Seed()
+	case *x25519.PublicKey:
+		m[int64(keyLabelCurve)] = int64(CurveX25519)
+		m[int64(keyLabelX)] = kk.Bytes()
+	case *x25519.PrivateKey:
+		m[int64(keyLabelCurve)] = int64(CurveX25519)
+		m[int64(keyLabelX)] = kk.PublicKey().Bytes()
+		m[int64(keyLabelD)] = kk.Bytes()
+	default:
+		return nil, errInvalidKeyType
+	}
+	if k.KeyID != nil {
+		m[int64(keyLabelKeyID)] = k.KeyID
+	}
+	if k.Algorithm != 0 {
+		m[int64(keyLabelAlgorithm)] = k.Algorithm
+	}
+
+	return cborMarshal(m)
+}
+
+// UnmarshalCBOR parses a CBOR encoded COSE_Key.  For private keys, the
+// public key ("x") must match the private key ("d").
+func (k *Key) UnmarshalCBOR(data []byte) error {
+	v, err := cborUnmarshal(data)
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return errInvalidKey
+	}
+
+	if kty, _ := m[int64(keyLabelKeyType)].(int64); kty != KeyTypeOKP {
+		return errInvalidKeyType
+	}
+	x, ok := m[int64(keyLabelX)].([]byte)
+	if !ok {
+		return errInvalidKey
+	}
+	d, hasD := m[int64(keyLabelD)]
+	var dBytes []byte
+	if hasD {
+		if dBytes, ok = d.([]byte); !ok {
+			return errInvalidKey
+		}
+	}
+	var keyID []byte
+	if kid, hasKeyID := m[int64(keyLabelKeyID)]; hasKeyID {
+		if keyID, ok = kid.([]byte); !ok {
+			return errInvalidKey
+		}
+	}
+	var alg int64
+	if a, hasAlg := m[int64(keyLabelAlgorithm)]; hasAlg {
+		if alg, ok = a.(int64); !ok || alg == 0 {
+			return errInvalidKey
+		}
+	}
+
+	crv, _ := m[int64(keyLabelCurve)].(int64)
+	var key interface{}
+	switch crv {
+	case CurveEd25519:
+		if alg != 0 && alg != AlgorithmEdDSA {
+			return errInvalidAlgorithm
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return errInvalidKey
+		}
+		key = ed25519.PublicKey(x)
+		if hasD {
+			if len(dBytes) != ed25519.SeedSize {
+				return errInvalidKey
+			}
+			privateKey := ed25519.NewKeyFromSeed(dBytes)
+			if !bytes.Equal(privateKey[32:], x) {
+				return errKeyMismatch
+			}
+			key = privateKey
+		}
+	case CurveX25519:
+		if alg == AlgorithmEdDSA {
+			return errInvalidAlgorithm
+		}
+		publicKey, err := x25519.NewPublicKey(x)
+		if err != nil {
+			return errInvalidKey
+		}
+		key = publicKey
+		if hasD {
+			privateKey, err := x25519.NewPrivateKey(dBytes)
+			if err != nil {
+				return errInvalidKey
+			}
+			if !privateKey.PublicKey().Equal(publicKey) {
+				return errKeyMismatch
+			}
+			key = privateKey
+		}
+	default:
+		return errInvalidCurve
+	}
+
+	*k = Key{
+		Key:       key,
+		KeyID:     keyID,
+		Algorithm: alg,
+	}
+
+	return nil
+}
@@ -0,0 +1,410 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cose
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+var (
+	// RFC 8032, Section 7.1, TEST 1.
+	testSeed, _ = hex.DecodeString("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60")
+
+	testPayload  = []byte("This is the content.")
+	testExternal = []byte{0x11, 0xaa, 0x22, 0xbb, 0x33, 0xcc, 0x44, 0xdd, 0x55, 0x00, 0x66, 0x99}
+)
+
+func mustUnhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("hex.DecodeString: %v", err)
+	}
+	return b
+}
+
+func TestSign1(t *testing.T) {
+	privateKey := ed25519.NewKeyFromSeed(testSeed)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	t.Run("SigStructure", func(t *testing.T) {
+		// go-cose, testdata/sign1-sign-0000.json.
+		b, err := cborMarshal([]interface{}{
+			contextSignature1,
+			mustUnhex(t, "a10126"),
+			testExternal,
+			testPayload,
+		})
+		if err != nil {
+			t.Fatalf("cborMarshal: %v", err)
+		}
+		expected := mustUnhex(t, "846a5369676e61747572653143a101264c11aa22bb33cc44dd5500669954546869732069732074686520636f6e74656e742e")
+		if !bytes.Equal(b, expected) {
+			t.Fatalf("Sig_structure: got %x, expected %x", b, expected)
+		}
+	})
+
+	t.Run("Encoding", func(t *testing.T) {
+		msg := &Sign1Message{
+			Unprotected: Header{KeyID: []byte("11")},
+			Payload:     testPayload,
+		}
+		b, err := msg.Sign(privateKey, testExternal)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+
+		toBeSigned := mustUnhex(t, "846a5369676e61747572653143a101274c11aa22bb33cc44dd5500669954546869732069732074686520636f6e74656e742e")
+		sig := ed25519.Sign(privateKey, toBeSigned)
+		expected := append(mustUnhex(t, "d28443a10127a10442313154546869732069732074686520636f6e74656e742e5840"), sig...)
+		if !bytes.Equal(b, expected) {
+			t.Fatalf("Sign: got %x, expected %x", b, expected)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, msg := range []*Sign1Message{
+			{Payload: testPayload},
+			{Protected: Header{KeyID: []byte("11")}, Payload: testPayload},
+			{Protected: Header{Algorithm: AlgorithmEdDSA}, Unprotected: Header{KeyID: []byte("11")}},
+		} {
+			for _, external := range [][]byte{nil, testExternal} {
+				b, err := msg.Sign(privateKey, external)
+				if err != nil {
+					t.Fatalf("Sign: %v", err)
+				}
+				m, err := VerifySign1(publicKey, b, external)
+				if err != nil {
+					t.Fatalf("VerifySign1: %v", err)
+				}
+				if m.Protected.Algorithm != AlgorithmEdDSA {
+					t.Fatalf("VerifySign1: unexpected algorithm: %d", m.Protected.Algorithm)
+				}
+				if !bytes.Equal(m.Protected.KeyID, msg.Protected.KeyID) || !bytes.Equal(m.Unprotected.KeyID, msg.Unprotected.KeyID) {
+					t.Fatalf("VerifySign1: key ID mismatch")
+				}
+				if !bytes.Equal(m.Payload, msg.Payload) {
+					t.Fatalf("VerifySign1: payload mismatch")
+				}
+
+				// Untagged.
+				if _, err = VerifySign1(publicKey, b[1:], external); err != nil {
+					t.Fatalf("VerifySign1(untagged): %v", err)
+				}
+
+				if _, err = VerifySign1(publicKey, b, []byte("wrong")); err == nil {
+					t.Fatalf("VerifySign1: accepted wrong external data")
+				}
+				wrongKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+				if _, err = VerifySign1(wrongKey.Public().(ed25519.PublicKey), b, external); err == nil {
+					t.Fatalf("VerifySign1: accepted wrong key")
+				}
+				tampered := append([]byte{}, b...)
+				tampered[len(tampered)-1] ^= 1
+				if _, err = VerifySign1(publicKey, tampered, external); err == nil {
+					t.Fatalf("VerifySign1: accepted tampered signature")
+				}
+			}
+		}
+	})
+
+	t.Run("BadHeaders", func(t *testing.T) {
+		for _, msg := range []*Sign1Message{
+			{Protected: Header{Algorithm: -7}},
+			{Unprotected: Header{Algorithm: AlgorithmEdDSA}},
+			{Protected: Header{KeyID: []byte("11")}, Unprotected: Header{KeyID: []byte("11")}},
+		} {
+			if _, err := msg.Sign(privateKey, nil); err == nil {
+				t.Fatalf("Sign: accepted bad headers: %+v", msg)
+			}
+		}
+		if _, err := (&Sign1Message{}).Sign(privateKey[:32], nil); err == nil {
+			t.Fatalf("Sign: accepted a truncated private key")
+		}
+	})
+
+	t.Run("BadMessages", func(t *testing.T) {
+		// go-cose, testdata/sign1-verify-0000.json (ES256).
+		es256 := mustUnhex(t, "d28443a10126a10442313154546869732069732074686520636f6e74656e742e58403a7487d9a528cb61dd8e99bd652c12577fc47d70ee5af2e703c420584f060fc7a8d61e4a35862b2b531a8447030ab966aeed8dd45ebc507c761431e349995770")
+		if _, err := VerifySign1(publicKey, es256, testExternal); err != errInvalidAlgorithm {
+			t.Fatalf("VerifySign1(ES256): unexpected error: %v", err)
+		}
+
+		sign := func(protected, unprotected map[interface{}]interface{}, payload interface{}, tag uint64) []byte {
+			var bodyProtected []byte
+			if len(protected) > 0 {
+				bodyProtected, _ = cborMarshal(protected)
+			}
+			toBeSigned, _ := cborMarshal([]interface{}{contextSignature1, nonNil(bodyProtected), []byte{}, testPayload})
+			b, _ := cborMarshal(cborTag{
+				number: tag,
+				content: []interface{}{
+					nonNil(bodyProtected),
+					unprotected,
+					payload,
+					ed25519.Sign(privateKey, toBeSigned),
+				},
+			})
+			return b
+		}
+		alg := map[interface{}]interface{}{int64(headerAlgorithm): int64(AlgorithmEdDSA)}
+
+		// Sanity check the helper.
+		if _, err := VerifySign1(publicKey, sign(alg, map[interface{}]interface{}{}, testPayload, TagSign1), nil); err != nil {
+			t.Fatalf("VerifySign1: %v", err)
+		}
+
+		for _, v := range []struct {
+			name        string
+			data        []byte
+			expectedErr error
+		}{
+			{
+				"NoAlgorithm",
+				sign(nil, map[interface{}]interface{}{}, testPayload, TagSign1),
+				errInvalidAlgorithm,
+			},
+			{
+				"UnprotectedAlgorithm",
+				sign(nil, alg, testPayload, TagSign1),
+				errUnprotectedAlgorithm,
+			},
+			{
+				"DuplicateLabel",
+				sign(alg, alg, testPayload, TagSign1),
+				errDuplicateLabel,
+			},
+			{
+				"Critical",
+				sign(map[interface{}]interface{}{
+					int64(headerAlgorithm): int64(AlgorithmEdDSA),
+					int64(headerCritical):  []interface{}{int64(-65537)},
+				}, map[interface{}]interface{}{}, testPayload, TagSign1),
+				errCritical,
+			},
+			{
+				"DetachedPayload",
+				sign(alg, map[interface{}]interface{}{}, nil, TagSign1),
+				errDetachedPayload,
+			},
+			{
+				"WrongTag",
+				sign(alg, map[interface{}]interface{}{}, testPayload, TagSign),
+				errInvalidMessage,
+			},
+		} {
+			if _, err := VerifySign1(publicKey, v.data, nil); err != v.expectedErr {
+				t.Fatalf("VerifySign1(%s): unexpected error: %v", v.name, err)
+			}
+		}
+	})
+}
+
+func TestSign(t *testing.T) {
+	var privateKeys []ed25519.PrivateKey
+	var signers []Signer
+	for i := 0; i < 3; i++ {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = byte(i)
+		privateKey := ed25519.NewKeyFromSeed(seed)
+		privateKeys = append(privateKeys, privateKey)
+		signers = append(signers, Signer{
+			PrivateKey:  privateKey,
+			Unprotected: Header{KeyID: []byte{byte(i)}},
+		})
+	}
+
+	msg := &SignMessage{
+		Protected: Header{KeyID: []byte("body")},
+		Payload:   testPayload,
+	}
+	b, err := msg.Sign(testExternal, signers...)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if b[0] != 0xd8 || b[1] != TagSign {
+		t.Fatalf("Sign: unexpected tag: %x", b[:2])
+	}
+
+	for i, privateKey := range privateKeys {
+		m, idx, err := VerifySign(privateKey.Public().(ed25519.PublicKey), b, testExternal)
+		if err != nil {
+			t.Fatalf("VerifySign(%d): %v", i, err)
+		}
+		if idx != i {
+			t.Fatalf("VerifySign(%d): unexpected index: %d", i, idx)
+		}
+		if len(m.Signatures) != len(signers) {
+			t.Fatalf("VerifySign(%d): unexpected number of signatures: %d", i, len(m.Signatures))
+		}
+		if !bytes.Equal(m.Signatures[i].Unprotected.KeyID, []byte{byte(i)}) {
+			t.Fatalf("VerifySign(%d): key ID mismatch", i)
+		}
+		if !bytes.Equal(m.Protected.KeyID, msg.Protected.KeyID) || !bytes.Equal(m.Payload, testPayload) {
+			t.Fatalf("VerifySign(%d): message mismatch", i)
+		}
+
+		if _, _, err = VerifySign(privateKey.Public().(ed25519.PublicKey), b[2:], testExternal); err != nil {
+			t.Fatalf("VerifySign(%d, untagged): %v", i, err)
+		}
+		if _, _, err = VerifySign(privateKey.Public().(ed25519.PublicKey), b, nil); err != errInvalidSignature {
+			t.Fatalf("VerifySign(%d): accepted wrong external data", i)
+		}
+	}
+
+	otherKey := ed25519.NewKeyFromSeed(testSeed)
+	if _, _, err = VerifySign(otherKey.Public().(ed25519.PublicKey), b, testExternal); err != errInvalidSignature {
+		t.Fatalf("VerifySign: accepted wrong key")
+	}
+
+	// A COSE_Sign1 message is not a COSE_Sign message.
+	b1, _ := (&Sign1Message{Payload: testPayload}).Sign(otherKey, nil)
+	if _, _, err = VerifySign(otherKey.Public().(ed25519.PublicKey), b1, nil); err != errInvalidMessage {
+		t.Fatalf("VerifySign(Sign1): unexpected error: %v", err)
+	}
+
+	if _, err = msg.Sign(nil); err != errNoSigners {
+		t.Fatalf("Sign: accepted no signers")
+	}
+	if _, err = (&SignMessage{Protected: Header{Algorithm: AlgorithmEdDSA}}).Sign(nil, signers...); err != errInvalidAlgorithm {
+		t.Fatalf("Sign: accepted algorithm in the body header")
+	}
+}
+
+func TestKey(t *testing.T) {
+	edPrivateKey := ed25519.NewKeyFromSeed(testSeed)
+	xPrivateKey, err := x25519.NewPrivateKeyFromEd25519(edPrivateKey)
+	if err != nil {
+		t.Fatalf("x25519.NewPrivateKeyFromEd25519: %v", err)
+	}
+
+	t.Run("Encoding", func(t *testing.T) {
+		k := &Key{
+			Key:       edPrivateKey.Public(),
+			KeyID:     []byte("11"),
+			Algorithm: AlgorithmEdDSA,
+		}
+		b, err := k.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("MarshalCBOR: %v", err)
+		}
+		// {1: 1, 2: '11', 3: -8, -1: 6, -2: h'd75a...'}
+		expected := mustUnhex(t, "a501010242313103272006215820d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")
+		if !bytes.Equal(b, expected) {
+			t.Fatalf("MarshalCBOR: got %x, expected %x", b, expected)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, v := range []struct {
+			key interface{}
+			alg int64
+		}{
+			{edPrivateKey.Public(), AlgorithmEdDSA},
+			{edPrivateKey, 0},
+			{xPrivateKey.PublicKey(), 0},
+			{xPrivateKey, -25}, // ECDH-ES + HKDF-256
+		} {
+			k := &Key{Key: v.key, KeyID: []byte("kid"), Algorithm: v.alg}
+			b, err := k.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("MarshalCBOR(%T): %v", v.key, err)
+			}
+			var k2 Key
+			if err = k2.UnmarshalCBOR(b); err != nil {
+				t.Fatalf("UnmarshalCBOR(%T): %v", v.key, err)
+			}
+			if !bytes.Equal(k2.KeyID, k.KeyID) || k2.Algorithm != k.Algorithm {
+				t.Fatalf("UnmarshalCBOR(%T): parameter mismatch", v.key)
+			}
+			switch key := v.key.(type) {
+			case ed25519.PublicKey:
+				if !key.Equal(k2.Key) {
+					t.Fatalf("UnmarshalCBOR(%T): key mismatch", v.key)
+				}
+			case ed25519.PrivateKey:
+				if !key.Equal(k2.Key) {
+					t.Fatalf("UnmarshalCBOR(%T): key mismatch", v.key)
+				}
+			case *x25519.PublicKey:
+				if !key.Equal(k2.Key) {
+					t.Fatalf("UnmarshalCBOR(%T): key mismatch", v.key)
+				}
+			case *x25519.PrivateKey:
+				if !key.Equal(k2.Key) {
+					t.Fatalf("UnmarshalCBOR(%T): key mismatch", v.key)
+				}
+			}
+		}
+
+		if _, err := (&Key{Key: edPrivateKey[:32]}).MarshalCBOR(); err == nil {
+			t.Fatalf("MarshalCBOR: accepted a truncated private key")
+		}
+		if _, err := (&Key{Key: "not a key"}).MarshalCBOR(); err != errInvalidKeyType {
+			t.Fatalf("MarshalCBOR: accepted an unsupported key type")
+		}
+	})
+
+	t.Run("BadInputs", func(t *testing.T) {
+		x := []byte(edPrivateKey[32:])
+		d := edPrivateKey.Seed()
+		otherD := make([]byte, 32)
+
+		for _, v := range []struct {
+			name        string
+			m           map[interface{}]interface{}
+			expectedErr error
+		}{
+			{"EC2", map[interface{}]interface{}{int64(1): int64(2), int64(-1): int64(1), int64(-2): x}, errInvalidKeyType},
+			{"NoKeyType", map[interface{}]interface{}{int64(-1): int64(6), int64(-2): x}, errInvalidKeyType},
+			{"Ed448", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(7), int64(-2): x}, errInvalidCurve},
+			{"NoX", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(6)}, errInvalidKey},
+			{"ShortX", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(6), int64(-2): x[:31]}, errInvalidKey},
+			{"ShortD", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(6), int64(-2): x, int64(-4): d[:31]}, errInvalidKey},
+			{"Mismatch", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(6), int64(-2): x, int64(-4): otherD}, errKeyMismatch},
+			{"X25519Mismatch", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(4), int64(-2): x, int64(-4): otherD}, errKeyMismatch},
+			{"EdDSAWithX25519", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(4), int64(-2): x, int64(3): int64(-8)}, errInvalidAlgorithm},
+			{"ES256WithEd25519", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(6), int64(-2): x, int64(3): int64(-7)}, errInvalidAlgorithm},
+			{"BadKeyID", map[interface{}]interface{}{int64(1): int64(1), int64(-1): int64(6), int64(-2): x, int64(2): "11"}, errInvalidKey},
+		} {
+			b, err := cborMarshal(v.m)
+			if err != nil {
+				t.Fatalf("cborMarshal: %v", err)
+			}
+			var k Key
+			if err = k.UnmarshalCBOR(b); err != v.expectedErr {
+				t.Fatalf("UnmarshalCBOR(%s): unexpected error: %v", v.name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package cose
+
+import "github.com/oasisprotocol/ed25519"
+
+// Sign1Message is a COSE_Sign1 message, with a single signature.
+type Sign1Message struct {
+	Protected   Header
+	Unprotected Header
+	Payload     []byte
+}
+
+// Sign signs the message and the externally supplied data externalAAD,
+// which may be nil, and returns the tagged COSE_Sign1 encoding.  The
+// algorithm is set to EdDSA in the protected header.
+func (m *Sign1Message) Sign(privateKey ed25519.PrivateKey, externalAAD []byte) ([]byte, error) {
+	protected, err := checkSigningHeaders(&m.Protected, &m.Unprotected)
+	if err != nil {
+		return nil, err
+	}
+	bodyProtected, err := protected.encodeProtected()
+	if err != nil {
+		return nil, err
+	}
+	payload := nonNil(m.Payload)
+
+	sig, err := sign(privateKey, []interface{}{
+		contextSignature1,
+		bodyProtected,
+		nonNil(externalAAD),
+		payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cborMarshal(cborTag{
+		number: TagSign1,
+		content: []interface{}{
+			bodyProtected,
+			m.Unprotected.toMap(),
+			payload,
+			sig,
+		},
+	})
+}
+
+// VerifySign1 verifies a tagged or untagged COSE_Sign1 message, and the
+// externally supplied data externalAAD, with the public key, and returns
+// the message.
+func VerifySign1(publicKey ed25519.PublicKey, data, externalAAD []byte) (*Sign1Message, error) {
+	arr, err := unmarshalMessage(data, TagSign1)
+	if err != nil {
+		return nil, err
+	}
+	if len(arr) != 4 {
+		return nil, errInvalidMessage
+	}
+
+	protected, unprotected, err := parseHeaders(arr[0], arr[1])
+	if err != nil {
+		return nil, err
+	}
+	if err = checkVerificationHeaders(protected, unprotected); err != nil {
+		return nil, err
+	}
+	payload, err := checkPayload(arr[2])
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verify(publicKey, []interface{}{
+		contextSignature1,
+		arr[0],
+		nonNil(externalAAD),
+		payload,
+	}, arr[3]); err != nil {
+		return nil, err
+	}
+
+	return &Sign1Message{
+		Protected:   *protected,
+		Unprotected: *unprotected,
+		Payload:     payload,
+	}, nil
+}
+
+// Signer is a private key, and the headers of the signature to create with
+// it.
+type Signer struct {
+	PrivateKey  ed25519.PrivateKey
+	Protected   Header
+	Unprotected Header
+}
+
+// Signature is the headers of a COSE_Signature.
+type Signature struct {
+	Protected   Header
+	Unprotected Header
+}
+
+// SignMessage is a COSE_Sign message, with one or more signatures.
+type SignMessage struct {
+	Protected   Header
+	Unprotected Header
+	Payload     []byte
+
+	// Signatures are the signatures of the message, which are populated
+	// by VerifySign.
+	Signatures []Signature
+}
+
+// Sign signs the message and the externally supplied data externalAAD,
+// which may be nil, with each of the signers, and returns the tagged
+// COSE_Sign encoding.
+func (m *SignMessage) Sign(externalAAD []byte, signers ...Signer) ([]byte, error) {
+	if len(signers) == 0 {
+		return nil, errNoSigners
+	}
+	if m.Protected.Algorithm != 0 || m.Unprotected.Algorithm != 0 {
+		return nil, errInvalidAlgorithm
+	}
+	bodyProtected, err := m.Protected.encodeProtected()
+	if err != nil {
+		return nil, err
+	}
+	payload := nonNil(m.Payload)
+
+	signatures := make([]interface{}, 0, len(signers))
+	for i := range signers {
+		s := &signers[i]
+		protected, err := checkSigningHeaders(&s.Protected, &s.Unprotected)
+		if err != nil {
+			return nil, err
+		}
+		signProtected, err := protected.encodeProtected()
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := sign(s.PrivateKey, []interface{}{
+			contextSignature,
+			bodyProtected,
+			signProtected,
+			nonNil(externalAAD),
+			payload,
+		})
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, []interface{}{
+			signProtected,
+			s.Unprotected.toMap(),
+			sig,
+		})
+	}
+
+	return cborMarshal(cborTag{
+		number: TagSign,
+		content: []interface{}{
+			bodyProtected,
+			m.Unprotected.toMap(),
+			payload,
+			signatures,
+		},
+	})
+}
+
+// VerifySign verifies a tagged or untagged COSE_Sign message, and the
+// externally supplied data externalAAD, with the public key, and returns
+// the message, and the index of the first signature that is valid.
+func VerifySign(publicKey ed25519.PublicKey, data, externalAAD []byte) (*SignMessage, int, error) {
+	arr, err := unmarshalMessage(data, TagSign)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(arr) != 4 {
+		return nil, 0, errInvalidMessage
+	}
+
+	bodyProtected, bodyUnprotected, err := parseHeaders(arr[0], arr[1])
+	if err != nil {
+		return nil, 0, err
+	}
+	payload, err := checkPayload(arr[2])
+	if err != nil {
+		return nil, 0, err
+	}
+	rawSignatures, ok := arr[3].([]interface{})
+	if !ok || len(rawSignatures) == 0 {
+		return nil, 0, errInvalidMessage
+	}
+
+	m := &SignMessage{
+		Protected:   *bodyProtected,
+		Unprotected: *bodyUnprotected,
+		Payload:     payload,
+	}
+	valid := -1
+	for i, rawSignature := range rawSignatures {
+		sigArr, ok := rawSignature.([]interface{})
+		if !ok || len(sigArr) != 3 {
+			return nil, 0, errInvalidMessage
+		}
+		protected, unprotected, err := parseHeaders(sigArr[0], sigArr[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		m.Signatures = append(m.Signatures, Signature{
+			Protected:   *protected,
+			Unprotected: *unprotected,
+		})
+
+		if valid >= 0 || checkVerificationHeaders(protected, unprotected) != nil {
+			continue
+		}
+		if verify(publicKey, []interface{}{
+			contextSignature,
+			arr[0],
+			sigArr[0],
+			nonNil(externalAAD),
+			payload,
+		}, sigArr[2]) == nil {
+			valid = i
+		}
+	}
+	if valid < 0 {
+		return nil, 0, errInvalidSignature
+	}
+
+	return m, valid, nil
+}
+
+func unmarshalMessage(data []byte, tag uint64) ([]interface{}, error) {
+	v, err := cborUnmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := v.(cborTag); ok {
+		if t.number != tag {
+			return nil, errInvalidMessage
+		}
+		v = t.content
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, errInvalidMessage
+	}
+	return arr, nil
+}
+
+func checkPayload(v interface{}) ([]byte, error) {
+	switch payload := v.(type) {
+	case []byte:
+		return payload, nil
+	case nil:
+		return nil, errDetachedPayload
+	default:
+		return nil, errInvalidMessage
+	}
+}
+
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,479 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package dkg implements a Pedersen distributed key generation protocol,
+// with Feldman verifiable secret sharing and complaint handling, that
+// produces FROST(Ed25519, SHA-512) key shares without a trusted dealer.
+//
+// Each participant acts as a dealer for a random polynomial, and the group
+// secret key is the sum of the constant terms of the polynomials of all
+// qualified participants.  No participant ever learns the group secret
+// key, and the resulting group public key is a standard Ed25519 public key.
+//
+// The protocol is driven through a Participant, and consists of four
+// rounds of messages followed by a local finalization step:
+//
+//  1. Round1 returns a Round1Message that must be broadcast to every
+//     other participant.
+//  2. Round2 consumes every participant's Round1Message, and returns a
+//     Round2Message for each other participant, that must be sent to
+//     it over a confidential and authenticated channel.
+//  3. Round3 consumes the Round2Messages addressed to the participant,
+//     and returns Complaints (if any) that must be broadcast.
+//  4. Round4 consumes every participant's Complaints, and returns the
+//     ComplaintResponses (if any) that must be broadcast.
+//
+// Finally Finalize consumes every participant's ComplaintResponses, and
+// returns the participant's key share.  The broadcast channel is assumed
+// to be reliable, so that every participant observes the same broadcast
+// messages.  All messages can be serialized with MarshalBinary.
+package dkg
+
+import (
+	cryptorand "crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/oasisprotocol/ed25519/extra/frost"
+	"github.com/oasisprotocol/ed25519/extra/internal/group"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+const (
+	// ContextMaxSize is the maximum allowed context length.
+	ContextMaxSize = 255
+
+	proofPrefix = "FROST-ED25519-SHA512-v1dkg"
+)
+
+var (
+	errBadParameters   = errors.New("dkg: invalid parameters")
+	errBadContext      = errors.New("dkg: bad context length")
+	errBadRound        = errors.New("dkg: round called out of order")
+	errTooFewQualified = errors.New("dkg: too few qualified participants")
+)
+
+type round int
+
+const (
+	roundInit round = iota
+	round1Done
+	round2Done
+	round3Done
+	round4Done
+	roundFinalized
+)
+
+// Participant is a single participant's state in a DKG session.
+type Participant struct {
+	id         frost.Identifier
+	maxSigners int
+	minSigners int
+	context    []byte
+	rand       io.Reader
+	round      round
+
+	coefficients []modm.Bignum256
+	ownShare     modm.Bignum256
+
+	commitments  map[frost.Identifier]frost.VSSCommitment
+	shares       map[frost.Identifier][]byte
+	complaints   []*Complaint
+	disqualified map[frost.Identifier]bool
+}
+
+// NewParticipant creates a new DKG participant with identifier id, for a
+// group of maxSigners participants (with identifiers 1 to maxSigners
+// inclusive) of which any minSigners can sign.  context is a session
+// identifier that must be identical for all participants and unique per
+// DKG session, and must be at most ContextMaxSize bytes.  If rand is nil,
+// crypto/rand.Reader will be used.
+func NewParticipant(rand io.Reader, id frost.Identifier, maxSigners, minSigners int, context []byte) (*Participant, error) {
+	if minSigners < 2 || maxSigners < minSigners || maxSigners > 0xffff {
+		return nil, errBadParameters
+	}
+	if id == 0 || int(id) > maxSigners {
+		return nil, errBadParameters
+	}
+	if len(context) > ContextMaxSize {
+		return nil, errBadContext
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	return &Participant{
+		id:           id,
+		maxSigners:   maxSigners,
+		minSigners:   minSigners,
+		context:      append([]byte{}, context...),
+		rand:         rand,
+		commitments:  make(map[frost.Identifier]frost.VSSCommitment),
+		shares:       make(map[frost.Identifier][]byte),
+		disqualified: make(map[frost.Identifier]bool),
+	}, nil
+}
+
+// Identifier returns the participant's identifier.
+func (p *Participant) Identifier() frost.Identifier {
+	return p.id
+}
+
+// Disqualified returns the identifiers of the participants that have been
+// disqualified so far, in ascending order.
+func (p *Participant) Disqualified() []frost.Identifier {
+	var ids []frost.Identifier
+	for i := 1; i <= p.maxSigners; i++ {
+		if p.disqualified[frost.Identifier(i)] {
+			ids = append(ids, frost.Identifier(i))
+		}
+	}
+	return ids
+}
+
+// Reset clears the participant's secret state.
+func (p *Participant) Reset() {
+	for i := range p.coefficients {
+		p.coefficients[i].Reset()
+	}
+	p.ownShare.Reset()
+	for _, v := range p.shares {
+		for i := range v {
+			v[i] = 0
+		}
+	}
+}
+
+// Round1 samples the participant's secret polynomial, and returns the
+// commitment to it along with a proof of knowledge of the secret constant
+// term.  The returned message must be broadcast to all participants.
+func (p *Participant) Round1() (*Round1Message, error) {
+	if p.round != roundInit {
+		return nil, errBadRound
+	}
+
+	p.coefficients = make([]modm.Bignum256, p.minSigners)
+	commitment := make(frost.VSSCommitment, 0, p.minSigners)
+	for i := range p.coefficients {
+		if err := group.RandomScalar(&p.coefficients[i], p.rand); err != nil {
+			p.Reset()
+			return nil, err
+		}
+
+		var C ge25519.Ge25519
+		ge25519.ScalarmultBaseNiels(&C, &ge25519.NielsBaseMultiples, &p.coefficients[i])
+		commitment = append(commitment, group.EncodeElement(&C))
+	}
+
+	// Prove knowledge of the constant term:
+	//   R = [k]B, c = H(id, ctx, C_0, R), mu = k + a_0 * c
+	var (
+		k, c, mu modm.Bignum256
+		R        ge25519.Ge25519
+	)
+	if err := group.RandomScalar(&k, p.rand); err != nil {
+		p.Reset()
+		return nil, err
+	}
+	ge25519.ScalarmultBaseNiels(&R, &ge25519.NielsBaseMultiples, &k)
+	RBytes := group.EncodeElement(&R)
+	proofChallenge(&c, p.id, p.context, commitment[0], RBytes)
+	modm.Mul(&mu, &p.coefficients[0], &c)
+	modm.Add(&mu, &mu, &k)
+	k.Reset()
+
+	p.commitments[p.id] = commitment
+	p.round = round1Done
+
+	return &Round1Message{
+		From:       p.id,
+		Commitment: commitment,
+		ProofR:     RBytes,
+		ProofMu:    group.EncodeScalar(&mu),
+	}, nil
+}
+
+// Round2 processes the broadcast Round1Messages of all participants, and
+// returns the secret shares for each other qualified participant.  Each
+// returned message must be sent only to its recipient (To), over a
+// confidential and authenticated channel.
+//
+// Participants with a missing or invalid Round1Message are disqualified.
+func (p *Participant) Round2(msgs []*Round1Message) ([]*Round2Message, error) {
+	if p.round != round1Done {
+		return nil, errBadRound
+	}
+
+	received := make(map[frost.Identifier]*Round1Message)
+	for _, msg := range msgs {
+		if msg.From == p.id || msg.From == 0 || int(msg.From) > p.maxSigners {
+			continue
+		}
+		if received[msg.From] != nil {
+			// Equivocation on the broadcast channel.
+			p.disqualified[msg.From] = true
+			continue
+		}
+		received[msg.From] = msg
+	}
+
+	for i := 1; i <= p.maxSigners; i++ {
+		id := frost.Identifier(i)
+		if id == p.id || p.disqualified[id] {
+			continue
+		}
+		msg := received[id]
+		if msg == nil || !msg.verify(p.context, p.minSigners) {
+			p.disqualified[id] = true
+			continue
+		}
+		p.commitments[id] = msg.Commitment
+	}
+
+	var out []*Round2Message
+	for i := 1; i <= p.maxSigners; i++ {
+		id := frost.Identifier(i)
+		if p.disqualified[id] {
+			continue
+		}
+
+		var share modm.Bignum256
+		group.PolynomialEvaluate(&share, uint64(id), p.coefficients)
+		if id == p.id {
+			p.ownShare = share
+		} else {
+			out = append(out, &Round2Message{
+				From:  p.id,
+				To:    id,
+				Share: group.EncodeScalar(&share),
+			})
+		}
+		share.Reset()
+	}
+
+	p.round = round2Done
+
+	return out, nil
+}
+
+// Round3 processes the Round2Messages addressed to the participant, and
+// returns a Complaint against each qualified participant whose share is
+// missing or does not match its commitment.  The returned complaints (if
+// any) must be broadcast to all participants.
+func (p *Participant) Round3(msgs []*Round2Message) ([]*Complaint, error) {
+	if p.round != round2Done {
+		return nil, errBadRound
+	}
+
+	received := make(map[frost.Identifier]*Round2Message)
+	for _, msg := range msgs {
+		if msg.To != p.id {
+			continue
+		}
+		if _, ok := p.commitments[msg.From]; !ok || msg.From == p.id {
+			continue
+		}
+		if received[msg.From] != nil {
+			// Two different shares from the same dealer, treat it
+			// the same as an invalid share.
+			received[msg.From] = &Round2Message{From: msg.From, To: msg.To}
+			continue
+		}
+		received[msg.From] = msg
+	}
+
+	var complaints []*Complaint
+	for i := 1; i <= p.maxSigners; i++ {
+		id := frost.Identifier(i)
+		if id == p.id || p.disqualified[id] {
+			continue
+		}
+
+		msg := received[id]
+		if msg == nil || !p.commitments[id].VerifyShare(p.id, msg.Share) {
+			complaints = append(complaints, &Complaint{
+				From:    p.id,
+				Against: id,
+			})
+			continue
+		}
+		p.shares[id] = append([]byte{}, msg.Share...)
+	}
+
+	// Record the participant's own complaints, so that the disputed
+	// shares are recovered (or the dealers disqualified) even if the
+	// complaints are not fed back into Round4.
+	p.complaints = append(p.complaints, complaints...)
+
+	p.round = round3Done
+
+	return complaints, nil
+}
+
+// Round4 processes the broadcast Complaints of all participants, and
+// returns a ComplaintResponse revealing the disputed share for each
+// complaint against this participant.  The participant's own complaints
+// from Round3 are already recorded, and may be omitted from complaints.
+// The returned responses (if any) must be broadcast to all participants.
+func (p *Participant) Round4(complaints []*Complaint) ([]*ComplaintResponse, error) {
+	if p.round != round3Done {
+		return nil, errBadRound
+	}
+
+	seen := make(map[Complaint]bool)
+	for _, c := range p.complaints {
+		seen[*c] = true
+	}
+	for _, c := range complaints {
+		if seen[*c] || c.From == c.Against {
+			continue
+		}
+		if _, ok := p.commitments[c.Against]; !ok {
+			continue
+		}
+		if _, ok := p.commitments[c.From]; !ok {
+			continue
+		}
+		seen[*c] = true
+		p.complaints = append(p.complaints, c)
+	}
+
+	var responses []*ComplaintResponse
+	for _, c := range p.complaints {
+		if c.Against != p.id {
+			continue
+		}
+
+		var share modm.Bignum256
+		group.PolynomialEvaluate(&share, uint64(c.From), p.coefficients)
+		responses = append(responses, &ComplaintResponse{
+			From:  p.id,
+			To:    c.From,
+			Share: group.EncodeScalar(&share),
+		})
+		share.Reset()
+	}
+
+	p.round = round4Done
+
+	return responses, nil
+}
+
+// Finalize processes the broadcast ComplaintResponses of all participants,
+// disqualifies every participant that failed to answer a complaint with a
+// valid share, and returns the participant's key share along with the
+// group's VSS commitment (from which every participant's public key share
+// can be derived).
+func (p *Participant) Finalize(responses []*ComplaintResponse) (*frost.KeyShare, frost.VSSCommitment, error) {
+	if p.round != round4Done {
+		return nil, nil, errBadRound
+	}
+	p.round = roundFinalized
+	defer p.Reset()
+
+	for _, c := range p.complaints {
+		var resp *ComplaintResponse
+		for _, r := range responses {
+			if r.From == c.Against && r.To == c.From {
+				resp = r
+				break
+			}
+		}
+		if resp == nil || !p.commitments[c.Against].VerifyShare(c.From, resp.Share) {
+			p.disqualified[c.Against] = true
+			continue
+		}
+		if c.From == p.id {
+			p.shares[c.Against] = append([]byte{}, resp.Share...)
+		}
+	}
+
+	// The qualified set is every participant that is not disqualified.
+	var qualified []frost.Identifier
+	for i := 1; i <= p.maxSigners; i++ {
+		id := frost.Identifier(i)
+		if !p.disqualified[id] {
+			qualified = append(qualified, id)
+		}
+	}
+	if len(qualified) < p.minSigners {
+		return nil, nil, errTooFewQualified
+	}
+
+	var (
+		sk, s  modm.Bignum256
+		groupC = make([]ge25519.Ge25519, p.minSigners)
+	)
+	for i := range groupC {
+		ge25519.SetIdentity(&groupC[i])
+	}
+	for _, id := range qualified {
+		if id == p.id {
+			modm.Add(&sk, &sk, &p.ownShare)
+		} else {
+			if !group.DecodeScalar(&s, p.shares[id]) {
+				return nil, nil, errInvalidScalar
+			}
+			modm.Add(&sk, &sk, &s)
+		}
+
+		for k, v := range p.commitments[id] {
+			var C ge25519.Ge25519
+			if !group.DecodeElement(&C, v) {
+				return nil, nil, errInvalidElement
+			}
+			ge25519.Add(&groupC[k], &groupC[k], &C)
+		}
+	}
+	s.Reset()
+
+	commitment := make(frost.VSSCommitment, 0, len(groupC))
+	for i := range groupC {
+		commitment = append(commitment, group.EncodeElement(&groupC[i]))
+	}
+
+	skBytes := group.EncodeScalar(&sk)
+	sk.Reset()
+	defer func() {
+		for i := range skBytes {
+			skBytes[i] = 0
+		}
+	}()
+
+	keyShare, err := frost.NewKeyShare(p.id, skBytes, commitment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keyShare, commitment, nil
+}
+
+func proofChallenge(c *modm.Bignum256, id frost.Identifier, context, C0, R []byte) {
+	group.HashToScalar(c, []byte(proofPrefix), []byte{byte(len(context))}, context, identifierBytes(id), C0, R)
+}
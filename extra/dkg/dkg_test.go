@@ -0,0 +1,391 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package dkg
+
+import (
+	"bytes"
+	"encoding"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/frost"
+)
+
+var testContext = []byte("dkg test session")
+
+type binaryMessage interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// transport round-trips a message through its binary encoding, as a real
+// transport would.
+func transport(t *testing.T, src, dst binaryMessage) {
+	b, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	if err = dst.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+}
+
+type testHooks struct {
+	// disqualified is set if the participant is expected to be
+	// disqualified, and thus unable to finalize.
+	disqualified bool
+
+	round1     func(*Round1Message)
+	round2     func(*Round2Message)
+	complaints func([]*Complaint) []*Complaint
+	responses  func([]*ComplaintResponse) []*ComplaintResponse
+}
+
+type testResult struct {
+	keyShares    map[frost.Identifier]*frost.KeyShare
+	commitments  map[frost.Identifier]frost.VSSCommitment
+	disqualified map[frost.Identifier][]frost.Identifier
+	complaints   int
+}
+
+func runDKG(t *testing.T, maxSigners, minSigners int, hooks map[frost.Identifier]*testHooks) *testResult {
+	participants := make([]*Participant, 0, maxSigners)
+	for i := 1; i <= maxSigners; i++ {
+		p, err := NewParticipant(nil, frost.Identifier(i), maxSigners, minSigners, testContext)
+		if err != nil {
+			t.Fatalf("NewParticipant(%d): %v", i, err)
+		}
+		participants = append(participants, p)
+	}
+	hook := func(id frost.Identifier) *testHooks {
+		if h := hooks[id]; h != nil {
+			return h
+		}
+		return &testHooks{}
+	}
+
+	var round1 []*Round1Message
+	for _, p := range participants {
+		msg, err := p.Round1()
+		if err != nil {
+			t.Fatalf("Round1(%d): %v", p.Identifier(), err)
+		}
+		if h := hook(p.Identifier()).round1; h != nil {
+			h(msg)
+		}
+		var recv Round1Message
+		transport(t, msg, &recv)
+		round1 = append(round1, &recv)
+	}
+
+	round2 := make(map[frost.Identifier][]*Round2Message)
+	for _, p := range participants {
+		msgs, err := p.Round2(round1)
+		if err != nil {
+			t.Fatalf("Round2(%d): %v", p.Identifier(), err)
+		}
+		for _, msg := range msgs {
+			if h := hook(p.Identifier()).round2; h != nil {
+				h(msg)
+			}
+			var recv Round2Message
+			transport(t, msg, &recv)
+			round2[msg.To] = append(round2[msg.To], &recv)
+		}
+	}
+
+	var complaints []*Complaint
+	for _, p := range participants {
+		msgs, err := p.Round3(round2[p.Identifier()])
+		if err != nil {
+			t.Fatalf("Round3(%d): %v", p.Identifier(), err)
+		}
+		for _, msg := range msgs {
+			var recv Complaint
+			transport(t, msg, &recv)
+			complaints = append(complaints, &recv)
+		}
+	}
+
+	var responses []*ComplaintResponse
+	for _, p := range participants {
+		in := complaints
+		if h := hook(p.Identifier()).complaints; h != nil {
+			in = h(in)
+		}
+		msgs, err := p.Round4(in)
+		if err != nil {
+			t.Fatalf("Round4(%d): %v", p.Identifier(), err)
+		}
+		if h := hook(p.Identifier()).responses; h != nil {
+			msgs = h(msgs)
+		}
+		for _, msg := range msgs {
+			var recv ComplaintResponse
+			transport(t, msg, &recv)
+			responses = append(responses, &recv)
+		}
+	}
+
+	res := &testResult{
+		keyShares:    make(map[frost.Identifier]*frost.KeyShare),
+		commitments:  make(map[frost.Identifier]frost.VSSCommitment),
+		disqualified: make(map[frost.Identifier][]frost.Identifier),
+		complaints:   len(complaints),
+	}
+	for _, p := range participants {
+		keyShare, commitment, err := p.Finalize(responses)
+		if hook(p.Identifier()).disqualified {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Finalize(%d): %v", p.Identifier(), err)
+		}
+		res.keyShares[p.Identifier()] = keyShare
+		res.commitments[p.Identifier()] = commitment
+		res.disqualified[p.Identifier()] = p.Disqualified()
+	}
+
+	return res
+}
+
+func (res *testResult) checkConsistent(t *testing.T) ed25519.PublicKey {
+	var groupPublicKey ed25519.PublicKey
+	for id, keyShare := range res.keyShares {
+		if groupPublicKey == nil {
+			groupPublicKey = keyShare.GroupPublicKey
+		}
+		if !bytes.Equal(groupPublicKey, keyShare.GroupPublicKey) {
+			t.Fatalf("participant %d: group public key mismatch", id)
+		}
+		if !res.commitments[id].VerifyShare(id, keyShare.SecretKey) {
+			t.Fatalf("participant %d: VerifyShare failed", id)
+		}
+	}
+	return groupPublicKey
+}
+
+func thresholdSign(t *testing.T, groupPublicKey ed25519.PublicKey, quorum []*frost.KeyShare, message []byte) []byte {
+	var (
+		nonces      []*frost.SigningNonces
+		commitments []*frost.SigningCommitment
+		sigShares   []*frost.SignatureShare
+	)
+	for _, share := range quorum {
+		n, c, err := frost.Commit(nil, share)
+		if err != nil {
+			t.Fatalf("frost.Commit: %v", err)
+		}
+		nonces = append(nonces, n)
+		commitments = append(commitments, c)
+	}
+	for i, share := range quorum {
+		sigShare, err := frost.Sign(share, nonces[i], message, commitments)
+		if err != nil {
+			t.Fatalf("frost.Sign: %v", err)
+		}
+		sigShares = append(sigShares, sigShare)
+	}
+
+	sig, err := frost.Aggregate(groupPublicKey, message, commitments, sigShares)
+	if err != nil {
+		t.Fatalf("frost.Aggregate: %v", err)
+	}
+	return sig
+}
+
+func TestDKG(t *testing.T) {
+	res := runDKG(t, 5, 3, nil)
+	groupPublicKey := res.checkConsistent(t)
+	if res.complaints != 0 {
+		t.Fatalf("unexpected complaints: %d", res.complaints)
+	}
+
+	message := []byte("test message")
+	for _, quorum := range [][]frost.Identifier{
+		{1, 2, 3},
+		{5, 3, 1},
+		{2, 4, 5},
+	} {
+		var shares []*frost.KeyShare
+		for _, id := range quorum {
+			shares = append(shares, res.keyShares[id])
+		}
+		sig := thresholdSign(t, groupPublicKey, shares, message)
+		if !ed25519.Verify(groupPublicKey, message, sig) {
+			t.Errorf("ed25519.Verify: failed for quorum %v", quorum)
+		}
+	}
+}
+
+func TestDKGComplaintResolved(t *testing.T) {
+	// Participant 2 sends a bad share to participant 3, but answers the
+	// complaint with the correct share, so it remains qualified.
+	hooks := map[frost.Identifier]*testHooks{
+		2: {
+			round2: func(msg *Round2Message) {
+				if msg.To == 3 {
+					msg.Share[0] ^= 1
+				}
+			},
+		},
+	}
+
+	res := runDKG(t, 4, 2, hooks)
+	groupPublicKey := res.checkConsistent(t)
+	if res.complaints != 1 {
+		t.Fatalf("unexpected complaints: %d", res.complaints)
+	}
+	for id, dq := range res.disqualified {
+		if len(dq) != 0 {
+			t.Fatalf("participant %d: unexpected disqualifications: %v", id, dq)
+		}
+	}
+
+	message := []byte("test message")
+	sig := thresholdSign(t, groupPublicKey, []*frost.KeyShare{res.keyShares[3], res.keyShares[4]}, message)
+	if !ed25519.Verify(groupPublicKey, message, sig) {
+		t.Errorf("ed25519.Verify: failed")
+	}
+}
+
+func TestDKGOwnComplaintsOmitted(t *testing.T) {
+	// Participant 3 complains about the bad share from participant 2, but
+	// only the other participants' complaints are fed back into its
+	// Round4.  The share in participant 2's response must still be used.
+	hooks := map[frost.Identifier]*testHooks{
+		2: {
+			round2: func(msg *Round2Message) {
+				if msg.To == 3 {
+					msg.Share[0] ^= 1
+				}
+			},
+		},
+		3: {
+			complaints: func(complaints []*Complaint) []*Complaint {
+				var others []*Complaint
+				for _, c := range complaints {
+					if c.From != 3 {
+						others = append(others, c)
+					}
+				}
+				return others
+			},
+		},
+	}
+
+	res := runDKG(t, 4, 2, hooks)
+	groupPublicKey := res.checkConsistent(t)
+	if len(res.keyShares) != 4 {
+		t.Fatalf("unexpected key shares: %d", len(res.keyShares))
+	}
+
+	message := []byte("test message")
+	sig := thresholdSign(t, groupPublicKey, []*frost.KeyShare{res.keyShares[3], res.keyShares[4]}, message)
+	if !ed25519.Verify(groupPublicKey, message, sig) {
+		t.Errorf("ed25519.Verify: failed")
+	}
+}
+
+func TestDKGDisqualification(t *testing.T) {
+	// Participant 2 sends a bad share to participant 3, and does not
+	// answer the complaint.  Participant 4 sends a bogus proof of
+	// knowledge.
+	hooks := map[frost.Identifier]*testHooks{
+		2: {
+			disqualified: true,
+			round2: func(msg *Round2Message) {
+				if msg.To == 3 {
+					msg.Share[0] ^= 1
+				}
+			},
+			responses: func([]*ComplaintResponse) []*ComplaintResponse {
+				return nil
+			},
+		},
+		4: {
+			disqualified: true,
+			round1: func(msg *Round1Message) {
+				msg.ProofMu[0] ^= 1
+			},
+		},
+	}
+
+	res := runDKG(t, 5, 3, hooks)
+	groupPublicKey := res.checkConsistent(t)
+	for id, dq := range res.disqualified {
+		if len(dq) != 2 || dq[0] != 2 || dq[1] != 4 {
+			t.Fatalf("participant %d: unexpected disqualifications: %v", id, dq)
+		}
+	}
+
+	// The qualified participants can sign.
+	message := []byte("test message")
+	sig := thresholdSign(t, groupPublicKey, []*frost.KeyShare{res.keyShares[1], res.keyShares[3], res.keyShares[5]}, message)
+	if !ed25519.Verify(groupPublicKey, message, sig) {
+		t.Errorf("ed25519.Verify: failed")
+	}
+}
+
+func TestRoundOrder(t *testing.T) {
+	p, err := NewParticipant(nil, 1, 3, 2, testContext)
+	if err != nil {
+		t.Fatalf("NewParticipant: %v", err)
+	}
+	if _, err = p.Round2(nil); err == nil {
+		t.Fatalf("Round2: called before Round1")
+	}
+	if _, err = p.Round1(); err != nil {
+		t.Fatalf("Round1: %v", err)
+	}
+	if _, err = p.Round1(); err == nil {
+		t.Fatalf("Round1: called twice")
+	}
+	if _, _, err = p.Finalize(nil); err == nil {
+		t.Fatalf("Finalize: called before Round4")
+	}
+}
+
+func TestBadParameters(t *testing.T) {
+	for _, v := range []struct {
+		id                     frost.Identifier
+		maxSigners, minSigners int
+		context                []byte
+	}{
+		{0, 3, 2, nil},
+		{4, 3, 2, nil},
+		{1, 3, 1, nil},
+		{1, 2, 3, nil},
+		{1, 3, 2, make([]byte, ContextMaxSize+1)},
+	} {
+		if _, err := NewParticipant(nil, v.id, v.maxSigners, v.minSigners, v.context); err == nil {
+			t.Errorf("NewParticipant(%d, %d, %d): accepted bad parameters", v.id, v.maxSigners, v.minSigners)
+		}
+	}
+}
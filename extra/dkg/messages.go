@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package dkg
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/oasisprotocol/ed25519/extra/frost"
+	"github.com/oasisprotocol/ed25519/extra/internal/group"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+var (
+	errMalformedMessage = errors.New("dkg: malformed message")
+	errInvalidScalar    = errors.New("dkg: invalid scalar")
+	errInvalidElement   = errors.New("dkg: invalid group element")
+)
+
+// Round1Message is a participant's broadcast commitment to its secret
+// polynomial, along with a proof of knowledge of the constant term.
+type Round1Message struct {
+	From       frost.Identifier
+	Commitment frost.VSSCommitment
+	ProofR     []byte
+	ProofMu    []byte
+}
+
+// MarshalBinary encodes the message into binary form.
+func (m *Round1Message) MarshalBinary() ([]byte, error) {
+	if len(m.ProofR) != frost.ElementSize || len(m.ProofMu) != frost.ScalarSize || len(m.Commitment) > 0xffff {
+		return nil, errMalformedMessage
+	}
+
+	b := make([]byte, 4, 4+len(m.Commitment)*frost.ElementSize+frost.ElementSize+frost.ScalarSize)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(m.From))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(len(m.Commitment)))
+	for _, v := range m.Commitment {
+		if len(v) != frost.ElementSize {
+			return nil, errMalformedMessage
+		}
+		b = append(b, v...)
+	}
+	b = append(b, m.ProofR...)
+	b = append(b, m.ProofMu...)
+
+	return b, nil
+}
+
+// UnmarshalBinary decodes a binary encoded message.
+func (m *Round1Message) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return errMalformedMessage
+	}
+	n := int(binary.LittleEndian.Uint16(data[2:4]))
+	if len(data) != 4+n*frost.ElementSize+frost.ElementSize+frost.ScalarSize {
+		return errMalformedMessage
+	}
+
+	m.From = frost.Identifier(binary.LittleEndian.Uint16(data[0:2]))
+	data = data[4:]
+	m.Commitment = make(frost.VSSCommitment, 0, n)
+	for i := 0; i < n; i++ {
+		m.Commitment = append(m.Commitment, append([]byte{}, data[:frost.ElementSize]...))
+		data = data[frost.ElementSize:]
+	}
+	m.ProofR = append([]byte{}, data[:frost.ElementSize]...)
+	m.ProofMu = append([]byte{}, data[frost.ElementSize:]...)
+
+	return nil
+}
+
+// verify checks the commitment length and the proof of knowledge.
+func (m *Round1Message) verify(context []byte, minSigners int) bool {
+	if len(m.Commitment) != minSigners {
+		return false
+	}
+
+	var (
+		C0, R, l, r ge25519.Ge25519
+		mu, c       modm.Bignum256
+	)
+	for i, v := range m.Commitment {
+		var C ge25519.Ge25519
+		if !group.DecodeElement(&C, v) {
+			return false
+		}
+		if i == 0 {
+			C0 = C
+		}
+	}
+	if !group.DecodeElement(&R, m.ProofR) || !group.DecodeScalar(&mu, m.ProofMu) {
+		return false
+	}
+
+	// [mu]B == R + [c]C_0
+	proofChallenge(&c, m.From, context, m.Commitment[0], m.ProofR)
+	ge25519.ScalarmultBaseNiels(&l, &ge25519.NielsBaseMultiples, &mu)
+	ge25519.ScalarmultVartime(&r, &C0, &c)
+	ge25519.Add(&r, &r, &R)
+
+	return ge25519.EqualVartime(&l, &r)
+}
+
+// Round2Message is a secret share sent by one participant (From) to
+// another (To).  It must be transmitted confidentially.
+type Round2Message struct {
+	From  frost.Identifier
+	To    frost.Identifier
+	Share []byte
+}
+
+// MarshalBinary encodes the message into binary form.
+func (m *Round2Message) MarshalBinary() ([]byte, error) {
+	return marshalShare(m.From, m.To, m.Share)
+}
+
+// UnmarshalBinary decodes a binary encoded message.
+func (m *Round2Message) UnmarshalBinary(data []byte) (err error) {
+	m.From, m.To, m.Share, err = unmarshalShare(data)
+	return
+}
+
+// Complaint is a broadcast accusation by one participant (From) that the
+// share it received from another (Against) is missing or invalid.
+type Complaint struct {
+	From    frost.Identifier
+	Against frost.Identifier
+}
+
+// MarshalBinary encodes the message into binary form.
+func (m *Complaint) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(m.From))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(m.Against))
+	return b, nil
+}
+
+// UnmarshalBinary decodes a binary encoded message.
+func (m *Complaint) UnmarshalBinary(data []byte) error {
+	if len(data) != 4 {
+		return errMalformedMessage
+	}
+	m.From = frost.Identifier(binary.LittleEndian.Uint16(data[0:2]))
+	m.Against = frost.Identifier(binary.LittleEndian.Uint16(data[2:4]))
+	return nil
+}
+
+// ComplaintResponse is a broadcast by an accused participant (From),
+// publicly revealing the share it sent to the complainer (To).
+type ComplaintResponse struct {
+	From  frost.Identifier
+	To    frost.Identifier
+	Share []byte
+}
+
+// MarshalBinary encodes the message into binary form.
+func (m *ComplaintResponse) MarshalBinary() ([]byte, error) {
+	return marshalShare(m.From, m.To, m.Share)
+}
+
+// UnmarshalBinary decodes a binary encoded message.
+func (m *ComplaintResponse) UnmarshalBinary(data []byte) (err error) {
+	m.From, m.To, m.Share, err = unmarshalShare(data)
+	return
+}
+
+func marshalShare(from, to frost.Identifier, share []byte) ([]byte, error) {
+	if len(share) != frost.ScalarSize {
+		return nil, errMalformedMessage
+	}
+
+	b := make([]byte, 4, 4+frost.ScalarSize)
+	binary.LittleEndian.PutUint16(b[0:2], uint16(from))
+	binary.LittleEndian.PutUint16(b[2:4], uint16(to))
+	return append(b, share...), nil
+}
+
+func unmarshalShare(data []byte) (frost.Identifier, frost.Identifier, []byte, error) {
+	if len(data) != 4+frost.ScalarSize {
+		return 0, 0, nil, errMalformedMessage
+	}
+
+	from := frost.Identifier(binary.LittleEndian.Uint16(data[0:2]))
+	to := frost.Identifier(binary.LittleEndian.Uint16(data[2:4]))
+	return from, to, append([]byte{}, data[4:]...), nil
+}
+
+func identifierBytes(id frost.Identifier) []byte {
+	var b [frost.ScalarSize]byte
+	binary.LittleEndian.PutUint16(b[:], uint16(id))
+	return b[:]
+}
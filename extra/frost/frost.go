@@ -0,0 +1,490 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package frost implements the FROST(Ed25519, SHA-512) threshold signature
+// scheme as specified in RFC 9591.  A quorum of at least minSigners out of
+// maxSigners participants can jointly produce a signature that is
+// indistinguishable from (and verifies as) a standard Ed25519 signature
+// under the group public key, without any single party ever holding the
+// group secret key.
+package frost
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/internal/group"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+const (
+	// ScalarSize is the size, in bytes, of serialized scalars (secret
+	// key shares, signature shares).
+	ScalarSize = group.ScalarSize
+
+	// ElementSize is the size, in bytes, of serialized group elements
+	// (public key shares, nonce commitments).
+	ElementSize = group.ElementSize
+
+	contextString = "FROST-ED25519-SHA512-v1"
+)
+
+var (
+	errInvalidIdentifier  = errors.New("frost: invalid identifier")
+	errInvalidScalar      = errors.New("frost: invalid scalar")
+	errInvalidElement     = errors.New("frost: invalid group element")
+	errDuplicateSigner    = errors.New("frost: duplicate identifier in commitment list")
+	errMissingSigner      = errors.New("frost: signer not in commitment list")
+	errCommitmentMismatch = errors.New("frost: commitment does not match signing nonces")
+	errNoncesUsed         = errors.New("frost: signing nonces already used")
+	errShareCount         = errors.New("frost: signature share count mismatch")
+	errKeyShareMismatch   = errors.New("frost: secret key share does not match public key share")
+)
+
+// Identifier is a participant identifier.  Valid identifiers are non-zero.
+type Identifier uint16
+
+func (id Identifier) toScalar(s *modm.Bignum256) {
+	modm.SetUint64(s, uint64(id))
+}
+
+func (id Identifier) bytes() []byte {
+	var b [ScalarSize]byte
+	b[0], b[1] = byte(id), byte(id>>8)
+	return b[:]
+}
+
+// SigningNonces is a participant's secret nonce pair for a single signing
+// operation.  It MUST NOT be reused, and is cleared by Sign.
+type SigningNonces struct {
+	hiding, binding modm.Bignum256
+	commitment      SigningCommitment
+	used            bool
+}
+
+// Reset clears the secret nonces.
+func (n *SigningNonces) Reset() {
+	n.hiding.Reset()
+	n.binding.Reset()
+	n.used = true
+}
+
+// SigningCommitment is a participant's public commitment to its signing
+// nonces, broadcast to the other signers in the first round.
+type SigningCommitment struct {
+	Identifier Identifier
+	Hiding     []byte
+	Binding    []byte
+}
+
+// SignatureShare is a participant's share of a signature, produced in the
+// second round.
+type SignatureShare struct {
+	Identifier Identifier
+	Share      []byte
+}
+
+// Commit generates a new pair of signing nonces and the corresponding
+// commitment for the participant holding share, using entropy from rand
+// (round one).  If rand is nil, crypto/rand.Reader will be used.
+//
+// The secret key share is checked against the public key share, so that a
+// corrupted share is detected before any nonces are committed to.
+func Commit(rand io.Reader, share *KeyShare) (*SigningNonces, *SigningCommitment, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var sk modm.Bignum256
+	if !group.DecodeScalar(&sk, share.SecretKey) {
+		return nil, nil, errInvalidScalar
+	}
+	defer sk.Reset()
+
+	var pk ge25519.Ge25519
+	ge25519.ScalarmultBaseNiels(&pk, &ge25519.NielsBaseMultiples, &sk)
+	if string(group.EncodeElement(&pk)) != string(share.PublicKey) {
+		return nil, nil, errKeyShareMismatch
+	}
+
+	nonces := new(SigningNonces)
+	if err := nonceGenerate(&nonces.hiding, rand, share.SecretKey); err != nil {
+		return nil, nil, err
+	}
+	if err := nonceGenerate(&nonces.binding, rand, share.SecretKey); err != nil {
+		return nil, nil, err
+	}
+
+	var D, E ge25519.Ge25519
+	ge25519.ScalarmultBaseNiels(&D, &ge25519.NielsBaseMultiples, &nonces.hiding)
+	ge25519.ScalarmultBaseNiels(&E, &ge25519.NielsBaseMultiples, &nonces.binding)
+	nonces.commitment = SigningCommitment{
+		Identifier: share.Identifier,
+		Hiding:     group.EncodeElement(&D),
+		Binding:    group.EncodeElement(&E),
+	}
+
+	commitment := nonces.commitment
+	return nonces, &commitment, nil
+}
+
+func nonceGenerate(r *modm.Bignum256, rand io.Reader, secret []byte) error {
+	var randomBytes [32]byte
+	if _, err := io.ReadFull(rand, randomBytes[:]); err != nil {
+		return err
+	}
+	h3(r, randomBytes[:], secret)
+
+	return nil
+}
+
+// Sign produces the participant's signature share over message (round
+// two), given the commitment list of every participant in the signing
+// quorum including itself.  nonces are cleared on return, and can not
+// be used again.
+func Sign(share *KeyShare, nonces *SigningNonces, message []byte, commitments []*SigningCommitment) (*SignatureShare, error) {
+	if nonces.used {
+		return nil, errNoncesUsed
+	}
+	defer nonces.Reset()
+
+	var sk modm.Bignum256
+	if !group.DecodeScalar(&sk, share.SecretKey) {
+		return nil, errInvalidScalar
+	}
+	defer sk.Reset()
+
+	list, err := newCommitmentList(commitments)
+	if err != nil {
+		return nil, err
+	}
+	idx := list.indexOf(share.Identifier)
+	if idx < 0 {
+		return nil, errMissingSigner
+	}
+	if !list.raw[idx].equal(&nonces.commitment) {
+		return nil, errCommitmentMismatch
+	}
+
+	bindingFactors := list.bindingFactors(share.GroupPublicKey, message)
+	var R ge25519.Ge25519
+	list.groupCommitment(&R, bindingFactors)
+
+	var lambda, c, z, t modm.Bignum256
+	if err = list.interpolatingValue(&lambda, share.Identifier); err != nil {
+		return nil, err
+	}
+	computeChallenge(&c, &R, share.GroupPublicKey, message)
+
+	// z = d + (e * rho) + (lambda * sk * c)
+	modm.Mul(&z, &nonces.binding, &bindingFactors[idx])
+	modm.Add(&z, &z, &nonces.hiding)
+	modm.Mul(&t, &lambda, &sk)
+	modm.Mul(&t, &t, &c)
+	modm.Add(&z, &z, &t)
+	t.Reset()
+
+	sigShare := &SignatureShare{
+		Identifier: share.Identifier,
+		Share:      group.EncodeScalar(&z),
+	}
+	z.Reset()
+
+	return sigShare, nil
+}
+
+// VerifySignatureShare reports whether sigShare is a valid signature share
+// over message by the participant with the public key share publicKeyShare,
+// given the commitment list used for signing.  It can be used by the
+// aggregator to identify misbehaving participants.
+func VerifySignatureShare(groupPublicKey ed25519.PublicKey, publicKeyShare, message []byte, commitments []*SigningCommitment, sigShare *SignatureShare) bool {
+	var (
+		z, lambda, c modm.Bignum256
+		PK, R        ge25519.Ge25519
+	)
+
+	if !group.DecodeScalar(&z, sigShare.Share) || !group.DecodeElement(&PK, publicKeyShare) {
+		return false
+	}
+
+	list, err := newCommitmentList(commitments)
+	if err != nil {
+		return false
+	}
+	idx := list.indexOf(sigShare.Identifier)
+	if idx < 0 {
+		return false
+	}
+
+	bindingFactors := list.bindingFactors(groupPublicKey, message)
+	list.groupCommitment(&R, bindingFactors)
+	if list.interpolatingValue(&lambda, sigShare.Identifier) != nil {
+		return false
+	}
+	computeChallenge(&c, &R, groupPublicKey, message)
+
+	// [z]B == D + [rho]E + [c * lambda]PK
+	var l, r, t ge25519.Ge25519
+	ge25519.ScalarmultBaseNiels(&l, &ge25519.NielsBaseMultiples, &z)
+
+	ge25519.ScalarmultVartime(&r, &list.entries[idx].binding, &bindingFactors[idx])
+	ge25519.Add(&r, &r, &list.entries[idx].hiding)
+	modm.Mul(&c, &c, &lambda)
+	ge25519.ScalarmultVartime(&t, &PK, &c)
+	ge25519.Add(&r, &r, &t)
+
+	return ge25519.EqualVartime(&l, &r)
+}
+
+// Aggregate combines the signature shares of every participant in the
+// commitment list into a standard 64 byte Ed25519 signature over message.
+// The signature shares are not individually verified, if the resulting
+// signature fails to verify, VerifySignatureShare can be used to find the
+// invalid shares.
+func Aggregate(groupPublicKey ed25519.PublicKey, message []byte, commitments []*SigningCommitment, sigShares []*SignatureShare) ([]byte, error) {
+	list, err := newCommitmentList(commitments)
+	if err != nil {
+		return nil, err
+	}
+	if len(sigShares) != len(list.entries) {
+		return nil, errShareCount
+	}
+
+	var (
+		z, zi modm.Bignum256
+		seen  = make(map[Identifier]bool)
+	)
+	for _, sigShare := range sigShares {
+		if list.indexOf(sigShare.Identifier) < 0 {
+			return nil, errMissingSigner
+		}
+		if seen[sigShare.Identifier] {
+			return nil, errDuplicateSigner
+		}
+		seen[sigShare.Identifier] = true
+
+		if !group.DecodeScalar(&zi, sigShare.Share) {
+			return nil, errInvalidScalar
+		}
+		modm.Add(&z, &z, &zi)
+	}
+
+	bindingFactors := list.bindingFactors(groupPublicKey, message)
+	var R ge25519.Ge25519
+	list.groupCommitment(&R, bindingFactors)
+
+	sig := make([]byte, ed25519.SignatureSize)
+	ge25519.Pack(sig[:32], &R)
+	modm.Contract(sig[32:], &z)
+
+	return sig, nil
+}
+
+func (c *SigningCommitment) equal(other *SigningCommitment) bool {
+	return c.Identifier == other.Identifier &&
+		string(c.Hiding) == string(other.Hiding) &&
+		string(c.Binding) == string(other.Binding)
+}
+
+type commitmentEntry struct {
+	id              Identifier
+	hiding, binding ge25519.Ge25519
+}
+
+// commitmentList is a validated commitment list, sorted by identifier.
+type commitmentList struct {
+	raw     []*SigningCommitment
+	entries []commitmentEntry
+}
+
+func newCommitmentList(commitments []*SigningCommitment) (*commitmentList, error) {
+	if len(commitments) == 0 {
+		return nil, errMissingSigner
+	}
+
+	raw := make([]*SigningCommitment, len(commitments))
+	copy(raw, commitments)
+	sort.SliceStable(raw, func(i, j int) bool {
+		return raw[i].Identifier < raw[j].Identifier
+	})
+
+	list := &commitmentList{
+		raw:     raw,
+		entries: make([]commitmentEntry, len(raw)),
+	}
+	for i, c := range raw {
+		if c.Identifier == 0 {
+			return nil, errInvalidIdentifier
+		}
+		if i > 0 && raw[i-1].Identifier == c.Identifier {
+			return nil, errDuplicateSigner
+		}
+
+		e := &list.entries[i]
+		e.id = c.Identifier
+		if !group.DecodeElement(&e.hiding, c.Hiding) {
+			return nil, errInvalidElement
+		}
+		if !group.DecodeElement(&e.binding, c.Binding) {
+			return nil, errInvalidElement
+		}
+	}
+
+	return list, nil
+}
+
+func (l *commitmentList) indexOf(id Identifier) int {
+	for i := range l.entries {
+		if l.entries[i].id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (l *commitmentList) encode() []byte {
+	b := make([]byte, 0, len(l.raw)*(ScalarSize+2*ElementSize))
+	for _, c := range l.raw {
+		b = append(b, c.Identifier.bytes()...)
+		b = append(b, c.Hiding...)
+		b = append(b, c.Binding...)
+	}
+	return b
+}
+
+func (l *commitmentList) bindingFactors(groupPublicKey ed25519.PublicKey, message []byte) []modm.Bignum256 {
+	msgHash := h4(message)
+	encodedCommitHash := h5(l.encode())
+
+	rhoInputPrefix := make([]byte, 0, len(groupPublicKey)+len(msgHash)+len(encodedCommitHash))
+	rhoInputPrefix = append(rhoInputPrefix, groupPublicKey...)
+	rhoInputPrefix = append(rhoInputPrefix, msgHash...)
+	rhoInputPrefix = append(rhoInputPrefix, encodedCommitHash...)
+
+	factors := make([]modm.Bignum256, len(l.entries))
+	for i := range l.entries {
+		h1(&factors[i], rhoInputPrefix, l.entries[i].id.bytes())
+	}
+
+	return factors
+}
+
+func (l *commitmentList) groupCommitment(r *ge25519.Ge25519, bindingFactors []modm.Bignum256) {
+	var t ge25519.Ge25519
+
+	ge25519.SetIdentity(r)
+	for i := range l.entries {
+		e := &l.entries[i]
+		ge25519.ScalarmultVartime(&t, &e.binding, &bindingFactors[i])
+		ge25519.Add(r, r, &t)
+		ge25519.Add(r, r, &e.hiding)
+	}
+}
+
+func (l *commitmentList) interpolatingValue(r *modm.Bignum256, id Identifier) error {
+	ids := make([]Identifier, 0, len(l.entries))
+	for i := range l.entries {
+		ids = append(ids, l.entries[i].id)
+	}
+
+	return deriveInterpolatingValue(r, ids, id)
+}
+
+// deriveInterpolatingValue computes the Lagrange coefficient for x_i at 0,
+// given the set of participants ids.
+func deriveInterpolatingValue(r *modm.Bignum256, ids []Identifier, xi Identifier) error {
+	var (
+		found                 bool
+		num, den, xiS, xjS, t modm.Bignum256
+	)
+
+	xi.toScalar(&xiS)
+	modm.SetUint64(&num, 1)
+	modm.SetUint64(&den, 1)
+	for i, xj := range ids {
+		if xj == 0 {
+			return errInvalidIdentifier
+		}
+		for _, xk := range ids[:i] {
+			if xk == xj {
+				return errDuplicateSigner
+			}
+		}
+		if xj == xi {
+			found = true
+			continue
+		}
+
+		xj.toScalar(&xjS)
+		modm.Mul(&num, &num, &xjS)
+		modm.Sub(&t, &xjS, &xiS)
+		modm.Mul(&den, &den, &t)
+	}
+	if !found {
+		return errMissingSigner
+	}
+
+	modm.Invert(&den, &den)
+	modm.Mul(r, &num, &den)
+
+	return nil
+}
+
+func computeChallenge(c *modm.Bignum256, R *ge25519.Ge25519, groupPublicKey ed25519.PublicKey, message []byte) {
+	h2(c, group.EncodeElement(R), groupPublicKey, message)
+}
+
+func hashToScalar(s *modm.Bignum256, tag string, m ...[]byte) {
+	if tag != "" {
+		m = append([][]byte{[]byte(contextString), []byte(tag)}, m...)
+	}
+	group.HashToScalar(s, m...)
+}
+
+func hashToBytes(tag string, m []byte) []byte {
+	h := sha512.New()
+	_, _ = h.Write([]byte(contextString))
+	_, _ = h.Write([]byte(tag))
+	_, _ = h.Write(m)
+	return h.Sum(nil)
+}
+
+func h1(s *modm.Bignum256, m ...[]byte) { hashToScalar(s, "rho", m...) }
+
+func h2(s *modm.Bignum256, m ...[]byte) { hashToScalar(s, "", m...) }
+
+func h3(s *modm.Bignum256, m ...[]byte) { hashToScalar(s, "nonce", m...) }
+
+func h4(m []byte) []byte { return hashToBytes("msg", m) }
+
+func h5(m []byte) []byte { return hashToBytes("com", m) }
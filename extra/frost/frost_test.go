@@ -0,0 +1,364 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package frost
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/internal/group"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+func mustUnhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestRFC9591 checks the implementation against the FROST(Ed25519, SHA-512)
+// test vectors from RFC 9591 Appendix E.1.
+func TestRFC9591(t *testing.T) {
+	message := mustUnhex(t, "74657374")
+	groupPublicKey := ed25519.PublicKey(mustUnhex(t, "15d21ccd7ee42959562fc8aa63224c8851fb3ec85a3faf66040d380fb9738673"))
+
+	secretKeys := map[Identifier]string{
+		1: "929dcc590407aae7d388761cddb0c0db6f5627aea8e217f4a033f2ec83d93509",
+		2: "a91e66e012e4364ac9aaa405fcafd370402d9859f7b6685c07eed76bf409e80d",
+		3: "d3cb090a075eb154e82fdb4b3cb507f110040905468bb9c46da8bdea643a9a02",
+	}
+
+	// The dealer's polynomial.
+	var commitment VSSCommitment
+	for _, coeff := range []string{
+		"7b1c33d3f5291d85de664833beb1ad469f7fb6025a0ec78b3a790c6e13a98304",
+		"178199860edd8c62f5212ee91eff1295d0d670ab4ed4506866bae57e7030b204",
+	} {
+		var (
+			a modm.Bignum256
+			C ge25519.Ge25519
+		)
+		if !group.DecodeScalar(&a, mustUnhex(t, coeff)) {
+			t.Fatalf("invalid coefficient: %s", coeff)
+		}
+		ge25519.ScalarmultBaseNiels(&C, &ge25519.NielsBaseMultiples, &a)
+		commitment = append(commitment, group.EncodeElement(&C))
+	}
+	if pk, _ := commitment.GroupPublicKey(); !bytes.Equal(pk, groupPublicKey) {
+		t.Fatalf("group public key mismatch: %x", pk)
+	}
+
+	keyShares := make(map[Identifier]*KeyShare)
+	for id, sk := range secretKeys {
+		share, err := NewKeyShare(id, mustUnhex(t, sk), commitment)
+		if err != nil {
+			t.Fatalf("NewKeyShare(%d): %v", id, err)
+		}
+		keyShares[id] = share
+	}
+
+	type signerVector struct {
+		id                      Identifier
+		hidingRand, bindingRand string
+		hiding, binding         string
+		sigShare                string
+	}
+	signers := []signerVector{
+		{
+			id:          1,
+			hidingRand:  "0fd2e39e111cdc266f6c0f4d0fd45c947761f1f5d3cb583dfcb9bbaf8d4c9fec",
+			bindingRand: "69cd85f631d5f7f2721ed5e40519b1366f340a87c2f6856363dbdcda348a7501",
+			hiding:      "b5aa8ab305882a6fc69cbee9327e5a45e54c08af61ae77cb8207be3d2ce13de3",
+			binding:     "67e98ab55aa310c3120418e5050c9cf76cf387cb20ac9e4b6fdb6f82a469f932",
+			sigShare:    "001719ab5a53ee1a12095cd088fd149702c0720ce5fd2f29dbecf24b7281b603",
+		},
+		{
+			id:          3,
+			hidingRand:  "86d64a260059e495d0fb4fcc17ea3da7452391baa494d4b00321098ed2a0062f",
+			bindingRand: "13e6b25afb2eba51716a9a7d44130c0dbae0004a9ef8d7b5550c8a0e07c61775",
+			hiding:      "cfbdb165bd8aad6eb79deb8d287bcc0ab6658ae57fdcc98ed12c0669e90aec91",
+			binding:     "7487bc41a6e712eea2f2af24681b58b1cf1da278ea11fe4e8b78398965f13552",
+			sigShare:    "bd86125de990acc5e1f13781d8e32c03a9bbd4c53539bbc106058bfd14326007",
+		},
+	}
+	expectedSig := mustUnhex(t, "36282629c383bb820a88b71cae937d41f2f2adfcc3d02e55507e2fb9e2dd3cbebd9d2b0844e49ae0f3fa935161e1419aab7b47d21a37ebeae1f17d4987b3160b")
+
+	var (
+		nonces      []*SigningNonces
+		commitments []*SigningCommitment
+	)
+	for _, v := range signers {
+		rd := bytes.NewReader(mustUnhex(t, v.hidingRand+v.bindingRand))
+		n, c, err := Commit(rd, keyShares[v.id])
+		if err != nil {
+			t.Fatalf("Commit(%d): %v", v.id, err)
+		}
+		if hex.EncodeToString(c.Hiding) != v.hiding {
+			t.Errorf("hiding nonce commitment mismatch (%d): %x", v.id, c.Hiding)
+		}
+		if hex.EncodeToString(c.Binding) != v.binding {
+			t.Errorf("binding nonce commitment mismatch (%d): %x", v.id, c.Binding)
+		}
+		nonces = append(nonces, n)
+		commitments = append(commitments, c)
+	}
+
+	var sigShares []*SignatureShare
+	for i, v := range signers {
+		share, err := Sign(keyShares[v.id], nonces[i], message, commitments)
+		if err != nil {
+			t.Fatalf("Sign(%d): %v", v.id, err)
+		}
+		if hex.EncodeToString(share.Share) != v.sigShare {
+			t.Errorf("signature share mismatch (%d): %x", v.id, share.Share)
+		}
+		if !VerifySignatureShare(groupPublicKey, keyShares[v.id].PublicKey, message, commitments, share) {
+			t.Errorf("VerifySignatureShare(%d): failed", v.id)
+		}
+		sigShares = append(sigShares, share)
+	}
+
+	sig, err := Aggregate(groupPublicKey, message, commitments, sigShares)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if !bytes.Equal(sig, expectedSig) {
+		t.Errorf("signature mismatch: %x", sig)
+	}
+	if !ed25519.Verify(groupPublicKey, message, sig) {
+		t.Errorf("ed25519.Verify: failed")
+	}
+}
+
+func signWithQuorum(t *testing.T, groupPublicKey ed25519.PublicKey, quorum []*KeyShare, message []byte) ([]byte, []*SigningCommitment, []*SignatureShare) {
+	var (
+		nonces      []*SigningNonces
+		commitments []*SigningCommitment
+		sigShares   []*SignatureShare
+	)
+	for _, share := range quorum {
+		n, c, err := Commit(nil, share)
+		if err != nil {
+			t.Fatalf("Commit(%d): %v", share.Identifier, err)
+		}
+		nonces = append(nonces, n)
+		commitments = append(commitments, c)
+	}
+	for i, share := range quorum {
+		sigShare, err := Sign(share, nonces[i], message, commitments)
+		if err != nil {
+			t.Fatalf("Sign(%d): %v", share.Identifier, err)
+		}
+		sigShares = append(sigShares, sigShare)
+	}
+
+	sig, err := Aggregate(groupPublicKey, message, commitments, sigShares)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+
+	return sig, commitments, sigShares
+}
+
+func TestThresholdSign(t *testing.T) {
+	const (
+		maxSigners = 5
+		minSigners = 3
+	)
+
+	shares, commitment, err := TrustedDealerKeygen(nil, nil, maxSigners, minSigners)
+	if err != nil {
+		t.Fatalf("TrustedDealerKeygen: %v", err)
+	}
+	groupPublicKey, err := commitment.GroupPublicKey()
+	if err != nil {
+		t.Fatalf("GroupPublicKey: %v", err)
+	}
+
+	for _, share := range shares {
+		if !commitment.VerifyShare(share.Identifier, share.SecretKey) {
+			t.Errorf("VerifyShare(%d): failed", share.Identifier)
+		}
+		pk, err := commitment.PublicKeyShare(share.Identifier)
+		if err != nil || !bytes.Equal(pk, share.PublicKey) {
+			t.Errorf("PublicKeyShare(%d): mismatch", share.Identifier)
+		}
+	}
+	if commitment.VerifyShare(shares[0].Identifier, shares[1].SecretKey) {
+		t.Errorf("VerifyShare: accepted share for a different participant")
+	}
+
+	message := []byte("test message")
+	for _, quorum := range [][]*KeyShare{
+		{shares[0], shares[1], shares[2]},
+		{shares[4], shares[2], shares[0]},
+		{shares[1], shares[3], shares[4]},
+		shares,
+	} {
+		sig, _, _ := signWithQuorum(t, groupPublicKey, quorum, message)
+		if !ed25519.Verify(groupPublicKey, message, sig) {
+			t.Errorf("ed25519.Verify: failed for quorum of %d", len(quorum))
+		}
+	}
+
+	// Too few signers must not produce a valid signature.
+	sig, _, _ := signWithQuorum(t, groupPublicKey, shares[:minSigners-1], message)
+	if ed25519.Verify(groupPublicKey, message, sig) {
+		t.Errorf("ed25519.Verify: accepted signature from below-threshold quorum")
+	}
+}
+
+func TestSignatureShareVerification(t *testing.T) {
+	shares, commitment, err := TrustedDealerKeygen(nil, nil, 3, 2)
+	if err != nil {
+		t.Fatalf("TrustedDealerKeygen: %v", err)
+	}
+	groupPublicKey, _ := commitment.GroupPublicKey()
+
+	message := []byte("test message")
+	quorum := shares[:2]
+	_, commitments, sigShares := signWithQuorum(t, groupPublicKey, quorum, message)
+
+	for i, sigShare := range sigShares {
+		if !VerifySignatureShare(groupPublicKey, quorum[i].PublicKey, message, commitments, sigShare) {
+			t.Errorf("VerifySignatureShare(%d): failed", sigShare.Identifier)
+		}
+		if VerifySignatureShare(groupPublicKey, quorum[i].PublicKey, []byte("wrong message"), commitments, sigShare) {
+			t.Errorf("VerifySignatureShare(%d): accepted share for wrong message", sigShare.Identifier)
+		}
+	}
+
+	bad := *sigShares[0]
+	bad.Share = append([]byte{}, bad.Share...)
+	bad.Share[0] ^= 1
+	if VerifySignatureShare(groupPublicKey, quorum[0].PublicKey, message, commitments, &bad) {
+		t.Errorf("VerifySignatureShare: accepted tampered share")
+	}
+	sig, err := Aggregate(groupPublicKey, message, commitments, []*SignatureShare{&bad, sigShares[1]})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if ed25519.Verify(groupPublicKey, message, sig) {
+		t.Errorf("ed25519.Verify: accepted signature with tampered share")
+	}
+
+	if _, err = Aggregate(groupPublicKey, message, commitments, sigShares[:1]); err == nil {
+		t.Errorf("Aggregate: accepted missing signature share")
+	}
+	if _, err = Aggregate(groupPublicKey, message, commitments, []*SignatureShare{sigShares[0], sigShares[0]}); err == nil {
+		t.Errorf("Aggregate: accepted duplicate signature share")
+	}
+}
+
+func TestSigningNonceReuse(t *testing.T) {
+	shares, _, err := TrustedDealerKeygen(nil, nil, 2, 2)
+	if err != nil {
+		t.Fatalf("TrustedDealerKeygen: %v", err)
+	}
+
+	n1, c1, _ := Commit(nil, shares[0])
+	_, c2, _ := Commit(nil, shares[1])
+	commitments := []*SigningCommitment{c1, c2}
+
+	if _, err = Sign(shares[0], n1, []byte("message 1"), commitments); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err = Sign(shares[0], n1, []byte("message 2"), commitments); err == nil {
+		t.Fatalf("Sign: reused signing nonces")
+	}
+
+	// Signing with a commitment list that does not contain the nonces'
+	// commitment must fail.
+	n1, _, _ = Commit(nil, shares[0])
+	if _, err = Sign(shares[0], n1, []byte("message"), commitments); err == nil {
+		t.Fatalf("Sign: accepted mismatched commitment list")
+	}
+}
+
+func TestCommitKeyShareMismatch(t *testing.T) {
+	shares, _, err := TrustedDealerKeygen(nil, nil, 2, 2)
+	if err != nil {
+		t.Fatalf("TrustedDealerKeygen: %v", err)
+	}
+
+	share := *shares[0]
+	share.PublicKey = shares[1].PublicKey
+	if _, _, err = Commit(nil, &share); err == nil {
+		t.Fatalf("Commit: accepted secret key share not matching public key share")
+	}
+}
+
+func TestSplitPrivateKey(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	shares, commitment, err := SplitPrivateKey(nil, privateKey, 3, 2)
+	if err != nil {
+		t.Fatalf("SplitPrivateKey: %v", err)
+	}
+	groupPublicKey, _ := commitment.GroupPublicKey()
+	if !bytes.Equal(groupPublicKey, publicKey) {
+		t.Fatalf("group public key mismatch: %x (expected %x)", groupPublicKey, publicKey)
+	}
+
+	message := []byte("test message")
+	sig, _, _ := signWithQuorum(t, groupPublicKey, shares[1:], message)
+	if !ed25519.Verify(publicKey, message, sig) {
+		t.Errorf("ed25519.Verify: failed")
+	}
+}
+
+func TestBadParameters(t *testing.T) {
+	for _, v := range [][2]int{
+		{3, 1},
+		{2, 3},
+		{0x10000, 2},
+	} {
+		if _, _, err := TrustedDealerKeygen(nil, nil, v[0], v[1]); err == nil {
+			t.Errorf("TrustedDealerKeygen(%d, %d): accepted bad parameters", v[0], v[1])
+		}
+	}
+
+	var zero [ScalarSize]byte
+	if _, _, err := TrustedDealerKeygen(nil, zero[:], 3, 2); err == nil {
+		t.Errorf("TrustedDealerKeygen: accepted zero secret key")
+	}
+	nonCanonical := bytes.Repeat([]byte{0xff}, ScalarSize)
+	if _, _, err := TrustedDealerKeygen(nil, nonCanonical, 3, 2); err == nil {
+		t.Errorf("TrustedDealerKeygen: accepted non-canonical secret key")
+	}
+}
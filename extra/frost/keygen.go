@@ -0,0 +1,276 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package frost
+
+import (
+	cryptorand "crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/internal/group"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+var (
+	errBadThreshold  = errors.New("frost: invalid threshold parameters")
+	errBadCommitment = errors.New("frost: invalid VSS commitment")
+	errBadPrivateKey = errors.New("frost: bad private key length")
+	errShareMismatch = errors.New("frost: secret share does not match VSS commitment")
+)
+
+// KeyShare is a participant's share of the group signing key.
+type KeyShare struct {
+	// Identifier is the participant's identifier.
+	Identifier Identifier
+
+	// SecretKey is the participant's secret key share (sk_i).
+	SecretKey []byte
+
+	// PublicKey is the participant's public key share (PK_i).
+	PublicKey []byte
+
+	// GroupPublicKey is the group's Ed25519 public key.
+	GroupPublicKey ed25519.PublicKey
+}
+
+// VSSCommitment is a Feldman verifiable secret sharing commitment to the
+// coefficients of a secret sharing polynomial, constant term first.  The
+// first element is the group public key.
+type VSSCommitment [][]byte
+
+// GroupPublicKey returns the group public key committed to by c.
+func (c VSSCommitment) GroupPublicKey() (ed25519.PublicKey, error) {
+	if len(c) == 0 {
+		return nil, errBadCommitment
+	}
+
+	var p ge25519.Ge25519
+	if !group.DecodeElement(&p, c[0]) {
+		return nil, errInvalidElement
+	}
+
+	return ed25519.PublicKey(group.EncodeElement(&p)), nil
+}
+
+// PublicKeyShare returns the public key share of the participant id, as
+// derived from the commitment.
+func (c VSSCommitment) PublicKeyShare(id Identifier) ([]byte, error) {
+	var p ge25519.Ge25519
+	if err := c.evaluate(&p, id); err != nil {
+		return nil, err
+	}
+
+	return group.EncodeElement(&p), nil
+}
+
+// VerifyShare reports whether secretKey is a valid secret share for the
+// participant id, under the commitment.
+func (c VSSCommitment) VerifyShare(id Identifier, secretKey []byte) bool {
+	var (
+		sk         modm.Bignum256
+		Si, SiComm ge25519.Ge25519
+	)
+
+	if !group.DecodeScalar(&sk, secretKey) {
+		return false
+	}
+	ge25519.ScalarmultBaseNiels(&Si, &ge25519.NielsBaseMultiples, &sk)
+	sk.Reset()
+
+	if c.evaluate(&SiComm, id) != nil {
+		return false
+	}
+
+	return ge25519.EqualVartime(&Si, &SiComm)
+}
+
+// evaluate computes sum(c[k] * id^k).
+func (c VSSCommitment) evaluate(r *ge25519.Ge25519, id Identifier) error {
+	if id == 0 {
+		return errInvalidIdentifier
+	}
+	if len(c) == 0 {
+		return errBadCommitment
+	}
+
+	var (
+		x, xPow modm.Bignum256
+		C, t    ge25519.Ge25519
+	)
+	id.toScalar(&x)
+	modm.SetUint64(&xPow, 1)
+
+	ge25519.SetIdentity(r)
+	for _, v := range c {
+		if !group.DecodeElement(&C, v) {
+			return errInvalidElement
+		}
+		ge25519.ScalarmultVartime(&t, &C, &xPow)
+		ge25519.Add(r, r, &t)
+		modm.Mul(&xPow, &xPow, &x)
+	}
+
+	return nil
+}
+
+// TrustedDealerKeygen splits secretKey, a canonically encoded scalar, into
+// maxSigners shares such that any minSigners of them can sign, using
+// entropy from rand.  If secretKey is nil, a new random group secret key
+// will be generated.  If rand is nil, crypto/rand.Reader will be used.
+//
+// The dealer learns the group secret key, and must be trusted to erase it.
+func TrustedDealerKeygen(rand io.Reader, secretKey []byte, maxSigners, minSigners int) ([]*KeyShare, VSSCommitment, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var s modm.Bignum256
+	defer s.Reset()
+	if secretKey == nil {
+		if err := group.RandomScalar(&s, rand); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if !group.DecodeScalar(&s, secretKey) {
+			return nil, nil, errInvalidScalar
+		}
+		if modm.IsZeroVartime(&s) {
+			return nil, nil, errInvalidScalar
+		}
+	}
+
+	return trustedDealerKeygen(rand, &s, maxSigners, minSigners)
+}
+
+// SplitPrivateKey splits an existing Ed25519 private key into maxSigners
+// shares such that any minSigners of them can sign, using entropy from
+// rand.  The resulting group public key is the private key's public key.
+// If rand is nil, crypto/rand.Reader will be used.
+//
+// Note: Signatures produced by the group are randomized, and will differ
+// from the deterministic signatures that privateKey would produce.
+func SplitPrivateKey(rand io.Reader, privateKey ed25519.PrivateKey, maxSigners, minSigners int) ([]*KeyShare, VSSCommitment, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, nil, errBadPrivateKey
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	expandedPrivateKey := ed25519.ExpandPrivateKey(privateKey)
+
+	var s modm.Bignum256
+	modm.Expand(&s, expandedPrivateKey[:32])
+	for i := range expandedPrivateKey {
+		expandedPrivateKey[i] = 0
+	}
+	defer s.Reset()
+
+	return trustedDealerKeygen(rand, &s, maxSigners, minSigners)
+}
+
+func trustedDealerKeygen(rand io.Reader, s *modm.Bignum256, maxSigners, minSigners int) ([]*KeyShare, VSSCommitment, error) {
+	if minSigners < 2 || maxSigners < minSigners || maxSigners > 0xffff {
+		return nil, nil, errBadThreshold
+	}
+
+	// Generate the random polynomial, with the constant term set to s.
+	coefficients := make([]modm.Bignum256, minSigners)
+	defer func() {
+		for i := range coefficients {
+			coefficients[i].Reset()
+		}
+	}()
+	coefficients[0] = *s
+	for i := 1; i < minSigners; i++ {
+		if err := group.RandomScalar(&coefficients[i], rand); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	commitment := make(VSSCommitment, 0, minSigners)
+	for i := range coefficients {
+		var C ge25519.Ge25519
+		ge25519.ScalarmultBaseNiels(&C, &ge25519.NielsBaseMultiples, &coefficients[i])
+		commitment = append(commitment, group.EncodeElement(&C))
+	}
+	groupPublicKey := ed25519.PublicKey(commitment[0])
+
+	shares := make([]*KeyShare, 0, maxSigners)
+	for i := 1; i <= maxSigners; i++ {
+		var (
+			id     = Identifier(i)
+			sk     modm.Bignum256
+			PK     ge25519.Ge25519
+			skData []byte
+		)
+		group.PolynomialEvaluate(&sk, uint64(id), coefficients)
+		skData = group.EncodeScalar(&sk)
+		ge25519.ScalarmultBaseNiels(&PK, &ge25519.NielsBaseMultiples, &sk)
+		sk.Reset()
+
+		shares = append(shares, &KeyShare{
+			Identifier:     id,
+			SecretKey:      skData,
+			PublicKey:      group.EncodeElement(&PK),
+			GroupPublicKey: groupPublicKey,
+		})
+	}
+
+	return shares, commitment, nil
+}
+
+// NewKeyShare reconstructs a participant's KeyShare from its secret key
+// share and the VSS commitment, after checking that the share is valid.
+func NewKeyShare(id Identifier, secretKey []byte, commitment VSSCommitment) (*KeyShare, error) {
+	if !commitment.VerifyShare(id, secretKey) {
+		return nil, errShareMismatch
+	}
+
+	groupPublicKey, err := commitment.GroupPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := commitment.PublicKeyShare(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sk := make([]byte, ScalarSize)
+	copy(sk, secretKey)
+
+	return &KeyShare{
+		Identifier:     id,
+		SecretKey:      sk,
+		PublicKey:      publicKey,
+		GroupPublicKey: groupPublicKey,
+	}, nil
+}
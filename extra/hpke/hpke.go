@@ -0,0 +1,347 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package hpke implements Hybrid Public Key Encryption (HPKE) as specified
+// in RFC 9180, with the DHKEM(X25519, HKDF-SHA256) KEM, the HKDF-SHA256
+// KDF, and the AES-128-GCM, AES-256-GCM, ChaCha20-Poly1305, and
+// export-only AEADs.  All four modes (base, PSK, auth, and auth-PSK) are
+// supported.
+//
+// Recipients that are identified by Ed25519 public keys can be converted
+// with x25519.NewPublicKeyFromEd25519, and the corresponding private key
+// with x25519.NewPrivateKeyFromEd25519.
+package hpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+// AEAD is an HPKE AEAD identifier.
+type AEAD uint16
+
+const (
+	// AEADAES128GCM is the identifier of AES-128-GCM.
+	AEADAES128GCM AEAD = 0x0001
+
+	// AEADAES256GCM is the identifier of AES-256-GCM.
+	AEADAES256GCM AEAD = 0x0002
+
+	// AEADChaCha20Poly1305 is the identifier of ChaCha20-Poly1305.
+	AEADChaCha20Poly1305 AEAD = 0x0003
+
+	// AEADExportOnly is the identifier of the export-only AEAD, for
+	// contexts that are only used with Export.
+	AEADExportOnly AEAD = 0xffff
+)
+
+const (
+	modeBase    byte = 0x00
+	modePSK     byte = 0x01
+	modeAuth    byte = 0x02
+	modeAuthPSK byte = 0x03
+
+	nonceSize = 12 // Nn, for all supported AEADs.
+
+	// MaxExportSize is the maximum length, in bytes, of an exported
+	// secret.
+	MaxExportSize = 255 * hashSize
+)
+
+var (
+	errShortIKM               = errors.New("hpke: input keying material too short")
+	errInvalidPublicKey       = errors.New("hpke: invalid public key")
+	errInvalidEncapsulatedKey = errors.New("hpke: invalid encapsulated key")
+	errInvalidAEAD            = errors.New("hpke: unsupported AEAD")
+	errInvalidPSK             = errors.New("hpke: invalid PSK inputs")
+	errExportOnly             = errors.New("hpke: export-only context")
+	errMessageLimit           = errors.New("hpke: message limit reached")
+	errOpen                   = errors.New("hpke: message authentication failed")
+	errExportSize             = errors.New("hpke: invalid export length")
+)
+
+func (a AEAD) keySize() int {
+	switch a {
+	case AEADAES128GCM:
+		return 16
+	case AEADAES256GCM, AEADChaCha20Poly1305:
+		return 32
+	case AEADExportOnly:
+		return 0
+	default:
+		return -1
+	}
+}
+
+func (a AEAD) new(key []byte) (cipher.AEAD, error) {
+	switch a {
+	case AEADAES128GCM, AEADAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AEADChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case AEADExportOnly:
+		return nil, nil
+	default:
+		return nil, errInvalidAEAD
+	}
+}
+
+type context struct {
+	aead           cipher.AEAD
+	baseNonce      []byte
+	exporterSecret []byte
+	suiteID        []byte
+	seq            uint64
+}
+
+// Sender is an HPKE context used to encrypt messages to a recipient.
+type Sender struct {
+	context
+}
+
+// Recipient is an HPKE context used to decrypt messages from a sender.
+type Recipient struct {
+	context
+}
+
+// SetupBaseSender sets up a base mode context for encrypting messages to
+// the recipient public key pkR, using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.  It returns the encapsulated key that
+// must be sent to the recipient, and the context.
+func SetupBaseSender(rand io.Reader, pkR *x25519.PublicKey, info []byte, aead AEAD) ([]byte, *Sender, error) {
+	return setupSender(rand, modeBase, pkR, info, nil, nil, nil, aead)
+}
+
+// SetupBaseRecipient sets up a base mode context for decrypting messages
+// sent to the recipient private key skR, given the encapsulated key enc.
+func SetupBaseRecipient(enc []byte, skR *x25519.PrivateKey, info []byte, aead AEAD) (*Recipient, error) {
+	return setupRecipient(enc, modeBase, skR, info, nil, nil, nil, aead)
+}
+
+// SetupPSKSender sets up a PSK mode context, where the sender is
+// authenticated by the possession of the pre-shared key psk, identified
+// by pskID.  See SetupBaseSender.
+func SetupPSKSender(rand io.Reader, pkR *x25519.PublicKey, info, psk, pskID []byte, aead AEAD) ([]byte, *Sender, error) {
+	return setupSender(rand, modePSK, pkR, info, psk, pskID, nil, aead)
+}
+
+// SetupPSKRecipient sets up a PSK mode context.  See SetupBaseRecipient.
+func SetupPSKRecipient(enc []byte, skR *x25519.PrivateKey, info, psk, pskID []byte, aead AEAD) (*Recipient, error) {
+	return setupRecipient(enc, modePSK, skR, info, psk, pskID, nil, aead)
+}
+
+// SetupAuthSender sets up an auth mode context, where the sender is
+// authenticated by the possession of the private key skS.  See
+// SetupBaseSender.
+func SetupAuthSender(rand io.Reader, pkR *x25519.PublicKey, info []byte, skS *x25519.PrivateKey, aead AEAD) ([]byte, *Sender, error) {
+	return setupSender(rand, modeAuth, pkR, info, nil, nil, skS, aead)
+}
+
+// SetupAuthRecipient sets up an auth mode context, for messages from the
+// sender public key pkS.  See SetupBaseRecipient.
+func SetupAuthRecipient(enc []byte, skR *x25519.PrivateKey, info []byte, pkS *x25519.PublicKey, aead AEAD) (*Recipient, error) {
+	return setupRecipient(enc, modeAuth, skR, info, nil, nil, pkS, aead)
+}
+
+// SetupAuthPSKSender sets up an auth-PSK mode context, combining the
+// PSK and auth modes.  See SetupBaseSender.
+func SetupAuthPSKSender(rand io.Reader, pkR *x25519.PublicKey, info, psk, pskID []byte, skS *x25519.PrivateKey, aead AEAD) ([]byte, *Sender, error) {
+	return setupSender(rand, modeAuthPSK, pkR, info, psk, pskID, skS, aead)
+}
+
+// SetupAuthPSKRecipient sets up an auth-PSK mode context.  See
+// SetupBaseRecipient.
+func SetupAuthPSKRecipient(enc []byte, skR *x25519.PrivateKey, info, psk, pskID []byte, pkS *x25519.PublicKey, aead AEAD) (*Recipient, error) {
+	return setupRecipient(enc, modeAuthPSK, skR, info, psk, pskID, pkS, aead)
+}
+
+func setupSender(rand io.Reader, mode byte, pkR *x25519.PublicKey, info, psk, pskID []byte, skS *x25519.PrivateKey, aead AEAD) ([]byte, *Sender, error) {
+	if err := verifyInputs(mode, psk, pskID, aead); err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, enc, err := encap(rand, pkR, skS)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := keySchedule(mode, sharedSecret, info, psk, pskID, aead)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, &Sender{*ctx}, nil
+}
+
+func setupRecipient(enc []byte, mode byte, skR *x25519.PrivateKey, info, psk, pskID []byte, pkS *x25519.PublicKey, aead AEAD) (*Recipient, error) {
+	if err := verifyInputs(mode, psk, pskID, aead); err != nil {
+		return nil, err
+	}
+	sharedSecret, err := decap(enc, skR, pkS)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := keySchedule(mode, sharedSecret, info, psk, pskID, aead)
+	if err != nil {
+		return nil, err
+	}
+	return &Recipient{*ctx}, nil
+}
+
+func verifyInputs(mode byte, psk, pskID []byte, aead AEAD) error {
+	if aead.keySize() < 0 {
+		return errInvalidAEAD
+	}
+
+	gotPSK, gotPSKID := len(psk) != 0, len(pskID) != 0
+	if gotPSK != gotPSKID {
+		return errInvalidPSK
+	}
+	switch mode {
+	case modeBase, modeAuth:
+		if gotPSK {
+			return errInvalidPSK
+		}
+	case modePSK, modeAuthPSK:
+		if !gotPSK {
+			return errInvalidPSK
+		}
+	}
+
+	return nil
+}
+
+func keySchedule(mode byte, sharedSecret, info, psk, pskID []byte, aead AEAD) (*context, error) {
+	suiteID := make([]byte, 0, 10)
+	suiteID = append(suiteID, "HPKE"...)
+	suiteID = appendUint16(suiteID, KEMX25519HKDFSHA256)
+	suiteID = appendUint16(suiteID, KDFHKDFSHA256)
+	suiteID = appendUint16(suiteID, uint16(aead))
+
+	pskIDHash := labeledExtract(suiteID, nil, "psk_id_hash", pskID)
+	infoHash := labeledExtract(suiteID, nil, "info_hash", info)
+	keyScheduleContext := make([]byte, 0, 1+len(pskIDHash)+len(infoHash))
+	keyScheduleContext = append(keyScheduleContext, mode)
+	keyScheduleContext = append(keyScheduleContext, pskIDHash...)
+	keyScheduleContext = append(keyScheduleContext, infoHash...)
+
+	secret := labeledExtract(suiteID, sharedSecret, "secret", psk)
+
+	ctx := &context{
+		exporterSecret: labeledExpand(suiteID, secret, "exp", keyScheduleContext, hashSize),
+		suiteID:        suiteID,
+	}
+	if aead != AEADExportOnly {
+		key := labeledExpand(suiteID, secret, "key", keyScheduleContext, aead.keySize())
+		var err error
+		if ctx.aead, err = aead.new(key); err != nil {
+			return nil, err
+		}
+		ctx.baseNonce = labeledExpand(suiteID, secret, "base_nonce", keyScheduleContext, nonceSize)
+	}
+
+	return ctx, nil
+}
+
+func (ctx *context) nextNonce() ([]byte, error) {
+	if ctx.aead == nil {
+		return nil, errExportOnly
+	}
+	if ctx.seq == math.MaxUint64 {
+		return nil, errMessageLimit
+	}
+
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce[nonceSize-8:], ctx.seq)
+	for i := range nonce {
+		nonce[i] ^= ctx.baseNonce[i]
+	}
+	return nonce, nil
+}
+
+// Export derives a secret of length bytes from the context and the
+// exporterContext.  The sender and recipient contexts will derive the
+// same secret.
+func (ctx *context) Export(exporterContext []byte, length int) ([]byte, error) {
+	if length < 0 || length > MaxExportSize {
+		return nil, errExportSize
+	}
+	return labeledExpand(ctx.suiteID, ctx.exporterSecret, "sec", exporterContext, length), nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates aad, and
+// returns the ciphertext.  Messages must be opened in the same order as
+// they are sealed.
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	nonce, err := s.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := s.aead.Seal(nil, nonce, plaintext, aad)
+	s.seq++
+
+	return ciphertext, nil
+}
+
+// Open decrypts ciphertext, authenticates it and aad, and returns the
+// plaintext.  A failure to open a message does not advance the context.
+func (r *Recipient) Open(aad, ciphertext []byte) ([]byte, error) {
+	nonce, err := r.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := r.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, errOpen
+	}
+	r.seq++
+
+	return plaintext, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func readRandom(rand io.Reader, b []byte) error {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	_, err := io.ReadFull(rand, b)
+	return err
+}
@@ -0,0 +1,348 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package hpke
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+type testVector struct {
+	Mode         byte     `json:"mode"`
+	KEM          uint16   `json:"kem_id"`
+	KDF          uint16   `json:"kdf_id"`
+	AEAD         AEAD     `json:"aead_id"`
+	Info         hexBytes `json:"info"`
+	IkmE         hexBytes `json:"ikmE"`
+	IkmR         hexBytes `json:"ikmR"`
+	IkmS         hexBytes `json:"ikmS"`
+	SkRm         hexBytes `json:"skRm"`
+	SkSm         hexBytes `json:"skSm"`
+	PkRm         hexBytes `json:"pkRm"`
+	PkSm         hexBytes `json:"pkSm"`
+	Enc          hexBytes `json:"enc"`
+	PSK          hexBytes `json:"psk"`
+	PSKID        hexBytes `json:"psk_id"`
+	SharedSecret hexBytes `json:"shared_secret"`
+	BaseNonce    hexBytes `json:"base_nonce"`
+	ExpSecret    hexBytes `json:"exporter_secret"`
+	Encryptions  []struct {
+		Seq int      `json:"seq"`
+		AAD hexBytes `json:"aad"`
+		PT  hexBytes `json:"pt"`
+		CT  hexBytes `json:"ct"`
+	} `json:"encryptions"`
+	Exports []struct {
+		Context hexBytes `json:"exporter_context"`
+		L       int      `json:"L"`
+		Value   hexBytes `json:"exported_value"`
+	} `json:"exports"`
+}
+
+type hexBytes []byte
+
+func (b *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	var err error
+	*b, err = hex.DecodeString(s)
+	return err
+}
+
+func setupFromVector(t *testing.T, v *testVector) (*Sender, *Recipient) {
+	skR, err := DeriveKeyPair(v.IkmR)
+	if err != nil {
+		t.Fatalf("DeriveKeyPair(ikmR): %v", err)
+	}
+	if !bytes.Equal(skR.Bytes(), v.SkRm) || !bytes.Equal(skR.PublicKey().Bytes(), v.PkRm) {
+		t.Fatalf("DeriveKeyPair(ikmR): key mismatch")
+	}
+	pkR := skR.PublicKey()
+
+	var (
+		skS *x25519.PrivateKey
+		pkS *x25519.PublicKey
+	)
+	if v.Mode == modeAuth || v.Mode == modeAuthPSK {
+		if skS, err = DeriveKeyPair(v.IkmS); err != nil {
+			t.Fatalf("DeriveKeyPair(ikmS): %v", err)
+		}
+		if !bytes.Equal(skS.Bytes(), v.SkSm) || !bytes.Equal(skS.PublicKey().Bytes(), v.PkSm) {
+			t.Fatalf("DeriveKeyPair(ikmS): key mismatch")
+		}
+		pkS = skS.PublicKey()
+	}
+
+	// The ephemeral key is derived from the entropy source.
+	rand := bytes.NewReader(v.IkmE)
+
+	var (
+		enc       []byte
+		sender    *Sender
+		recipient *Recipient
+	)
+	switch v.Mode {
+	case modeBase:
+		enc, sender, err = SetupBaseSender(rand, pkR, v.Info, v.AEAD)
+	case modePSK:
+		enc, sender, err = SetupPSKSender(rand, pkR, v.Info, v.PSK, v.PSKID, v.AEAD)
+	case modeAuth:
+		enc, sender, err = SetupAuthSender(rand, pkR, v.Info, skS, v.AEAD)
+	case modeAuthPSK:
+		enc, sender, err = SetupAuthPSKSender(rand, pkR, v.Info, v.PSK, v.PSKID, skS, v.AEAD)
+	}
+	if err != nil {
+		t.Fatalf("setup sender: %v", err)
+	}
+	if !bytes.Equal(enc, v.Enc) {
+		t.Fatalf("enc mismatch: %x != %x", enc, v.Enc)
+	}
+
+	switch v.Mode {
+	case modeBase:
+		recipient, err = SetupBaseRecipient(enc, skR, v.Info, v.AEAD)
+	case modePSK:
+		recipient, err = SetupPSKRecipient(enc, skR, v.Info, v.PSK, v.PSKID, v.AEAD)
+	case modeAuth:
+		recipient, err = SetupAuthRecipient(enc, skR, v.Info, pkS, v.AEAD)
+	case modeAuthPSK:
+		recipient, err = SetupAuthPSKRecipient(enc, skR, v.Info, v.PSK, v.PSKID, pkS, v.AEAD)
+	}
+	if err != nil {
+		t.Fatalf("setup recipient: %v", err)
+	}
+
+	return sender, recipient
+}
+
+func TestRFC9180(t *testing.T) {
+	f, err := os.Open("testdata/rfc9180.json.gz")
+	if err != nil {
+		t.Fatalf("failed to open test vectors: %v", err)
+	}
+	defer f.Close()
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to create gzip reader: %v", err)
+	}
+	defer rd.Close()
+
+	var vectors []testVector
+	if err = json.NewDecoder(rd).Decode(&vectors); err != nil {
+		t.Fatalf("failed to parse test vectors: %v", err)
+	}
+
+	for i := range vectors {
+		v := &vectors[i]
+		name := fmt.Sprintf("mode %d aead %04x", v.Mode, uint16(v.AEAD))
+		t.Run(name, func(t *testing.T) {
+			if v.KEM != KEMX25519HKDFSHA256 || v.KDF != KDFHKDFSHA256 {
+				t.Fatalf("unexpected suite: kem %04x kdf %04x", v.KEM, v.KDF)
+			}
+
+			sender, recipient := setupFromVector(t, v)
+			if !bytes.Equal(sender.exporterSecret, v.ExpSecret) {
+				t.Fatalf("exporter_secret mismatch: %x != %x", sender.exporterSecret, v.ExpSecret)
+			}
+			if v.AEAD != AEADExportOnly && !bytes.Equal(sender.baseNonce, v.BaseNonce) {
+				t.Fatalf("base_nonce mismatch: %x != %x", sender.baseNonce, v.BaseNonce)
+			}
+
+			if v.AEAD == AEADExportOnly {
+				if _, err := sender.Seal(nil, nil); err != errExportOnly {
+					t.Fatalf("Seal: expected export-only error, got %v", err)
+				}
+				if _, err := recipient.Open(nil, nil); err != errExportOnly {
+					t.Fatalf("Open: expected export-only error, got %v", err)
+				}
+			}
+
+			// The vectors only include a subset of the encryptions, all
+			// of which share the same plaintext, with aad "Count-<seq>".
+			var seq int
+			for _, enc := range v.Encryptions {
+				for ; seq < enc.Seq; seq++ {
+					aad := []byte(fmt.Sprintf("Count-%d", seq))
+					ct, err := sender.Seal(aad, enc.PT)
+					if err != nil {
+						t.Fatalf("Seal(%d): %v", seq, err)
+					}
+					if _, err = recipient.Open(aad, ct); err != nil {
+						t.Fatalf("Open(%d): %v", seq, err)
+					}
+				}
+
+				ct, err := sender.Seal(enc.AAD, enc.PT)
+				if err != nil {
+					t.Fatalf("Seal(%d): %v", seq, err)
+				}
+				if !bytes.Equal(ct, enc.CT) {
+					t.Fatalf("Seal(%d): ciphertext mismatch: %x != %x", seq, ct, enc.CT)
+				}
+				pt, err := recipient.Open(enc.AAD, enc.CT)
+				if err != nil {
+					t.Fatalf("Open(%d): %v", seq, err)
+				}
+				if !bytes.Equal(pt, enc.PT) {
+					t.Fatalf("Open(%d): plaintext mismatch: %x != %x", seq, pt, enc.PT)
+				}
+				seq++
+			}
+
+			for _, exp := range v.Exports {
+				value, err := sender.Export(exp.Context, exp.L)
+				if err != nil {
+					t.Fatalf("sender.Export: %v", err)
+				}
+				if !bytes.Equal(value, exp.Value) {
+					t.Fatalf("sender.Export: mismatch: %x != %x", value, exp.Value)
+				}
+				value, err = recipient.Export(exp.Context, exp.L)
+				if err != nil {
+					t.Fatalf("recipient.Export: %v", err)
+				}
+				if !bytes.Equal(value, exp.Value) {
+					t.Fatalf("recipient.Export: mismatch: %x != %x", value, exp.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestEd25519Recipient(t *testing.T) {
+	edPublic, edPrivate, _ := ed25519.GenerateKey(nil)
+	pkR, err := x25519.NewPublicKeyFromEd25519(edPublic)
+	if err != nil {
+		t.Fatalf("NewPublicKeyFromEd25519: %v", err)
+	}
+	skR, err := x25519.NewPrivateKeyFromEd25519(edPrivate)
+	if err != nil {
+		t.Fatalf("NewPrivateKeyFromEd25519: %v", err)
+	}
+
+	info := []byte("node configuration")
+	for _, aead := range []AEAD{AEADAES128GCM, AEADAES256GCM, AEADChaCha20Poly1305} {
+		enc, sender, err := SetupBaseSender(nil, pkR, info, aead)
+		if err != nil {
+			t.Fatalf("SetupBaseSender: %v", err)
+		}
+		recipient, err := SetupBaseRecipient(enc, skR, info, aead)
+		if err != nil {
+			t.Fatalf("SetupBaseRecipient: %v", err)
+		}
+
+		msg := []byte("test message")
+		ct, _ := sender.Seal(nil, msg)
+		pt, err := recipient.Open(nil, ct)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if !bytes.Equal(pt, msg) {
+			t.Fatalf("Open: plaintext mismatch")
+		}
+	}
+}
+
+func TestBadInputs(t *testing.T) {
+	skR, _ := x25519.GenerateKey(nil)
+	skS, _ := x25519.GenerateKey(nil)
+	pkR := skR.PublicKey()
+	psk, pskID := bytes.Repeat([]byte{0x42}, 32), []byte("psk")
+
+	if _, _, err := SetupBaseSender(nil, pkR, nil, AEAD(0x1234)); err != errInvalidAEAD {
+		t.Fatalf("expected invalid AEAD error, got %v", err)
+	}
+	if _, _, err := SetupPSKSender(nil, pkR, nil, psk, nil, AEADAES128GCM); err != errInvalidPSK {
+		t.Fatalf("expected PSK error for missing psk_id, got %v", err)
+	}
+	if _, _, err := SetupPSKSender(nil, pkR, nil, nil, nil, AEADAES128GCM); err != errInvalidPSK {
+		t.Fatalf("expected PSK error for missing psk, got %v", err)
+	}
+	if _, err := SetupAuthPSKRecipient(make([]byte, 32), skR, nil, nil, nil, skS.PublicKey(), AEADAES128GCM); err != errInvalidPSK {
+		t.Fatalf("expected PSK error for missing psk, got %v", err)
+	}
+	if _, err := DeriveKeyPair(make([]byte, 31)); err != errShortIKM {
+		t.Fatalf("expected short IKM error, got %v", err)
+	}
+
+	// Low order public keys must be rejected.
+	lowOrder, _ := x25519.NewPublicKey(make([]byte, x25519.PointSize))
+	if _, _, err := SetupBaseSender(nil, lowOrder, nil, AEADAES128GCM); err != errInvalidPublicKey {
+		t.Fatalf("expected invalid public key error, got %v", err)
+	}
+	if _, err := SetupBaseRecipient(make([]byte, EncapsulatedKeySize), skR, nil, AEADAES128GCM); err != errInvalidEncapsulatedKey {
+		t.Fatalf("expected invalid encapsulated key error, got %v", err)
+	}
+	if _, err := SetupBaseRecipient(make([]byte, EncapsulatedKeySize-1), skR, nil, AEADAES128GCM); err != errInvalidEncapsulatedKey {
+		t.Fatalf("expected invalid encapsulated key error, got %v", err)
+	}
+
+	// The recipient must reject messages from the wrong sender, or
+	// with the wrong PSK.
+	enc, sender, err := SetupAuthPSKSender(nil, pkR, nil, psk, pskID, skS, AEADChaCha20Poly1305)
+	if err != nil {
+		t.Fatalf("SetupAuthPSKSender: %v", err)
+	}
+	ct, _ := sender.Seal(nil, []byte("test message"))
+
+	other, _ := x25519.GenerateKey(nil)
+	for i, pkS := range []*x25519.PublicKey{skS.PublicKey(), other.PublicKey()} {
+		for j, p := range [][]byte{psk, bytes.Repeat([]byte{0x43}, 32)} {
+			recipient, err := SetupAuthPSKRecipient(enc, skR, nil, p, pskID, pkS, AEADChaCha20Poly1305)
+			if err != nil {
+				t.Fatalf("SetupAuthPSKRecipient: %v", err)
+			}
+			_, err = recipient.Open(nil, ct)
+			if valid := i == 0 && j == 0; valid != (err == nil) {
+				t.Fatalf("Open(%d, %d): unexpected result: %v", i, j, err)
+			}
+			if err != nil && recipient.seq != 0 {
+				t.Fatalf("Open(%d, %d): failure advanced the context", i, j)
+			}
+		}
+	}
+
+	if _, err = sender.Export(nil, MaxExportSize+1); err != errExportSize {
+		t.Fatalf("expected export size error, got %v", err)
+	}
+	sender.seq = 1<<64 - 1
+	if _, err = sender.Seal(nil, nil); err != errMessageLimit {
+		t.Fatalf("expected message limit error, got %v", err)
+	}
+}
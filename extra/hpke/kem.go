@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package hpke
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+const (
+	// KEMX25519HKDFSHA256 is the identifier of DHKEM(X25519, HKDF-SHA256).
+	KEMX25519HKDFSHA256 = 0x0020
+
+	// KDFHKDFSHA256 is the identifier of HKDF-SHA256.
+	KDFHKDFSHA256 = 0x0001
+
+	// EncapsulatedKeySize is the size, in bytes, of the encapsulated key
+	// (Nenc).
+	EncapsulatedKeySize = x25519.PointSize
+
+	sharedSecretSize = 32 // Nsecret
+	privateKeySize   = 32 // Nsk
+	hashSize         = sha256.Size
+
+	versionLabel = "HPKE-v1"
+)
+
+var kemSuiteID = []byte{'K', 'E', 'M', 0x00, KEMX25519HKDFSHA256}
+
+func labeledExtract(suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := make([]byte, 0, len(versionLabel)+len(suiteID)+len(label)+len(ikm))
+	labeledIKM = append(labeledIKM, versionLabel...)
+	labeledIKM = append(labeledIKM, suiteID...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+
+	return hkdf.Extract(sha256.New, labeledIKM, salt)
+}
+
+func labeledExpand(suiteID, prk []byte, label string, info []byte, length int) []byte {
+	labeledInfo := make([]byte, 2, 2+len(versionLabel)+len(suiteID)+len(label)+len(info))
+	binary.BigEndian.PutUint16(labeledInfo, uint16(length))
+	labeledInfo = append(labeledInfo, versionLabel...)
+	labeledInfo = append(labeledInfo, suiteID...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, labeledInfo), out); err != nil {
+		// Only possible if length > 255 * Nh, which callers check.
+		panic("hpke: failed to expand: " + err.Error())
+	}
+	return out
+}
+
+// DeriveKeyPair deterministically derives a DHKEM(X25519, HKDF-SHA256)
+// key pair from the input keying material ikm, which must be at least
+// 32 bytes long, and have at least 32 bytes of entropy.
+func DeriveKeyPair(ikm []byte) (*x25519.PrivateKey, error) {
+	if len(ikm) < privateKeySize {
+		return nil, errShortIKM
+	}
+
+	dkpPRK := labeledExtract(kemSuiteID, nil, "dkp_prk", ikm)
+	sk := labeledExpand(kemSuiteID, dkpPRK, "sk", nil, privateKeySize)
+
+	return x25519.NewPrivateKey(sk)
+}
+
+func extractAndExpand(dh, kemContext []byte) []byte {
+	eaePRK := labeledExtract(kemSuiteID, nil, "eae_prk", dh)
+	return labeledExpand(kemSuiteID, eaePRK, "shared_secret", kemContext, sharedSecretSize)
+}
+
+// encap implements Encap and AuthEnc, depending on if skS is non-nil.
+func encap(rand io.Reader, pkR *x25519.PublicKey, skS *x25519.PrivateKey) ([]byte, []byte, error) {
+	ikm := make([]byte, privateKeySize)
+	if err := readRandom(rand, ikm); err != nil {
+		return nil, nil, err
+	}
+	skE, err := DeriveKeyPair(ikm)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer skE.Reset()
+
+	dh, err := skE.ECDH(pkR)
+	if err != nil {
+		return nil, nil, errInvalidPublicKey
+	}
+	enc := skE.PublicKey().Bytes()
+	kemContext := append(append([]byte{}, enc...), pkR.Bytes()...)
+	if skS != nil {
+		dhS, err := skS.ECDH(pkR)
+		if err != nil {
+			return nil, nil, errInvalidPublicKey
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, skS.PublicKey().Bytes()...)
+	}
+
+	return extractAndExpand(dh, kemContext), enc, nil
+}
+
+// decap implements Decap and AuthDecap, depending on if pkS is non-nil.
+func decap(enc []byte, skR *x25519.PrivateKey, pkS *x25519.PublicKey) ([]byte, error) {
+	pkE, err := x25519.NewPublicKey(enc)
+	if err != nil {
+		return nil, errInvalidEncapsulatedKey
+	}
+	dh, err := skR.ECDH(pkE)
+	if err != nil {
+		return nil, errInvalidEncapsulatedKey
+	}
+	kemContext := append(pkE.Bytes(), skR.PublicKey().Bytes()...)
+	if pkS != nil {
+		dhS, err := skR.ECDH(pkS)
+		if err != nil {
+			return nil, errInvalidPublicKey
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, pkS.Bytes()...)
+	}
+
+	return extractAndExpand(dh, kemContext), nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package bcryptpbkdf implements OpenBSD's bcrypt_pbkdf(3), as used by
+// OpenSSH and signify to derive encryption keys from passphrases.
+package bcryptpbkdf
+
+import (
+	"crypto/sha512"
+	"errors"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+const blockSize = 32
+
+// magic is the bcrypt plaintext, "OxychromaticBlowfishSwatDynamite".
+var magic = []byte("OxychromaticBlowfishSwatDynamite")
+
+// Key derives a key of keyLen bytes from the password and salt, with
+// the given number of rounds.
+func Key(password, salt []byte, rounds, keyLen int) ([]byte, error) {
+	switch {
+	case rounds < 1:
+		return nil, errors.New("bcrypt_pbkdf: invalid number of rounds")
+	case len(password) == 0:
+		return nil, errors.New("bcrypt_pbkdf: empty password")
+	case len(salt) == 0 || len(salt) > 1<<20:
+		return nil, errors.New("bcrypt_pbkdf: invalid salt length")
+	case keyLen < 1 || keyLen > 1024:
+		return nil, errors.New("bcrypt_pbkdf: invalid key length")
+	}
+
+	numBlocks := (keyLen + blockSize - 1) / blockSize
+	key := make([]byte, numBlocks*blockSize)
+
+	h := sha512.New()
+	_, _ = h.Write(password)
+	shaPass := h.Sum(nil)
+
+	var (
+		shaSalt    = make([]byte, 0, sha512.Size)
+		cnt        [4]byte
+		tmp, block [blockSize]byte
+	)
+	for b := 1; b <= numBlocks; b++ {
+		cnt[0], cnt[1], cnt[2], cnt[3] = byte(b>>24), byte(b>>16), byte(b>>8), byte(b)
+
+		h.Reset()
+		_, _ = h.Write(salt)
+		_, _ = h.Write(cnt[:])
+		hash(&tmp, shaPass, h.Sum(shaSalt))
+		block = tmp
+
+		for i := 1; i < rounds; i++ {
+			h.Reset()
+			_, _ = h.Write(tmp[:])
+			hash(&tmp, shaPass, h.Sum(shaSalt))
+			for j := range block {
+				block[j] ^= tmp[j]
+			}
+		}
+
+		// The output is interleaved, rather than concatenated.
+		for i, v := range block {
+			key[i*numBlocks+(b-1)] = v
+		}
+	}
+
+	return key[:keyLen], nil
+}
+
+func hash(out *[blockSize]byte, shaPass, shaSalt []byte) {
+	c, err := blowfish.NewSaltedCipher(shaPass, shaSalt)
+	if err != nil {
+		panic("bcrypt_pbkdf: failed to initialize Blowfish: " + err.Error())
+	}
+	for i := 0; i < 64; i++ {
+		blowfish.ExpandKey(shaSalt, c)
+		blowfish.ExpandKey(shaPass, c)
+	}
+
+	copy(out[:], magic)
+	for i := 0; i < blockSize; i += blowfish.BlockSize {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(out[i:i+blowfish.BlockSize], out[i:i+blowfish.BlockSize])
+		}
+	}
+
+	// Swap to little endian.
+	for i := 0; i < blockSize; i += 4 {
+		out[i], out[i+1], out[i+2], out[i+3] = out[i+3], out[i+2], out[i+1], out[i]
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package group implements the Ed25519 prime order group scalar and element
+// encodings, and the related helpers, shared by the threshold and
+// multi-signature packages.
+package group
+
+import (
+	"crypto/sha512"
+	"io"
+
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+const (
+	// ScalarSize is the size, in bytes, of serialized scalars.
+	ScalarSize = 32
+
+	// ElementSize is the size, in bytes, of serialized group elements.
+	ElementSize = 32
+)
+
+// RandomScalar samples a uniformly random non-zero scalar from rand.
+func RandomScalar(s *modm.Bignum256, rand io.Reader) error {
+	var b [64]byte
+	for {
+		if _, err := io.ReadFull(rand, b[:]); err != nil {
+			return err
+		}
+		modm.Expand(s, b[:])
+		for i := range b {
+			b[i] = 0
+		}
+		if !modm.IsZeroVartime(s) {
+			return nil
+		}
+	}
+}
+
+// HashToScalar sets s to SHA-512 of the concatenation of m, reduced
+// modulo the group order.
+func HashToScalar(s *modm.Bignum256, m ...[]byte) {
+	var digest [64]byte
+	h := sha512.New()
+	for _, v := range m {
+		_, _ = h.Write(v)
+	}
+	h.Sum(digest[:0])
+	modm.Expand(s, digest[:])
+}
+
+// DecodeScalar deserializes a scalar, rejecting non-canonical encodings.
+func DecodeScalar(s *modm.Bignum256, b []byte) bool {
+	if len(b) != ScalarSize || !modm.IsCanonical(b) {
+		return false
+	}
+	modm.Expand(s, b)
+
+	return true
+}
+
+// EncodeScalar serializes a scalar.
+func EncodeScalar(s *modm.Bignum256) []byte {
+	b := make([]byte, ScalarSize)
+	modm.Contract(b, s)
+	return b
+}
+
+// DecodeElement deserializes a group element, rejecting non-canonical
+// encodings, the identity element, and elements that are not in the
+// prime order subgroup.
+func DecodeElement(p *ge25519.Ge25519, b []byte) bool {
+	return UnpackCanonical(p, b) && !ge25519.IsNeutralVartime(p) && ge25519.IsTorsionFreeVartime(p)
+}
+
+// UnpackCanonical deserializes a point, rejecting non-canonical encodings.
+// Unlike DecodeElement, the identity element and points with a torsion
+// component are accepted.
+func UnpackCanonical(p *ge25519.Ge25519, b []byte) bool {
+	if len(b) != ElementSize || !ge25519.UnpackVartime(p, b) {
+		return false
+	}
+
+	var check [ElementSize]byte
+	ge25519.Pack(check[:], p)
+	return string(check[:]) == string(b)
+}
+
+// EncodeElement serializes a group element.
+func EncodeElement(p *ge25519.Ge25519) []byte {
+	b := make([]byte, ElementSize)
+	ge25519.Pack(b, p)
+	return b
+}
+
+// PolynomialEvaluate evaluates the polynomial with the given coefficients
+// (constant term first) at x, using Horner's method.
+func PolynomialEvaluate(r *modm.Bignum256, x uint64, coefficients []modm.Bignum256) {
+	var xS modm.Bignum256
+	modm.SetUint64(&xS, x)
+
+	r.Reset()
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		modm.Mul(r, r, &xS)
+		modm.Add(r, r, &coefficients[i])
+	}
+}
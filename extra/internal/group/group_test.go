@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package group
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+func TestDecodeScalar(t *testing.T) {
+	for i, v := range []struct {
+		s  string
+		ok bool
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000000", true},
+		{"ecd3f55c1a631258d69cf7a2def9de1400000000000000000000000000000010", true},  // L - 1
+		{"edd3f55c1a631258d69cf7a2def9de1400000000000000000000000000000010", false}, // L
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", false},
+		{"00", false},
+	} {
+		b, _ := hex.DecodeString(v.s)
+
+		var s modm.Bignum256
+		if ok := DecodeScalar(&s, b); ok != v.ok {
+			t.Fatalf("%d: DecodeScalar: expected %v, got %v", i, v.ok, ok)
+		}
+		if v.ok && !bytes.Equal(EncodeScalar(&s), b) {
+			t.Fatalf("%d: EncodeScalar: round trip mismatch", i)
+		}
+	}
+}
+
+func TestDecodeElement(t *testing.T) {
+	for i, v := range []struct {
+		p  string
+		ok bool
+	}{
+		{"5866666666666666666666666666666666666666666666666666666666666666", true},  // B
+		{"0100000000000000000000000000000000000000000000000000000000000000", false}, // Identity
+		{"eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f", false}, // Non-canonical identity
+		{"0000000000000000000000000000000000000000000000000000000000000000", false}, // Order 4
+		{"c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a", false}, // Order 8
+		{"58666666666666666666666666666666666666666666666666666666666666", false},
+	} {
+		b, _ := hex.DecodeString(v.p)
+
+		var p ge25519.Ge25519
+		if ok := DecodeElement(&p, b); ok != v.ok {
+			t.Fatalf("%d: DecodeElement: expected %v, got %v", i, v.ok, ok)
+		}
+		if v.ok && !bytes.Equal(EncodeElement(&p), b) {
+			t.Fatalf("%d: EncodeElement: round trip mismatch", i)
+		}
+	}
+}
+
+func TestPolynomialEvaluate(t *testing.T) {
+	// f(x) = 3 + 5x + 7x^2, f(4) = 135.
+	coefficients := make([]modm.Bignum256, 3)
+	for i, c := range []uint64{3, 5, 7} {
+		modm.SetUint64(&coefficients[i], c)
+	}
+
+	var r, expected modm.Bignum256
+	PolynomialEvaluate(&r, 4, coefficients)
+	modm.SetUint64(&expected, 135)
+	if !bytes.Equal(EncodeScalar(&r), EncodeScalar(&expected)) {
+		t.Fatalf("expected f(4) = 135, got %x", EncodeScalar(&r))
+	}
+}
+
+func TestRandomScalar(t *testing.T) {
+	// The all zero draw must be rejected and resampled.
+	rand := bytes.NewReader(append(make([]byte, 64), bytes.Repeat([]byte{1}, 64)...))
+
+	var s modm.Bignum256
+	if err := RandomScalar(&s, rand); err != nil {
+		t.Fatalf("RandomScalar: %v", err)
+	}
+	if modm.IsZeroVartime(&s) {
+		t.Fatalf("RandomScalar: returned zero")
+	}
+	if err := RandomScalar(&s, rand); err == nil {
+		t.Fatalf("RandomScalar: succeeded with exhausted entropy")
+	}
+}
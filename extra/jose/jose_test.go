@@ -0,0 +1,339 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package jose
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+// RFC 8037, Appendix A.
+const (
+	rfc8037PrivateKey = `{"kty":"OKP","crv":"Ed25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	rfc8037PublicKey  = `{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+	rfc8037Thumbprint = "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"
+	rfc8037Payload    = "Example of Ed25519 signing"
+	rfc8037JWS        = "eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc.hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+
+	rfc8037X25519PublicKey = `{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`
+)
+
+func mustParseJWK(t *testing.T, s string) *JWK {
+	var k JWK
+	if err := json.Unmarshal([]byte(s), &k); err != nil {
+		t.Fatalf("failed to parse JWK %s: %v", s, err)
+	}
+	return &k
+}
+
+func TestRFC8037(t *testing.T) {
+	privateJWK := mustParseJWK(t, rfc8037PrivateKey)
+	publicJWK := mustParseJWK(t, rfc8037PublicKey)
+	privateKey := privateJWK.Key.(ed25519.PrivateKey)
+	publicKey := publicJWK.Key.(ed25519.PublicKey)
+	if !bytes.Equal(privateKey.Public().(ed25519.PublicKey), publicKey) {
+		t.Fatalf("public key mismatch")
+	}
+
+	for _, k := range []*JWK{privateJWK, publicJWK} {
+		thumbprint, err := k.Thumbprint()
+		if err != nil {
+			t.Fatalf("Thumbprint: %v", err)
+		}
+		if b64.EncodeToString(thumbprint) != rfc8037Thumbprint {
+			t.Fatalf("Thumbprint: got %s", b64.EncodeToString(thumbprint))
+		}
+	}
+
+	// EdDSA is deterministic.
+	token, err := SignCompact(privateKey, nil, []byte(rfc8037Payload))
+	if err != nil {
+		t.Fatalf("SignCompact: %v", err)
+	}
+	if token != rfc8037JWS {
+		t.Fatalf("SignCompact: got %s", token)
+	}
+	header, payload, err := VerifyCompact(publicKey, rfc8037JWS)
+	if err != nil {
+		t.Fatalf("VerifyCompact: %v", err)
+	}
+	if header.Algorithm != AlgorithmEdDSA || string(payload) != rfc8037Payload {
+		t.Fatalf("VerifyCompact: unexpected result: %+v %q", header, payload)
+	}
+
+	// The X25519 public key from the ECDH-ES example, which is Bob's
+	// public key from RFC 7748, Section 6.1.
+	x25519JWK := mustParseJWK(t, rfc8037X25519PublicKey)
+	expected, _ := hex.DecodeString("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	if !bytes.Equal(x25519JWK.Key.(*x25519.PublicKey).Bytes(), expected) {
+		t.Fatalf("X25519 public key mismatch")
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(nil)
+	xPrivate, _ := x25519.GenerateKey(nil)
+
+	for _, key := range []interface{}{edPrivate, xPrivate} {
+		k, err := NewJWK(key)
+		if err != nil {
+			t.Fatalf("NewJWK: %v", err)
+		}
+		k.KeyID = "test"
+		if !k.IsPrivate() {
+			t.Fatalf("IsPrivate: expected private key")
+		}
+		pub, err := k.Public()
+		if err != nil {
+			t.Fatalf("Public: %v", err)
+		}
+		if pub.IsPrivate() || pub.KeyID != k.KeyID {
+			t.Fatalf("Public: unexpected result")
+		}
+
+		for _, kk := range []*JWK{k, pub} {
+			b, err := json.Marshal(kk)
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+			kk2 := mustParseJWK(t, string(b))
+			b2, _ := json.Marshal(kk2)
+			if !bytes.Equal(b, b2) {
+				t.Fatalf("round-trip mismatch: %s != %s", b, b2)
+			}
+			if kk2.IsPrivate() != kk.IsPrivate() {
+				t.Fatalf("round-trip changed key type")
+			}
+			if kk.IsPrivate() != bytes.Contains(b, []byte(`"d"`)) {
+				t.Fatalf("unexpected serialization: %s", b)
+			}
+		}
+
+		tp1, _ := k.Thumbprint()
+		tp2, _ := pub.Thumbprint()
+		if !bytes.Equal(tp1, tp2) {
+			t.Fatalf("thumbprint mismatch")
+		}
+	}
+}
+
+func TestJWKBadInputs(t *testing.T) {
+	for i, s := range []string{
+		`{"kty":"EC","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+		`{"kty":"OKP","crv":"Ed448","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHUR"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURp"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo="}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","alg":"ES256"}`,
+		`{"kty":"OKP","crv":"Ed25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`,
+		`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08","alg":"EdDSA"}`,
+		`{"kty":"OKP","crv":"X25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`,
+		`{"kty":"OKP","crv":"Ed25519"}`,
+		`[]`,
+	} {
+		var k JWK
+		if err := json.Unmarshal([]byte(s), &k); err == nil {
+			t.Errorf("%d: accepted bad JWK", i)
+		}
+	}
+
+	if _, err := NewJWK([]byte{}); err == nil {
+		t.Errorf("NewJWK: accepted an unsupported key type")
+	}
+	if _, err := NewJWK(ed25519.PublicKey(make([]byte, 31))); err == nil {
+		t.Errorf("NewJWK: accepted a truncated key")
+	}
+}
+
+func TestJWKSet(t *testing.T) {
+	privateJWK := mustParseJWK(t, rfc8037PrivateKey)
+	set := `{"keys":[
+		{"kty":"RSA","n":"0vx7","e":"AQAB","kid":"rsa"},
+		{"kty":"OKP","crv":"Ed448","x":"AAAA","kid":"ed448"},
+		{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08","kid":"x25519"},
+		{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","kid":"enc","use":"enc"},
+		{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","kid":"sig","use":"sig"}
+	]}`
+
+	var s JWKSet
+	if err := json.Unmarshal([]byte(set), &s); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if len(s.Keys) != 3 {
+		t.Fatalf("unexpected number of keys: %d", len(s.Keys))
+	}
+	if keys := s.Lookup("sig"); len(keys) != 1 {
+		t.Fatalf("Lookup: unexpected result")
+	}
+
+	privateKey := privateJWK.Key.(ed25519.PrivateKey)
+	for _, v := range []struct {
+		kid string
+		ok  bool
+	}{
+		{"sig", true},
+		{"", true},
+		{"enc", false},
+		{"x25519", false},
+		{"missing", false},
+	} {
+		token, _ := SignCompact(privateKey, &Header{KeyID: v.kid}, []byte("payload"))
+		_, _, err := VerifyCompactWithKeySet(&s, token)
+		if (err == nil) != v.ok {
+			t.Errorf("VerifyCompactWithKeySet(kid = %q): unexpected result: %v", v.kid, err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"AAAA"}]}`), &s); err == nil {
+		t.Errorf("json.Unmarshal: accepted malformed Ed25519 key")
+	}
+	if err := json.Unmarshal([]byte(`{}`), &s); err == nil {
+		t.Errorf("json.Unmarshal: accepted set without keys")
+	}
+}
+
+func TestJWSJSON(t *testing.T) {
+	publicKey1, privateKey1, _ := ed25519.GenerateKey(nil)
+	publicKey2, privateKey2, _ := ed25519.GenerateKey(nil)
+	publicKey3, _, _ := ed25519.GenerateKey(nil)
+	payload := []byte("test payload")
+
+	data, err := SignJSON(payload,
+		Signer{PrivateKey: privateKey1, Header: Header{KeyID: "1"}},
+		Signer{PrivateKey: privateKey2, Header: Header{KeyID: "2"}},
+	)
+	if err != nil {
+		t.Fatalf("SignJSON: %v", err)
+	}
+
+	for i, publicKey := range []ed25519.PublicKey{publicKey1, publicKey2} {
+		header, payload2, err := VerifyJSON(publicKey, data)
+		if err != nil {
+			t.Fatalf("%d: VerifyJSON: %v", i, err)
+		}
+		if header.KeyID != []string{"1", "2"}[i] || !bytes.Equal(payload, payload2) {
+			t.Fatalf("%d: VerifyJSON: unexpected result", i)
+		}
+	}
+	if _, _, err = VerifyJSON(publicKey3, data); err == nil {
+		t.Fatalf("VerifyJSON: accepted signature from wrong key")
+	}
+
+	// Flattened serialization, converted from compact.
+	token, _ := SignCompact(privateKey1, nil, payload)
+	parts := strings.Split(token, ".")
+	flattened := func(unprotected string) []byte {
+		s := `{"payload":"` + parts[1] + `","protected":"` + parts[0] + `","signature":"` + parts[2] + `"`
+		if unprotected != "" {
+			s += `,"header":` + unprotected
+		}
+		return []byte(s + "}")
+	}
+	if _, _, err = VerifyJSON(publicKey1, flattened("")); err != nil {
+		t.Fatalf("VerifyJSON(flattened): %v", err)
+	}
+	if _, _, err = VerifyJSON(publicKey1, flattened(`{"kid":"1"}`)); err != nil {
+		t.Fatalf("VerifyJSON(flattened): %v", err)
+	}
+	for _, unprotected := range []string{
+		`{"alg":"EdDSA"}`,
+		`{"crit":["exp"]}`,
+		`[]`,
+	} {
+		if _, _, err = VerifyJSON(publicKey1, flattened(unprotected)); err == nil {
+			t.Errorf("VerifyJSON(flattened): accepted unprotected header %s", unprotected)
+		}
+	}
+
+	// Mixing the general and flattened serializations.
+	var mixed map[string]interface{}
+	_ = json.Unmarshal(data, &mixed)
+	mixed["signature"] = parts[2]
+	data, _ = json.Marshal(mixed)
+	if _, _, err = VerifyJSON(publicKey1, data); err == nil {
+		t.Fatalf("VerifyJSON: accepted mixed serialization")
+	}
+
+	if _, err = SignJSON(payload); err == nil {
+		t.Fatalf("SignJSON: accepted no signers")
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	payloadB64 := b64.EncodeToString([]byte("payload"))
+	sig := func(protected string) string {
+		return b64.EncodeToString(ed25519.Sign(privateKey, []byte(protected+"."+payloadB64)))
+	}
+
+	for _, rawHeader := range []string{
+		`{"alg":"none"}`,
+		`{"alg":"HS256"}`,
+		`{"alg":"ES256"}`,
+		`{"alg":"eddsa"}`,
+		`{}`,
+		`{"alg":"EdDSA","crit":["b64"],"b64":false}`,
+		`null`,
+	} {
+		protected := b64.EncodeToString([]byte(rawHeader))
+		token := protected + "." + payloadB64 + "." + sig(protected)
+		if _, _, err := VerifyCompact(publicKey, token); err == nil {
+			t.Errorf("VerifyCompact: accepted header %s", rawHeader)
+		}
+	}
+
+	// Unsigned tokens.
+	protected := b64.EncodeToString([]byte(`{"alg":"none"}`))
+	if _, _, err := VerifyCompact(publicKey, protected+"."+payloadB64+"."); err == nil {
+		t.Errorf("VerifyCompact: accepted unsigned token")
+	}
+
+	if _, err := SignCompact(privateKey, &Header{Algorithm: "HS256"}, nil); err == nil {
+		t.Errorf("SignCompact: accepted non-EdDSA algorithm")
+	}
+
+	// Malformed tokens.
+	token, _ := SignCompact(privateKey, nil, []byte("payload"))
+	for _, bad := range []string{
+		token + ".",
+		token[:strings.LastIndexByte(token, '.')],
+		token + "=",
+		strings.Replace(token, payloadB64, b64.EncodeToString([]byte("Payload")), 1),
+	} {
+		if _, _, err := VerifyCompact(publicKey, bad); err == nil {
+			t.Errorf("VerifyCompact: accepted malformed token %s", bad)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package jose implements JSON Web Keys (RFC 7517) and JSON Web
+// Signatures (RFC 7515) for Ed25519 and X25519 keys, as specified in
+// RFC 8037, and JWK thumbprints (RFC 7638).
+//
+// Only the "EdDSA" JWS algorithm is supported, and it is required to be
+// present in the integrity protected header.  All other algorithms,
+// including "none", are rejected.
+package jose
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/x25519"
+)
+
+const (
+	// KeyTypeOKP is the "kty" of Octet Key Pair keys.
+	KeyTypeOKP = "OKP"
+
+	// CurveEd25519 is the "crv" of Ed25519 keys.
+	CurveEd25519 = "Ed25519"
+
+	// CurveX25519 is the "crv" of X25519 keys.
+	CurveX25519 = "X25519"
+
+	// AlgorithmEdDSA is the "alg" of EdDSA signatures.
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	errInvalidKey       = errors.New("jose: invalid key")
+	errInvalidKeyType   = errors.New("jose: unsupported key type")
+	errInvalidCurve     = errors.New("jose: unsupported curve")
+	errInvalidAlgorithm = errors.New("jose: unsupported algorithm")
+	errKeyMismatch      = errors.New("jose: private key does not match public key")
+	errInvalidEncoding  = errors.New("jose: invalid base64url encoding")
+	errMissingKeys      = errors.New("jose: JWK set has no \"keys\" member")
+)
+
+// b64 is the base64url encoding without padding, that rejects
+// non-canonical encodings.
+var b64 = base64.RawURLEncoding.Strict()
+
+// JWK is a JSON Web Key, holding an Ed25519 or X25519 key.
+type JWK struct {
+	// Key is one of ed25519.PublicKey, ed25519.PrivateKey,
+	// *x25519.PublicKey, or *x25519.PrivateKey.
+	Key interface{}
+
+	// KeyID is the optional "kid" parameter.
+	KeyID string
+
+	// Use is the optional "use" parameter.
+	Use string
+
+	// Algorithm is the optional "alg" parameter.
+	Algorithm string
+}
+
+type rawJWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	D         string `json:"d,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+}
+
+// NewJWK returns a JWK for the key, which must be one of the types
+// supported by JWK.Key.
+func NewJWK(key interface{}) (*JWK, error) {
+	jwk := &JWK{Key: key}
+	if _, _, _, err := jwk.components(); err != nil {
+		return nil, err
+	}
+	return jwk, nil
+}
+
+// IsPrivate returns true iff the JWK holds a private key.
+func (k *JWK) IsPrivate() bool {
+	switch k.Key.(type) {
+	case ed25519.PrivateKey, *x25519.PrivateKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// Public returns a copy of the JWK that holds the public key.
+func (k *JWK) Public() (*JWK, error) {
+	var key interface{}
+	switch kk := k.Key.(type) {
+	case ed25519.PublicKey, *x25519.PublicKey:
+		key = kk
+	case ed25519.PrivateKey:
+		if len(kk) != ed25519.PrivateKeySize {
+			return nil, errInvalidKey
+		}
+		key = kk.Public()
+	case *x25519.PrivateKey:
+		key = kk.PublicKey()
+	default:
+		return nil, errInvalidKeyType
+	}
+
+	return &JWK{
+		Key:       key,
+		KeyID:     k.KeyID,
+		Use:       k.Use,
+		Algorithm: k.Algorithm,
+	}, nil
+}
+
+// components returns the "crv", "x", and "d" parameters of the key.
+func (k *JWK) components() (string, []byte, []byte, error) {
+	switch kk := k.Key.(type) {
+	case ed25519.PublicKey:
+		if len(kk) != ed25519.PublicKeySize {
+			return "", nil, nil, errInvalidKey
+		}
+		return CurveEd25519, kk, nil, nil
+	case ed25519.PrivateKey:
+		if len(kk) != ed25519.PrivateKeySize {
+			return "", nil, nil, errInvalidKey
+		}
+		return CurveEd25519, kk[32:], kk.Seed(), nil
+	case *x25519.PublicKey:
+		return CurveX25519, kk.Bytes(), nil, nil
+	case *x25519.PrivateKey:
+		return CurveX25519, kk.PublicKey().Bytes(), kk.Bytes(), nil
+	default:
+		return "", nil, nil, errInvalidKeyType
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (k *JWK) MarshalJSON() ([]byte, error) {
+	crv, x, d, err := k.components()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := rawJWK{
+		KeyType:   KeyTypeOKP,
+		Curve:     crv,
+		X:         b64.EncodeToString(x),
+		KeyID:     k.KeyID,
+		Use:       k.Use,
+		Algorithm: k.Algorithm,
+	}
+	if d != nil {
+		raw.D = b64.EncodeToString(d)
+	}
+
+	return json.Marshal(&raw)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  For private keys, the public
+// key ("x") must match the private key ("d").
+func (k *JWK) UnmarshalJSON(data []byte) error {
+	var raw rawJWK
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.KeyType != KeyTypeOKP {
+		return errInvalidKeyType
+	}
+
+	x, err := decodeComponent(raw.X)
+	if err != nil {
+		return err
+	}
+	var d []byte
+	if raw.D != "" {
+		if d, err = decodeComponent(raw.D); err != nil {
+			return err
+		}
+	}
+
+	var key interface{}
+	switch raw.Curve {
+	case CurveEd25519:
+		if raw.Algorithm != "" && raw.Algorithm != AlgorithmEdDSA {
+			return errInvalidAlgorithm
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return errInvalidKey
+		}
+		key = ed25519.PublicKey(x)
+		if d != nil {
+			if len(d) != ed25519.SeedSize {
+				return errInvalidKey
+			}
+			privateKey := ed25519.NewKeyFromSeed(d)
+			if subtle.ConstantTimeCompare(privateKey[32:], x) != 1 {
+				return errKeyMismatch
+			}
+			key = privateKey
+		}
+	case CurveX25519:
+		if raw.Algorithm == AlgorithmEdDSA {
+			return errInvalidAlgorithm
+		}
+		publicKey, err := x25519.NewPublicKey(x)
+		if err != nil {
+			return errInvalidKey
+		}
+		key = publicKey
+		if d != nil {
+			privateKey, err := x25519.NewPrivateKey(d)
+			if err != nil {
+				return errInvalidKey
+			}
+			if !privateKey.PublicKey().Equal(publicKey) {
+				return errKeyMismatch
+			}
+			key = privateKey
+		}
+	default:
+		return errInvalidCurve
+	}
+
+	*k = JWK{
+		Key:       key,
+		KeyID:     raw.KeyID,
+		Use:       raw.Use,
+		Algorithm: raw.Algorithm,
+	}
+
+	return nil
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of the key.  The
+// thumbprint of a private key is that of the corresponding public key.
+func (k *JWK) Thumbprint() ([]byte, error) {
+	crv, x, _, err := k.components()
+	if err != nil {
+		return nil, err
+	}
+
+	// The required members, in lexicographic order, with no whitespace.
+	// None of the values require escaping.
+	h := sha256.New()
+	_, _ = h.Write([]byte(`{"crv":"` + crv + `","kty":"` + KeyTypeOKP + `","x":"` + b64.EncodeToString(x) + `"}`))
+
+	return h.Sum(nil), nil
+}
+
+// JWKSet is a JSON Web Key Set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  As per RFC 7517, Section
+// 5, keys with unsupported key types or curves are ignored.
+func (s *JWKSet) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Keys == nil {
+		return errMissingKeys
+	}
+
+	keys := make([]JWK, 0, len(raw.Keys))
+	for _, rawKey := range raw.Keys {
+		var key JWK
+		switch err := key.UnmarshalJSON(rawKey); err {
+		case nil:
+			keys = append(keys, key)
+		case errInvalidKeyType, errInvalidCurve:
+		default:
+			return err
+		}
+	}
+	s.Keys = keys
+
+	return nil
+}
+
+// Lookup returns the keys in the set with the key ID.
+func (s *JWKSet) Lookup(keyID string) []*JWK {
+	var keys []*JWK
+	for i := range s.Keys {
+		if s.Keys[i].KeyID == keyID {
+			keys = append(keys, &s.Keys[i])
+		}
+	}
+	return keys
+}
+
+func decodeComponent(s string) ([]byte, error) {
+	b, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidEncoding
+	}
+	return b, nil
+}
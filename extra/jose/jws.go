@@ -0,0 +1,356 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package jose
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+var (
+	errInvalidJWS       = errors.New("jose: malformed JWS")
+	errInvalidHeader    = errors.New("jose: malformed JWS header")
+	errCritical         = errors.New("jose: unsupported critical header parameter")
+	errDuplicateParam   = errors.New("jose: header parameter in both protected and unprotected headers")
+	errInvalidSignature = errors.New("jose: invalid signature")
+	errNoSigners        = errors.New("jose: no signers")
+	errNoKey            = errors.New("jose: no suitable key")
+)
+
+// Header is a JWS JOSE header.  Unrecognized header parameters are
+// ignored, unless they are listed in "crit", which is always rejected as
+// no extensions are supported.
+type Header struct {
+	// Algorithm is the "alg" parameter, which is always AlgorithmEdDSA.
+	Algorithm string `json:"alg"`
+
+	// KeyID is the optional "kid" parameter.
+	KeyID string `json:"kid,omitempty"`
+
+	// Type is the optional "typ" parameter.
+	Type string `json:"typ,omitempty"`
+
+	// ContentType is the optional "cty" parameter.
+	ContentType string `json:"cty,omitempty"`
+}
+
+// Signer is a private key, and the protected header to sign with it.
+type Signer struct {
+	PrivateKey ed25519.PrivateKey
+	Header     Header
+}
+
+type jsonSignature struct {
+	Protected string          `json:"protected,omitempty"`
+	Header    json.RawMessage `json:"header,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+}
+
+type jsonJWS struct {
+	Payload    string          `json:"payload"`
+	Signatures []jsonSignature `json:"signatures,omitempty"`
+
+	// The flattened JWS JSON serialization.
+	jsonSignature
+}
+
+// SignCompact signs the payload, and returns the JWS compact
+// serialization.  If header is nil, the protected header will only contain
+// the algorithm.
+func SignCompact(privateKey ed25519.PrivateKey, header *Header, payload []byte) (string, error) {
+	if header == nil {
+		header = new(Header)
+	}
+	payloadB64 := b64.EncodeToString(payload)
+	protected, sig, err := sign(privateKey, header, payloadB64)
+	if err != nil {
+		return "", err
+	}
+
+	return protected + "." + payloadB64 + "." + sig, nil
+}
+
+// VerifyCompact verifies a JWS compact serialization with the public key,
+// and returns the protected header and payload.
+func VerifyCompact(publicKey ed25519.PublicKey, token string) (*Header, []byte, error) {
+	return verifyCompact(token, func(*Header) []ed25519.PublicKey {
+		return []ed25519.PublicKey{publicKey}
+	})
+}
+
+// VerifyCompactWithKeySet verifies a JWS compact serialization with the
+// Ed25519 public keys in the set that match the "kid" of the header (or
+// all of them, if the header does not have a "kid"), and returns the
+// protected header and payload.
+func VerifyCompactWithKeySet(set *JWKSet, token string) (*Header, []byte, error) {
+	return verifyCompact(token, func(header *Header) []ed25519.PublicKey {
+		return set.verificationKeys(header.KeyID)
+	})
+}
+
+func verifyCompact(token string, keysFn func(*Header) []ed25519.PublicKey) (*Header, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, errInvalidJWS
+	}
+
+	header, err := parseProtectedHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = verify(keysFn(header), parts[0], parts[1], parts[2]); err != nil {
+		return nil, nil, err
+	}
+	payload, err := decodeComponent(parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return header, payload, nil
+}
+
+// SignJSON signs the payload with each of the signers, and returns the
+// general JWS JSON serialization.
+func SignJSON(payload []byte, signers ...Signer) ([]byte, error) {
+	if len(signers) == 0 {
+		return nil, errNoSigners
+	}
+
+	jws := jsonJWS{
+		Payload: b64.EncodeToString(payload),
+	}
+	for i := range signers {
+		protected, sig, err := sign(signers[i].PrivateKey, &signers[i].Header, jws.Payload)
+		if err != nil {
+			return nil, err
+		}
+		jws.Signatures = append(jws.Signatures, jsonSignature{
+			Protected: protected,
+			Signature: sig,
+		})
+	}
+
+	return json.Marshal(&jws)
+}
+
+// VerifyJSON verifies a general or flattened JWS JSON serialization with
+// the public key, and returns the protected header of the first signature
+// that is valid, and the payload.
+func VerifyJSON(publicKey ed25519.PublicKey, data []byte) (*Header, []byte, error) {
+	return verifyJSON(data, func(*Header) []ed25519.PublicKey {
+		return []ed25519.PublicKey{publicKey}
+	})
+}
+
+// VerifyJSONWithKeySet verifies a general or flattened JWS JSON
+// serialization with the Ed25519 public keys in the set.  See
+// VerifyCompactWithKeySet and VerifyJSON.
+func VerifyJSONWithKeySet(set *JWKSet, data []byte) (*Header, []byte, error) {
+	return verifyJSON(data, func(header *Header) []ed25519.PublicKey {
+		return set.verificationKeys(header.KeyID)
+	})
+}
+
+func verifyJSON(data []byte, keysFn func(*Header) []ed25519.PublicKey) (*Header, []byte, error) {
+	var jws jsonJWS
+	if err := json.Unmarshal(data, &jws); err != nil {
+		return nil, nil, errInvalidJWS
+	}
+
+	signatures := jws.Signatures
+	switch {
+	case signatures == nil:
+		// Flattened.
+		signatures = []jsonSignature{jws.jsonSignature}
+	case jws.Protected != "" || jws.Header != nil || jws.Signature != "":
+		return nil, nil, errInvalidJWS
+	}
+
+	err := errInvalidSignature
+	for i := range signatures {
+		sig := &signatures[i]
+
+		var header *Header
+		if header, err = parseProtectedHeader(sig.Protected); err != nil {
+			continue
+		}
+		if err = checkUnprotectedHeader(sig.Protected, sig.Header); err != nil {
+			continue
+		}
+		if err = verify(keysFn(header), sig.Protected, jws.Payload, sig.Signature); err != nil {
+			continue
+		}
+
+		payload, err := decodeComponent(jws.Payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		return header, payload, nil
+	}
+
+	return nil, nil, err
+}
+
+func sign(privateKey ed25519.PrivateKey, header *Header, payloadB64 string) (string, string, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return "", "", errInvalidKey
+	}
+	if header.Algorithm != "" && header.Algorithm != AlgorithmEdDSA {
+		return "", "", errInvalidAlgorithm
+	}
+
+	h := *header
+	h.Algorithm = AlgorithmEdDSA
+	rawHeader, err := json.Marshal(&h)
+	if err != nil {
+		return "", "", err
+	}
+	protected := b64.EncodeToString(rawHeader)
+
+	sig, err := privateKey.Sign(nil, []byte(protected+"."+payloadB64), crypto.Hash(0))
+	if err != nil {
+		return "", "", err
+	}
+
+	return protected, b64.EncodeToString(sig), nil
+}
+
+func verify(publicKeys []ed25519.PublicKey, protected, payloadB64, sigB64 string) error {
+	if len(publicKeys) == 0 {
+		return errNoKey
+	}
+	sig, err := decodeComponent(sigB64)
+	if err != nil {
+		return err
+	}
+
+	signingInput := []byte(protected + "." + payloadB64)
+	for _, publicKey := range publicKeys {
+		if len(publicKey) != ed25519.PublicKeySize {
+			continue
+		}
+		if ed25519.Verify(publicKey, signingInput, sig) {
+			return nil
+		}
+	}
+
+	return errInvalidSignature
+}
+
+func parseProtectedHeader(protected string) (*Header, error) {
+	rawHeader, err := decodeComponent(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	var params map[string]json.RawMessage
+	if err = json.Unmarshal(rawHeader, &params); err != nil || params == nil {
+		return nil, errInvalidHeader
+	}
+	if _, ok := params["crit"]; ok {
+		return nil, errCritical
+	}
+
+	var header Header
+	if err = json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, errInvalidHeader
+	}
+
+	// Reject anything other than EdDSA, to prevent algorithm confusion,
+	// in particular "none", and HMAC with the public key as the secret.
+	if header.Algorithm != AlgorithmEdDSA {
+		return nil, errInvalidAlgorithm
+	}
+
+	return &header, nil
+}
+
+func checkUnprotectedHeader(protected string, unprotected json.RawMessage) error {
+	if unprotected == nil {
+		return nil
+	}
+
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(unprotected, &params); err != nil || params == nil {
+		return errInvalidHeader
+	}
+
+	// The algorithm must be integrity protected, and "crit" must only be
+	// in the protected header.
+	if _, ok := params["alg"]; ok {
+		return errInvalidAlgorithm
+	}
+	if _, ok := params["crit"]; ok {
+		return errCritical
+	}
+
+	rawProtected, _ := decodeComponent(protected)
+	var protectedParams map[string]json.RawMessage
+	_ = json.Unmarshal(rawProtected, &protectedParams)
+	for name := range params {
+		if _, ok := protectedParams[name]; ok {
+			return errDuplicateParam
+		}
+	}
+
+	return nil
+}
+
+// verificationKeys returns the Ed25519 public keys in the set that may be
+// used to verify EdDSA signatures, with the key ID.  If keyID is empty,
+// all such keys are returned.
+func (s *JWKSet) verificationKeys(keyID string) []ed25519.PublicKey {
+	var keys []ed25519.PublicKey
+	for i := range s.Keys {
+		k := &s.Keys[i]
+		if keyID != "" && k.KeyID != keyID {
+			continue
+		}
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if k.Algorithm != "" && k.Algorithm != AlgorithmEdDSA {
+			continue
+		}
+
+		switch kk := k.Key.(type) {
+		case ed25519.PublicKey:
+			keys = append(keys, kk)
+		case ed25519.PrivateKey:
+			if len(kk) == ed25519.PrivateKeySize {
+				keys = append(keys, kk.Public().(ed25519.PublicKey))
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,661 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package minisign implements the minisign and OpenBSD signify file
+// formats for Ed25519 public keys, secret keys, and signatures.
+//
+// minisign signatures are made over the BLAKE2b-512 digest of the message
+// ("ED", the default), or over the message itself ("Ed", the legacy mode),
+// and carry a signed trusted comment.  Note that the "ED" mode is Ed25519
+// over a digest computed by the caller, and not Ed25519ph.  signify
+// signatures are plain Ed25519 signatures over the message.
+//
+// Public keys share the same encoding in both formats.  Secret keys may be
+// encrypted with a passphrase, using scrypt for minisign, and bcrypt_pbkdf
+// for signify.
+//
+// See: https://jedisct1.github.io/minisign/
+// See: https://man.openbsd.org/signify
+package minisign
+
+import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	// KeyIDSize is the size of a key ID in bytes.
+	KeyIDSize = 8
+
+	// DefaultScryptOpsLimit and DefaultScryptMemLimit are the scrypt
+	// parameters used by minisign to encrypt secret keys.
+	DefaultScryptOpsLimit = 33554432
+	DefaultScryptMemLimit = 1073741824
+
+	untrustedCommentPrefix = "untrusted comment: "
+	trustedCommentPrefix   = "trusted comment: "
+
+	algEd          = "Ed"
+	algEdPrehashed = "ED"
+	kdfNone        = "\x00\x00"
+	kdfScrypt      = "Sc"
+	checksumBLAKE2 = "B2"
+
+	publicKeySize        = 2 + KeyIDSize + ed25519.PublicKeySize
+	signatureSize        = 2 + KeyIDSize + ed25519.SignatureSize
+	secretKeySize        = 2 + 2 + 2 + scryptSaltSize + 8 + 8 + secretKeyDataSize
+	secretKeyDataSize    = KeyIDSize + ed25519.PrivateKeySize + checksumSize
+	scryptSaltSize       = 32
+	checksumSize         = 32
+	maxCommentSize       = 1024
+	maxTrustedCommentLen = 8192 - len(trustedCommentPrefix)
+
+	// The scrypt limits accepted when parsing are capped at minisign's
+	// defaults, so that a crafted secret key file can not force excessive
+	// work or memory use (scrypt will use at most maxScryptMemLimit bytes).
+	maxScryptOpsLimit = DefaultScryptOpsLimit
+	maxScryptMemLimit = DefaultScryptMemLimit
+
+	defaultPublicKeyComment = "minisign public key "
+	defaultSecretKeyComment = "minisign encrypted secret key"
+	defaultSignatureComment = "signature from minisign secret key"
+)
+
+var (
+	// ErrPassphraseRequired is the error returned when parsing a
+	// passphrase protected secret key without a passphrase.
+	ErrPassphraseRequired = errors.New("minisign: passphrase required")
+
+	// ErrIncorrectPassphrase is the error returned when the passphrase
+	// fails to decrypt a secret key.
+	ErrIncorrectPassphrase = errors.New("minisign: incorrect passphrase")
+
+	errInvalidPublicKey   = errors.New("minisign: malformed public key")
+	errInvalidSecretKey   = errors.New("minisign: malformed secret key")
+	errInvalidSignature   = errors.New("minisign: malformed signature")
+	errInvalidAlgorithm   = errors.New("minisign: unsupported signature algorithm")
+	errInvalidKDF         = errors.New("minisign: unsupported key derivation function")
+	errInvalidChecksumAlg = errors.New("minisign: unsupported checksum algorithm")
+	errInvalidKDFParams   = errors.New("minisign: invalid key derivation parameters")
+	errInvalidChecksum    = errors.New("minisign: secret key checksum mismatch")
+	errInvalidComment     = errors.New("minisign: invalid comment")
+	errPublicKeyMismatch  = errors.New("minisign: public key does not match secret key")
+	errKeyIDMismatch      = errors.New("minisign: signature key ID does not match public key")
+	errSignatureMismatch  = errors.New("minisign: signature verification failed")
+	errGlobalSigMismatch  = errors.New("minisign: trusted comment signature verification failed")
+	errUnexpectedPass     = errors.New("minisign: passphrase provided for unencrypted key")
+	errEmptyPassphrase    = errors.New("minisign: empty passphrase")
+	errInvalidKey         = errors.New("minisign: invalid Ed25519 key")
+)
+
+// KeyID is the random identifier of a key pair, which is included in
+// signatures to identify the public key required to verify them.
+type KeyID [KeyIDSize]byte
+
+// String returns the key ID as formatted by minisign.
+func (id KeyID) String() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(id[:]))
+}
+
+// PublicKey is a minisign or signify public key.
+type PublicKey struct {
+	KeyID KeyID
+	Key   ed25519.PublicKey
+}
+
+// PrivateKey is a minisign or signify secret key.
+type PrivateKey struct {
+	KeyID KeyID
+	Key   ed25519.PrivateKey
+}
+
+// Public returns the public key corresponding to the secret key.
+func (k *PrivateKey) Public() *PublicKey {
+	return &PublicKey{
+		KeyID: k.KeyID,
+		Key:   k.Key.Public().(ed25519.PublicKey),
+	}
+}
+
+// GenerateKey generates a secret key with a random key ID, using entropy
+// from rand.  If rand is nil, crypto/rand.Reader will be used.
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var k PrivateKey
+	if _, err := io.ReadFull(rand, k.KeyID[:]); err != nil {
+		return nil, err
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	k.Key = privateKey
+
+	return &k, nil
+}
+
+// MarshalPublicKey serializes a public key to the public key file format
+// shared by minisign and signify, with an untrusted comment.  If comment is
+// empty, minisign's default comment is used.
+func MarshalPublicKey(publicKey *PublicKey, comment string) ([]byte, error) {
+	if len(publicKey.Key) != ed25519.PublicKeySize {
+		return nil, errInvalidKey
+	}
+	if comment == "" {
+		comment = defaultPublicKeyComment + publicKey.KeyID.String()
+	}
+
+	b := make([]byte, 0, publicKeySize)
+	b = append(b, algEd...)
+	b = append(b, publicKey.KeyID[:]...)
+	b = append(b, publicKey.Key...)
+
+	return encodeFile(comment, b)
+}
+
+// ParsePublicKey parses a minisign or signify public key file.  The bare
+// base64 encoded key, as printed by `minisign -G` and passed to
+// `minisign -P`, is also accepted.
+func ParsePublicKey(in []byte) (*PublicKey, error) {
+	lines := splitLines(in)
+	if len(lines) == 1 {
+		lines = append([]string{untrustedCommentPrefix}, lines...)
+	}
+	if len(lines) != 2 {
+		return nil, errInvalidPublicKey
+	}
+	_, b, ok := decodeFile(lines, publicKeySize)
+	if !ok {
+		return nil, errInvalidPublicKey
+	}
+	if string(b[:2]) != algEd {
+		return nil, errInvalidAlgorithm
+	}
+
+	var k PublicKey
+	copy(k.KeyID[:], b[2:])
+	k.Key = append(ed25519.PublicKey{}, b[2+KeyIDSize:]...)
+	return &k, nil
+}
+
+// MarshalPrivateKey serializes a secret key to an unencrypted minisign
+// secret key file, as created by `minisign -G -W`, with an untrusted
+// comment.  If comment is empty, minisign's default comment is used.
+func MarshalPrivateKey(privateKey *PrivateKey, comment string) ([]byte, error) {
+	return marshalPrivateKey(nil, privateKey, comment, nil, 0, 0)
+}
+
+// MarshalPrivateKeyWithPassphrase serializes a secret key to a minisign
+// secret key file, encrypted with a key derived from the passphrase with
+// scrypt, with an untrusted comment.  If comment is empty, minisign's
+// default comment is used.  The salt is generated using entropy from rand.
+// If rand is nil, crypto/rand.Reader will be used.
+func MarshalPrivateKeyWithPassphrase(rand io.Reader, privateKey *PrivateKey, comment string, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errEmptyPassphrase
+	}
+	return marshalPrivateKey(rand, privateKey, comment, passphrase, DefaultScryptOpsLimit, DefaultScryptMemLimit)
+}
+
+func marshalPrivateKey(rand io.Reader, privateKey *PrivateKey, comment string, passphrase []byte, opsLimit, memLimit uint64) ([]byte, error) {
+	if len(privateKey.Key) != ed25519.PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	if comment == "" {
+		comment = defaultSecretKeyComment
+	}
+
+	b := make([]byte, 0, secretKeySize)
+	b = append(b, algEd...)
+	if passphrase != nil {
+		b = append(b, kdfScrypt...)
+	} else {
+		b = append(b, kdfNone...)
+	}
+	b = append(b, checksumBLAKE2...)
+
+	var salt [scryptSaltSize]byte
+	if passphrase != nil {
+		if rand == nil {
+			rand = cryptorand.Reader
+		}
+		if _, err := io.ReadFull(rand, salt[:]); err != nil {
+			return nil, err
+		}
+	}
+	b = append(b, salt[:]...)
+	b = appendUint64LE(b, opsLimit)
+	b = appendUint64LE(b, memLimit)
+
+	// minisign only sets the checksum for encrypted keys.
+	data := make([]byte, 0, secretKeyDataSize)
+	data = append(data, privateKey.KeyID[:]...)
+	data = append(data, privateKey.Key...)
+	if passphrase != nil {
+		data = append(data, secretKeyChecksum(privateKey.KeyID[:], privateKey.Key)...)
+		stream, err := scryptStream(passphrase, salt[:], opsLimit, memLimit)
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(data, stream)
+	} else {
+		data = append(data, make([]byte, checksumSize)...)
+	}
+	b = append(b, data...)
+
+	return encodeFile(comment, b)
+}
+
+// ParsePrivateKey parses an unencrypted minisign secret key file.  If the
+// key is passphrase protected, ErrPassphraseRequired is returned.
+func ParsePrivateKey(in []byte) (*PrivateKey, error) {
+	return parsePrivateKey(in, nil)
+}
+
+// ParsePrivateKeyWithPassphrase parses a passphrase protected minisign
+// secret key file.  If the passphrase is wrong, ErrIncorrectPassphrase is
+// returned.
+func ParsePrivateKeyWithPassphrase(in, passphrase []byte) (*PrivateKey, error) {
+	if len(passphrase) == 0 {
+		return nil, errEmptyPassphrase
+	}
+	return parsePrivateKey(in, passphrase)
+}
+
+func parsePrivateKey(in, passphrase []byte) (*PrivateKey, error) {
+	lines := splitLines(in)
+	if len(lines) != 2 {
+		return nil, errInvalidSecretKey
+	}
+	_, b, ok := decodeFile(lines, secretKeySize)
+	if !ok {
+		return nil, errInvalidSecretKey
+	}
+	if string(b[0:2]) != algEd {
+		return nil, errInvalidAlgorithm
+	}
+	if string(b[4:6]) != checksumBLAKE2 {
+		return nil, errInvalidChecksumAlg
+	}
+	salt := b[6 : 6+scryptSaltSize]
+	opsLimit := binary.LittleEndian.Uint64(b[6+scryptSaltSize:])
+	memLimit := binary.LittleEndian.Uint64(b[6+scryptSaltSize+8:])
+	data := b[6+scryptSaltSize+16:]
+
+	keyID := data[:KeyIDSize]
+	privateKey := data[KeyIDSize : KeyIDSize+ed25519.PrivateKeySize]
+	checksum := data[KeyIDSize+ed25519.PrivateKeySize:]
+
+	switch string(b[2:4]) {
+	case kdfNone:
+		// The checksum is not set for unencrypted keys, which are
+		// instead checked against the public key.
+		if passphrase != nil {
+			return nil, errUnexpectedPass
+		}
+	case kdfScrypt:
+		if passphrase == nil {
+			return nil, ErrPassphraseRequired
+		}
+		stream, err := scryptStream(passphrase, salt, opsLimit, memLimit)
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(data, stream)
+		if subtle.ConstantTimeCompare(checksum, secretKeyChecksum(keyID, privateKey)) != 1 {
+			return nil, ErrIncorrectPassphrase
+		}
+	default:
+		return nil, errInvalidKDF
+	}
+
+	return newPrivateKey(keyID, privateKey)
+}
+
+// SignOptions are the options for creating minisign signatures.
+type SignOptions struct {
+	// Legacy selects the legacy ("Ed") mode, which signs the message
+	// directly instead of its BLAKE2b-512 digest, and requires the entire
+	// message to be buffered.
+	Legacy bool
+
+	// UntrustedComment is the untrusted comment.  If empty, minisign's
+	// default comment is used.
+	UntrustedComment string
+
+	// TrustedComment is the trusted comment, which is signed.  If empty,
+	// "timestamp:<unix time>" is used.
+	TrustedComment string
+}
+
+// Signature is a parsed minisign signature.
+type Signature struct {
+	// Legacy is set if the signature is made over the message, and not
+	// its BLAKE2b-512 digest.
+	Legacy bool
+
+	// KeyID is the ID of the key that made the signature.
+	KeyID KeyID
+
+	// Signature is the Ed25519 signature of the message.
+	Signature []byte
+
+	// UntrustedComment is the untrusted comment.
+	UntrustedComment string
+
+	// TrustedComment is the trusted comment, which is only trustworthy
+	// after the signature has been verified.
+	TrustedComment string
+
+	// GlobalSignature is the Ed25519 signature of the message signature
+	// and the trusted comment.
+	GlobalSignature []byte
+}
+
+// Sign signs the message read from r with the secret key, and returns a
+// minisign signature file.  If opts is nil, the default options are used.
+func Sign(privateKey *PrivateKey, r io.Reader, opts *SignOptions) ([]byte, error) {
+	if len(privateKey.Key) != ed25519.PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	if opts == nil {
+		opts = &SignOptions{}
+	}
+	untrustedComment, trustedComment := opts.UntrustedComment, opts.TrustedComment
+	if untrustedComment == "" {
+		untrustedComment = defaultSignatureComment
+	}
+	if trustedComment == "" {
+		trustedComment = "timestamp:" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	if !isValidComment(trustedComment) || len(trustedComment) > maxTrustedCommentLen {
+		return nil, errInvalidComment
+	}
+
+	message, err := messageToSign(r, opts.Legacy)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(privateKey.Key, message)
+
+	s := &Signature{
+		Legacy:           opts.Legacy,
+		KeyID:            privateKey.KeyID,
+		Signature:        sig,
+		UntrustedComment: untrustedComment,
+		TrustedComment:   trustedComment,
+		GlobalSignature:  ed25519.Sign(privateKey.Key, globalMessage(sig, trustedComment)),
+	}
+	return s.Marshal()
+}
+
+// Verify verifies the minisign signature file of the message read from r,
+// with the public key, and returns the signature, including the trusted
+// comment.
+func Verify(publicKey *PublicKey, r io.Reader, signature []byte) (*Signature, error) {
+	s, err := ParseSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicKey.Key) != ed25519.PublicKeySize {
+		return nil, errInvalidKey
+	}
+	if s.KeyID != publicKey.KeyID {
+		return nil, errKeyIDMismatch
+	}
+
+	message, err := messageToSign(r, s.Legacy)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(publicKey.Key, message, s.Signature) {
+		return nil, errSignatureMismatch
+	}
+	if !ed25519.Verify(publicKey.Key, globalMessage(s.Signature, s.TrustedComment), s.GlobalSignature) {
+		return nil, errGlobalSigMismatch
+	}
+
+	return s, nil
+}
+
+// ParseSignature parses a minisign signature file.  The signature is not
+// verified.
+func ParseSignature(signature []byte) (*Signature, error) {
+	lines := splitLines(signature)
+	if len(lines) != 4 {
+		return nil, errInvalidSignature
+	}
+	untrustedComment, b, ok := decodeFile(lines[:2], signatureSize)
+	if !ok {
+		return nil, errInvalidSignature
+	}
+	if !strings.HasPrefix(lines[2], trustedCommentPrefix) {
+		return nil, errInvalidSignature
+	}
+	globalSig, err := base64.StdEncoding.Strict().DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return nil, errInvalidSignature
+	}
+
+	s := &Signature{
+		UntrustedComment: untrustedComment,
+		TrustedComment:   strings.TrimPrefix(lines[2], trustedCommentPrefix),
+		Signature:        append([]byte{}, b[2+KeyIDSize:]...),
+		GlobalSignature:  globalSig,
+	}
+	switch string(b[:2]) {
+	case algEdPrehashed:
+	case algEd:
+		s.Legacy = true
+	default:
+		return nil, errInvalidAlgorithm
+	}
+	copy(s.KeyID[:], b[2:])
+
+	return s, nil
+}
+
+// Marshal serializes the signature to the minisign signature file format.
+func (s *Signature) Marshal() ([]byte, error) {
+	if len(s.Signature) != ed25519.SignatureSize || len(s.GlobalSignature) != ed25519.SignatureSize {
+		return nil, errInvalidSignature
+	}
+	if !isValidComment(s.TrustedComment) {
+		return nil, errInvalidComment
+	}
+
+	b := make([]byte, 0, signatureSize)
+	if s.Legacy {
+		b = append(b, algEd...)
+	} else {
+		b = append(b, algEdPrehashed...)
+	}
+	b = append(b, s.KeyID[:]...)
+	b = append(b, s.Signature...)
+
+	out, err := encodeFile(s.UntrustedComment, b)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, trustedCommentPrefix+s.TrustedComment+"\n"...)
+	out = append(out, base64.StdEncoding.EncodeToString(s.GlobalSignature)+"\n"...)
+
+	return out, nil
+}
+
+func newPrivateKey(keyID, privateKey []byte) (*PrivateKey, error) {
+	derived := ed25519.NewKeyFromSeed(privateKey[:ed25519.SeedSize])
+	if subtle.ConstantTimeCompare(derived[ed25519.SeedSize:], privateKey[ed25519.SeedSize:]) != 1 {
+		return nil, errPublicKeyMismatch
+	}
+
+	var k PrivateKey
+	copy(k.KeyID[:], keyID)
+	k.Key = derived
+	return &k, nil
+}
+
+func messageToSign(r io.Reader, legacy bool) ([]byte, error) {
+	if legacy {
+		return ioutil.ReadAll(r)
+	}
+
+	h, _ := blake2b.New512(nil)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func globalMessage(sig []byte, trustedComment string) []byte {
+	b := make([]byte, 0, len(sig)+len(trustedComment))
+	b = append(b, sig...)
+	return append(b, trustedComment...)
+}
+
+func secretKeyChecksum(keyID, privateKey []byte) []byte {
+	h, _ := blake2b.New256(nil)
+	_, _ = h.Write([]byte(algEd))
+	_, _ = h.Write(keyID)
+	_, _ = h.Write(privateKey)
+	return h.Sum(nil)
+}
+
+// scryptStream derives the secret key encryption stream, picking the
+// scrypt parameters from the limits in the same manner as libsodium's
+// crypto_pwhash_scryptsalsa208sha256.
+func scryptStream(passphrase, salt []byte, opsLimit, memLimit uint64) ([]byte, error) {
+	if opsLimit > maxScryptOpsLimit || memLimit > maxScryptMemLimit {
+		return nil, errInvalidKDFParams
+	}
+	if opsLimit < 32768 {
+		opsLimit = 32768
+	}
+
+	const r = 8
+	var (
+		logN uint
+		p    uint64
+	)
+	pickLogN := func(maxN uint64) uint {
+		logN := uint(1)
+		for ; logN < 63; logN++ {
+			if uint64(1)<<logN > maxN/2 {
+				break
+			}
+		}
+		return logN
+	}
+	if opsLimit < memLimit/32 {
+		p = 1
+		logN = pickLogN(opsLimit / (r * 4))
+	} else {
+		logN = pickLogN(memLimit / (r * 128))
+		maxRP := (opsLimit / 4) / (uint64(1) << logN)
+		if maxRP > 0x3fffffff {
+			maxRP = 0x3fffffff
+		}
+		p = maxRP / r
+	}
+	if p == 0 {
+		return nil, errInvalidKDFParams
+	}
+
+	return scrypt.Key(passphrase, salt, 1<<logN, r, int(p), secretKeyDataSize)
+}
+
+// splitLines splits a file into lines, ignoring a trailing newline and
+// carriage returns.
+func splitLines(in []byte) []string {
+	s := strings.TrimSuffix(string(in), "\n")
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines
+}
+
+// decodeFile decodes the untrusted comment line and the base64 encoded
+// line of a minisign or signify file.
+func decodeFile(lines []string, size int) (string, []byte, bool) {
+	if !strings.HasPrefix(lines[0], untrustedCommentPrefix) || len(lines[0]) > maxCommentSize {
+		return "", nil, false
+	}
+	b, err := base64.StdEncoding.Strict().DecodeString(lines[1])
+	if err != nil || len(b) != size {
+		return "", nil, false
+	}
+	return strings.TrimPrefix(lines[0], untrustedCommentPrefix), b, true
+}
+
+// encodeFile encodes the untrusted comment line and the base64 encoded
+// line of a minisign or signify file.
+func encodeFile(comment string, b []byte) ([]byte, error) {
+	if !isValidComment(comment) || len(untrustedCommentPrefix)+len(comment) > maxCommentSize {
+		return nil, errInvalidComment
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(untrustedCommentPrefix + comment + "\n")
+	buf.WriteString(base64.StdEncoding.EncodeToString(b) + "\n")
+	return buf.Bytes(), nil
+}
+
+func isValidComment(comment string) bool {
+	return !strings.ContainsAny(comment, "\r\n")
+}
+
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+func appendUint64LE(b []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[:]...)
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package minisign
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+// Test vectors from github.com/jedisct1/go-minisign.
+const (
+	testUnencryptedSecretKey = `untrusted comment: minisign encrypted secret key
+RWQAAEIyAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAOItWpGuGQbG4C9WXaxEYLgZ2xxuqfbuZmDgAhQ8Unot8t7SyxZ0nVh0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcSAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=
+`
+	testUnencryptedPublicKey = `untrusted comment: minisign public key B141866BA4568B38
+RWQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcS
+`
+
+	testEncryptedSecretKey = `untrusted comment: minisign encrypted secret key
+RWRTY0IyxYlIT2FS5i8PqThE9swBemvY94JDIMqo75UBK3XO/aUAAAACAAAAAAAAAEAAAAAAUhlw8nsT1tuVUekS6Je3iUwoWFdb1xiLonO35G66RiVvM/QgrBtnDa0Dhbt7H3oYMh4aFLiNxMs24gzXqHVsvRVthMeF08fN8r6siRdBpiBZ36B7rox2lmYIYgg5T8qt7tOxo9doAxk=
+`
+	testEncryptedPublicKey = `untrusted comment: minisign public key 9149E58DCF22FFC1
+RWTB/yLPjeVJkXKtzk1nZI0TU+fZPqEaIzg1ABHwfnI8pZNWtifIpWBq
+`
+	testEncryptedPassphrase = "testpass"
+
+	testSignaturePublicKey = "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	testSignatureMessage   = "test"
+	testLegacySignature    = "untrusted comment: signature from minisign secret key\nRWQf6LRCGA9i59SLOFxz6NxvASXDJeRtuZykwQepbDEGt87ig1BNpWaVWuNrm73YiIiJbq71Wi+dP9eKL8OC351vwIasSSbXxwA=\ntrusted comment: timestamp:1635442742\tfile:test\n0YteLgV960ia80vnA/fHbvkyjl/IoP/HNOCaZfrF0CdhAlp7ok+Tpkya+VpWPX5C/Is3q8a/kEDSY7fBmmgJCg==\n"
+	testPrehashedSignature = "untrusted comment: signature from minisign secret key\nRUQf6LRCGA9i559r3g7V1qNyJDApGip8MfqcadIgT9CuhV3EMhHoN1mGTkUidF/z7SrlQgXdy8ofjb7bNJJylDOocrCo8KLzZwo=\ntrusted comment: timestamp:1635443258\tfile:test\thashed\n/cj37GK60vryibFn+ftOgbCvW9NKhKYgjVpFFQUcWPAnjO23wrvVDTt7cloNC06maoBli9q6qwZDXXoaxweICQ==\n"
+)
+
+func TestVerify(t *testing.T) {
+	publicKey, err := ParsePublicKey([]byte(testSignaturePublicKey))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+
+	for _, v := range []struct {
+		signature      string
+		legacy         bool
+		trustedComment string
+	}{
+		{testLegacySignature, true, "timestamp:1635442742\tfile:test"},
+		{testPrehashedSignature, false, "timestamp:1635443258\tfile:test\thashed"},
+	} {
+		s, err := Verify(publicKey, strings.NewReader(testSignatureMessage), []byte(v.signature))
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if s.Legacy != v.legacy || s.TrustedComment != v.trustedComment || s.KeyID != publicKey.KeyID {
+			t.Fatalf("Verify: unexpected signature: %+v", s)
+		}
+		b, err := s.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if string(b) != v.signature {
+			t.Fatalf("Marshal: got %q", b)
+		}
+
+		if _, err = Verify(publicKey, strings.NewReader("tesT"), []byte(v.signature)); err != errSignatureMismatch {
+			t.Fatalf("Verify: accepted wrong message: %v", err)
+		}
+		tampered := strings.Replace(v.signature, "timestamp:", "timestamp:1", 1)
+		if _, err = Verify(publicKey, strings.NewReader(testSignatureMessage), []byte(tampered)); err != errGlobalSigMismatch {
+			t.Fatalf("Verify: accepted tampered trusted comment: %v", err)
+		}
+		otherKeyID := *publicKey
+		otherKeyID.KeyID[0] ^= 1
+		if _, err = Verify(&otherKeyID, strings.NewReader(testSignatureMessage), []byte(v.signature)); err != errKeyIDMismatch {
+			t.Fatalf("Verify: accepted wrong key ID: %v", err)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	privateKey, err := ParsePrivateKey([]byte(testUnencryptedSecretKey))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey: %v", err)
+	}
+	publicKey, err := ParsePublicKey([]byte(testUnencryptedPublicKey))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if publicKey.KeyID.String() != "B141866BA4568B38" {
+		t.Fatalf("KeyID: unexpected string: %s", publicKey.KeyID)
+	}
+	if derived := privateKey.Public(); derived.KeyID != publicKey.KeyID || !bytes.Equal(derived.Key, publicKey.Key) {
+		t.Fatalf("Public: public key mismatch")
+	}
+
+	b, err := MarshalPublicKey(publicKey, "")
+	if err != nil {
+		t.Fatalf("MarshalPublicKey: %v", err)
+	}
+	if string(b) != testUnencryptedPublicKey {
+		t.Fatalf("MarshalPublicKey: got %q", b)
+	}
+	b, err = MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatalf("MarshalPrivateKey: %v", err)
+	}
+	if string(b) != testUnencryptedSecretKey {
+		t.Fatalf("MarshalPrivateKey: got %q", b)
+	}
+
+	if _, err = ParsePrivateKey([]byte(testEncryptedSecretKey)); err != ErrPassphraseRequired {
+		t.Fatalf("ParsePrivateKey: unexpected error for encrypted key: %v", err)
+	}
+	if _, err = ParsePrivateKeyWithPassphrase([]byte(testUnencryptedSecretKey), []byte(testEncryptedPassphrase)); err != errUnexpectedPass {
+		t.Fatalf("ParsePrivateKeyWithPassphrase: unexpected error for unencrypted key: %v", err)
+	}
+}
+
+func TestEncryptedKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the default scrypt parameters (1 GiB) in short mode")
+	}
+
+	privateKey, err := ParsePrivateKeyWithPassphrase([]byte(testEncryptedSecretKey), []byte(testEncryptedPassphrase))
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithPassphrase: %v", err)
+	}
+	publicKey, err := ParsePublicKey([]byte(testEncryptedPublicKey))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if derived := privateKey.Public(); derived.KeyID != publicKey.KeyID || !bytes.Equal(derived.Key, publicKey.Key) {
+		t.Fatalf("Public: public key mismatch")
+	}
+
+	if _, err = ParsePrivateKeyWithPassphrase([]byte(testEncryptedSecretKey), []byte("wrong")); err != ErrIncorrectPassphrase {
+		t.Fatalf("ParsePrivateKeyWithPassphrase: unexpected error for wrong passphrase: %v", err)
+	}
+}
+
+func TestEncryptedKeyRoundTrip(t *testing.T) {
+	privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	// Use cheap scrypt parameters (N = 2^10, r = 8, p = 1).
+	passphrase := []byte("correct horse battery staple")
+	b, err := marshalPrivateKey(nil, privateKey, "test key", passphrase, 32768, 1<<20)
+	if err != nil {
+		t.Fatalf("marshalPrivateKey: %v", err)
+	}
+	if !bytes.HasPrefix(b, []byte("untrusted comment: test key\nRWRTY0Iy")) {
+		t.Fatalf("marshalPrivateKey: unexpected encoding: %q", b)
+	}
+
+	privateKey2, err := ParsePrivateKeyWithPassphrase(b, passphrase)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithPassphrase: %v", err)
+	}
+	if privateKey2.KeyID != privateKey.KeyID || !privateKey2.Key.Equal(privateKey.Key) {
+		t.Fatalf("ParsePrivateKeyWithPassphrase: private key mismatch")
+	}
+	if _, err = ParsePrivateKeyWithPassphrase(b, []byte("wrong")); err != ErrIncorrectPassphrase {
+		t.Fatalf("ParsePrivateKeyWithPassphrase: unexpected error for wrong passphrase: %v", err)
+	}
+	if _, err = MarshalPrivateKeyWithPassphrase(nil, privateKey, "", nil); err != errEmptyPassphrase {
+		t.Fatalf("MarshalPrivateKeyWithPassphrase: accepted an empty passphrase")
+	}
+}
+
+func TestSign(t *testing.T) {
+	privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	publicKey := privateKey.Public()
+
+	msg := "a release artifact"
+	for _, opts := range []*SignOptions{
+		nil,
+		{Legacy: true},
+		{UntrustedComment: "signature from a test key", TrustedComment: "timestamp:1700000000\tfile:artifact\thashed"},
+	} {
+		b, err := Sign(privateKey, strings.NewReader(msg), opts)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		s, err := Verify(publicKey, strings.NewReader(msg), b)
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if opts == nil {
+			if s.Legacy || s.UntrustedComment != defaultSignatureComment || !strings.HasPrefix(s.TrustedComment, "timestamp:") {
+				t.Fatalf("Sign: unexpected defaults: %+v", s)
+			}
+			continue
+		}
+		if s.Legacy != opts.Legacy {
+			t.Fatalf("Sign: unexpected mode")
+		}
+		if opts.TrustedComment != "" && (s.TrustedComment != opts.TrustedComment || s.UntrustedComment != opts.UntrustedComment) {
+			t.Fatalf("Sign: comment mismatch: %+v", s)
+		}
+	}
+
+	// The "ED" mode is Ed25519 over the BLAKE2b-512 digest.
+	b, _ := Sign(privateKey, strings.NewReader(msg), nil)
+	s, _ := ParseSignature(b)
+	digest, _ := messageToSign(strings.NewReader(msg), false)
+	if !ed25519.Verify(publicKey.Key, digest, s.Signature) {
+		t.Fatalf("Sign: signature is not over the BLAKE2b-512 digest")
+	}
+
+	for _, opts := range []*SignOptions{
+		{TrustedComment: "multi\nline"},
+		{UntrustedComment: "multi\nline"},
+		{TrustedComment: strings.Repeat("x", maxTrustedCommentLen+1)},
+	} {
+		if _, err = Sign(privateKey, strings.NewReader(msg), opts); err != errInvalidComment {
+			t.Fatalf("Sign: accepted a bad comment: %v", err)
+		}
+	}
+}
+
+func TestBadInputs(t *testing.T) {
+	for _, v := range []string{
+		"",
+		"RWQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDc",
+		"RWQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcS\nRWQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcS",
+		"comment: minisign public key\nRWQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcS",
+		"RUQ4i1aka4ZBsR0gESesJ6Ay57fGFJ9T1ajVmanT7MFMCCDbPZ8uqDcS",
+	} {
+		if _, err := ParsePublicKey([]byte(v)); err == nil {
+			t.Errorf("ParsePublicKey(%q): accepted bad input", v)
+		}
+	}
+
+	lines := splitLines([]byte(testUnencryptedSecretKey))
+	for _, v := range []struct {
+		name string
+		line string
+	}{
+		{"Algorithm", "RUQAAEIy" + lines[1][8:]},
+		{"KDF", "RWQAAEIz" + lines[1][8:]},
+		{"PublicKey", lines[1][:150] + "A" + lines[1][151:]},
+		{"Truncated", lines[1][:len(lines[1])-4]},
+	} {
+		if _, err := ParsePrivateKey([]byte(lines[0] + "\n" + v.line + "\n")); err == nil {
+			t.Errorf("ParsePrivateKey(%s): accepted bad input", v.name)
+		}
+	}
+
+	sigLines := splitLines([]byte(testPrehashedSignature))
+	for _, v := range []string{
+		"",
+		strings.Join(sigLines[:3], "\n"),
+		strings.Join([]string{sigLines[0], sigLines[1], "untrusted comment: x", sigLines[3]}, "\n"),
+		strings.Join([]string{sigLines[0], "RXQ" + sigLines[1][3:], sigLines[2], sigLines[3]}, "\n"),
+		strings.Join([]string{sigLines[0], sigLines[1], sigLines[2], sigLines[3][4:]}, "\n"),
+	} {
+		if _, err := ParseSignature([]byte(v)); err == nil {
+			t.Errorf("ParseSignature(%q): accepted bad input", v)
+		}
+	}
+
+	if _, err := scryptStream([]byte("x"), make([]byte, scryptSaltSize), maxScryptOpsLimit+1, 0); err != errInvalidKDFParams {
+		t.Fatalf("scryptStream: accepted excessive parameters")
+	}
+	if _, err := scryptStream([]byte("x"), make([]byte, scryptSaltSize), DefaultScryptOpsLimit, maxScryptMemLimit+1); err != errInvalidKDFParams {
+		t.Fatalf("scryptStream: accepted excessive memory limit")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package minisign
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"io"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/internal/bcryptpbkdf"
+)
+
+const (
+	// DefaultSignifyRounds is the default number of bcrypt_pbkdf rounds
+	// used by signify to encrypt secret keys.
+	DefaultSignifyRounds = 42
+
+	kdfBcrypt = "BK"
+
+	signifySaltSize      = 16
+	signifyChecksumSize  = 8
+	signifySecretKeySize = 2 + 2 + 4 + signifySaltSize + signifyChecksumSize + KeyIDSize + ed25519.PrivateKeySize
+
+	// maxSignifyRounds bounds the bcrypt_pbkdf work factor accepted when
+	// parsing, so that a crafted secret key file can not stall the caller.
+	maxSignifyRounds = 4096
+
+	defaultSignifySecretKeyComment = "signify secret key"
+	defaultSignifySignatureComment = "signature from signify secret key"
+)
+
+// MarshalSignifyPrivateKey serializes a secret key to an unencrypted
+// signify secret key file, as created by `signify -G -n`, with an untrusted
+// comment.  If comment is empty, signify's default comment is used.  The
+// (unused) salt is generated using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+func MarshalSignifyPrivateKey(rand io.Reader, privateKey *PrivateKey, comment string) ([]byte, error) {
+	return marshalSignifyPrivateKey(rand, privateKey, comment, nil, 0)
+}
+
+// MarshalSignifyPrivateKeyWithPassphrase serializes a secret key to a
+// signify secret key file, encrypted with a key derived from the
+// passphrase with bcrypt_pbkdf, with an untrusted comment.  If comment is
+// empty, signify's default comment is used.  The salt is generated using
+// entropy from rand.  If rand is nil, crypto/rand.Reader will be used.
+func MarshalSignifyPrivateKeyWithPassphrase(rand io.Reader, privateKey *PrivateKey, comment string, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errEmptyPassphrase
+	}
+	return marshalSignifyPrivateKey(rand, privateKey, comment, passphrase, DefaultSignifyRounds)
+}
+
+func marshalSignifyPrivateKey(rand io.Reader, privateKey *PrivateKey, comment string, passphrase []byte, rounds int) ([]byte, error) {
+	if len(privateKey.Key) != ed25519.PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	if comment == "" {
+		comment = defaultSignifySecretKeyComment
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var salt [signifySaltSize]byte
+	if _, err := io.ReadFull(rand, salt[:]); err != nil {
+		return nil, err
+	}
+	checksum := sha512.Sum512(privateKey.Key)
+
+	secretKey := append([]byte{}, privateKey.Key...)
+	if passphrase != nil {
+		k, err := bcryptpbkdf.Key(passphrase, salt[:], rounds, ed25519.PrivateKeySize)
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(secretKey, k)
+	}
+
+	b := make([]byte, 0, signifySecretKeySize)
+	b = append(b, algEd...)
+	b = append(b, kdfBcrypt...)
+	b = appendUint32(b, uint32(rounds))
+	b = append(b, salt[:]...)
+	b = append(b, checksum[:signifyChecksumSize]...)
+	b = append(b, privateKey.KeyID[:]...)
+	b = append(b, secretKey...)
+
+	return encodeFile(comment, b)
+}
+
+// ParseSignifyPrivateKey parses an unencrypted signify secret key file.  If
+// the key is passphrase protected, ErrPassphraseRequired is returned.
+func ParseSignifyPrivateKey(in []byte) (*PrivateKey, error) {
+	return parseSignifyPrivateKey(in, nil)
+}
+
+// ParseSignifyPrivateKeyWithPassphrase parses a passphrase protected
+// signify secret key file.  If the passphrase is wrong,
+// ErrIncorrectPassphrase is returned.
+func ParseSignifyPrivateKeyWithPassphrase(in, passphrase []byte) (*PrivateKey, error) {
+	if len(passphrase) == 0 {
+		return nil, errEmptyPassphrase
+	}
+	return parseSignifyPrivateKey(in, passphrase)
+}
+
+func parseSignifyPrivateKey(in, passphrase []byte) (*PrivateKey, error) {
+	lines := splitLines(in)
+	if len(lines) != 2 {
+		return nil, errInvalidSecretKey
+	}
+	_, b, ok := decodeFile(lines, signifySecretKeySize)
+	if !ok {
+		return nil, errInvalidSecretKey
+	}
+	if string(b[0:2]) != algEd {
+		return nil, errInvalidAlgorithm
+	}
+	if string(b[2:4]) != kdfBcrypt {
+		return nil, errInvalidKDF
+	}
+	rounds := binary.BigEndian.Uint32(b[4:])
+	b = b[8:]
+	salt, b := b[:signifySaltSize], b[signifySaltSize:]
+	checksum, b := b[:signifyChecksumSize], b[signifyChecksumSize:]
+	keyID, secretKey := b[:KeyIDSize], b[KeyIDSize:]
+
+	switch {
+	case rounds == 0:
+		if passphrase != nil {
+			return nil, errUnexpectedPass
+		}
+	case passphrase == nil:
+		return nil, ErrPassphraseRequired
+	case rounds > maxSignifyRounds:
+		return nil, errInvalidKDFParams
+	default:
+		k, err := bcryptpbkdf.Key(passphrase, salt, int(rounds), ed25519.PrivateKeySize)
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(secretKey, k)
+	}
+
+	expectedChecksum := sha512.Sum512(secretKey)
+	if subtle.ConstantTimeCompare(checksum, expectedChecksum[:signifyChecksumSize]) != 1 {
+		if passphrase != nil {
+			return nil, ErrIncorrectPassphrase
+		}
+		return nil, errInvalidChecksum
+	}
+
+	return newPrivateKey(keyID, secretKey)
+}
+
+// SignifySign signs the message with the secret key, and returns a signify
+// signature file, with an untrusted comment.  If comment is empty, a
+// default comment is used.
+func SignifySign(privateKey *PrivateKey, message []byte, comment string) ([]byte, error) {
+	if len(privateKey.Key) != ed25519.PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	if comment == "" {
+		comment = defaultSignifySignatureComment
+	}
+
+	b := make([]byte, 0, signatureSize)
+	b = append(b, algEd...)
+	b = append(b, privateKey.KeyID[:]...)
+	b = append(b, ed25519.Sign(privateKey.Key, message)...)
+
+	return encodeFile(comment, b)
+}
+
+// SignifyVerify verifies the signify signature file of the message, with
+// the public key.
+func SignifyVerify(publicKey *PublicKey, message, signature []byte) error {
+	if len(publicKey.Key) != ed25519.PublicKeySize {
+		return errInvalidKey
+	}
+	lines := splitLines(signature)
+	if len(lines) != 2 {
+		return errInvalidSignature
+	}
+	_, b, ok := decodeFile(lines, signatureSize)
+	if !ok {
+		return errInvalidSignature
+	}
+	if string(b[:2]) != algEd {
+		return errInvalidAlgorithm
+	}
+	if subtle.ConstantTimeCompare(b[2:2+KeyIDSize], publicKey.KeyID[:]) != 1 {
+		return errKeyIDMismatch
+	}
+	if !ed25519.Verify(publicKey.Key, message, b[2+KeyIDSize:]) {
+		return errSignatureMismatch
+	}
+	return nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
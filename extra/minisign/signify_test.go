@@ -0,0 +1,212 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package minisign
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+func TestSignify(t *testing.T) {
+	privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	publicKey := privateKey.Public()
+
+	b, err := MarshalPublicKey(publicKey, "signify public key")
+	if err != nil {
+		t.Fatalf("MarshalPublicKey: %v", err)
+	}
+	publicKey2, err := ParsePublicKey(b)
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if publicKey2.KeyID != publicKey.KeyID || !bytes.Equal(publicKey2.Key, publicKey.Key) {
+		t.Fatalf("ParsePublicKey: public key mismatch")
+	}
+
+	msg := []byte("a release artifact\n")
+	sig, err := SignifySign(privateKey, msg, "verify with release.pub")
+	if err != nil {
+		t.Fatalf("SignifySign: %v", err)
+	}
+
+	// "Ed" || keynum || Ed25519 signature over the message.
+	lines := splitLines(sig)
+	if len(lines) != 2 || lines[0] != "untrusted comment: verify with release.pub" {
+		t.Fatalf("SignifySign: unexpected encoding: %q", sig)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(lines[1])
+	if !bytes.Equal(raw[:2], []byte("Ed")) || !bytes.Equal(raw[2:10], privateKey.KeyID[:]) || !bytes.Equal(raw[10:], ed25519.Sign(privateKey.Key, msg)) {
+		t.Fatalf("SignifySign: unexpected signature: %x", raw)
+	}
+
+	if err = SignifyVerify(publicKey, msg, sig); err != nil {
+		t.Fatalf("SignifyVerify: %v", err)
+	}
+	if err = SignifyVerify(publicKey, append(msg, 'x'), sig); err != errSignatureMismatch {
+		t.Fatalf("SignifyVerify: accepted wrong message: %v", err)
+	}
+	otherKeyID := *publicKey
+	otherKeyID.KeyID[0] ^= 1
+	if err = SignifyVerify(&otherKeyID, msg, sig); err != errKeyIDMismatch {
+		t.Fatalf("SignifyVerify: accepted wrong key ID: %v", err)
+	}
+	for _, bad := range []string{
+		"",
+		lines[1],
+		lines[0] + "\n" + lines[1][:len(lines[1])-4],
+		lines[0] + "\n" + "RU" + lines[1][2:],
+		string(sig) + "trailing\n",
+	} {
+		if err = SignifyVerify(publicKey, msg, []byte(bad)); err == nil {
+			t.Errorf("SignifyVerify(%q): accepted bad input", bad)
+		}
+	}
+
+	// minisign signatures are not signify signatures.
+	minisig, _ := Sign(privateKey, bytes.NewReader(msg), &SignOptions{Legacy: true})
+	if err = SignifyVerify(publicKey, msg, minisig); err == nil {
+		t.Fatalf("SignifyVerify: accepted a minisign signature")
+	}
+}
+
+func TestSignifyPrivateKey(t *testing.T) {
+	privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	passphrase := []byte("correct horse battery staple")
+
+	unencrypted, err := MarshalSignifyPrivateKey(nil, privateKey, "")
+	if err != nil {
+		t.Fatalf("MarshalSignifyPrivateKey: %v", err)
+	}
+	encrypted, err := MarshalSignifyPrivateKeyWithPassphrase(nil, privateKey, "release secret key", passphrase)
+	if err != nil {
+		t.Fatalf("MarshalSignifyPrivateKeyWithPassphrase: %v", err)
+	}
+
+	// "Ed" || "BK" || rounds || salt || checksum || keynum || seckey
+	for _, v := range []struct {
+		b       []byte
+		comment string
+		rounds  uint32
+	}{
+		{unencrypted, defaultSignifySecretKeyComment, 0},
+		{encrypted, "release secret key", DefaultSignifyRounds},
+	} {
+		lines := splitLines(v.b)
+		if len(lines) != 2 || lines[0] != untrustedCommentPrefix+v.comment {
+			t.Fatalf("MarshalSignifyPrivateKey: unexpected encoding: %q", v.b)
+		}
+		raw, _ := base64.StdEncoding.DecodeString(lines[1])
+		if len(raw) != signifySecretKeySize || string(raw[:4]) != "EdBK" || binary.BigEndian.Uint32(raw[4:]) != v.rounds {
+			t.Fatalf("MarshalSignifyPrivateKey: unexpected header: %x", raw[:8])
+		}
+		checksum := sha512.Sum512(privateKey.Key)
+		if !bytes.Equal(raw[24:32], checksum[:8]) || !bytes.Equal(raw[32:40], privateKey.KeyID[:]) {
+			t.Fatalf("MarshalSignifyPrivateKey: unexpected checksum or key number")
+		}
+		if isEncrypted := !bytes.Equal(raw[40:], privateKey.Key); isEncrypted != (v.rounds != 0) {
+			t.Fatalf("MarshalSignifyPrivateKey: unexpected secret key encryption")
+		}
+	}
+
+	privateKey2, err := ParseSignifyPrivateKey(unencrypted)
+	if err != nil {
+		t.Fatalf("ParseSignifyPrivateKey: %v", err)
+	}
+	if privateKey2.KeyID != privateKey.KeyID || !privateKey2.Key.Equal(privateKey.Key) {
+		t.Fatalf("ParseSignifyPrivateKey: private key mismatch")
+	}
+	privateKey2, err = ParseSignifyPrivateKeyWithPassphrase(encrypted, passphrase)
+	if err != nil {
+		t.Fatalf("ParseSignifyPrivateKeyWithPassphrase: %v", err)
+	}
+	if privateKey2.KeyID != privateKey.KeyID || !privateKey2.Key.Equal(privateKey.Key) {
+		t.Fatalf("ParseSignifyPrivateKeyWithPassphrase: private key mismatch")
+	}
+
+	if _, err = ParseSignifyPrivateKey(encrypted); err != ErrPassphraseRequired {
+		t.Fatalf("ParseSignifyPrivateKey: unexpected error for encrypted key: %v", err)
+	}
+	if _, err = ParseSignifyPrivateKeyWithPassphrase(encrypted, []byte("wrong")); err != ErrIncorrectPassphrase {
+		t.Fatalf("ParseSignifyPrivateKeyWithPassphrase: unexpected error for wrong passphrase: %v", err)
+	}
+	if _, err = ParseSignifyPrivateKeyWithPassphrase(unencrypted, passphrase); err != errUnexpectedPass {
+		t.Fatalf("ParseSignifyPrivateKeyWithPassphrase: unexpected error for unencrypted key: %v", err)
+	}
+
+	if _, err = ParseSignifyPrivateKey([]byte(testUnencryptedSecretKey)); err == nil {
+		t.Fatalf("ParseSignifyPrivateKey: accepted a minisign secret key")
+	}
+
+	lines := splitLines(unencrypted)
+	raw, _ := base64.StdEncoding.DecodeString(lines[1])
+	for _, v := range []struct {
+		name   string
+		offset int
+	}{
+		{"Algorithm", 0},
+		{"KDF", 2},
+		{"Checksum", 24},
+		{"PublicKey", len(raw) - 1},
+	} {
+		tampered := append([]byte{}, raw...)
+		tampered[v.offset] ^= 1
+		b := []byte(lines[0] + "\n" + base64.StdEncoding.EncodeToString(tampered) + "\n")
+		if _, err = ParseSignifyPrivateKey(b); err == nil {
+			t.Errorf("ParseSignifyPrivateKey(%s): accepted bad input", v.name)
+		}
+	}
+
+	lines = splitLines(encrypted)
+	raw, _ = base64.StdEncoding.DecodeString(lines[1])
+	binary.BigEndian.PutUint32(raw[4:], maxSignifyRounds+1)
+	b := []byte(lines[0] + "\n" + base64.StdEncoding.EncodeToString(raw) + "\n")
+	if _, err = ParseSignifyPrivateKeyWithPassphrase(b, passphrase); err != errInvalidKDFParams {
+		t.Fatalf("ParseSignifyPrivateKeyWithPassphrase: unexpected error for excessive rounds: %v", err)
+	}
+	if _, err = MarshalSignifyPrivateKeyWithPassphrase(nil, privateKey, "", nil); err != errEmptyPassphrase {
+		t.Fatalf("MarshalSignifyPrivateKeyWithPassphrase: accepted an empty passphrase")
+	}
+	if _, err = SignifySign(privateKey, nil, "bad\ncomment"); err != errInvalidComment {
+		t.Fatalf("SignifySign: accepted a multi-line comment")
+	}
+	if _, err = SignifySign(&PrivateKey{}, nil, ""); err != errInvalidKey {
+		t.Fatalf("SignifySign: accepted an invalid key")
+	}
+}
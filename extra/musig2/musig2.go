@@ -0,0 +1,400 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package musig2 implements n-of-n multi-signatures over Ed25519, using the
+// two round MuSig2 protocol by Nick, Ruffing, and Seurin, adapted to use
+// Ed25519's challenge hash.  The aggregate public key is a standard
+// Ed25519 public key, and the aggregate signature is a standard 64 byte
+// Ed25519 signature that can be verified with ed25519.Verify.
+//
+// Key aggregation uses per-key coefficients derived from the full list of
+// public keys, so that no signer can choose its key as a function of the
+// other signers' keys to take control of the aggregate key (rogue-key
+// attacks).
+//
+// A signing session proceeds as follows:
+//
+//  1. Each signer calls NonceGen, and sends the public nonce to all
+//     other signers.
+//  2. Each signer calls AggregateNonces on all of the public nonces,
+//     and produces a partial signature with Sign.
+//  3. Any party can combine the partial signatures with
+//     AggregatePartialSignatures.
+package musig2
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/internal/group"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+const (
+	// PublicNonceSize is the size, in bytes, of public nonces, and
+	// aggregate nonces.
+	PublicNonceSize = 64
+
+	// PartialSignatureSize is the size, in bytes, of partial signatures.
+	PartialSignatureSize = group.ScalarSize
+
+	tagKeyAggList = "MuSig2-Ed25519/KeyAgg list"
+	tagKeyAggCoef = "MuSig2-Ed25519/KeyAgg coefficient"
+	tagNonce      = "MuSig2-Ed25519/nonce"
+	tagNonceCoef  = "MuSig2-Ed25519/noncecoef"
+)
+
+var (
+	errNoPublicKeys      = errors.New("musig2: no public keys")
+	errDuplicateKey      = errors.New("musig2: duplicate public key")
+	errInvalidPublicKey  = errors.New("musig2: invalid public key")
+	errInvalidPrivateKey = errors.New("musig2: bad private key length")
+	errInvalidNonce      = errors.New("musig2: invalid public nonce")
+	errInvalidPartialSig = errors.New("musig2: invalid partial signature")
+	errNotSigner         = errors.New("musig2: public key not in aggregate")
+	errNonceUsed         = errors.New("musig2: secret nonce already used")
+	errNonceMismatch     = errors.New("musig2: secret nonce generated for a different key")
+)
+
+// KeyAggContext is the result of aggregating a list of public keys.
+type KeyAggContext struct {
+	publicKeys   []ed25519.PublicKey
+	coefficients []modm.Bignum256
+	aggKey       ge25519.Ge25519
+	aggKeyBytes  ed25519.PublicKey
+}
+
+// AggregatePublicKeys aggregates the signers' public keys into a single
+// public key.  The order of publicKeys is significant, and all signers
+// must use the same order.  Each signer must appear exactly once, and
+// lists containing duplicate public keys are rejected.
+func AggregatePublicKeys(publicKeys []ed25519.PublicKey) (*KeyAggContext, error) {
+	if len(publicKeys) == 0 {
+		return nil, errNoPublicKeys
+	}
+
+	ctx := &KeyAggContext{
+		publicKeys:   make([]ed25519.PublicKey, 0, len(publicKeys)),
+		coefficients: make([]modm.Bignum256, len(publicKeys)),
+	}
+
+	// L = H(pk_1 || ... || pk_n)
+	h := sha512.New()
+	_, _ = h.Write([]byte(tagKeyAggList))
+	for _, pk := range publicKeys {
+		_, _ = h.Write(pk)
+	}
+	listHash := h.Sum(nil)
+
+	// X = sum(a_i * X_i), a_i = H(L || pk_i)
+	ge25519.SetIdentity(&ctx.aggKey)
+	for i, pk := range publicKeys {
+		var X, t ge25519.Ge25519
+		if err := decodePublicKey(&X, pk); err != nil {
+			return nil, err
+		}
+		if _, ok := ctx.coefficient(pk); ok {
+			return nil, errDuplicateKey
+		}
+		ctx.publicKeys = append(ctx.publicKeys, append(ed25519.PublicKey{}, pk...))
+
+		group.HashToScalar(&ctx.coefficients[i], []byte(tagKeyAggCoef), listHash, pk)
+		ge25519.ScalarmultVartime(&t, &X, &ctx.coefficients[i])
+		ge25519.Add(&ctx.aggKey, &ctx.aggKey, &t)
+	}
+	if ge25519.IsNeutralVartime(&ctx.aggKey) {
+		return nil, errInvalidPublicKey
+	}
+
+	ctx.aggKeyBytes = make(ed25519.PublicKey, ed25519.PublicKeySize)
+	ge25519.Pack(ctx.aggKeyBytes, &ctx.aggKey)
+
+	return ctx, nil
+}
+
+// PublicKey returns the aggregate public key.
+func (ctx *KeyAggContext) PublicKey() ed25519.PublicKey {
+	return append(ed25519.PublicKey{}, ctx.aggKeyBytes...)
+}
+
+func (ctx *KeyAggContext) coefficient(publicKey ed25519.PublicKey) (*modm.Bignum256, bool) {
+	for i, pk := range ctx.publicKeys {
+		if pk.Equal(publicKey) {
+			return &ctx.coefficients[i], true
+		}
+	}
+	return nil, false
+}
+
+// SecretNonce is a signer's secret nonce pair for a single signing session.
+// It MUST NOT be reused, and is cleared by Sign.
+type SecretNonce struct {
+	r1, r2    modm.Bignum256
+	publicKey ed25519.PublicKey
+	used      bool
+}
+
+// Reset clears the secret nonce.
+func (n *SecretNonce) Reset() {
+	n.r1.Reset()
+	n.r2.Reset()
+	n.used = true
+}
+
+// NonceGen generates a new secret nonce pair for the signer holding
+// privateKey, and the corresponding public nonce that must be sent to
+// the other signers, using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+//
+// The nonces are derived from the randomness along with the private key
+// and the aggregate public key, so that a weak source of randomness does
+// not immediately lead to nonce reuse across different sessions.
+func NonceGen(rand io.Reader, privateKey ed25519.PrivateKey, ctx *KeyAggContext) (*SecretNonce, []byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, nil, errInvalidPrivateKey
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var randomBytes [32]byte
+	if _, err := io.ReadFull(rand, randomBytes[:]); err != nil {
+		return nil, nil, err
+	}
+
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	secNonce := &SecretNonce{
+		publicKey: publicKey,
+	}
+	group.HashToScalar(&secNonce.r1, []byte(tagNonce), randomBytes[:], privateKey[:ed25519.SeedSize], ctx.aggKeyBytes, []byte{1})
+	group.HashToScalar(&secNonce.r2, []byte(tagNonce), randomBytes[:], privateKey[:ed25519.SeedSize], ctx.aggKeyBytes, []byte{2})
+	for i := range randomBytes {
+		randomBytes[i] = 0
+	}
+
+	var R1, R2 ge25519.Ge25519
+	pubNonce := make([]byte, PublicNonceSize)
+	ge25519.ScalarmultBaseNiels(&R1, &ge25519.NielsBaseMultiples, &secNonce.r1)
+	ge25519.ScalarmultBaseNiels(&R2, &ge25519.NielsBaseMultiples, &secNonce.r2)
+	ge25519.Pack(pubNonce[:32], &R1)
+	ge25519.Pack(pubNonce[32:], &R2)
+
+	return secNonce, pubNonce, nil
+}
+
+// AggregateNonces aggregates the public nonces of all signers into a
+// single aggregate nonce.
+func AggregateNonces(pubNonces [][]byte) ([]byte, error) {
+	if len(pubNonces) == 0 {
+		return nil, errInvalidNonce
+	}
+
+	var R1, R2, t ge25519.Ge25519
+	ge25519.SetIdentity(&R1)
+	ge25519.SetIdentity(&R2)
+	for _, pubNonce := range pubNonces {
+		if len(pubNonce) != PublicNonceSize {
+			return nil, errInvalidNonce
+		}
+		if !group.DecodeElement(&t, pubNonce[:32]) {
+			return nil, errInvalidNonce
+		}
+		ge25519.Add(&R1, &R1, &t)
+		if !group.DecodeElement(&t, pubNonce[32:]) {
+			return nil, errInvalidNonce
+		}
+		ge25519.Add(&R2, &R2, &t)
+	}
+
+	aggNonce := make([]byte, PublicNonceSize)
+	ge25519.Pack(aggNonce[:32], &R1)
+	ge25519.Pack(aggNonce[32:], &R2)
+
+	return aggNonce, nil
+}
+
+// session is the per-message signing state derived from the aggregate
+// nonce.
+type session struct {
+	b, c   modm.Bignum256
+	R      ge25519.Ge25519
+	RBytes [32]byte
+}
+
+func newSession(ctx *KeyAggContext, aggNonce, message []byte) (*session, error) {
+	if len(aggNonce) != PublicNonceSize {
+		return nil, errInvalidNonce
+	}
+
+	// The aggregate nonce components may be the identity, so they are
+	// only checked for being canonical.
+	var R1, R2 ge25519.Ge25519
+	if !group.UnpackCanonical(&R1, aggNonce[:32]) || !group.UnpackCanonical(&R2, aggNonce[32:]) {
+		return nil, errInvalidNonce
+	}
+
+	s := new(session)
+
+	// b = H(X || R_1 || R_2 || m)
+	group.HashToScalar(&s.b, []byte(tagNonceCoef), ctx.aggKeyBytes, aggNonce, message)
+
+	// R = R_1 + [b]R_2
+	ge25519.ScalarmultVartime(&s.R, &R2, &s.b)
+	ge25519.Add(&s.R, &s.R, &R1)
+	ge25519.Pack(s.RBytes[:], &s.R)
+
+	// c = H(R || X || m), as in Ed25519.
+	group.HashToScalar(&s.c, s.RBytes[:], ctx.aggKeyBytes, message)
+
+	return s, nil
+}
+
+// Sign produces the signer's partial signature over message, given the
+// aggregate nonce of all signers.  secNonce is cleared on return, and can
+// not be used again.
+func Sign(secNonce *SecretNonce, privateKey ed25519.PrivateKey, ctx *KeyAggContext, aggNonce, message []byte) ([]byte, error) {
+	if secNonce.used {
+		return nil, errNonceUsed
+	}
+	defer secNonce.Reset()
+
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errInvalidPrivateKey
+	}
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	if !publicKey.Equal(secNonce.publicKey) {
+		return nil, errNonceMismatch
+	}
+	a, ok := ctx.coefficient(publicKey)
+	if !ok {
+		return nil, errNotSigner
+	}
+
+	s, err := newSession(ctx, aggNonce, message)
+	if err != nil {
+		return nil, err
+	}
+
+	var x, t, partial modm.Bignum256
+	expandedPrivateKey := ed25519.ExpandPrivateKey(privateKey)
+	modm.Expand(&x, expandedPrivateKey[:32])
+	for i := range expandedPrivateKey {
+		expandedPrivateKey[i] = 0
+	}
+
+	// s_i = r_1 + b * r_2 + c * a_i * x_i
+	modm.Mul(&partial, &s.b, &secNonce.r2)
+	modm.Add(&partial, &partial, &secNonce.r1)
+	modm.Mul(&t, &s.c, a)
+	modm.Mul(&t, &t, &x)
+	modm.Add(&partial, &partial, &t)
+	x.Reset()
+	t.Reset()
+
+	partialSig := make([]byte, PartialSignatureSize)
+	modm.Contract(partialSig, &partial)
+	partial.Reset()
+
+	return partialSig, nil
+}
+
+// VerifyPartialSignature reports whether partialSig is a valid partial
+// signature over message by the signer with publicKey, that contributed
+// pubNonce to the aggregate nonce.
+func VerifyPartialSignature(ctx *KeyAggContext, publicKey ed25519.PublicKey, pubNonce, aggNonce, message, partialSig []byte) bool {
+	a, ok := ctx.coefficient(publicKey)
+	if !ok || len(pubNonce) != PublicNonceSize {
+		return false
+	}
+
+	var (
+		z                  modm.Bignum256
+		X, R1, R2, l, r, t ge25519.Ge25519
+	)
+	if !group.DecodeScalar(&z, partialSig) {
+		return false
+	}
+	if decodePublicKey(&X, publicKey) != nil || !group.DecodeElement(&R1, pubNonce[:32]) || !group.DecodeElement(&R2, pubNonce[32:]) {
+		return false
+	}
+
+	s, err := newSession(ctx, aggNonce, message)
+	if err != nil {
+		return false
+	}
+
+	// [s_i]B == R_i1 + [b]R_i2 + [c * a_i]X_i
+	var ca modm.Bignum256
+	modm.Mul(&ca, &s.c, a)
+	ge25519.ScalarmultBaseNiels(&l, &ge25519.NielsBaseMultiples, &z)
+	ge25519.ScalarmultVartime(&r, &R2, &s.b)
+	ge25519.Add(&r, &r, &R1)
+	ge25519.ScalarmultVartime(&t, &X, &ca)
+	ge25519.Add(&r, &r, &t)
+
+	return ge25519.EqualVartime(&l, &r)
+}
+
+// AggregatePartialSignatures combines the partial signatures of all signers
+// into a standard Ed25519 signature over message by the aggregate public
+// key.
+func AggregatePartialSignatures(ctx *KeyAggContext, aggNonce, message []byte, partialSigs [][]byte) ([]byte, error) {
+	if len(partialSigs) != len(ctx.publicKeys) {
+		return nil, errInvalidPartialSig
+	}
+
+	s, err := newSession(ctx, aggNonce, message)
+	if err != nil {
+		return nil, err
+	}
+
+	var z, zi modm.Bignum256
+	for _, partialSig := range partialSigs {
+		if !group.DecodeScalar(&zi, partialSig) {
+			return nil, errInvalidPartialSig
+		}
+		modm.Add(&z, &z, &zi)
+	}
+
+	sig := make([]byte, ed25519.SignatureSize)
+	copy(sig[:32], s.RBytes[:])
+	modm.Contract(sig[32:], &z)
+
+	return sig, nil
+}
+
+func decodePublicKey(p *ge25519.Ge25519, publicKey ed25519.PublicKey) error {
+	if !group.DecodeElement(p, publicKey) {
+		return errInvalidPublicKey
+	}
+	return nil
+}
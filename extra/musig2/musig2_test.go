@@ -0,0 +1,304 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package musig2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+type testSigner struct {
+	privateKey ed25519.PrivateKey
+	secNonce   *SecretNonce
+	pubNonce   []byte
+}
+
+func newTestSigners(t *testing.T, n int) ([]*testSigner, *KeyAggContext) {
+	signers := make([]*testSigner, 0, n)
+	publicKeys := make([]ed25519.PublicKey, 0, n)
+	for i := 0; i < n; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("GenerateKey: %v", err)
+		}
+		signers = append(signers, &testSigner{privateKey: priv})
+		publicKeys = append(publicKeys, pub)
+	}
+
+	ctx, err := AggregatePublicKeys(publicKeys)
+	if err != nil {
+		t.Fatalf("AggregatePublicKeys: %v", err)
+	}
+
+	for _, signer := range signers {
+		if signer.secNonce, signer.pubNonce, err = NonceGen(nil, signer.privateKey, ctx); err != nil {
+			t.Fatalf("NonceGen: %v", err)
+		}
+	}
+
+	return signers, ctx
+}
+
+func TestMultiSign(t *testing.T) {
+	message := []byte("test message")
+
+	for _, n := range []int{1, 2, 3, 7} {
+		signers, ctx := newTestSigners(t, n)
+
+		pubNonces := make([][]byte, 0, n)
+		for _, signer := range signers {
+			pubNonces = append(pubNonces, signer.pubNonce)
+		}
+		aggNonce, err := AggregateNonces(pubNonces)
+		if err != nil {
+			t.Fatalf("AggregateNonces: %v", err)
+		}
+
+		partialSigs := make([][]byte, 0, n)
+		for _, signer := range signers {
+			partialSig, err := Sign(signer.secNonce, signer.privateKey, ctx, aggNonce, message)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			publicKey := signer.privateKey.Public().(ed25519.PublicKey)
+			if !VerifyPartialSignature(ctx, publicKey, signer.pubNonce, aggNonce, message, partialSig) {
+				t.Fatalf("n = %d: VerifyPartialSignature failed", n)
+			}
+			if VerifyPartialSignature(ctx, publicKey, signer.pubNonce, aggNonce, []byte("wrong message"), partialSig) {
+				t.Fatalf("n = %d: VerifyPartialSignature succeeded on wrong message", n)
+			}
+			partialSigs = append(partialSigs, partialSig)
+		}
+
+		sig, err := AggregatePartialSignatures(ctx, aggNonce, message, partialSigs)
+		if err != nil {
+			t.Fatalf("AggregatePartialSignatures: %v", err)
+		}
+		if !ed25519.Verify(ctx.PublicKey(), message, sig) {
+			t.Fatalf("n = %d: ed25519.Verify failed", n)
+		}
+		if ed25519.Verify(ctx.PublicKey(), []byte("wrong message"), sig) {
+			t.Fatalf("n = %d: ed25519.Verify succeeded on wrong message", n)
+		}
+
+		// A missing partial signature must not produce a valid signature.
+		if n > 1 {
+			if _, err = AggregatePartialSignatures(ctx, aggNonce, message, partialSigs[1:]); err == nil {
+				t.Fatalf("n = %d: AggregatePartialSignatures succeeded with missing share", n)
+			}
+		}
+	}
+}
+
+func TestKeyAggregation(t *testing.T) {
+	signers, ctx := newTestSigners(t, 3)
+
+	publicKeys := make([]ed25519.PublicKey, 0, len(signers))
+	for _, signer := range signers {
+		publicKeys = append(publicKeys, signer.privateKey.Public().(ed25519.PublicKey))
+	}
+
+	// The aggregate key depends on the key order, and is not the plain
+	// sum of the public keys.
+	reordered, err := AggregatePublicKeys([]ed25519.PublicKey{publicKeys[2], publicKeys[0], publicKeys[1]})
+	if err != nil {
+		t.Fatalf("AggregatePublicKeys: %v", err)
+	}
+	if bytes.Equal(ctx.PublicKey(), reordered.PublicKey()) {
+		t.Fatalf("aggregate key is independent of key order")
+	}
+
+	// Invalid public keys are rejected.
+	identity := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	identity[0] = 1
+	for _, bad := range []ed25519.PublicKey{
+		identity,
+		publicKeys[0][:31],
+	} {
+		if _, err = AggregatePublicKeys([]ed25519.PublicKey{publicKeys[0], bad}); err == nil {
+			t.Fatalf("AggregatePublicKeys accepted invalid key: %x", bad)
+		}
+	}
+	if _, err = AggregatePublicKeys(nil); err == nil {
+		t.Fatalf("AggregatePublicKeys accepted no keys")
+	}
+
+	// Duplicate public keys are rejected.
+	if _, err = AggregatePublicKeys([]ed25519.PublicKey{publicKeys[0], publicKeys[1], publicKeys[0]}); err != errDuplicateKey {
+		t.Fatalf("AggregatePublicKeys accepted duplicate keys: %v", err)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	// There are no published test vectors for MuSig2 over Ed25519 with
+	// these domain separation tags, so these known answers pin the
+	// construction against accidental changes.  The final signature is
+	// checked to be a valid Ed25519 signature as well.
+	message := []byte("MuSig2-Ed25519 known answer test")
+	vectors := []struct {
+		seed, rand byte
+		publicKey  string
+		pubNonce   string
+		partialSig string
+	}{
+		{
+			0x11, 0xa0,
+			"d04ab232742bb4ab3a1368bd4615e4e6d0224ab71a016baf8520a332c9778737",
+			"402ed66c2d5d699debe46c50c46f83c5518a69da1b8e8a4e24ee92b657998f766b61fe7f97cd42745d8955fafe0ffb1c55089ef3c2fdf00cfc3748648a833b1a",
+			"b666530d8525329534095ab28b198eb27aa12152089f44625d2bca2bffbc5301",
+		},
+		{
+			0x22, 0xa1,
+			"a09aa5f47a6759802ff955f8dc2d2a14a5c99d23be97f864127ff9383455a4f0",
+			"fd53f8dab7df85545bd8f2481128d0cebc8904bf603c0e017d5182ffc2487dc3bd9b8f1b380ca69ea5bece6ce576ee602a202d6d6b064dd18a783ae7a44d1cf3",
+			"6f3adde72267897382afde30299db0c22ad613fdeaecfdb08e9da897a287e307",
+		},
+		{
+			0x33, 0xa2,
+			"17cb79fb2b4120f2b1ec65e4198d6e08b28e813feb01e4a400839b85e18080ce",
+			"dc6de9cf0902202d3c09d9138952805339a22ca3d464400e3f62db1996bf549b33de1cb4e72e4d53dac771346884591b04882cc78b11016423537f90e2f7138e",
+			"5076dcddd0c5e994a9d4ac9a97aff205ef71be419d57fa23f097b9ad5084bc09",
+		},
+	}
+	const (
+		expectedAggKey   = "b78966f4b37ee9942d57cc141a9a77664f23393db8b8b5f022a52257f21a02fd"
+		expectedAggNonce = "0a06583ef8ebe77ff86817e475645438d479f0862c8121e2b29ff9d6791b195ab7f8e2701354632189f93ce5f624908c914f4ac651ec64a8d61cd4a9bc616d75"
+		expectedSig      = "f0b650309f3ee78ba2f1abf44eed6c97e1118dd0b4625b0d3806f77adfaaf05b884317765eef92458af0edda6d6c526694e9f39090e33c37dc602c71f2c8f302"
+	)
+
+	var (
+		privateKeys []ed25519.PrivateKey
+		publicKeys  []ed25519.PublicKey
+	)
+	for i, v := range vectors {
+		privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{v.seed}, ed25519.SeedSize))
+		publicKey := privateKey.Public().(ed25519.PublicKey)
+		if hex.EncodeToString(publicKey) != v.publicKey {
+			t.Fatalf("%d: public key mismatch: %x", i, publicKey)
+		}
+		privateKeys = append(privateKeys, privateKey)
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	ctx, err := AggregatePublicKeys(publicKeys)
+	if err != nil {
+		t.Fatalf("AggregatePublicKeys: %v", err)
+	}
+	if hex.EncodeToString(ctx.PublicKey()) != expectedAggKey {
+		t.Fatalf("aggregate key mismatch: %x", ctx.PublicKey())
+	}
+
+	var (
+		secNonces []*SecretNonce
+		pubNonces [][]byte
+	)
+	for i, v := range vectors {
+		secNonce, pubNonce, err := NonceGen(bytes.NewReader(bytes.Repeat([]byte{v.rand}, 32)), privateKeys[i], ctx)
+		if err != nil {
+			t.Fatalf("%d: NonceGen: %v", i, err)
+		}
+		if hex.EncodeToString(pubNonce) != v.pubNonce {
+			t.Fatalf("%d: public nonce mismatch: %x", i, pubNonce)
+		}
+		secNonces = append(secNonces, secNonce)
+		pubNonces = append(pubNonces, pubNonce)
+	}
+
+	aggNonce, err := AggregateNonces(pubNonces)
+	if err != nil {
+		t.Fatalf("AggregateNonces: %v", err)
+	}
+	if hex.EncodeToString(aggNonce) != expectedAggNonce {
+		t.Fatalf("aggregate nonce mismatch: %x", aggNonce)
+	}
+
+	var partialSigs [][]byte
+	for i, v := range vectors {
+		partialSig, err := Sign(secNonces[i], privateKeys[i], ctx, aggNonce, message)
+		if err != nil {
+			t.Fatalf("%d: Sign: %v", i, err)
+		}
+		if hex.EncodeToString(partialSig) != v.partialSig {
+			t.Fatalf("%d: partial signature mismatch: %x", i, partialSig)
+		}
+		partialSigs = append(partialSigs, partialSig)
+	}
+
+	sig, err := AggregatePartialSignatures(ctx, aggNonce, message, partialSigs)
+	if err != nil {
+		t.Fatalf("AggregatePartialSignatures: %v", err)
+	}
+	if hex.EncodeToString(sig) != expectedSig {
+		t.Fatalf("signature mismatch: %x", sig)
+	}
+	if !ed25519.Verify(ctx.PublicKey(), message, sig) {
+		t.Fatalf("ed25519.Verify failed")
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	message := []byte("test message")
+	signers, ctx := newTestSigners(t, 2)
+
+	aggNonce, err := AggregateNonces([][]byte{signers[0].pubNonce, signers[1].pubNonce})
+	if err != nil {
+		t.Fatalf("AggregateNonces: %v", err)
+	}
+
+	// A nonce generated for a different key is rejected.
+	if _, err = Sign(signers[1].secNonce, signers[0].privateKey, ctx, aggNonce, message); err == nil {
+		t.Fatalf("Sign accepted a mismatched nonce")
+	}
+
+	// A signer that is not part of the aggregate key is rejected.
+	_, outsider, _ := ed25519.GenerateKey(nil)
+	secNonce, _, err := NonceGen(nil, outsider, ctx)
+	if err != nil {
+		t.Fatalf("NonceGen: %v", err)
+	}
+	if _, err = Sign(secNonce, outsider, ctx, aggNonce, message); err == nil {
+		t.Fatalf("Sign accepted a non-participant")
+	}
+
+	// Nonces can only be used once.
+	if _, err = Sign(signers[0].secNonce, signers[0].privateKey, ctx, aggNonce, message); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err = Sign(signers[0].secNonce, signers[0].privateKey, ctx, aggNonce, message); err != errNonceUsed {
+		t.Fatalf("Sign reused a nonce: %v", err)
+	}
+
+	// Malformed nonces are rejected.
+	if _, err = AggregateNonces([][]byte{signers[0].pubNonce[:63]}); err == nil {
+		t.Fatalf("AggregateNonces accepted a truncated nonce")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package openssh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	optionCertAuthority = "cert-authority"
+	optionNamespaces    = "namespaces"
+	optionValidAfter    = "valid-after"
+	optionValidBefore   = "valid-before"
+)
+
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// AllowedSigner is an entry of an OpenSSH allowed_signers file, as used by
+// `ssh-keygen -Y verify`.
+//
+// See: ssh-keygen(1), "ALLOWED SIGNERS"
+type AllowedSigner struct {
+	// Principals are the principal patterns, which may contain the "*"
+	// and "?" wildcards, and may be negated with a leading "!".
+	Principals []string
+
+	// CertAuthority is set if the key is trusted as a certificate
+	// authority.  Certificates are not supported, so such entries never
+	// match a signature.
+	CertAuthority bool
+
+	// Namespaces are the namespace patterns the key may sign in, or nil
+	// if the key may sign in any namespace.
+	Namespaces []string
+
+	// ValidAfter and ValidBefore are the validity interval of the key, or
+	// the zero time if the interval is open.
+	ValidAfter  time.Time
+	ValidBefore time.Time
+
+	// PublicKey is the public key of the signer.
+	PublicKey ed25519.PublicKey
+}
+
+// ParseAllowedSigners parses an OpenSSH allowed_signers file.  Blank lines
+// and comments are skipped, as are entries with key types other than
+// "ssh-ed25519".
+func ParseAllowedSigners(in []byte) ([]AllowedSigner, error) {
+	var signers []AllowedSigner
+	for i, line := range bytes.Split(in, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		signer, err := parseAllowedSigner(string(line))
+		if err != nil {
+			return nil, fmt.Errorf("openssh: allowed_signers line %d: %v", i+1, err)
+		}
+		if signer != nil {
+			signers = append(signers, *signer)
+		}
+	}
+	return signers, nil
+}
+
+// VerifyAllowedSigners verifies the PEM armored SSHSIG signature of the
+// message read from r, as `ssh-keygen -Y verify` does.  The signature must
+// be in the namespace, and made at time t by a key that allowedSigners
+// authorizes for the principal.
+func VerifyAllowedSigners(allowedSigners []AllowedSigner, principal, namespace string, r io.Reader, signature []byte, t time.Time) error {
+	sig, err := ParseSignature(signature)
+	if err != nil {
+		return err
+	}
+
+	var authorized bool
+	for i := range allowedSigners {
+		s := &allowedSigners[i]
+		if s.matchesKey(sig.PublicKey, t) && matchPatternList(principal, s.Principals) && s.matchesNamespace(namespace) {
+			authorized = true
+			break
+		}
+	}
+	if !authorized {
+		return errSignerMismatch
+	}
+
+	return sig.Verify(namespace, r)
+}
+
+// FindPrincipals returns the principal patterns that allowedSigners
+// associates with the public key of the PEM armored SSHSIG signature at
+// time t, as `ssh-keygen -Y find-principals` does.  The signature is not
+// verified.
+func FindPrincipals(allowedSigners []AllowedSigner, signature []byte, t time.Time) ([]string, error) {
+	sig, err := ParseSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var principals []string
+	for i := range allowedSigners {
+		s := &allowedSigners[i]
+		if s.matchesKey(sig.PublicKey, t) {
+			principals = append(principals, s.Principals...)
+		}
+	}
+	if len(principals) == 0 {
+		return nil, errSignerMismatch
+	}
+	return principals, nil
+}
+
+func (s *AllowedSigner) matchesKey(publicKey ed25519.PublicKey, t time.Time) bool {
+	if s.CertAuthority || !bytes.Equal(s.PublicKey, publicKey) {
+		return false
+	}
+	if !s.ValidAfter.IsZero() && t.Before(s.ValidAfter) {
+		return false
+	}
+	if !s.ValidBefore.IsZero() && t.After(s.ValidBefore) {
+		return false
+	}
+	return true
+}
+
+func (s *AllowedSigner) matchesNamespace(namespace string) bool {
+	return s.Namespaces == nil || matchPatternList(namespace, s.Namespaces)
+}
+
+func parseAllowedSigner(line string) (*AllowedSigner, error) {
+	principals, line, err := nextField(line)
+	if err != nil {
+		return nil, err
+	}
+	if principals == "" {
+		return nil, errInvalidFormat
+	}
+	signer := &AllowedSigner{
+		Principals: strings.Split(principals, ","),
+	}
+
+	field, line, err := nextField(line)
+	if err != nil {
+		return nil, err
+	}
+	if isOptions(field) {
+		if err = signer.parseOptions(field); err != nil {
+			return nil, err
+		}
+		if field, line, err = nextField(line); err != nil {
+			return nil, err
+		}
+	}
+
+	// The remainder is "keytype base64 [comment]".
+	keyType := field
+	encoded, _, err := nextField(line)
+	if err != nil {
+		return nil, err
+	}
+	if keyType == "" || encoded == "" {
+		return nil, errInvalidFormat
+	}
+	if keyType != KeyType {
+		return nil, nil
+	}
+	wire, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidFormat
+	}
+	if signer.PublicKey, err = ParsePublicKey(wire); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// isOptions returns true if the field is an option list rather than a key
+// type, which never contain '=' or '"'.
+func isOptions(field string) bool {
+	return strings.ContainsAny(field, "=\",") || strings.EqualFold(field, optionCertAuthority)
+}
+
+func (s *AllowedSigner) parseOptions(options string) error {
+	var seen []string
+	for options != "" {
+		var option string
+		option, options = nextOption(options)
+
+		name, value := option, ""
+		hasValue := false
+		if idx := strings.IndexByte(option, '='); idx >= 0 {
+			name, value, hasValue = option[:idx], option[idx+1:], true
+			if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+				return fmt.Errorf("malformed option %q", option)
+			}
+			value = value[1 : len(value)-1]
+		}
+		name = strings.ToLower(name)
+		for _, v := range seen {
+			if v == name {
+				return fmt.Errorf("duplicate option %q", name)
+			}
+		}
+		seen = append(seen, name)
+
+		var err error
+		switch {
+		case name == optionCertAuthority && !hasValue:
+			s.CertAuthority = true
+		case name == optionNamespaces && hasValue:
+			s.Namespaces = strings.Split(value, ",")
+		case name == optionValidAfter && hasValue:
+			s.ValidAfter, err = parseTime(value)
+		case name == optionValidBefore && hasValue:
+			s.ValidBefore, err = parseTime(value)
+		default:
+			return fmt.Errorf("unsupported option %q", option)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextField splits off the next whitespace delimited field, which may be
+// quoted, from line.
+func nextField(line string) (string, string, error) {
+	line = strings.TrimLeft(line, " \t")
+	if strings.HasPrefix(line, "\"") {
+		idx := strings.IndexByte(line[1:], '"')
+		if idx < 0 {
+			return "", "", errUnterminatedQuote
+		}
+		return line[1 : idx+1], line[idx+2:], nil
+	}
+
+	// Option lists may contain quoted values with whitespace.
+	inQuote := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case (c == ' ' || c == '\t') && !inQuote:
+			return line[:i], line[i:], nil
+		}
+	}
+	if inQuote {
+		return "", "", errUnterminatedQuote
+	}
+	return line, "", nil
+}
+
+// nextOption splits off the next comma delimited option, whose value may
+// be quoted, from options.
+func nextOption(options string) (string, string) {
+	inQuote := false
+	for i := 0; i < len(options); i++ {
+		switch c := options[i]; {
+		case c == '"':
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			return options[:i], options[i+1:]
+		}
+	}
+	return options, ""
+}
+
+// parseTime parses a YYYYMMDD[HHMM[SS]] timestamp, in UTC if suffixed with
+// "Z", and in the local time zone otherwise.
+func parseTime(s string) (time.Time, error) {
+	loc := time.Local
+	if strings.HasSuffix(s, "Z") || strings.HasSuffix(s, "z") {
+		s, loc = s[:len(s)-1], time.UTC
+	}
+
+	var layout string
+	switch len(s) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("malformed timestamp %q", s)
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed timestamp %q", s)
+	}
+	return t, nil
+}
+
+// matchPatternList matches s against a list of patterns, as OpenSSH's
+// match_pattern_list does: s matches if it matches at least one pattern,
+// and none of the negated patterns.
+func matchPatternList(s string, patterns []string) bool {
+	var matched bool
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if negated {
+			pattern = pattern[1:]
+		}
+		if matchPattern(s, pattern) {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern matches s against a pattern containing the "*" and "?"
+// wildcards.
+func matchPattern(s, pattern string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(s[i:], pattern) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		s, pattern = s[1:], pattern[1:]
+	}
+	return len(s) == 0
+}
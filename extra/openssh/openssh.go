@@ -0,0 +1,517 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package openssh implements the OpenSSH encodings of Ed25519 keys: the
+// "ssh-ed25519" public key wire format and authorized_keys lines, and the
+// "openssh-key-v1" private key file format, optionally protected by a
+// passphrase with bcrypt_pbkdf and aes256-ctr.  It also implements the
+// "SSHSIG" signature format produced by `ssh-keygen -Y sign`, and
+// verification against allowed_signers files.
+//
+// See: https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.key
+// See: https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+package openssh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/extra/internal/bcryptpbkdf"
+)
+
+const (
+	// KeyType is the OpenSSH key type of Ed25519 keys.
+	KeyType = "ssh-ed25519"
+
+	// PrivateKeyPEMType is the PEM block type of OpenSSH private keys.
+	PrivateKeyPEMType = "OPENSSH PRIVATE KEY"
+
+	// DefaultRounds is the default number of bcrypt_pbkdf rounds used to
+	// derive the encryption key from the passphrase, matching ssh-keygen.
+	DefaultRounds = 16
+
+	privateKeyMagic = "openssh-key-v1\x00"
+
+	cipherNone      = "none"
+	cipherAES256CTR = "aes256-ctr"
+	kdfNone         = "none"
+	kdfBcrypt       = "bcrypt"
+
+	saltSize      = 16
+	aesKeySize    = 32
+	blockSizeNone = 8
+
+	// maxBcryptRounds bounds the bcrypt_pbkdf work factor accepted when
+	// parsing, so that a crafted key file can not stall the caller before
+	// the passphrase is checked.  ssh-keygen defaults to 16 rounds.
+	maxBcryptRounds = 4096
+)
+
+var (
+	// ErrPassphraseRequired is the error returned when parsing a
+	// passphrase protected private key without a passphrase.
+	ErrPassphraseRequired = errors.New("openssh: passphrase required")
+
+	// ErrIncorrectPassphrase is the error returned when the passphrase
+	// fails to decrypt a private key.
+	ErrIncorrectPassphrase = errors.New("openssh: incorrect passphrase")
+
+	errInvalidPublicKey  = errors.New("openssh: invalid Ed25519 public key")
+	errInvalidPrivateKey = errors.New("openssh: invalid Ed25519 private key")
+	errInvalidKeyType    = errors.New("openssh: unsupported key type")
+	errInvalidFormat     = errors.New("openssh: malformed key")
+	errInvalidPEM        = errors.New("openssh: failed to decode PEM block")
+	errUnexpectedPEMType = errors.New("openssh: unexpected PEM block type")
+	errInvalidCipher     = errors.New("openssh: unsupported cipher")
+	errInvalidKDF        = errors.New("openssh: unsupported KDF")
+	errInvalidNumKeys    = errors.New("openssh: unsupported number of keys")
+	errInvalidPadding    = errors.New("openssh: invalid padding")
+	errPublicKeyMismatch = errors.New("openssh: public key does not match private key")
+	errUnexpectedPass    = errors.New("openssh: passphrase provided for unencrypted key")
+	errEmptyPassphrase   = errors.New("openssh: empty passphrase")
+)
+
+// MarshalPublicKey serializes an Ed25519 public key to the SSH wire
+// format.
+func MarshalPublicKey(publicKey ed25519.PublicKey) ([]byte, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+
+	var b []byte
+	b = appendString(b, []byte(KeyType))
+	b = appendString(b, publicKey)
+	return b, nil
+}
+
+// ParsePublicKey parses an Ed25519 public key in the SSH wire format.
+func ParsePublicKey(in []byte) (ed25519.PublicKey, error) {
+	r := reader{b: in}
+	publicKey, err := r.readPublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !r.empty() {
+		return nil, errInvalidFormat
+	}
+	return publicKey, nil
+}
+
+// MarshalAuthorizedKey serializes an Ed25519 public key as a single line,
+// suitable for an OpenSSH authorized_keys file, with an optional comment.
+// The line includes a trailing newline.
+func MarshalAuthorizedKey(publicKey ed25519.PublicKey, comment string) ([]byte, error) {
+	wire, err := MarshalPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.ContainsAny([]byte(comment), "\r\n") {
+		return nil, errInvalidFormat
+	}
+
+	b := make([]byte, 0, len(KeyType)+1+base64.StdEncoding.EncodedLen(len(wire))+1+len(comment)+1)
+	b = append(b, KeyType...)
+	b = append(b, ' ')
+	b = append(b, base64.StdEncoding.EncodeToString(wire)...)
+	if comment != "" {
+		b = append(b, ' ')
+		b = append(b, comment...)
+	}
+	b = append(b, '\n')
+
+	return b, nil
+}
+
+// ParseAuthorizedKey parses a single "ssh-ed25519 <base64> [comment]"
+// public key line, as found in authorized_keys and .pub files, and returns
+// the public key and comment.  Leading and trailing whitespace is ignored,
+// and authorized_keys options are not supported.
+func ParseAuthorizedKey(in []byte) (ed25519.PublicKey, string, error) {
+	line := bytes.TrimSpace(in)
+	if bytes.ContainsAny(line, "\r\n") {
+		return nil, "", errInvalidFormat
+	}
+
+	keyType, rest := splitField(line)
+	encoded, rest := splitField(rest)
+	if len(encoded) == 0 {
+		return nil, "", errInvalidFormat
+	}
+	if string(keyType) != KeyType {
+		return nil, "", errInvalidKeyType
+	}
+	wire, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, "", errInvalidFormat
+	}
+	publicKey, err := ParsePublicKey(wire)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return publicKey, string(rest), nil
+}
+
+// splitField splits b at the first run of spaces or tabs, the field
+// separators used by sshd for authorized_keys lines.
+func splitField(b []byte) ([]byte, []byte) {
+	idx := bytes.IndexAny(b, " \t")
+	if idx < 0 {
+		return b, nil
+	}
+	return b[:idx], bytes.TrimLeft(b[idx:], " \t")
+}
+
+// MarshalPrivateKey serializes an Ed25519 private key to an unencrypted,
+// PEM encoded "openssh-key-v1" private key, with an optional comment.  The
+// check integer is generated using entropy from rand.  If rand is nil,
+// crypto/rand.Reader will be used.
+func MarshalPrivateKey(rand io.Reader, privateKey ed25519.PrivateKey, comment string) ([]byte, error) {
+	return marshalPrivateKey(rand, privateKey, comment, nil, 0)
+}
+
+// MarshalPrivateKeyWithPassphrase serializes an Ed25519 private key to a
+// PEM encoded "openssh-key-v1" private key, encrypted with aes256-ctr under
+// a key derived from the passphrase with bcrypt_pbkdf, using
+// DefaultRounds.  The salt and check integer are generated using entropy
+// from rand.  If rand is nil, crypto/rand.Reader will be used.
+func MarshalPrivateKeyWithPassphrase(rand io.Reader, privateKey ed25519.PrivateKey, comment string, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errEmptyPassphrase
+	}
+	return marshalPrivateKey(rand, privateKey, comment, passphrase, DefaultRounds)
+}
+
+func marshalPrivateKey(rand io.Reader, privateKey ed25519.PrivateKey, comment string, passphrase []byte, rounds int) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errInvalidPrivateKey
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	var checkInt [4]byte
+	if _, err := io.ReadFull(rand, checkInt[:]); err != nil {
+		return nil, err
+	}
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	wirePublicKey, _ := MarshalPublicKey(publicKey)
+
+	cipherName, kdfName, blockSize := cipherNone, kdfNone, blockSizeNone
+	var kdfOptions, salt []byte
+	if passphrase != nil {
+		cipherName, kdfName, blockSize = cipherAES256CTR, kdfBcrypt, aes.BlockSize
+
+		salt = make([]byte, saltSize)
+		if _, err := io.ReadFull(rand, salt); err != nil {
+			return nil, err
+		}
+		kdfOptions = appendString(kdfOptions, salt)
+		kdfOptions = appendUint32(kdfOptions, uint32(rounds))
+	}
+
+	// The private section.
+	var priv []byte
+	priv = append(priv, checkInt[:]...)
+	priv = append(priv, checkInt[:]...)
+	priv = appendString(priv, []byte(KeyType))
+	priv = appendString(priv, publicKey)
+	priv = appendString(priv, privateKey)
+	priv = appendString(priv, []byte(comment))
+	for i := 1; len(priv)%blockSize != 0; i++ {
+		priv = append(priv, byte(i))
+	}
+
+	if passphrase != nil {
+		stream, err := newStream(passphrase, salt, rounds)
+		if err != nil {
+			return nil, err
+		}
+		stream.XORKeyStream(priv, priv)
+	}
+
+	var b []byte
+	b = append(b, privateKeyMagic...)
+	b = appendString(b, []byte(cipherName))
+	b = appendString(b, []byte(kdfName))
+	b = appendString(b, kdfOptions)
+	b = appendUint32(b, 1)
+	b = appendString(b, wirePublicKey)
+	b = appendString(b, priv)
+
+	return encodePEM(PrivateKeyPEMType, b), nil
+}
+
+// encodePEM encodes a PEM block in the same manner as ssh-keygen, which
+// wraps the base64 encoding at 70 columns instead of the 64 used by
+// encoding/pem.
+func encodePEM(blockType string, b []byte) []byte {
+	const lineLength = 70
+
+	encoded := base64.StdEncoding.EncodeToString(b)
+
+	var buf bytes.Buffer
+	buf.WriteString("-----BEGIN " + blockType + "-----\n")
+	for len(encoded) > 0 {
+		n := lineLength
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		buf.WriteString(encoded[:n])
+		buf.WriteByte('\n')
+		encoded = encoded[n:]
+	}
+	buf.WriteString("-----END " + blockType + "-----\n")
+
+	return buf.Bytes()
+}
+
+// ParsePrivateKey parses an unencrypted, PEM encoded "openssh-key-v1"
+// Ed25519 private key, and returns the private key and comment.  If the
+// key is passphrase protected, ErrPassphraseRequired is returned.
+func ParsePrivateKey(pemBytes []byte) (ed25519.PrivateKey, string, error) {
+	return parsePrivateKey(pemBytes, nil)
+}
+
+// ParsePrivateKeyWithPassphrase parses a passphrase protected, PEM encoded
+// "openssh-key-v1" Ed25519 private key, and returns the private key and
+// comment.  If the passphrase is wrong, ErrIncorrectPassphrase is returned.
+func ParsePrivateKeyWithPassphrase(pemBytes, passphrase []byte) (ed25519.PrivateKey, string, error) {
+	if len(passphrase) == 0 {
+		return nil, "", errEmptyPassphrase
+	}
+	return parsePrivateKey(pemBytes, passphrase)
+}
+
+func parsePrivateKey(pemBytes, passphrase []byte) (ed25519.PrivateKey, string, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, "", errInvalidPEM
+	}
+	if block.Type != PrivateKeyPEMType {
+		return nil, "", errUnexpectedPEMType
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(privateKeyMagic)) {
+		return nil, "", errInvalidFormat
+	}
+
+	r := reader{b: block.Bytes[len(privateKeyMagic):]}
+	cipherName := r.readString()
+	kdfName := r.readString()
+	kdfOptions := r.readString()
+	numKeys := r.readUint32()
+	wirePublicKey := r.readString()
+	priv := r.readString()
+	if r.err != nil || !r.empty() {
+		return nil, "", errInvalidFormat
+	}
+	if numKeys != 1 {
+		return nil, "", errInvalidNumKeys
+	}
+	publicKey, err := ParsePublicKey(wirePublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	blockSize := blockSizeNone
+	switch string(cipherName) {
+	case cipherNone:
+		if string(kdfName) != kdfNone || len(kdfOptions) != 0 {
+			return nil, "", errInvalidKDF
+		}
+		if passphrase != nil {
+			return nil, "", errUnexpectedPass
+		}
+	case cipherAES256CTR:
+		if string(kdfName) != kdfBcrypt {
+			return nil, "", errInvalidKDF
+		}
+		if passphrase == nil {
+			return nil, "", ErrPassphraseRequired
+		}
+
+		kr := reader{b: kdfOptions}
+		salt := kr.readString()
+		rounds := kr.readUint32()
+		if kr.err != nil || !kr.empty() || rounds == 0 || rounds > maxBcryptRounds {
+			return nil, "", errInvalidKDF
+		}
+
+		blockSize = aes.BlockSize
+		if len(priv)%blockSize != 0 {
+			return nil, "", errInvalidFormat
+		}
+		stream, err := newStream(passphrase, salt, int(rounds))
+		if err != nil {
+			return nil, "", err
+		}
+		priv = append([]byte{}, priv...)
+		stream.XORKeyStream(priv, priv)
+	default:
+		return nil, "", errInvalidCipher
+	}
+
+	return parsePrivateSection(priv, publicKey, blockSize, passphrase != nil)
+}
+
+func parsePrivateSection(priv []byte, publicKey ed25519.PublicKey, blockSize int, encrypted bool) (ed25519.PrivateKey, string, error) {
+	if len(priv)%blockSize != 0 {
+		return nil, "", errInvalidFormat
+	}
+
+	r := reader{b: priv}
+	checkInt1, checkInt2 := r.readUint32(), r.readUint32()
+	if r.err != nil {
+		return nil, "", errInvalidFormat
+	}
+	if checkInt1 != checkInt2 {
+		if encrypted {
+			return nil, "", ErrIncorrectPassphrase
+		}
+		return nil, "", errInvalidFormat
+	}
+
+	privPublicKey, err := r.readPublicKey()
+	if err != nil {
+		return nil, "", err
+	}
+	rawPrivateKey := r.readString()
+	comment := r.readString()
+	if r.err != nil {
+		return nil, "", errInvalidFormat
+	}
+
+	// The padding is 1, 2, 3, ..., and may not be a full block.
+	padding := r.b
+	if len(padding) >= blockSize {
+		return nil, "", errInvalidPadding
+	}
+	for i, v := range padding {
+		if v != byte(i+1) {
+			return nil, "", errInvalidPadding
+		}
+	}
+
+	// The private key is the 32 byte seed followed by the public key.
+	if len(rawPrivateKey) != ed25519.PrivateKeySize {
+		return nil, "", errInvalidPrivateKey
+	}
+	privateKey := ed25519.NewKeyFromSeed(rawPrivateKey[:ed25519.SeedSize])
+	if subtle.ConstantTimeCompare(privateKey[32:], rawPrivateKey[32:]) != 1 {
+		return nil, "", errPublicKeyMismatch
+	}
+	if !bytes.Equal(publicKey, privPublicKey) || !bytes.Equal(publicKey, privateKey[32:]) {
+		return nil, "", errPublicKeyMismatch
+	}
+
+	return privateKey, string(comment), nil
+}
+
+func newStream(passphrase, salt []byte, rounds int) (cipher.Stream, error) {
+	k, err := bcryptpbkdf.Key(passphrase, salt, rounds, aesKeySize+aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(k[:aesKeySize])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, k[aesKeySize:]), nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+func appendString(b, s []byte) []byte {
+	b = appendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// reader parses the SSH wire encoding, latching the first error.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) empty() bool {
+	return len(r.b) == 0
+}
+
+func (r *reader) readUint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 4 {
+		r.err = errInvalidFormat
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *reader) readString() []byte {
+	l := r.readUint32()
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.b)) < uint64(l) {
+		r.err = errInvalidFormat
+		return nil
+	}
+	s := r.b[:l]
+	r.b = r.b[l:]
+	return s
+}
+
+func (r *reader) readPublicKey() (ed25519.PublicKey, error) {
+	keyType := r.readString()
+	publicKey := r.readString()
+	if r.err != nil {
+		return nil, errInvalidFormat
+	}
+	if string(keyType) != KeyType {
+		return nil, errInvalidKeyType
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+	return append(ed25519.PublicKey{}, publicKey...), nil
+}
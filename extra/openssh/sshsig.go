@@ -0,0 +1,254 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package openssh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	// SignaturePEMType is the PEM block type of SSHSIG signatures.
+	SignaturePEMType = "SSH SIGNATURE"
+
+	// HashSHA256 is the SSHSIG hash algorithm name of SHA-256.
+	HashSHA256 = "sha256"
+
+	// HashSHA512 is the SSHSIG hash algorithm name of SHA-512, which is
+	// the default used by ssh-keygen.
+	HashSHA512 = "sha512"
+
+	signatureMagic   = "SSHSIG"
+	signatureVersion = 1
+)
+
+var (
+	errInvalidSignature     = errors.New("openssh: invalid signature")
+	errInvalidSignatureBlob = errors.New("openssh: malformed signature")
+	errInvalidSigVersion    = errors.New("openssh: unsupported signature version")
+	errInvalidHash          = errors.New("openssh: unsupported hash algorithm")
+	errEmptyNamespace       = errors.New("openssh: empty namespace")
+	errNamespaceMismatch    = errors.New("openssh: signature namespace mismatch")
+	errSignerMismatch       = errors.New("openssh: signature public key mismatch")
+)
+
+// Signature is a parsed SSHSIG signature.
+type Signature struct {
+	// PublicKey is the public key of the signer.
+	PublicKey ed25519.PublicKey
+
+	// Namespace is the namespace (domain separation tag) of the signature,
+	// for example "file" or "git".
+	Namespace string
+
+	// HashAlgorithm is the hash algorithm used to digest the message,
+	// either HashSHA256 or HashSHA512.
+	HashAlgorithm string
+
+	// Signature is the raw Ed25519 signature.
+	Signature []byte
+}
+
+// Sign signs the message read from r with the private key in the
+// namespace, which must not be empty, and returns the PEM armored SSHSIG
+// signature, as produced by `ssh-keygen -Y sign`.  If hashAlgorithm is
+// empty, HashSHA512 is used.
+func Sign(privateKey ed25519.PrivateKey, namespace, hashAlgorithm string, r io.Reader) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errInvalidPrivateKey
+	}
+	if hashAlgorithm == "" {
+		hashAlgorithm = HashSHA512
+	}
+	if namespace == "" {
+		return nil, errEmptyNamespace
+	}
+	signedData, err := signedData(namespace, hashAlgorithm, r)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &Signature{
+		PublicKey:     privateKey.Public().(ed25519.PublicKey),
+		Namespace:     namespace,
+		HashAlgorithm: hashAlgorithm,
+		Signature:     ed25519.Sign(privateKey, signedData),
+	}
+
+	return sig.Marshal()
+}
+
+// Verify verifies the PEM armored SSHSIG signature of the message read
+// from r, made by the public key in the namespace.
+func Verify(publicKey ed25519.PublicKey, namespace string, r io.Reader, signature []byte) error {
+	sig, err := ParseSignature(signature)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sig.PublicKey, publicKey) {
+		return errSignerMismatch
+	}
+	return sig.Verify(namespace, r)
+}
+
+// ParseSignature parses a PEM armored SSHSIG signature.  The signature is
+// not verified.
+func ParseSignature(signature []byte) (*Signature, error) {
+	block, rest := pem.Decode(signature)
+	if block == nil {
+		return nil, errInvalidPEM
+	}
+	if block.Type != SignaturePEMType {
+		return nil, errUnexpectedPEMType
+	}
+	if len(block.Headers) != 0 || len(bytes.TrimSpace(rest)) != 0 {
+		return nil, errInvalidPEM
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(signatureMagic)) {
+		return nil, errInvalidSignatureBlob
+	}
+
+	r := reader{b: block.Bytes[len(signatureMagic):]}
+	version := r.readUint32()
+	wirePublicKey := r.readString()
+	namespace := r.readString()
+	_ = r.readString() // reserved, ignored as per PROTOCOL.sshsig
+	hashAlgorithm := r.readString()
+	wireSignature := r.readString()
+	if r.err != nil || !r.empty() {
+		return nil, errInvalidSignatureBlob
+	}
+	if version != signatureVersion {
+		return nil, errInvalidSigVersion
+	}
+	publicKey, err := ParsePublicKey(wirePublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	r = reader{b: wireSignature}
+	sigType := r.readString()
+	rawSig := r.readString()
+	if r.err != nil || !r.empty() {
+		return nil, errInvalidSignatureBlob
+	}
+	if string(sigType) != KeyType {
+		return nil, errInvalidKeyType
+	}
+	if len(rawSig) != ed25519.SignatureSize {
+		return nil, errInvalidSignatureBlob
+	}
+
+	return &Signature{
+		PublicKey:     publicKey,
+		Namespace:     string(namespace),
+		HashAlgorithm: string(hashAlgorithm),
+		Signature:     append([]byte{}, rawSig...),
+	}, nil
+}
+
+// Marshal serializes the signature to the PEM armored SSHSIG format.
+func (s *Signature) Marshal() ([]byte, error) {
+	wirePublicKey, err := MarshalPublicKey(s.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Signature) != ed25519.SignatureSize {
+		return nil, errInvalidSignatureBlob
+	}
+
+	var wireSignature []byte
+	wireSignature = appendString(wireSignature, []byte(KeyType))
+	wireSignature = appendString(wireSignature, s.Signature)
+
+	b := []byte(signatureMagic)
+	b = appendUint32(b, signatureVersion)
+	b = appendString(b, wirePublicKey)
+	b = appendString(b, []byte(s.Namespace))
+	b = appendString(b, nil) // reserved
+	b = appendString(b, []byte(s.HashAlgorithm))
+	b = appendString(b, wireSignature)
+
+	return encodePEM(SignaturePEMType, b), nil
+}
+
+// Verify verifies the signature of the message read from r, made by the
+// signature's public key in the namespace.  The caller is responsible for
+// deciding if the public key is trusted.
+func (s *Signature) Verify(namespace string, r io.Reader) error {
+	if namespace == "" {
+		return errEmptyNamespace
+	}
+	if s.Namespace != namespace {
+		return errNamespaceMismatch
+	}
+	if len(s.PublicKey) != ed25519.PublicKeySize {
+		return errInvalidPublicKey
+	}
+	signedData, err := signedData(namespace, s.HashAlgorithm, r)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(s.PublicKey, signedData, s.Signature) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// signedData returns the data that is signed by an SSHSIG signature, over
+// the digest of the message read from r.  The reserved field is always
+// empty, matching ssh-keygen.
+func signedData(namespace, hashAlgorithm string, r io.Reader) ([]byte, error) {
+	var h hash.Hash
+	switch hashAlgorithm {
+	case HashSHA256:
+		h = sha256.New()
+	case HashSHA512:
+		h = sha512.New()
+	default:
+		return nil, errInvalidHash
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+
+	b := []byte(signatureMagic)
+	b = appendString(b, []byte(namespace))
+	b = appendString(b, nil) // reserved
+	b = appendString(b, []byte(hashAlgorithm))
+	b = appendString(b, h.Sum(nil))
+	return b, nil
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package openssh
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+// Signatures of testMessage with testPrivateKeyPEM, generated with
+// OpenSSH 9.2 `ssh-keygen -Y sign`.
+const (
+	testMessage = "Hello, SSHSIG!\n"
+
+	testSignatureFileSHA512 = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgPt3+4Uu4OVFsFzhlU6zH4Zscq4
+6s+0scW8eyNY/A778AAAAEZmlsZQAAAAAAAAAGc2hhNTEyAAAAUwAAAAtzc2gtZWQyNTUx
+OQAAAEBUcGZws+thpCeNe+utXl32eHDalbgqlmvXjcS70ufAr5o3O+EavawkZFx7Zt4paY
+8inN0cByGYDAcjiK25BK4F
+-----END SSH SIGNATURE-----
+`
+
+	testSignatureGitSHA256 = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgPt3+4Uu4OVFsFzhlU6zH4Zscq4
+6s+0scW8eyNY/A778AAAADZ2l0AAAAAAAAAAZzaGEyNTYAAABTAAAAC3NzaC1lZDI1NTE5
+AAAAQInhmSF3fGKLIuuZU3bFLCvPji32xGTiP/7hIV7Objq5jnzkIBQNqpgyz174ThnBdd
+rnF2HJdnC5RJ2q//H8zAI=
+-----END SSH SIGNATURE-----
+`
+
+	testSignatureEmptyMessage = `-----BEGIN SSH SIGNATURE-----
+U1NIU0lHAAAAAQAAADMAAAALc3NoLWVkMjU1MTkAAAAgPt3+4Uu4OVFsFzhlU6zH4Zscq4
+6s+0scW8eyNY/A778AAAAEZmlsZQAAAAAAAAAGc2hhNTEyAAAAUwAAAAtzc2gtZWQyNTUx
+OQAAAEA0vhhh/85GteNp2NXKJ39pO/WpOWkp7Dmd9aZg1FGUv/3wJoFjeRfCgSZxrqp7wj
+J1GxwsJJJ7GNYsWlAs2fcB
+-----END SSH SIGNATURE-----
+`
+
+	testAuthorizedKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID7d/uFLuDlRbBc4ZVOsx+GbHKuOrPtLHFvHsjWPwO+/"
+)
+
+func TestSSHSIG(t *testing.T) {
+	privateKey, _, err := ParsePrivateKey([]byte(testPrivateKeyPEM))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey: %v", err)
+	}
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	for _, v := range []struct {
+		namespace     string
+		hashAlgorithm string
+		message       string
+		signature     string
+	}{
+		{"file", "", testMessage, testSignatureFileSHA512},
+		{"file", HashSHA512, testMessage, testSignatureFileSHA512},
+		{"git", HashSHA256, testMessage, testSignatureGitSHA256},
+		{"file", HashSHA512, "", testSignatureEmptyMessage},
+	} {
+		b, err := Sign(privateKey, v.namespace, v.hashAlgorithm, strings.NewReader(v.message))
+		if err != nil {
+			t.Fatalf("Sign(%s, %s): %v", v.namespace, v.hashAlgorithm, err)
+		}
+		if string(b) != v.signature {
+			t.Fatalf("Sign(%s, %s): got %s", v.namespace, v.hashAlgorithm, b)
+		}
+
+		if err = Verify(publicKey, v.namespace, strings.NewReader(v.message), []byte(v.signature)); err != nil {
+			t.Fatalf("Verify(%s, %s): %v", v.namespace, v.hashAlgorithm, err)
+		}
+
+		sig, err := ParseSignature([]byte(v.signature))
+		if err != nil {
+			t.Fatalf("ParseSignature: %v", err)
+		}
+		if !bytes.Equal(sig.PublicKey, publicKey) || sig.Namespace != v.namespace {
+			t.Fatalf("ParseSignature: mismatch: %+v", sig)
+		}
+		if v.hashAlgorithm != "" && sig.HashAlgorithm != v.hashAlgorithm {
+			t.Fatalf("ParseSignature: hash algorithm mismatch: %s", sig.HashAlgorithm)
+		}
+		b, err = sig.Marshal()
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if string(b) != v.signature {
+			t.Fatalf("Marshal: got %s", b)
+		}
+
+		if err = Verify(publicKey, "other", strings.NewReader(v.message), []byte(v.signature)); err != errNamespaceMismatch {
+			t.Fatalf("Verify: accepted wrong namespace: %v", err)
+		}
+		if err = Verify(publicKey, v.namespace, strings.NewReader(v.message+"x"), []byte(v.signature)); err != errInvalidSignature {
+			t.Fatalf("Verify: accepted wrong message: %v", err)
+		}
+		otherKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+		if err = Verify(otherKey.Public().(ed25519.PublicKey), v.namespace, strings.NewReader(v.message), []byte(v.signature)); err != errSignerMismatch {
+			t.Fatalf("Verify: accepted wrong public key: %v", err)
+		}
+	}
+
+	if _, err = Sign(privateKey, "", "", strings.NewReader(testMessage)); err != errEmptyNamespace {
+		t.Fatalf("Sign: accepted an empty namespace")
+	}
+	if _, err = Sign(privateKey, "file", "sha1", strings.NewReader(testMessage)); err != errInvalidHash {
+		t.Fatalf("Sign: accepted an unsupported hash algorithm")
+	}
+	if _, err = Sign(privateKey[:32], "file", "", strings.NewReader(testMessage)); err == nil {
+		t.Fatalf("Sign: accepted a truncated private key")
+	}
+}
+
+func TestParseSignatureBadInputs(t *testing.T) {
+	sig, _ := ParseSignature([]byte(testSignatureFileSHA512))
+
+	// Blobs are re-armored with encodePEM, with the fields modified.
+	blob := func(version uint32, publicKey, sigType, rawSig []byte, trailing bool) []byte {
+		var wireSignature []byte
+		wireSignature = appendString(wireSignature, sigType)
+		wireSignature = appendString(wireSignature, rawSig)
+
+		b := []byte(signatureMagic)
+		b = appendUint32(b, version)
+		b = appendString(b, publicKey)
+		b = appendString(b, []byte("file"))
+		b = appendString(b, nil)
+		b = appendString(b, []byte(HashSHA512))
+		b = appendString(b, wireSignature)
+		if trailing {
+			b = append(b, 0)
+		}
+		return encodePEM(SignaturePEMType, b)
+	}
+	wirePublicKey, _ := MarshalPublicKey(sig.PublicKey)
+
+	// Sanity check the helper.
+	if b := blob(signatureVersion, wirePublicKey, []byte(KeyType), sig.Signature, false); string(b) != testSignatureFileSHA512 {
+		t.Fatalf("blob: got %s", b)
+	}
+
+	for _, v := range []struct {
+		name string
+		b    []byte
+	}{
+		{"Empty", nil},
+		{"PrivateKey", []byte(testPrivateKeyPEM)},
+		{"Headers", []byte(strings.Replace(testSignatureFileSHA512, "\n", "\nProc-Type: 4,ENCRYPTED\n\n", 1))},
+		{"TrailingPEM", []byte(testSignatureFileSHA512 + testSignatureFileSHA512)},
+		{"Version", blob(2, wirePublicKey, []byte(KeyType), sig.Signature, false)},
+		{"PublicKey", blob(signatureVersion, wirePublicKey[:len(wirePublicKey)-1], []byte(KeyType), sig.Signature, false)},
+		{"SignatureType", blob(signatureVersion, wirePublicKey, []byte("ssh-rsa"), sig.Signature, false)},
+		{"SignatureSize", blob(signatureVersion, wirePublicKey, []byte(KeyType), sig.Signature[:63], false)},
+		{"TrailingData", blob(signatureVersion, wirePublicKey, []byte(KeyType), sig.Signature, true)},
+		{"Magic", encodePEM(SignaturePEMType, []byte("SSHSIX"))},
+	} {
+		if _, err := ParseSignature(v.b); err == nil {
+			t.Errorf("ParseSignature(%s): accepted bad input", v.name)
+		}
+	}
+
+	sig.HashAlgorithm = "sha1"
+	if err := sig.Verify("file", strings.NewReader(testMessage)); err != errInvalidHash {
+		t.Fatalf("Verify: accepted an unsupported hash algorithm: %v", err)
+	}
+}
+
+func TestAllowedSigners(t *testing.T) {
+	otherKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	otherAuthorizedKey, _ := MarshalAuthorizedKey(otherKey.Public().(ed25519.PublicKey), "")
+
+	allowedSigners, err := ParseAllowedSigners([]byte(`# Release signers
+"*@example.com,!mallory@example.com" namespaces="file",valid-before="20990101Z" ` + testAuthorizedKey + ` release key
+
+dev@example.org,ops@example.org ` + testAuthorizedKey + `
+ci@example.org namespaces="git",valid-after="20990101Z" ` + testAuthorizedKey + `
+ca@example.org cert-authority ` + testAuthorizedKey + `
+rsa@example.org ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQC
+other@example.org ` + string(otherAuthorizedKey)))
+	if err != nil {
+		t.Fatalf("ParseAllowedSigners: %v", err)
+	}
+	if len(allowedSigners) != 5 {
+		t.Fatalf("ParseAllowedSigners: unexpected number of signers: %d", len(allowedSigners))
+	}
+	s := allowedSigners[0]
+	if len(s.Principals) != 2 || s.Principals[1] != "!mallory@example.com" || len(s.Namespaces) != 1 || !s.ValidBefore.Equal(time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)) || !s.ValidAfter.IsZero() {
+		t.Fatalf("ParseAllowedSigners: unexpected first signer: %+v", s)
+	}
+	if !allowedSigners[3].CertAuthority {
+		t.Fatalf("ParseAllowedSigners: cert-authority not set")
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []struct {
+		principal string
+		namespace string
+		signature string
+		t         time.Time
+		ok        bool
+	}{
+		{"alice@example.com", "file", testSignatureFileSHA512, now, true},
+		{"mallory@example.com", "file", testSignatureFileSHA512, now, false},
+		{"bob@other.com", "file", testSignatureFileSHA512, now, false},
+		{"alice@example.com", "file", testSignatureFileSHA512, future, false},
+		{"alice@example.com", "git", testSignatureGitSHA256, now, false},
+		{"dev@example.org", "git", testSignatureGitSHA256, now, true},
+		{"ops@example.org", "file", testSignatureFileSHA512, future, true},
+		{"ci@example.org", "git", testSignatureGitSHA256, now, false},
+		{"ci@example.org", "git", testSignatureGitSHA256, future, true},
+		{"ca@example.org", "file", testSignatureFileSHA512, now, false},
+		{"other@example.org", "file", testSignatureFileSHA512, now, false},
+		{"dev@example.org", "file", testSignatureGitSHA256, now, false},
+	} {
+		err := VerifyAllowedSigners(allowedSigners, v.principal, v.namespace, strings.NewReader(testMessage), []byte(v.signature), v.t)
+		if (err == nil) != v.ok {
+			t.Errorf("VerifyAllowedSigners(%s, %s, %v): unexpected result: %v", v.principal, v.namespace, v.t, err)
+		}
+	}
+
+	principals, err := FindPrincipals(allowedSigners, []byte(testSignatureFileSHA512), now)
+	if err != nil {
+		t.Fatalf("FindPrincipals: %v", err)
+	}
+	if strings.Join(principals, ",") != "*@example.com,!mallory@example.com,dev@example.org,ops@example.org" {
+		t.Fatalf("FindPrincipals: unexpected principals: %v", principals)
+	}
+	if _, err = FindPrincipals(allowedSigners[3:4], []byte(testSignatureFileSHA512), now); err == nil {
+		t.Fatalf("FindPrincipals: matched a cert-authority key")
+	}
+}
+
+func TestParseAllowedSignersBadInputs(t *testing.T) {
+	for _, line := range []string{
+		"alice@example.com",
+		"alice@example.com ssh-ed25519",
+		"\"alice@example.com " + testAuthorizedKey,
+		"alice@example.com unknown=\"x\" " + testAuthorizedKey,
+		"alice@example.com namespaces=file " + testAuthorizedKey,
+		"alice@example.com namespaces=\"file\",namespaces=\"git\" " + testAuthorizedKey,
+		"alice@example.com namespaces=\"file " + testAuthorizedKey,
+		"alice@example.com valid-after=\"2020\" " + testAuthorizedKey,
+		"alice@example.com valid-after=\"20201341\" " + testAuthorizedKey,
+		"alice@example.com cert-authority=\"yes\" " + testAuthorizedKey,
+		"alice@example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID7d/uFLuDlRbBc4ZVOsx+GbHKuOrPtLHFvHsjWPwO+",
+		"alice@example.com ssh-ed25519 AAAAB3NzaC1yc2EAAAAgPt3+4Uu4OVFsFzhlU6zH4ZscsY6s+0scW8eyNY/A778=",
+	} {
+		if _, err := ParseAllowedSigners([]byte(line)); err == nil {
+			t.Errorf("ParseAllowedSigners(%q): accepted bad input", line)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	for _, v := range []struct {
+		s, pattern string
+		ok         bool
+	}{
+		{"", "", true},
+		{"", "*", true},
+		{"a", "", false},
+		{"alice@example.com", "alice@example.com", true},
+		{"alice@example.com", "*@example.com", true},
+		{"alice@example.com", "*@example.co", false},
+		{"alice@example.com", "a?ice@*.com", true},
+		{"alice@example.com", "a*e*e*m", true},
+		{"alice@example.com", "a?", false},
+		{"alice@example.com", "ALICE@example.com", false},
+	} {
+		if matchPattern(v.s, v.pattern) != v.ok {
+			t.Errorf("matchPattern(%q, %q): expected %v", v.s, v.pattern, v.ok)
+		}
+	}
+}
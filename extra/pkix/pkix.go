@@ -0,0 +1,293 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package pkix implements the PKCS #8 and X.509 SubjectPublicKeyInfo
+// encodings of Ed25519 keys, as specified in RFC 8410, and their PEM
+// wrappers.
+//
+// Parsing is strict: only the id-Ed25519 algorithm identifier with absent
+// parameters is accepted, key lengths must be exact, and trailing data is
+// rejected.
+package pkix
+
+import (
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	// PrivateKeyPEMType is the PEM block type of PKCS #8 private keys.
+	PrivateKeyPEMType = "PRIVATE KEY"
+
+	// PublicKeyPEMType is the PEM block type of SubjectPublicKeyInfo
+	// public keys.
+	PublicKeyPEMType = "PUBLIC KEY"
+
+	pkcs8V1 = 0 // RFC 5208
+	pkcs8V2 = 1 // RFC 5958
+)
+
+// oidEd25519 is id-Ed25519, 1.3.101.112.
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+var (
+	errInvalidPrivateKey    = errors.New("pkix: invalid Ed25519 private key")
+	errInvalidPublicKey     = errors.New("pkix: invalid Ed25519 public key")
+	errTrailingData         = errors.New("pkix: trailing data")
+	errInvalidAlgorithm     = errors.New("pkix: unsupported algorithm")
+	errInvalidVersion       = errors.New("pkix: unsupported PKCS #8 version")
+	errPublicKeyMismatch    = errors.New("pkix: public key does not match private key")
+	errInvalidPEM           = errors.New("pkix: failed to decode PEM block")
+	errUnexpectedPEMType    = errors.New("pkix: unexpected PEM block type")
+	errUnexpectedPEMHeaders = errors.New("pkix: unexpected PEM headers")
+	errPublicKeyInV1PKCS8   = errors.New("pkix: public key in version 1 PKCS #8")
+)
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// oneAsymmetricKey is the RFC 5958 OneAsymmetricKey, which is a superset
+// of the RFC 5208 PrivateKeyInfo.
+type oneAsymmetricKey struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue  `asn1:"optional,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm algorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// MarshalPKCS8PrivateKey converts an Ed25519 private key to the (version 1)
+// PKCS #8, ASN.1 DER form.
+func MarshalPKCS8PrivateKey(privateKey ed25519.PrivateKey) ([]byte, error) {
+	return marshalPKCS8PrivateKey(privateKey, false)
+}
+
+// MarshalPKCS8PrivateKeyWithPublicKey converts an Ed25519 private key to
+// the version 2 PKCS #8 (OneAsymmetricKey), ASN.1 DER form, that includes
+// the public key.
+func MarshalPKCS8PrivateKeyWithPublicKey(privateKey ed25519.PrivateKey) ([]byte, error) {
+	return marshalPKCS8PrivateKey(privateKey, true)
+}
+
+func marshalPKCS8PrivateKey(privateKey ed25519.PrivateKey, withPublicKey bool) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errInvalidPrivateKey
+	}
+
+	// CurvePrivateKey ::= OCTET STRING
+	curvePrivateKey, err := asn1.Marshal(privateKey.Seed())
+	if err != nil {
+		return nil, err
+	}
+
+	key := oneAsymmetricKey{
+		Version: pkcs8V1,
+		Algorithm: algorithmIdentifier{
+			Algorithm: oidEd25519,
+		},
+		PrivateKey: curvePrivateKey,
+	}
+	if withPublicKey {
+		key.Version = pkcs8V2
+		key.PublicKey = asn1.BitString{
+			Bytes:     append([]byte{}, privateKey[32:]...),
+			BitLength: ed25519.PublicKeySize * 8,
+		}
+	}
+
+	return asn1.Marshal(key)
+}
+
+// ParsePKCS8PrivateKey parses an Ed25519 private key in the version 1 or
+// version 2 PKCS #8, ASN.1 DER form.  If the public key is present, it must
+// match the private key.
+func ParsePKCS8PrivateKey(der []byte) (ed25519.PrivateKey, error) {
+	var key oneAsymmetricKey
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errTrailingData
+	}
+
+	// encoding/asn1 leaves an absent optional publicKey as the zero value,
+	// while a present one has non-nil Bytes, even if it is empty.
+	hasPublicKey := key.PublicKey.Bytes != nil
+
+	switch key.Version {
+	case pkcs8V1:
+		if hasPublicKey {
+			return nil, errPublicKeyInV1PKCS8
+		}
+	case pkcs8V2:
+	default:
+		return nil, errInvalidVersion
+	}
+	if err = checkAlgorithm(&key.Algorithm); err != nil {
+		return nil, err
+	}
+
+	var seed []byte
+	if rest, err = asn1.Unmarshal(key.PrivateKey, &seed); err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 || len(seed) != ed25519.SeedSize {
+		return nil, errInvalidPrivateKey
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+
+	if hasPublicKey {
+		publicKey, err := checkPublicKeyBits(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare(publicKey, privateKey[32:]) != 1 {
+			return nil, errPublicKeyMismatch
+		}
+	}
+
+	return privateKey, nil
+}
+
+// MarshalPKIXPublicKey converts an Ed25519 public key to the
+// SubjectPublicKeyInfo, ASN.1 DER form.
+func MarshalPKIXPublicKey(publicKey ed25519.PublicKey) ([]byte, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: algorithmIdentifier{
+			Algorithm: oidEd25519,
+		},
+		PublicKey: asn1.BitString{
+			Bytes:     append([]byte{}, publicKey...),
+			BitLength: ed25519.PublicKeySize * 8,
+		},
+	})
+}
+
+// ParsePKIXPublicKey parses an Ed25519 public key in the
+// SubjectPublicKeyInfo, ASN.1 DER form.
+func ParsePKIXPublicKey(der []byte) (ed25519.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errTrailingData
+	}
+	if err = checkAlgorithm(&spki.Algorithm); err != nil {
+		return nil, err
+	}
+
+	return checkPublicKeyBits(&spki.PublicKey)
+}
+
+// MarshalPKCS8PrivateKeyPEM converts an Ed25519 private key to a PEM
+// encoded (version 1) PKCS #8 block.
+func MarshalPKCS8PrivateKeyPEM(privateKey ed25519.PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PrivateKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePKCS8PrivateKeyPEM parses a PEM encoded PKCS #8 Ed25519 private key.
+// Only the first PEM block is considered.
+func ParsePKCS8PrivateKeyPEM(data []byte) (ed25519.PrivateKey, error) {
+	der, err := decodePEM(data, PrivateKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePKCS8PrivateKey(der)
+}
+
+// MarshalPKIXPublicKeyPEM converts an Ed25519 public key to a PEM encoded
+// SubjectPublicKeyInfo block.
+func MarshalPKIXPublicKeyPEM(publicKey ed25519.PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePKIXPublicKeyPEM parses a PEM encoded SubjectPublicKeyInfo Ed25519
+// public key.  Only the first PEM block is considered.
+func ParsePKIXPublicKeyPEM(data []byte) (ed25519.PublicKey, error) {
+	der, err := decodePEM(data, PublicKeyPEMType)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePKIXPublicKey(der)
+}
+
+func checkAlgorithm(algorithm *algorithmIdentifier) error {
+	// RFC 8410, Section 3: "For all of the OIDs, the parameters MUST be
+	// absent."
+	if !algorithm.Algorithm.Equal(oidEd25519) || len(algorithm.Parameters.FullBytes) != 0 {
+		return errInvalidAlgorithm
+	}
+	return nil
+}
+
+func checkPublicKeyBits(bits *asn1.BitString) (ed25519.PublicKey, error) {
+	if bits.BitLength != ed25519.PublicKeySize*8 || len(bits.Bytes) != ed25519.PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+	return append(ed25519.PublicKey{}, bits.Bytes...), nil
+}
+
+func decodePEM(data []byte, pemType string) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errInvalidPEM
+	}
+	if block.Type != pemType {
+		return nil, errUnexpectedPEMType
+	}
+	if len(block.Headers) != 0 {
+		return nil, errUnexpectedPEMHeaders
+	}
+	return block.Bytes, nil
+}
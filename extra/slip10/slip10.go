@@ -0,0 +1,216 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// Package slip10 implements SLIP-0010 hierarchical deterministic key
+// derivation for Ed25519.
+//
+// Ed25519 only supports hardened derivation, so every component of a
+// derivation path must be hardened.
+//
+// See: https://github.com/satoshilabs/slips/blob/master/slip-0010.md
+package slip10
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+const (
+	// HardenedOffset is the index of the first hardened child key.
+	HardenedOffset uint32 = 0x80000000
+
+	// ChainCodeSize is the size, in bytes, of chain codes.
+	ChainCodeSize = 32
+
+	// MinSecretSize is the minimum size, in bytes, of a master secret.
+	MinSecretSize = 16
+
+	// MaxSecretSize is the maximum size, in bytes, of a master secret.
+	MaxSecretSize = 64
+
+	curveSeed = "ed25519 seed"
+)
+
+var (
+	errInvalidSecret      = errors.New("slip10: invalid master secret size")
+	errInvalidPath        = errors.New("slip10: invalid derivation path")
+	errNonHardenedIndex   = errors.New("slip10: non-hardened derivation is not supported for Ed25519")
+	errPathComponentRange = errors.New("slip10: derivation path component out of range")
+)
+
+// Key is an extended private key, consisting of an Ed25519 seed and
+// a chain code.
+type Key struct {
+	seed      [ed25519.SeedSize]byte
+	chainCode [ChainCodeSize]byte
+}
+
+// NewMasterKey derives the master key from a master secret.
+func NewMasterKey(secret []byte) (*Key, error) {
+	if l := len(secret); l < MinSecretSize || l > MaxSecretSize {
+		return nil, errInvalidSecret
+	}
+
+	return newKey([]byte(curveSeed), secret), nil
+}
+
+// DeriveForPath derives the key for a path (eg: `m/44'/474'/0'`) from a
+// master secret.
+func DeriveForPath(secret []byte, path string) (*Key, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := NewMasterKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.DeriveIndexes(indexes)
+}
+
+// Derive derives the child key at the (hardened) index.
+func (k *Key) Derive(index uint32) (*Key, error) {
+	if index < HardenedOffset {
+		return nil, errNonHardenedIndex
+	}
+
+	// I = HMAC-SHA512(Key = c_par, Data = 0x00 || ser256(k_par) || ser32(i))
+	var data [1 + ed25519.SeedSize + 4]byte
+	copy(data[1:], k.seed[:])
+	binary.BigEndian.PutUint32(data[1+ed25519.SeedSize:], index)
+	child := newKey(k.chainCode[:], data[:])
+
+	for i := range data {
+		data[i] = 0
+	}
+
+	return child, nil
+}
+
+// DeriveIndexes derives the descendant key along the (hardened) indexes.
+func (k *Key) DeriveIndexes(indexes []uint32) (*Key, error) {
+	var err error
+	for _, index := range indexes {
+		if k, err = k.Derive(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// Seed returns the Ed25519 seed (the RFC 8032 private key).
+func (k *Key) Seed() []byte {
+	return append([]byte{}, k.seed[:]...)
+}
+
+// ChainCode returns the chain code.
+func (k *Key) ChainCode() []byte {
+	return append([]byte{}, k.chainCode[:]...)
+}
+
+// PrivateKey returns the Ed25519 private key.
+func (k *Key) PrivateKey() ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(k.seed[:])
+}
+
+// PublicKey returns the Ed25519 public key.
+func (k *Key) PublicKey() ed25519.PublicKey {
+	return k.PrivateKey().Public().(ed25519.PublicKey)
+}
+
+// Reset clears the key.
+func (k *Key) Reset() {
+	for i := range k.seed {
+		k.seed[i] = 0
+	}
+	for i := range k.chainCode {
+		k.chainCode[i] = 0
+	}
+}
+
+// ParsePath parses a derivation path of the form `m/44'/474'/0'` into
+// child indexes.  Hardened components may be marked with `'`, `h` or
+// `H`, and every component must be hardened.
+func ParsePath(path string) ([]uint32, error) {
+	components := strings.Split(path, "/")
+	if components[0] != "m" {
+		return nil, errInvalidPath
+	}
+
+	indexes := make([]uint32, 0, len(components)-1)
+	for _, component := range components[1:] {
+		if component == "" {
+			return nil, errInvalidPath
+		}
+		trimmed := strings.TrimRight(component, "'hH")
+		switch len(component) - len(trimmed) {
+		case 0:
+			return nil, errNonHardenedIndex
+		case 1:
+		default:
+			return nil, errInvalidPath
+		}
+
+		// strconv.ParseUint accepts a leading '+', which is not valid.
+		if trimmed == "" || trimmed[0] < '0' || trimmed[0] > '9' {
+			return nil, errInvalidPath
+		}
+		index, err := strconv.ParseUint(trimmed, 10, 32)
+		if err != nil || uint32(index) >= HardenedOffset {
+			return nil, errPathComponentRange
+		}
+		indexes = append(indexes, uint32(index)+HardenedOffset)
+	}
+
+	return indexes, nil
+}
+
+func newKey(hmacKey, data []byte) *Key {
+	var sum [sha512.Size]byte
+	mac := hmac.New(sha512.New, hmacKey)
+	_, _ = mac.Write(data)
+	mac.Sum(sum[:0])
+
+	k := new(Key)
+	copy(k.seed[:], sum[:32])
+	copy(k.chainCode[:], sum[32:])
+	for i := range sum {
+		sum[i] = 0
+	}
+
+	return k
+}
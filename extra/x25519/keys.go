@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+// PrivateKey is an X25519 private key.
+type PrivateKey struct {
+	privateKey []byte
+	publicKey  *PublicKey
+}
+
+// PublicKey is an X25519 public key.
+type PublicKey struct {
+	publicKey []byte
+}
+
+// GenerateKey generates a new X25519 private key, using entropy from rand.
+// If rand is nil, crypto/rand.Reader will be used.
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	key := make([]byte, ScalarSize)
+	if err := readRandom(rand, key); err != nil {
+		return nil, err
+	}
+	return newPrivateKey(key)
+}
+
+// NewPrivateKey checks that key is valid and returns a PrivateKey.  The
+// key is the 32-byte scalar input to X25519, and is clamped internally.
+func NewPrivateKey(key []byte) (*PrivateKey, error) {
+	if l := len(key); l != ScalarSize {
+		return nil, fmt.Errorf("bad private key length: %d, expected %d", l, ScalarSize)
+	}
+	return newPrivateKey(append([]byte{}, key...))
+}
+
+// NewPrivateKeyFromEd25519 returns the X25519 private key corresponding to
+// the Ed25519 private key.  See EdPrivateKeyToX25519.
+//
+// Note: The conversion is one-way, as the Ed25519 seed can not be
+// recovered from the X25519 private key.
+func NewPrivateKeyFromEd25519(privateKey ed25519.PrivateKey) (*PrivateKey, error) {
+	if l := len(privateKey); l != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("bad Ed25519 private key length: %d, expected %d", l, ed25519.PrivateKeySize)
+	}
+	return newPrivateKey(EdPrivateKeyToX25519(privateKey))
+}
+
+func newPrivateKey(key []byte) (*PrivateKey, error) {
+	publicKey, err := X25519(key, Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{
+		privateKey: key,
+		publicKey:  &PublicKey{publicKey: publicKey},
+	}, nil
+}
+
+// ECDH performs an ECDH exchange and returns the shared secret.  An error
+// is returned if the result is the all-zero value, which happens when the
+// remote public key is of low order.
+func (k *PrivateKey) ECDH(remote *PublicKey) ([]byte, error) {
+	var dst [32]byte
+	return x25519(&dst, k.privateKey, remote.publicKey)
+}
+
+// Bytes returns a copy of the encoding of the private key.
+func (k *PrivateKey) Bytes() []byte {
+	return append([]byte{}, k.privateKey...)
+}
+
+// Equal returns whether x represents the same private key as k.
+func (k *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	xx, ok := x.(*PrivateKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(k.privateKey, xx.privateKey) == 1
+}
+
+// PublicKey returns the public key corresponding to k.
+func (k *PrivateKey) PublicKey() *PublicKey {
+	return k.publicKey
+}
+
+// Public implements the same method as crypto.Signer and crypto.Decrypter.
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return k.PublicKey()
+}
+
+// Reset clears the private key.
+func (k *PrivateKey) Reset() {
+	zeroBytes(k.privateKey)
+}
+
+// NewPublicKey checks that key is valid and returns a PublicKey.
+//
+// Note: Low order points are accepted here, and rejected by ECDH.
+func NewPublicKey(key []byte) (*PublicKey, error) {
+	if l := len(key); l != PointSize {
+		return nil, fmt.Errorf("bad public key length: %d, expected %d", l, PointSize)
+	}
+	return &PublicKey{publicKey: append([]byte{}, key...)}, nil
+}
+
+// NewPublicKeyFromEd25519 returns the X25519 public key corresponding to
+// the Ed25519 public key.  See EdPublicKeyToX25519.
+func NewPublicKeyFromEd25519(publicKey ed25519.PublicKey) (*PublicKey, error) {
+	if l := len(publicKey); l != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("bad Ed25519 public key length: %d, expected %d", l, ed25519.PublicKeySize)
+	}
+	key, ok := EdPublicKeyToX25519(publicKey)
+	if !ok {
+		return nil, fmt.Errorf("bad Ed25519 public key")
+	}
+	return &PublicKey{publicKey: key}, nil
+}
+
+// Ed25519PublicKey returns the Ed25519 public key with the given sign bit,
+// that corresponds to k.  See X25519ToEdPublicKey.
+func (k *PublicKey) Ed25519PublicKey(signBit byte) (ed25519.PublicKey, error) {
+	publicKey, ok := X25519ToEdPublicKey(k.publicKey, signBit)
+	if !ok {
+		return nil, fmt.Errorf("public key has no Ed25519 equivalent")
+	}
+	return publicKey, nil
+}
+
+// Bytes returns a copy of the encoding of the public key.
+func (k *PublicKey) Bytes() []byte {
+	return append([]byte{}, k.publicKey...)
+}
+
+// Equal returns whether x represents the same public key as k.
+func (k *PublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*PublicKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(k.publicKey, xx.publicKey) == 1
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+)
+
+func TestKeys(t *testing.T) {
+	alice, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	bob, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	aliceShared, err := alice.ECDH(bob.PublicKey())
+	if err != nil {
+		t.Fatalf("alice.ECDH: %v", err)
+	}
+	bobShared, err := bob.ECDH(alice.PublicKey())
+	if err != nil {
+		t.Fatalf("bob.ECDH: %v", err)
+	}
+	if !bytes.Equal(aliceShared, bobShared) {
+		t.Fatalf("Shared secret mismatch: %x != %x", aliceShared, bobShared)
+	}
+	expected, _ := X25519(alice.Bytes(), bob.PublicKey().Bytes())
+	if !bytes.Equal(aliceShared, expected) {
+		t.Fatalf("Shared secret mismatch with X25519: %x != %x", aliceShared, expected)
+	}
+
+	alice2, err := NewPrivateKey(alice.Bytes())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: %v", err)
+	}
+	if !alice.Equal(alice2) || alice.Equal(bob) || alice.Equal(nil) {
+		t.Fatalf("PrivateKey.Equal is broken")
+	}
+	if !alice.PublicKey().Equal(alice2.Public()) || alice.PublicKey().Equal(bob.PublicKey()) {
+		t.Fatalf("PublicKey.Equal is broken")
+	}
+
+	pub, err := NewPublicKey(alice.PublicKey().Bytes())
+	if err != nil {
+		t.Fatalf("NewPublicKey: %v", err)
+	}
+	if !pub.Equal(alice.PublicKey()) {
+		t.Fatalf("NewPublicKey round-trip mismatch")
+	}
+
+	// Bytes must return copies.
+	b := alice.Bytes()
+	b[0] ^= 0xff
+	if !alice.Equal(alice2) || bytes.Equal(b, alice.Bytes()) {
+		t.Fatalf("PrivateKey.Bytes did not return a copy")
+	}
+
+	alice2.Reset()
+	if alice.Equal(alice2) {
+		t.Fatalf("PrivateKey.Reset did not clear the key")
+	}
+}
+
+func TestKeysEd25519(t *testing.T) {
+	edPublic, edPrivate, _ := ed25519.GenerateKey(nil)
+
+	priv, err := NewPrivateKeyFromEd25519(edPrivate)
+	if err != nil {
+		t.Fatalf("NewPrivateKeyFromEd25519: %v", err)
+	}
+	pub, err := NewPublicKeyFromEd25519(edPublic)
+	if err != nil {
+		t.Fatalf("NewPublicKeyFromEd25519: %v", err)
+	}
+	if !priv.PublicKey().Equal(pub) {
+		t.Fatalf("Converted public key mismatch")
+	}
+
+	edPublic2, err := pub.Ed25519PublicKey(edPublic[31] >> 7)
+	if err != nil {
+		t.Fatalf("Ed25519PublicKey: %v", err)
+	}
+	if !bytes.Equal(edPublic, edPublic2) {
+		t.Fatalf("Ed25519 public key round-trip mismatch: %x != %x", edPublic, edPublic2)
+	}
+
+	if _, err = NewPrivateKeyFromEd25519(edPrivate[:32]); err == nil {
+		t.Fatalf("NewPrivateKeyFromEd25519 accepted a truncated key")
+	}
+	if _, err = NewPublicKeyFromEd25519(edPublic[:31]); err == nil {
+		t.Fatalf("NewPublicKeyFromEd25519 accepted a truncated key")
+	}
+}
+
+func TestKeysBadInputs(t *testing.T) {
+	priv, _ := GenerateKey(nil)
+
+	for i, p := range lowOrderPoints {
+		pub, err := NewPublicKey(p)
+		if err != nil {
+			t.Fatalf("%d: NewPublicKey: %v", i, err)
+		}
+		if shared, err := priv.ECDH(pub); err == nil {
+			t.Errorf("%d: expected error, got %x", i, shared)
+		}
+		if _, err = pub.Ed25519PublicKey(0); err == nil {
+			t.Errorf("%d: expected Ed25519PublicKey to fail", i)
+		}
+	}
+
+	if _, err := NewPrivateKey(make([]byte, ScalarSize-1)); err == nil {
+		t.Fatalf("NewPrivateKey accepted a truncated key")
+	}
+	if _, err := NewPublicKey(make([]byte, PointSize+1)); err == nil {
+		t.Fatalf("NewPublicKey accepted an oversized key")
+	}
+	if _, err := GenerateKey(bytes.NewReader(nil)); err == nil {
+		t.Fatalf("GenerateKey succeeded with a failing entropy source")
+	}
+}
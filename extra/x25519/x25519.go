@@ -37,8 +37,6 @@ import (
 	"crypto/subtle"
 	"fmt"
 
-	xcurve "golang.org/x/crypto/curve25519"
-
 	"github.com/oasisprotocol/ed25519"
 	"github.com/oasisprotocol/ed25519/internal/curve25519"
 	"github.com/oasisprotocol/ed25519/internal/ge25519"
@@ -64,7 +62,19 @@ var basePoint = [32]byte{9, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0
 // zeroes, irrespective of the scalar. Instead, use the X25519 function, which
 // will return an error.
 func ScalarMult(dst, in, base *[32]byte) {
-	xcurve.ScalarMult(dst, in, base)
+	var e [32]byte
+
+	// clamp
+	copy(e[:], in[:])
+	e[0] &= 248
+	e[31] &= 127
+	e[31] |= 64
+
+	curve25519.ScalarMult(dst, &e, base)
+
+	for i := range e {
+		e[i] = 0
+	}
 }
 
 // ScalarBaseMult sets dst to the product in*base where dst and base are
@@ -196,7 +206,9 @@ func edwardsToMontgomeryX(outX, y *curve25519.Bignum25519) {
 }
 
 // EdPublicKeyToX25519 converts an Ed25519 public key into the X25519 public
-// key that would be generated from the same private key.
+// key that would be generated from the same private key.  The sign of the
+// x-coordinate is discarded, use X25519ToEdPublicKey with the original sign
+// bit (publicKey[31] >> 7) to recover the Ed25519 public key.
 func EdPublicKeyToX25519(publicKey ed25519.PublicKey) ([]byte, bool) {
 	var A ge25519.Ge25519
 	if !ge25519.UnpackVartime(&A, publicKey[:]) {
@@ -212,6 +224,53 @@ func EdPublicKeyToX25519(publicKey ed25519.PublicKey) ([]byte, bool) {
 	return dst, true
 }
 
+// X25519ToEdPublicKey converts an X25519 public key into the Ed25519
+// public key with the given sign bit (0 or 1), using the birational map
+// y = (u - 1) / (u + 1).  As the u-coordinate does not determine the sign
+// of x, the caller must supply it (eg: EdPublicKeyToX25519 discards it).
+//
+// This returns false if u is not canonically encoded (u >= p), u = -1
+// (where the map is undefined), u is not the u-coordinate of a point on
+// Curve25519 (rather than on the twist), or the resulting point is of
+// small order.
+func X25519ToEdPublicKey(publicKey []byte, signBit byte) (ed25519.PublicKey, bool) {
+	if len(publicKey) != PointSize || signBit > 1 {
+		return nil, false
+	}
+
+	var (
+		uf, uPlusOne curve25519.Bignum25519
+		one          curve25519.Bignum25519
+		check, zero  [32]byte
+	)
+	curve25519One(&one)
+	curve25519.Expand(&uf, publicKey)
+	curve25519.AddReduce(&uPlusOne, &uf, &one)
+	curve25519.Contract(check[:], &uPlusOne)
+	if subtle.ConstantTimeCompare(check[:], zero[:]) == 1 {
+		return nil, false
+	}
+
+	var y [32]byte
+	if !montgomeryToEdwards(&y, publicKey, signBit) {
+		return nil, false
+	}
+
+	var A, tmp ge25519.Ge25519
+	if !ge25519.UnpackVartime(&A, y[:]) {
+		return nil, false
+	}
+	ge25519.CofactorMultiply(&tmp, &A)
+	if ge25519.IsNeutralVartime(&tmp) {
+		return nil, false
+	}
+
+	dst := make([]byte, ed25519.PublicKeySize)
+	copy(dst, y[:])
+
+	return dst, true
+}
+
 func init() {
 	Basepoint = basePoint[:]
 }
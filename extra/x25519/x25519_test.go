@@ -31,8 +31,12 @@ package x25519
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/oasisprotocol/ed25519"
@@ -136,3 +140,237 @@ func TestX25519Conversion(t *testing.T) {
 		t.Errorf("Values didn't match: curve25519 produced %x, conversion produced %x", xPublic, xPublic2)
 	}
 }
+
+func TestX25519ToEdPublicKey(t *testing.T) {
+	message := []byte("test message")
+	for i := 0; i < 32; i++ {
+		public, private, _ := ed25519.GenerateKey(rand.Reader)
+
+		xPublic, ok := EdPublicKeyToX25519(public)
+		if !ok {
+			t.Fatalf("EdPublicKeyToX25519(public): failed")
+		}
+
+		signBit := public[31] >> 7
+		edPublic, ok := X25519ToEdPublicKey(xPublic, signBit)
+		if !ok {
+			t.Fatalf("X25519ToEdPublicKey(xPublic, %d): failed", signBit)
+		}
+		if !bytes.Equal(public, edPublic) {
+			t.Fatalf("Round-trip mismatch: %x != %x", public, edPublic)
+		}
+
+		sig := ed25519.Sign(private, message)
+		if !ed25519.Verify(edPublic, message, sig) {
+			t.Fatalf("ed25519.Verify failed with converted key")
+		}
+
+		// The other sign bit yields the negated point, which maps to
+		// the same u-coordinate.
+		negPublic, ok := X25519ToEdPublicKey(xPublic, signBit^1)
+		if !ok {
+			t.Fatalf("X25519ToEdPublicKey(xPublic, %d): failed", signBit^1)
+		}
+		if !bytes.Equal(public[:31], negPublic[:31]) || public[31]^negPublic[31] != 0x80 {
+			t.Fatalf("Negated key mismatch: %x, %x", public, negPublic)
+		}
+		if ed25519.Verify(negPublic, message, sig) {
+			t.Fatalf("ed25519.Verify succeeded with negated key")
+		}
+		xPublic2, _ := EdPublicKeyToX25519(negPublic)
+		if !bytes.Equal(xPublic, xPublic2) {
+			t.Fatalf("Negated key u-coordinate mismatch: %x != %x", xPublic, xPublic2)
+		}
+	}
+}
+
+func TestX25519ToEdPublicKeyBadInputs(t *testing.T) {
+	xPublic, _ := X25519(make([]byte, ScalarSize), Basepoint)
+
+	minusOne := bytes.Repeat([]byte{0xff}, PointSize)
+	minusOne[0], minusOne[31] = 0xec, 0x7f
+	highBit := append([]byte{}, xPublic...)
+	highBit[31] |= 0x80
+
+	for i, u := range append([][]byte{
+		minusOne,
+		highBit,
+		xPublic[:31],
+		nil,
+	}, lowOrderPoints...) {
+		for signBit := byte(0); signBit < 2; signBit++ {
+			if edPublic, ok := X25519ToEdPublicKey(u, signBit); ok {
+				t.Errorf("%d: expected failure for %x, got %x", i, u, edPublic)
+			}
+		}
+	}
+
+	if _, ok := X25519ToEdPublicKey(xPublic, 2); ok {
+		t.Errorf("X25519ToEdPublicKey accepted an invalid sign bit")
+	}
+
+	// Roughly half of all u-coordinates are on the twist, and must be
+	// rejected.
+	var onTwist bool
+	for u := byte(2); u < 32 && !onTwist; u++ {
+		point := make([]byte, PointSize)
+		point[0] = u
+		_, ok := X25519ToEdPublicKey(point, 0)
+		onTwist = !ok
+	}
+	if !onTwist {
+		t.Errorf("X25519ToEdPublicKey accepted every u-coordinate")
+	}
+}
+
+// RFC 7748, Section 5.2 test vectors.
+var rfc7748Vectors = []struct {
+	scalar, point, expected string
+}{
+	{
+		"a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+		"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+		"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552",
+	},
+	{
+		"4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+		"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+		"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957",
+	},
+}
+
+func TestRFC7748(t *testing.T) {
+	for i, v := range rfc7748Vectors {
+		scalar, _ := hex.DecodeString(v.scalar)
+		point, _ := hex.DecodeString(v.point)
+
+		out, err := X25519(scalar, point)
+		if err != nil {
+			t.Fatalf("%d: X25519: %v", i, err)
+		}
+		if s := hex.EncodeToString(out); s != v.expected {
+			t.Errorf("%d: incorrect result: got %s, want %s", i, s, v.expected)
+		}
+	}
+
+	// RFC 7748, Section 5.2 iterated test vectors.
+	k, u := make([]byte, 32), make([]byte, 32)
+	k[0], u[0] = 9, 9
+	for i := 1; i <= 1000; i++ {
+		r, err := X25519(k, u)
+		if err != nil {
+			t.Fatalf("iteration %d: X25519: %v", i, err)
+		}
+		u, k = k, r
+
+		var expected string
+		switch i {
+		case 1:
+			expected = "422c8e7a6227d7bca1350b3e2bb7279f7897b87bb6854b783c60e80311ae3079"
+		case 1000:
+			expected = "684cf59ba83309552800ef566f2f4d3c1c3887c49360e3875f2eb94d99532c51"
+		default:
+			continue
+		}
+		if s := hex.EncodeToString(k); s != expected {
+			t.Errorf("iteration %d: incorrect result: got %s, want %s", i, s, expected)
+		}
+	}
+}
+
+type wycheproofTestCase struct {
+	ID      int    `json:"tcId"`
+	Comment string `json:"comment"`
+	Public  string `json:"public"`
+	Private string `json:"private"`
+	Shared  string `json:"shared"`
+	Result  string `json:"result"`
+}
+
+func TestWycheproof(t *testing.T) {
+	f, err := os.Open("testdata/x25519_test.json.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rd, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	var testCases []wycheproofTestCase
+
+	dec := json.NewDecoder(rd)
+	if err = dec.Decode(&testCases); err != nil {
+		t.Fatal(err)
+	}
+
+	var zero [32]byte
+	for _, tc := range testCases {
+		public, _ := hex.DecodeString(tc.Public)
+		private, _ := hex.DecodeString(tc.Private)
+		shared, _ := hex.DecodeString(tc.Shared)
+
+		var dst, scalar, point [32]byte
+		copy(scalar[:], private)
+		copy(point[:], public)
+		ScalarMult(&dst, &scalar, &point)
+		if !bytes.Equal(dst[:], shared) {
+			t.Errorf("%d (%s): ScalarMult: got %x, want %x", tc.ID, tc.Comment, dst, shared)
+		}
+
+		// X25519 rejects all-zero outputs.
+		out, err := X25519(private, public)
+		if bytes.Equal(shared, zero[:]) {
+			if err == nil {
+				t.Errorf("%d (%s): X25519 accepted a low order point", tc.ID, tc.Comment)
+			}
+		} else if err != nil || !bytes.Equal(out, shared) {
+			t.Errorf("%d (%s): X25519: got %x (%v), want %x", tc.ID, tc.Comment, out, err, shared)
+		}
+	}
+}
+
+func TestScalarMultBasepoint(t *testing.T) {
+	var scalar, fixed, ladder [32]byte
+	for i := 0; i < 64; i++ {
+		if _, err := rand.Read(scalar[:]); err != nil {
+			t.Fatal(err)
+		}
+
+		ScalarBaseMult(&fixed, &scalar)
+		ScalarMult(&ladder, &scalar, &basePoint)
+		if fixed != ladder {
+			t.Fatalf("basepoint mismatch: ScalarBaseMult %x, ScalarMult %x", fixed, ladder)
+		}
+	}
+}
+
+func TestHighBitIgnored(t *testing.T) {
+	var scalar, point, hi, lo [32]byte
+	if _, err := rand.Read(scalar[:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rand.Read(point[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	point[31] &= 0x7f
+	ScalarMult(&lo, &scalar, &point)
+	point[31] |= 0x80
+	ScalarMult(&hi, &scalar, &point)
+	if lo != hi {
+		t.Fatalf("high bit of point was not ignored")
+	}
+}
+
+func BenchmarkScalarMult(b *testing.B) {
+	var in, out [32]byte
+	in[0] = 1
+
+	b.SetBytes(32)
+	for i := 0; i < b.N; i++ {
+		ScalarMult(&out, &in, &basePoint)
+	}
+}
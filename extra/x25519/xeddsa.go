@@ -0,0 +1,486 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/ed25519/internal/curve25519"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+// XEdDSA and VXEdDSA, as specified in "The XEdDSA and VXEdDSA Signature
+// Schemes" (Revision 1, 2016-10-20) by Trevor Perrin.
+// See: https://signal.org/docs/specifications/xeddsa/
+
+const (
+	// XEdDSASignatureSize is the size of XEdDSA signatures.
+	XEdDSASignatureSize = 64
+	// VXEdDSASignatureSize is the size of VXEdDSA signatures.
+	VXEdDSASignatureSize = 96
+	// VXEdDSAOutputSize is the size of the VXEdDSA VRF output.
+	VXEdDSAOutputSize = 32
+
+	// xeddsaRandomSize is the size of the random input Z to signing.
+	xeddsaRandomSize = 64
+)
+
+// montgomeryA is the Montgomery curve parameter A = 486662.
+var montgomeryA = curve25519.Bignum25519{486662}
+
+// XEdDSASign signs the message with the X25519 private key, using entropy
+// from rand.  If rand is nil, crypto/rand.Reader will be used.  The
+// signature can be verified with XEdDSAVerify, or ed25519.Verify using the
+// Ed25519 public key with the sign bit cleared.
+func XEdDSASign(rand io.Reader, privateKey, message []byte) ([]byte, error) {
+	var (
+		Z            [xeddsaRandomSize]byte
+		a, r, h, s   modm.Bignum256
+		A, aBytes, R [32]byte
+		Rp           ge25519.Ge25519
+	)
+
+	if err := readRandom(rand, Z[:]); err != nil {
+		return nil, err
+	}
+	if err := calculateKeyPair(&a, &A, privateKey); err != nil {
+		return nil, err
+	}
+	modm.Contract(aBytes[:], &a)
+
+	// r = hash1(a || M || Z) (mod q)
+	hashToScalar(&r, hashPrefix(1), aBytes[:], message, Z[:])
+
+	// R = rB
+	ge25519.ScalarmultBaseNiels(&Rp, &ge25519.NielsBaseMultiples, &r)
+	ge25519.Pack(R[:], &Rp)
+
+	// h = hash(R || A || M) (mod q)
+	hashToScalar(&h, nil, R[:], A[:], message)
+
+	// s = r + ha (mod q)
+	modm.Mul(&s, &h, &a)
+	modm.Add(&s, &s, &r)
+
+	sig := make([]byte, XEdDSASignatureSize)
+	copy(sig, R[:])
+	modm.Contract(sig[32:], &s)
+
+	a.Reset()
+	r.Reset()
+	zeroBytes(aBytes[:])
+	zeroBytes(Z[:])
+
+	return sig, nil
+}
+
+// XEdDSAVerify reports whether sig is a valid XEdDSA signature of message
+// by the X25519 public key.
+func XEdDSAVerify(publicKey, message, sig []byte) bool {
+	var (
+		A, check       [32]byte
+		h, s           modm.Bignum256
+		negA, Rproj, R ge25519.Ge25519
+	)
+
+	if len(publicKey) != PointSize || len(sig) != XEdDSASignatureSize {
+		return false
+	}
+
+	// if u >= p or s >= 2^|q|: return false
+	if !montgomeryToEdwards(&A, publicKey, 0) || sig[63]&0xe0 != 0 {
+		return false
+	}
+
+	// A = convert_mont(u), if not on_curve(A): return false
+	if !ge25519.UnpackNegativeVartime(&negA, A[:]) {
+		return false
+	}
+
+	// h = hash(R || A || M) (mod q)
+	hashToScalar(&h, nil, sig[:32], A[:], message)
+
+	// Rcheck = sB - hA
+	modm.Expand(&s, sig[32:])
+	ge25519.DoubleScalarmultVartime(&Rproj, &negA, &h, &s)
+	ge25519.ProjectiveToExtended(&R, &Rproj)
+	ge25519.Pack(check[:], &R)
+
+	return subtle.ConstantTimeCompare(check[:], sig[:32]) == 1
+}
+
+// VXEdDSASign signs the message with the X25519 private key, using entropy
+// from rand, and returns the signature and the VRF output.  If rand is nil,
+// crypto/rand.Reader will be used.
+func VXEdDSASign(rand io.Reader, privateKey, message []byte) ([]byte, []byte, error) {
+	var (
+		Z                       [xeddsaRandomSize]byte
+		a, r, h, s              modm.Bignum256
+		A, aBytes, V, R, Rv, cV [32]byte
+		Bv, Vp, Rp, Rvp         ge25519.Ge25519
+	)
+
+	if err := readRandom(rand, Z[:]); err != nil {
+		return nil, nil, err
+	}
+	if err := calculateKeyPair(&a, &A, privateKey); err != nil {
+		return nil, nil, err
+	}
+	modm.Contract(aBytes[:], &a)
+
+	// Bv = hash_to_point(A || M)
+	if !hashToPoint(&Bv, A[:], message) {
+		return nil, nil, fmt.Errorf("failed to hash message to point")
+	}
+
+	// V = aBv
+	ge25519.Scalarmult(&Vp, &Bv, &a)
+	ge25519.Pack(V[:], &Vp)
+
+	// r = hash3(a || V || Z) (mod q)
+	hashToScalar(&r, hashPrefix(3), aBytes[:], V[:], Z[:])
+
+	// R = rB, Rv = rBv
+	ge25519.ScalarmultBaseNiels(&Rp, &ge25519.NielsBaseMultiples, &r)
+	ge25519.Pack(R[:], &Rp)
+	ge25519.Scalarmult(&Rvp, &Bv, &r)
+	ge25519.Pack(Rv[:], &Rvp)
+
+	// h = hash4(A || V || R || Rv || M) (mod q)
+	hashToScalar(&h, hashPrefix(4), A[:], V[:], R[:], Rv[:], message)
+
+	// s = r + ha (mod q)
+	modm.Mul(&s, &h, &a)
+	modm.Add(&s, &s, &r)
+
+	// v = hash5(cV) (mod 2^b)
+	ge25519.CofactorMultiply(&Vp, &Vp)
+	ge25519.Pack(cV[:], &Vp)
+	output := vxeddsaOutput(cV[:])
+
+	sig := make([]byte, VXEdDSASignatureSize)
+	copy(sig, V[:])
+	modm.Contract(sig[32:], &h)
+	modm.Contract(sig[64:], &s)
+
+	a.Reset()
+	r.Reset()
+	zeroBytes(aBytes[:])
+	zeroBytes(Z[:])
+
+	return sig, output, nil
+}
+
+// VXEdDSAVerify checks if sig is a valid VXEdDSA signature of message by the
+// X25519 public key, and returns the VRF output iff the signature is valid.
+func VXEdDSAVerify(publicKey, message, sig []byte) ([]byte, bool) {
+	var (
+		A, R, Rv, cV, hCheck [32]byte
+		h, s, hc             modm.Bignum256
+		negA, Bv, V, t       ge25519.Ge25519
+		Rproj, Rp, Rvp       ge25519.Ge25519
+	)
+
+	if len(publicKey) != PointSize || len(sig) != VXEdDSASignatureSize {
+		return nil, false
+	}
+
+	// if u >= p or V.y >= p or h >= 2^|q| or s >= 2^|q|: return false
+	if !montgomeryToEdwards(&A, publicKey, 0) || !isCanonicalY(sig[:32]) || sig[63]&0xe0 != 0 || sig[95]&0xe0 != 0 {
+		return nil, false
+	}
+
+	// A = convert_mont(u), Bv = hash_to_point(A || M)
+	// if not on_curve(A) or not on_curve(V): return false
+	if !ge25519.UnpackNegativeVartime(&negA, A[:]) || !ge25519.UnpackVartime(&V, sig[:32]) {
+		return nil, false
+	}
+	if !hashToPoint(&Bv, A[:], message) {
+		return nil, false
+	}
+
+	// if cA == I or cV == I or Bv == I: return false
+	ge25519.CofactorMultiply(&t, &negA)
+	if ge25519.IsNeutralVartime(&t) || ge25519.IsNeutralVartime(&Bv) {
+		return nil, false
+	}
+	ge25519.CofactorMultiply(&t, &V)
+	if ge25519.IsNeutralVartime(&t) {
+		return nil, false
+	}
+	ge25519.Pack(cV[:], &t)
+
+	modm.Expand(&h, sig[32:64])
+	modm.Expand(&s, sig[64:])
+
+	// R = sB - hA
+	ge25519.DoubleScalarmultVartime(&Rproj, &negA, &h, &s)
+	ge25519.ProjectiveToExtended(&Rp, &Rproj)
+	ge25519.Pack(R[:], &Rp)
+
+	// Rv = sBv - hV
+	ge25519.ScalarmultVartime(&Rvp, &Bv, &s)
+	ge25519.ScalarmultVartime(&t, &V, &h)
+	ge25519.Sub(&Rvp, &Rvp, &t)
+	ge25519.Pack(Rv[:], &Rvp)
+
+	// hcheck = hash4(A || V || R || Rv || M) (mod q)
+	hashToScalar(&hc, hashPrefix(4), A[:], sig[:32], R[:], Rv[:], message)
+	modm.Contract(hCheck[:], &hc)
+	if subtle.ConstantTimeCompare(hCheck[:], sig[32:64]) != 1 {
+		return nil, false
+	}
+
+	return vxeddsaOutput(cV[:]), true
+}
+
+// calculateKeyPair converts the Montgomery private key k into the Edwards
+// private scalar a, and the public key A with a sign bit of 0.
+func calculateKeyPair(a *modm.Bignum256, A *[32]byte, privateKey []byte) error {
+	if l := len(privateKey); l != ScalarSize {
+		return fmt.Errorf("bad scalar length: %d, expected %d", l, ScalarSize)
+	}
+
+	var (
+		k, negK   modm.Bignum256
+		E         ge25519.Ge25519
+		kb, negKb [32]byte
+	)
+
+	// clamp
+	copy(kb[:], privateKey)
+	kb[0] &= 248
+	kb[31] &= 127
+	kb[31] |= 64
+
+	// E = kB
+	modm.ExpandRaw(&k, kb[:])
+	ge25519.ScalarmultBaseNiels(&E, &ge25519.NielsBaseMultiples, &k)
+	ge25519.Pack(A[:], &E)
+
+	// if E.s == 1: a = -k (mod q), else: a = k (mod q)
+	signBit := int(A[31] >> 7)
+	modm.Expand(&k, kb[:])
+	modm.Neg(&negK, &k)
+	modm.Contract(kb[:], &k)
+	modm.Contract(negKb[:], &negK)
+	subtle.ConstantTimeCopy(signBit, kb[:], negKb[:])
+	modm.Expand(a, kb[:])
+
+	// A.y = E.y, A.s = 0
+	A[31] &= 0x7f
+
+	k.Reset()
+	negK.Reset()
+	zeroBytes(kb[:])
+	zeroBytes(negKb[:])
+
+	return nil
+}
+
+// montgomeryToEdwards converts the Montgomery u-coordinate to the encoding
+// of the Edwards point with y = (u - 1) / (u + 1) and the sign bit.  It
+// returns false iff u is not a canonical encoding (u >= p).
+//
+// Note: u = -1 maps to y = 0, as the inverse of 0 is computed as 0.
+func montgomeryToEdwards(out *[32]byte, u []byte, signBit byte) bool {
+	var (
+		uf, y curve25519.Bignum25519
+		check [32]byte
+	)
+
+	curve25519.Expand(&uf, u)
+	curve25519.Contract(check[:], &uf)
+	if subtle.ConstantTimeCompare(check[:], u) != 1 {
+		return false
+	}
+
+	uToY(&y, &uf)
+	curve25519.Contract(out[:], &y)
+	out[31] |= signBit << 7
+
+	return true
+}
+
+// isCanonicalY returns true iff the y-coordinate of the encoded point is
+// fully reduced (y < p).
+func isCanonicalY(b []byte) bool {
+	var (
+		y     curve25519.Bignum25519
+		check [32]byte
+	)
+
+	curve25519.Expand(&y, b)
+	curve25519.Contract(check[:], &y)
+	check[31] |= b[31] & 0x80
+
+	return subtle.ConstantTimeCompare(check[:], b) == 1
+}
+
+// uToY sets y = (u - 1) / (u + 1).
+func uToY(y, u *curve25519.Bignum25519) {
+	var one, num, den curve25519.Bignum25519
+
+	curve25519One(&one)
+	curve25519.SubReduce(&num, u, &one)
+	curve25519.AddReduce(&den, u, &one)
+	curve25519.Recip(&den, &den)
+	curve25519.Mul(y, &num, &den)
+}
+
+// hashToPoint implements hash_to_point(A || M).
+func hashToPoint(P *ge25519.Ge25519, A, message []byte) bool {
+	var (
+		h   [64]byte
+		r   curve25519.Bignum25519
+		u   curve25519.Bignum25519
+		enc [32]byte
+	)
+
+	// h = hash2(X), r = h (mod 2^|p|), s = floor(h / 2^|p|) (mod 2)
+	hh := sha512.New()
+	_, _ = hh.Write(hashPrefix(2))
+	_, _ = hh.Write(A)
+	_, _ = hh.Write(message)
+	hh.Sum(h[:0])
+	signBit := h[31] >> 7
+	h[31] &= 0x7f
+
+	// u = elligator2(r), P.y = u_to_y(u), P.s = s
+	curve25519.Expand(&r, h[:32])
+	elligator2(&u, &r)
+	uToY(&u, &u)
+	curve25519.Contract(enc[:], &u)
+	enc[31] |= signBit << 7
+	if !ge25519.UnpackVartime(P, enc[:]) {
+		return false
+	}
+
+	// return cP
+	ge25519.CofactorMultiply(P, P)
+
+	return true
+}
+
+// elligator2 maps the field element r to a Montgomery u-coordinate.
+func elligator2(u, r *curve25519.Bignum25519) {
+	var (
+		one, u1, u2, w, t curve25519.Bignum25519
+		wb, negOne        [32]byte
+	)
+	curve25519One(&one)
+
+	// u1 = -A * inv(1 + 2r^2) (mod p)
+	curve25519.Square(&t, r)
+	curve25519.AddReduce(&t, &t, &t)
+	curve25519.AddReduce(&t, &t, &one)
+	curve25519.Recip(&t, &t)
+	curve25519.Neg(&u1, &montgomeryA)
+	curve25519.Mul(&u1, &u1, &t)
+
+	// w1 = u1 * (u1^2 + A * u1 + 1)
+	curve25519.Square(&w, &u1)
+	curve25519.Mul(&t, &montgomeryA, &u1)
+	curve25519.AddReduce(&w, &w, &t)
+	curve25519.AddReduce(&w, &w, &one)
+	curve25519.Mul(&w, &w, &u1)
+
+	// if w1^((p-1)/2) == -1: u = -A - u1, else: u = u1
+	legendre(&w, &w)
+	curve25519.Contract(wb[:], &w)
+	curve25519.Neg(&t, &one)
+	curve25519.Contract(negOne[:], &t)
+	isNonSquare := uint64(subtle.ConstantTimeCompare(wb[:], negOne[:]))
+
+	curve25519.Neg(&u2, &montgomeryA)
+	curve25519.SubReduce(&u2, &u2, &u1)
+	curve25519.SwapConditional(&u1, &u2, isNonSquare)
+	curve25519.Copy(u, &u1)
+}
+
+// legendre sets out = z^((p-1)/2) = z^(2^254 - 10).
+func legendre(out, z *curve25519.Bignum25519) {
+	var t, z2 curve25519.Bignum25519
+
+	curve25519.PowTwo252m3(&t, z)     // 2^252 - 3
+	curve25519.SquareTimes(&t, &t, 2) // 2^254 - 12
+	curve25519.Square(&z2, z)
+	curve25519.Mul(out, &t, &z2) // 2^254 - 10
+}
+
+// hashPrefix returns the domain separation prefix for hash_i,
+// 2^256 - 1 - i encoded as 32 little-endian bytes.
+func hashPrefix(i byte) []byte {
+	prefix := make([]byte, 32)
+	for j := range prefix {
+		prefix[j] = 0xff
+	}
+	prefix[0] -= i
+	return prefix
+}
+
+func hashToScalar(s *modm.Bignum256, prefix []byte, m ...[]byte) {
+	var digest [64]byte
+	h := sha512.New()
+	_, _ = h.Write(prefix)
+	for _, v := range m {
+		_, _ = h.Write(v)
+	}
+	h.Sum(digest[:0])
+	modm.Expand(s, digest[:])
+}
+
+func vxeddsaOutput(cV []byte) []byte {
+	var digest [64]byte
+	h := sha512.New()
+	_, _ = h.Write(hashPrefix(5))
+	_, _ = h.Write(cV)
+	h.Sum(digest[:0])
+
+	return digest[:VXEdDSAOutputSize]
+}
+
+func readRandom(rand io.Reader, b []byte) error {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	_, err := io.ReadFull(rand, b)
+	return err
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package x25519
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519"
+	"github.com/oasisprotocol/ed25519/internal/ge25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+func newTestX25519Key(t *testing.T) ([]byte, []byte) {
+	privateKey := make([]byte, ScalarSize)
+	if _, err := rand.Read(privateKey); err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := X25519(privateKey, Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return publicKey, privateKey
+}
+
+// edwardsSignBit returns the sign bit of E = kB, for the clamped k.
+func edwardsSignBit(privateKey []byte) byte {
+	var (
+		kb [32]byte
+		k  modm.Bignum256
+		E  ge25519.Ge25519
+	)
+	copy(kb[:], privateKey)
+	kb[0] &= 248
+	kb[31] &= 127
+	kb[31] |= 64
+	modm.ExpandRaw(&k, kb[:])
+	ge25519.ScalarmultBaseNiels(&E, &ge25519.NielsBaseMultiples, &k)
+	ge25519.Pack(kb[:], &E)
+
+	return kb[31] >> 7
+}
+
+func TestXEdDSA(t *testing.T) {
+	message := []byte("test message")
+
+	// Exercise both values of the sign bit of the Edwards public key.
+	var sawSignBit [2]bool
+	for i := 0; i < 32 || !sawSignBit[0] || !sawSignBit[1]; i++ {
+		publicKey, privateKey := newTestX25519Key(t)
+		sawSignBit[edwardsSignBit(privateKey)] = true
+
+		sig, err := XEdDSASign(nil, privateKey, message)
+		if err != nil {
+			t.Fatalf("XEdDSASign: %v", err)
+		}
+		if !XEdDSAVerify(publicKey, message, sig) {
+			t.Fatalf("XEdDSAVerify failed")
+		}
+		if XEdDSAVerify(publicKey, []byte("wrong message"), sig) {
+			t.Fatalf("XEdDSAVerify succeeded on wrong message")
+		}
+
+		// XEdDSA signatures are Ed25519 signatures, under the
+		// converted public key with the sign bit cleared.
+		var A [32]byte
+		if !montgomeryToEdwards(&A, publicKey, 0) {
+			t.Fatalf("montgomeryToEdwards failed")
+		}
+		if !ed25519.Verify(A[:], message, sig) {
+			t.Fatalf("ed25519.Verify failed")
+		}
+
+		for _, idx := range []int{0, 31, 32, 63} {
+			badSig := append([]byte{}, sig...)
+			badSig[idx] ^= 0x01
+			if XEdDSAVerify(publicKey, message, badSig) {
+				t.Fatalf("XEdDSAVerify succeeded on corrupted signature (byte %d)", idx)
+			}
+		}
+	}
+}
+
+func TestXEdDSAVectors(t *testing.T) {
+	// Test vectors from libsignal-protocol-c (commit 3a83a4f), in
+	// src/curve25519/ed25519/tests/internal_fast_tests.c and
+	// internal_slow_tests.c.  Z is read from a single stream across the
+	// tests there.  The upstream test alters byte 16 of the last signature
+	// before comparing it, so the actual value is 0xb4 rather than 0xb5.
+	for i, v := range []struct {
+		privateKey, random, message, signature string
+	}{
+		{
+			"0000000000000000bd0000000000000000000000000000000000000000000040",
+			strings.Repeat("00", 64),
+			strings.Repeat("00", 200),
+			"11c7f3e6c4df9e8a5150e1db3b30f92de3a3b3aa438656545fa7390f4bcc7bb26c431d9e90643e4f0eaa0e9c557766fa69ada576d63dcaf2ac326c11d0b97702",
+		},
+		{
+			"b03d85796d92897826af9db91398f3f9737d5f5cde76d1c44c3a3fa96ee51946",
+			"4e6cd9e8404de1e0c522728f78de8826584b44efbcf6ac10976760cc41f1820bb86906f3ada5466fc2f309787b3c6fd63919b148d606b779fbf7da61ee577be6",
+			"0001000101010000010001000101000101010100010101000000010001010101000001000000000101000100010000000000000001000101000000010000000000000100010000010101000100000000000100010100000000010101010001010100010100010001000100000101010100000000000100010000010000000101010100000101010000010001000101010100010100010101000100000000000101000101010100010000000000010101010000010000000101010101010000010000010101000100",
+			"152903386616cd26bb3eece29f72a25c7d05c9cb843f9296b3fbb9ddd6ed9904c1a80216cf493ff1be69f9f1cc16d7dc6ed378aa04eb71519de87a5bd8497b05",
+		},
+		{
+			"b89604b2cce11be9d54ae5011e3c2bfe24353f0606733eb7e4a16fb9e9bfff64",
+			"5653d89441204d9ad63cfa3a784a9e2e4d1307a166def7a7b43ac84f232c9a6d161710e6e7912e3861e3e99f902c6d0982c644d6eb12d053662761d03533319d",
+			"0001000001010001000101010100010001000000010101010101010101000100010100010000000101010100010001000000010101010000010001010001000001010100000000000001010001010001000001000001000001000100000001010001000101010000010101010001000000000100000000000001010000010000010001010100000000000101010100000101010001000100010101010001010100000101010000010100010100010101010000010100000101010100000000000000000000010100",
+			"3350a868cd9e7499a35c33752b2203f8b40fea8c331c688bbbf331cf7c423735a00e15b85d2be1a20377943d135cd49b6a31f4dcfe24ad54ebd29847f1ccbf0d",
+		},
+	} {
+		privateKey, _ := hex.DecodeString(v.privateKey)
+		random, _ := hex.DecodeString(v.random)
+		message, _ := hex.DecodeString(v.message)
+		expected, _ := hex.DecodeString(v.signature)
+
+		sig, err := XEdDSASign(bytes.NewReader(random), privateKey, message)
+		if err != nil {
+			t.Fatalf("%d: XEdDSASign: %v", i, err)
+		}
+		if !bytes.Equal(sig, expected) {
+			t.Fatalf("%d: signature mismatch: %x != %x", i, sig, expected)
+		}
+
+		publicKey, err := X25519(privateKey, Basepoint)
+		if err != nil {
+			t.Fatalf("%d: X25519: %v", i, err)
+		}
+		if !XEdDSAVerify(publicKey, message, expected) {
+			t.Fatalf("%d: XEdDSAVerify failed", i)
+		}
+	}
+}
+
+func TestXEdDSARandomness(t *testing.T) {
+	publicKey, privateKey := newTestX25519Key(t)
+	message := []byte("test message")
+
+	zero := bytes.NewReader(make([]byte, 2*xeddsaRandomSize))
+	sig1, _ := XEdDSASign(zero, privateKey, message)
+	sig2, _ := XEdDSASign(zero, privateKey, message)
+	if !bytes.Equal(sig1, sig2) {
+		t.Fatalf("XEdDSASign is not deterministic given Z")
+	}
+
+	sig3, _ := XEdDSASign(nil, privateKey, message)
+	if bytes.Equal(sig1, sig3) {
+		t.Fatalf("XEdDSASign ignored Z")
+	}
+	if !XEdDSAVerify(publicKey, message, sig3) {
+		t.Fatalf("XEdDSAVerify failed")
+	}
+
+	if _, err := XEdDSASign(bytes.NewReader(nil), privateKey, message); err == nil {
+		t.Fatalf("XEdDSASign succeeded with a failing entropy source")
+	}
+}
+
+func TestXEdDSAEdwardsKey(t *testing.T) {
+	// The Montgomery form of an Ed25519 key produces identical XEdDSA
+	// public keys (modulo the sign bit).
+	edPublic, edPrivate, _ := ed25519.GenerateKey(nil)
+	xPrivate := EdPrivateKeyToX25519(edPrivate)
+	xPublic, _ := EdPublicKeyToX25519(edPublic)
+
+	message := []byte("test message")
+	sig, err := XEdDSASign(nil, xPrivate, message)
+	if err != nil {
+		t.Fatalf("XEdDSASign: %v", err)
+	}
+	if !XEdDSAVerify(xPublic, message, sig) {
+		t.Fatalf("XEdDSAVerify failed")
+	}
+
+	A := append(ed25519.PublicKey{}, edPublic...)
+	A[31] &= 0x7f
+	if !ed25519.Verify(A, message, sig) {
+		t.Fatalf("ed25519.Verify failed with converted key")
+	}
+}
+
+func TestXEdDSABadInputs(t *testing.T) {
+	publicKey, privateKey := newTestX25519Key(t)
+	message := []byte("test message")
+	sig, _ := XEdDSASign(nil, privateKey, message)
+
+	// u >= p
+	p := bytes.Repeat([]byte{0xff}, 32)
+	p[0], p[31] = 0xed, 0x7f
+	if XEdDSAVerify(p, message, sig) {
+		t.Fatalf("XEdDSAVerify accepted u = p")
+	}
+	highBit := append([]byte{}, publicKey...)
+	highBit[31] |= 0x80
+	if XEdDSAVerify(highBit, message, sig) {
+		t.Fatalf("XEdDSAVerify accepted u >= 2^255")
+	}
+
+	// s >= 2^253
+	badSig := append([]byte{}, sig...)
+	badSig[63] |= 0x20
+	if XEdDSAVerify(publicKey, message, badSig) {
+		t.Fatalf("XEdDSAVerify accepted s >= 2^253")
+	}
+
+	if XEdDSAVerify(publicKey, message, sig[:63]) {
+		t.Fatalf("XEdDSAVerify accepted a truncated signature")
+	}
+	if _, err := XEdDSASign(nil, privateKey[:31], message); err == nil {
+		t.Fatalf("XEdDSASign accepted a truncated private key")
+	}
+}
+
+func TestVXEdDSA(t *testing.T) {
+	message := []byte("test message")
+
+	var sawSignBit [2]bool
+	for i := 0; i < 16 || !sawSignBit[0] || !sawSignBit[1]; i++ {
+		publicKey, privateKey := newTestX25519Key(t)
+		sawSignBit[edwardsSignBit(privateKey)] = true
+
+		sig, output, err := VXEdDSASign(nil, privateKey, message)
+		if err != nil {
+			t.Fatalf("VXEdDSASign: %v", err)
+		}
+		verifyOutput, ok := VXEdDSAVerify(publicKey, message, sig)
+		if !ok {
+			t.Fatalf("VXEdDSAVerify failed")
+		}
+		if !bytes.Equal(output, verifyOutput) {
+			t.Fatalf("VRF output mismatch: %x != %x", output, verifyOutput)
+		}
+
+		// The VRF output only depends on the key and message.
+		sig2, output2, _ := VXEdDSASign(nil, privateKey, message)
+		if bytes.Equal(sig, sig2) {
+			t.Fatalf("VXEdDSASign ignored Z")
+		}
+		if !bytes.Equal(output, output2) {
+			t.Fatalf("VRF output is not deterministic")
+		}
+		_, output3, _ := VXEdDSASign(nil, privateKey, []byte("other message"))
+		if bytes.Equal(output, output3) {
+			t.Fatalf("VRF output is independent of the message")
+		}
+
+		if _, ok = VXEdDSAVerify(publicKey, []byte("wrong message"), sig); ok {
+			t.Fatalf("VXEdDSAVerify succeeded on wrong message")
+		}
+		for _, idx := range []int{0, 32, 64, 95} {
+			badSig := append([]byte{}, sig...)
+			badSig[idx] ^= 0x01
+			if _, ok = VXEdDSAVerify(publicKey, message, badSig); ok {
+				t.Fatalf("VXEdDSAVerify succeeded on corrupted signature (byte %d)", idx)
+			}
+		}
+	}
+}
+
+func TestVXEdDSABadInputs(t *testing.T) {
+	publicKey, privateKey := newTestX25519Key(t)
+	message := []byte("test message")
+	sig, _, _ := VXEdDSASign(nil, privateKey, message)
+
+	// V.y >= p, using the non-canonical encoding of the point with y = 3
+	// (y + p), which is on the curve and not of small order.
+	nonCanonicalV, _ := hex.DecodeString("f0ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f")
+	var V ge25519.Ge25519
+	if !ge25519.UnpackVartime(&V, nonCanonicalV) {
+		t.Fatalf("UnpackVartime rejected V")
+	}
+	badSig := append([]byte{}, sig...)
+	copy(badSig, nonCanonicalV)
+	if _, ok := VXEdDSAVerify(publicKey, message, badSig); ok {
+		t.Fatalf("VXEdDSAVerify accepted V.y >= p")
+	}
+
+	// h >= 2^253, s >= 2^253
+	for _, idx := range []int{63, 95} {
+		badSig = append([]byte{}, sig...)
+		badSig[idx] |= 0x20
+		if _, ok := VXEdDSAVerify(publicKey, message, badSig); ok {
+			t.Fatalf("VXEdDSAVerify accepted an oversized scalar (byte %d)", idx)
+		}
+	}
+
+	if _, ok := VXEdDSAVerify(publicKey, message, sig[:95]); ok {
+		t.Fatalf("VXEdDSAVerify accepted a truncated signature")
+	}
+}
+
+func TestIsCanonicalY(t *testing.T) {
+	for _, v := range []struct {
+		y  string
+		ok bool
+	}{
+		{"0000000000000000000000000000000000000000000000000000000000000000", true},
+		{"0000000000000000000000000000000000000000000000000000000000000080", true},  // Sign bit
+		{"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f", true},  // p - 1
+		{"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", true},  // p - 1, sign bit
+		{"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f", false}, // p
+		{"eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", false}, // p + 1, sign bit
+		{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f", false}, // 2^255 - 1
+	} {
+		y, _ := hex.DecodeString(v.y)
+		if ok := isCanonicalY(y); ok != v.ok {
+			t.Fatalf("isCanonicalY(%s): expected %v, got %v", v.y, v.ok, ok)
+		}
+	}
+}
@@ -2,4 +2,7 @@ module github.com/oasisprotocol/ed25519
 
 go 1.12
 
-require golang.org/x/crypto v0.0.0-20191119213627-4f8c1d86b1ba
+require (
+	golang.org/x/crypto v0.0.0-20191119213627-4f8c1d86b1ba
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d
+)
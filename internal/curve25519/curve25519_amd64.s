@@ -0,0 +1,491 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build amd64,!noasm
+// +build !force32bit
+
+#include "textflag.h"
+
+// The products are accumulated into 128 bit values t0..t4, held in
+// AX:BX, CX:SI, R8:R9, R10:R11 and R12:R13 (lo:hi).  The first term of
+// each is written directly by MULX, which does not touch the flags, and
+// the rest are accumulated with alternating ADCX (CF) and ADOX (OF) carry
+// chains.  The sums never overflow 128 bits, so both chains always end
+// with the flag clear.
+
+// func mulAsm(out, a, b *Bignum25519)
+TEXT ·mulAsm(SB), NOSPLIT, $72-24
+	// Spill a and 19*a[1..4] to 0(SP) and 40(SP).
+	MOVQ a+8(FP), SI
+	MOVQ 0(SI), AX
+	MOVQ AX, 0(SP)
+	MOVQ 8(SI), AX
+	MOVQ AX, 8(SP)
+	IMUL3Q $19, AX, AX
+	MOVQ AX, 40(SP)
+	MOVQ 16(SI), AX
+	MOVQ AX, 16(SP)
+	IMUL3Q $19, AX, AX
+	MOVQ AX, 48(SP)
+	MOVQ 24(SI), AX
+	MOVQ AX, 24(SP)
+	IMUL3Q $19, AX, AX
+	MOVQ AX, 56(SP)
+	MOVQ 32(SI), AX
+	MOVQ AX, 32(SP)
+	IMUL3Q $19, AX, AX
+	MOVQ AX, 64(SP)
+	MOVQ b+16(FP), DI
+
+
+	// t = a * b[0]
+	MOVQ 0(DI), DX
+	MULXQ 0(SP), AX, BX
+	MULXQ 8(SP), CX, SI
+	MULXQ 16(SP), R8, R9
+	MULXQ 24(SP), R10, R11
+	MULXQ 32(SP), R12, R13
+
+	// Clear CF and OF.
+	XORQ R14, R14
+
+	// t += a * b[1]
+	MOVQ 8(DI), DX
+	MULXQ 0(SP), R14, R15
+	ADCXQ R14, CX
+	ADCXQ R15, SI
+	MULXQ 8(SP), R14, R15
+	ADOXQ R14, R8
+	ADOXQ R15, R9
+	MULXQ 16(SP), R14, R15
+	ADCXQ R14, R10
+	ADCXQ R15, R11
+	MULXQ 24(SP), R14, R15
+	ADOXQ R14, R12
+	ADOXQ R15, R13
+	MULXQ 64(SP), R14, R15
+	ADCXQ R14, AX
+	ADCXQ R15, BX
+
+	// t += a * b[2]
+	MOVQ 16(DI), DX
+	MULXQ 0(SP), R14, R15
+	ADCXQ R14, R8
+	ADCXQ R15, R9
+	MULXQ 8(SP), R14, R15
+	ADOXQ R14, R10
+	ADOXQ R15, R11
+	MULXQ 16(SP), R14, R15
+	ADCXQ R14, R12
+	ADCXQ R15, R13
+	MULXQ 56(SP), R14, R15
+	ADOXQ R14, AX
+	ADOXQ R15, BX
+	MULXQ 64(SP), R14, R15
+	ADCXQ R14, CX
+	ADCXQ R15, SI
+
+	// t += a * b[3]
+	MOVQ 24(DI), DX
+	MULXQ 0(SP), R14, R15
+	ADCXQ R14, R10
+	ADCXQ R15, R11
+	MULXQ 8(SP), R14, R15
+	ADOXQ R14, R12
+	ADOXQ R15, R13
+	MULXQ 48(SP), R14, R15
+	ADCXQ R14, AX
+	ADCXQ R15, BX
+	MULXQ 56(SP), R14, R15
+	ADOXQ R14, CX
+	ADOXQ R15, SI
+	MULXQ 64(SP), R14, R15
+	ADCXQ R14, R8
+	ADCXQ R15, R9
+
+	// t += a * b[4]
+	MOVQ 32(DI), DX
+	MULXQ 0(SP), R14, R15
+	ADCXQ R14, R12
+	ADCXQ R15, R13
+	MULXQ 40(SP), R14, R15
+	ADOXQ R14, AX
+	ADOXQ R15, BX
+	MULXQ 48(SP), R14, R15
+	ADCXQ R14, CX
+	ADCXQ R15, SI
+	MULXQ 56(SP), R14, R15
+	ADOXQ R14, R8
+	ADOXQ R15, R9
+	MULXQ 64(SP), R14, R15
+	ADCXQ R14, R10
+	ADCXQ R15, R11
+
+	MOVQ out+0(FP), DI
+	// Carry the 128 bit t0..t4 into 51 bit limbs.
+	MOVQ $0x7ffffffffffff, DX
+	MOVQ AX, R14
+	ANDQ DX, R14
+	SHRQ $51, AX
+	SHLQ $13, BX
+	ORQ BX, AX
+	ADDQ AX, CX
+	ADCQ $0, SI
+	MOVQ CX, R15
+	ANDQ DX, R15
+	SHRQ $51, CX
+	SHLQ $13, SI
+	ORQ SI, CX
+	ADDQ CX, R8
+	ADCQ $0, R9
+	MOVQ R8, AX
+	ANDQ DX, AX
+	MOVQ AX, 16(DI)
+	SHRQ $51, R8
+	SHLQ $13, R9
+	ORQ R9, R8
+	ADDQ R8, R10
+	ADCQ $0, R11
+	MOVQ R10, AX
+	ANDQ DX, AX
+	MOVQ AX, 24(DI)
+	SHRQ $51, R10
+	SHLQ $13, R11
+	ORQ R11, R10
+	ADDQ R10, R12
+	ADCQ $0, R13
+	MOVQ R12, AX
+	ANDQ DX, AX
+	MOVQ AX, 32(DI)
+	SHRQ $51, R12
+	SHLQ $13, R13
+	ORQ R13, R12
+	IMUL3Q $19, R12, R12
+	ADDQ R12, R14
+	MOVQ R14, AX
+	SHRQ $51, AX
+	ANDQ DX, R14
+	ADDQ AX, R15
+	MOVQ R14, 0(DI)
+	MOVQ R15, 8(DI)
+	RET
+
+// func squareAsm(out, in *Bignum25519)
+TEXT ·squareAsm(SB), NOSPLIT, $88-16
+	MOVQ in+8(FP), SI
+	MOVQ 0(SI), R14
+	MOVQ 8(SI), CX
+	MOVQ 16(SI), R8
+	MOVQ 24(SI), R10
+	MOVQ 32(SI), R12
+	// Spill r0..r4, d0 = 2*r0, d1 = 2*r1, d2 = 38*r2, d419 = 19*r4,
+	// d4 = 38*r4 and r319 = 19*r3.
+	MOVQ R14, 0(SP)
+	MOVQ CX, 8(SP)
+	MOVQ R8, 16(SP)
+	MOVQ R10, 24(SP)
+	MOVQ R12, 32(SP)
+	LEAQ (R14)(R14*1), AX
+	MOVQ AX, 40(SP)
+	LEAQ (CX)(CX*1), AX
+	MOVQ AX, 48(SP)
+	IMUL3Q $38, R8, AX
+	MOVQ AX, 56(SP)
+	IMUL3Q $19, R12, AX
+	MOVQ AX, 64(SP)
+	IMUL3Q $38, R12, AX
+	MOVQ AX, 72(SP)
+	IMUL3Q $19, R10, AX
+	MOVQ AX, 80(SP)
+
+	MOVQ 0(SP), DX
+	MULXQ 0(SP), AX, BX
+	MOVQ 40(SP), DX
+	MULXQ 8(SP), CX, SI
+	MOVQ 40(SP), DX
+	MULXQ 16(SP), R8, R9
+	MOVQ 40(SP), DX
+	MULXQ 24(SP), R10, R11
+	MOVQ 40(SP), DX
+	MULXQ 32(SP), R12, R13
+
+	// Clear CF and OF.
+	XORQ R14, R14
+
+	// t0
+	MOVQ 72(SP), DX
+	MULXQ 8(SP), R14, R15
+	ADCXQ R14, AX
+	ADCXQ R15, BX
+	MOVQ 56(SP), DX
+	MULXQ 24(SP), R14, R15
+	ADOXQ R14, AX
+	ADOXQ R15, BX
+
+	// t1
+	MOVQ 72(SP), DX
+	MULXQ 16(SP), R14, R15
+	ADCXQ R14, CX
+	ADCXQ R15, SI
+	MOVQ 24(SP), DX
+	MULXQ 80(SP), R14, R15
+	ADOXQ R14, CX
+	ADOXQ R15, SI
+
+	// t2
+	MOVQ 8(SP), DX
+	MULXQ 8(SP), R14, R15
+	ADCXQ R14, R8
+	ADCXQ R15, R9
+	MOVQ 72(SP), DX
+	MULXQ 24(SP), R14, R15
+	ADOXQ R14, R8
+	ADOXQ R15, R9
+
+	// t3
+	MOVQ 48(SP), DX
+	MULXQ 16(SP), R14, R15
+	ADCXQ R14, R10
+	ADCXQ R15, R11
+	MOVQ 32(SP), DX
+	MULXQ 64(SP), R14, R15
+	ADOXQ R14, R10
+	ADOXQ R15, R11
+
+	// t4
+	MOVQ 48(SP), DX
+	MULXQ 24(SP), R14, R15
+	ADCXQ R14, R12
+	ADCXQ R15, R13
+	MOVQ 16(SP), DX
+	MULXQ 16(SP), R14, R15
+	ADOXQ R14, R12
+	ADOXQ R15, R13
+
+	MOVQ out+0(FP), DI
+	// Carry the 128 bit t0..t4 into 51 bit limbs.
+	MOVQ $0x7ffffffffffff, DX
+	MOVQ AX, R14
+	ANDQ DX, R14
+	SHRQ $51, AX
+	SHLQ $13, BX
+	ORQ BX, AX
+	ADDQ AX, CX
+	ADCQ $0, SI
+	MOVQ CX, R15
+	ANDQ DX, R15
+	SHRQ $51, CX
+	SHLQ $13, SI
+	ORQ SI, CX
+	ADDQ CX, R8
+	ADCQ $0, R9
+	MOVQ R8, AX
+	ANDQ DX, AX
+	MOVQ AX, 16(DI)
+	SHRQ $51, R8
+	SHLQ $13, R9
+	ORQ R9, R8
+	ADDQ R8, R10
+	ADCQ $0, R11
+	MOVQ R10, AX
+	ANDQ DX, AX
+	MOVQ AX, 24(DI)
+	SHRQ $51, R10
+	SHLQ $13, R11
+	ORQ R11, R10
+	ADDQ R10, R12
+	ADCQ $0, R13
+	MOVQ R12, AX
+	ANDQ DX, AX
+	MOVQ AX, 32(DI)
+	SHRQ $51, R12
+	SHLQ $13, R13
+	ORQ R13, R12
+	IMUL3Q $19, R12, R12
+	ADDQ R12, R14
+	MOVQ R14, AX
+	SHRQ $51, AX
+	ANDQ DX, R14
+	ADDQ AX, R15
+	MOVQ R14, 0(DI)
+	MOVQ R15, 8(DI)
+	RET
+
+// func squareTimesAsm(out, in *Bignum25519, count int)
+TEXT ·squareTimesAsm(SB), NOSPLIT, $88-24
+	MOVQ in+8(FP), SI
+	MOVQ 0(SI), R14
+	MOVQ 8(SI), CX
+	MOVQ 16(SI), R8
+	MOVQ 24(SI), R10
+	MOVQ 32(SI), R12
+	MOVQ count+16(FP), DI
+	TESTQ DI, DI
+	JLE done
+
+loop:
+	// Spill r0..r4, d0 = 2*r0, d1 = 2*r1, d2 = 38*r2, d419 = 19*r4,
+	// d4 = 38*r4 and r319 = 19*r3.
+	MOVQ R14, 0(SP)
+	MOVQ CX, 8(SP)
+	MOVQ R8, 16(SP)
+	MOVQ R10, 24(SP)
+	MOVQ R12, 32(SP)
+	LEAQ (R14)(R14*1), AX
+	MOVQ AX, 40(SP)
+	LEAQ (CX)(CX*1), AX
+	MOVQ AX, 48(SP)
+	IMUL3Q $38, R8, AX
+	MOVQ AX, 56(SP)
+	IMUL3Q $19, R12, AX
+	MOVQ AX, 64(SP)
+	IMUL3Q $38, R12, AX
+	MOVQ AX, 72(SP)
+	IMUL3Q $19, R10, AX
+	MOVQ AX, 80(SP)
+
+	MOVQ 0(SP), DX
+	MULXQ 0(SP), AX, BX
+	MOVQ 40(SP), DX
+	MULXQ 8(SP), CX, SI
+	MOVQ 40(SP), DX
+	MULXQ 16(SP), R8, R9
+	MOVQ 40(SP), DX
+	MULXQ 24(SP), R10, R11
+	MOVQ 40(SP), DX
+	MULXQ 32(SP), R12, R13
+
+	// Clear CF and OF.
+	XORQ R14, R14
+
+	// t0
+	MOVQ 72(SP), DX
+	MULXQ 8(SP), R14, R15
+	ADCXQ R14, AX
+	ADCXQ R15, BX
+	MOVQ 56(SP), DX
+	MULXQ 24(SP), R14, R15
+	ADOXQ R14, AX
+	ADOXQ R15, BX
+
+	// t1
+	MOVQ 72(SP), DX
+	MULXQ 16(SP), R14, R15
+	ADCXQ R14, CX
+	ADCXQ R15, SI
+	MOVQ 24(SP), DX
+	MULXQ 80(SP), R14, R15
+	ADOXQ R14, CX
+	ADOXQ R15, SI
+
+	// t2
+	MOVQ 8(SP), DX
+	MULXQ 8(SP), R14, R15
+	ADCXQ R14, R8
+	ADCXQ R15, R9
+	MOVQ 72(SP), DX
+	MULXQ 24(SP), R14, R15
+	ADOXQ R14, R8
+	ADOXQ R15, R9
+
+	// t3
+	MOVQ 48(SP), DX
+	MULXQ 16(SP), R14, R15
+	ADCXQ R14, R10
+	ADCXQ R15, R11
+	MOVQ 32(SP), DX
+	MULXQ 64(SP), R14, R15
+	ADOXQ R14, R10
+	ADOXQ R15, R11
+
+	// t4
+	MOVQ 48(SP), DX
+	MULXQ 24(SP), R14, R15
+	ADCXQ R14, R12
+	ADCXQ R15, R13
+	MOVQ 16(SP), DX
+	MULXQ 16(SP), R14, R15
+	ADOXQ R14, R12
+	ADOXQ R15, R13
+
+	// Carry the low 64 bits of t0..t4, and then the limbs, matching
+	// SquareTimes.
+	MOVQ $0x7ffffffffffff, DX
+	MOVQ AX, R14
+	ANDQ DX, R14
+	SHRQ $51, BX, AX
+	MOVQ CX, R15
+	SHRQ $51, SI, R15
+	ANDQ DX, CX
+	ADDQ AX, CX
+	MOVQ R8, AX
+	SHRQ $51, R9, AX
+	ANDQ DX, R8
+	ADDQ R15, R8
+	MOVQ R10, R15
+	SHRQ $51, R11, R15
+	ANDQ DX, R10
+	ADDQ AX, R10
+	MOVQ R12, AX
+	SHRQ $51, R13, AX
+	ANDQ DX, R12
+	ADDQ R15, R12
+	IMUL3Q $19, AX, AX
+	ADDQ AX, R14
+	MOVQ R14, AX
+	SHRQ $51, AX
+	ANDQ DX, R14
+	ADDQ AX, CX
+	MOVQ CX, AX
+	SHRQ $51, AX
+	ANDQ DX, CX
+	ADDQ AX, R8
+	MOVQ R8, AX
+	SHRQ $51, AX
+	ANDQ DX, R8
+	ADDQ AX, R10
+	MOVQ R10, AX
+	SHRQ $51, AX
+	ANDQ DX, R10
+	ADDQ AX, R12
+	MOVQ R12, AX
+	SHRQ $51, AX
+	ANDQ DX, R12
+	IMUL3Q $19, AX, AX
+	ADDQ AX, R14
+	DECQ DI
+	JNZ loop
+
+done:
+	MOVQ out+0(FP), DI
+	MOVQ R14, 0(DI)
+	MOVQ CX, 8(DI)
+	MOVQ R8, 16(DI)
+	MOVQ R10, 24(DI)
+	MOVQ R12, 32(DI)
+	RET
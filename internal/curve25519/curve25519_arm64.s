@@ -0,0 +1,433 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build arm64,go1.13,!noasm
+// +build !force32bit
+
+#include "textflag.h"
+
+// The products are accumulated one column at a time into R19:R20
+// (lo:hi), carrying into the next column as soon as it is complete,
+// which yields the same limbs as the add-then-carry order of the Go
+// implementation.  R21:R22 hold each partial product, and R23 the carry.
+
+// func mulAsm(out, a, b *Bignum25519)
+TEXT ·mulAsm(SB), NOSPLIT, $0-24
+	MOVD out+0(FP), R0
+	MOVD a+8(FP), R1
+	MOVD b+16(FP), R2
+	LDP 0(R1), (R3, R4)
+	LDP 16(R1), (R5, R6)
+	MOVD 32(R1), R7
+	LDP 0(R2), (R8, R9)
+	LDP 16(R2), (R10, R11)
+	MOVD 32(R2), R12
+	MOVD $19, R17
+	MUL R17, R4, R13
+	MUL R17, R5, R14
+	MUL R17, R6, R15
+	MUL R17, R7, R16
+
+	// t0
+	MUL R3, R8, R19
+	UMULH R3, R8, R20
+	MUL R13, R12, R21
+	UMULH R13, R12, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R14, R11, R21
+	UMULH R14, R11, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R15, R10, R21
+	UMULH R15, R10, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R16, R9, R21
+	UMULH R16, R9, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R1
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t1
+	MUL R3, R9, R19
+	UMULH R3, R9, R20
+	MUL R4, R8, R21
+	UMULH R4, R8, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R14, R12, R21
+	UMULH R14, R12, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R15, R11, R21
+	UMULH R15, R11, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R16, R10, R21
+	UMULH R16, R10, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R2
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t2
+	MUL R3, R10, R19
+	UMULH R3, R10, R20
+	MUL R4, R9, R21
+	UMULH R4, R9, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R5, R8, R21
+	UMULH R5, R8, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R15, R12, R21
+	UMULH R15, R12, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R16, R11, R21
+	UMULH R16, R11, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R21
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+	MOVD R21, 16(R0)
+
+	// t3
+	MUL R3, R11, R19
+	UMULH R3, R11, R20
+	MUL R4, R10, R21
+	UMULH R4, R10, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R5, R9, R21
+	UMULH R5, R9, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R6, R8, R21
+	UMULH R6, R8, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R16, R12, R21
+	UMULH R16, R12, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R21
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+	MOVD R21, 24(R0)
+
+	// t4
+	MUL R3, R12, R19
+	UMULH R3, R12, R20
+	MUL R4, R11, R21
+	UMULH R4, R11, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R5, R10, R21
+	UMULH R5, R10, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R6, R9, R21
+	UMULH R6, R9, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R7, R8, R21
+	UMULH R7, R8, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R21
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+	MOVD R21, 32(R0)
+
+	// r0 += 19 * c, and carry once more into r1.
+	MUL R17, R23, R23
+	ADD R23, R1, R1
+	LSR $51, R1, R21
+	AND $0x7ffffffffffff, R1, R1
+	ADD R21, R2, R2
+	STP (R1, R2), 0(R0)
+	RET
+
+// func squareAsm(out, in *Bignum25519)
+TEXT ·squareAsm(SB), NOSPLIT, $0-16
+	MOVD out+0(FP), R0
+	MOVD in+8(FP), R1
+	LDP 0(R1), (R3, R4)
+	LDP 16(R1), (R5, R6)
+	MOVD 32(R1), R7
+	MOVD $19, R17
+	// d0 = 2*r0, d1 = 2*r1, d2 = 38*r2, d419 = 19*r4, d4 = 38*r4,
+	// r319 = 19*r3
+	LSL $1, R3, R8
+	LSL $1, R4, R9
+	MUL R17, R5, R10
+	LSL $1, R10, R10
+	MUL R17, R7, R11
+	LSL $1, R11, R12
+	MUL R17, R6, R13
+
+	// t0
+	MUL R3, R3, R19
+	UMULH R3, R3, R20
+	MUL R12, R4, R21
+	UMULH R12, R4, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R10, R6, R21
+	UMULH R10, R6, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R1
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t1
+	MUL R8, R4, R19
+	UMULH R8, R4, R20
+	MUL R12, R5, R21
+	UMULH R12, R5, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R6, R13, R21
+	UMULH R6, R13, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R2
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t2
+	MUL R8, R5, R19
+	UMULH R8, R5, R20
+	MUL R4, R4, R21
+	UMULH R4, R4, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R12, R6, R21
+	UMULH R12, R6, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R21
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+	MOVD R21, 16(R0)
+
+	// t3
+	MUL R8, R6, R19
+	UMULH R8, R6, R20
+	MUL R9, R5, R21
+	UMULH R9, R5, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R7, R11, R21
+	UMULH R7, R11, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R21
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+	MOVD R21, 24(R0)
+
+	// t4
+	MUL R8, R7, R19
+	UMULH R8, R7, R20
+	MUL R9, R6, R21
+	UMULH R9, R6, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R5, R5, R21
+	UMULH R5, R5, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	ADDS R23, R19, R19
+	ADC ZR, R20, R20
+	AND $0x7ffffffffffff, R19, R21
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+	MOVD R21, 32(R0)
+
+	// r0 += 19 * c, and carry once more into r1.
+	MUL R17, R23, R23
+	ADD R23, R1, R1
+	LSR $51, R1, R21
+	AND $0x7ffffffffffff, R1, R1
+	ADD R21, R2, R2
+	STP (R1, R2), 0(R0)
+	RET
+
+// func squareTimesAsm(out, in *Bignum25519, count int)
+TEXT ·squareTimesAsm(SB), NOSPLIT, $0-24
+	MOVD in+8(FP), R1
+	LDP 0(R1), (R3, R4)
+	LDP 16(R1), (R5, R6)
+	MOVD 32(R1), R7
+	MOVD $19, R17
+	MOVD count+16(FP), R26
+	CMP $0, R26
+	BLE done
+
+loop:
+	// d0 = 2*r0, d1 = 2*r1, d2 = 38*r2, d419 = 19*r4, d4 = 38*r4,
+	// r319 = 19*r3
+	LSL $1, R3, R8
+	LSL $1, R4, R9
+	MUL R17, R5, R10
+	LSL $1, R10, R10
+	MUL R17, R7, R11
+	LSL $1, R11, R12
+	MUL R17, R6, R13
+
+	// t0
+	MUL R3, R3, R19
+	UMULH R3, R3, R20
+	MUL R12, R4, R21
+	UMULH R12, R4, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R10, R6, R21
+	UMULH R10, R6, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R14
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t1
+	MUL R8, R4, R19
+	UMULH R8, R4, R20
+	MUL R12, R5, R21
+	UMULH R12, R5, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R6, R13, R21
+	UMULH R6, R13, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R15
+	ADD R23, R15, R15
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t2
+	MUL R8, R5, R19
+	UMULH R8, R5, R20
+	MUL R4, R4, R21
+	UMULH R4, R4, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R12, R6, R21
+	UMULH R12, R6, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R16
+	ADD R23, R16, R16
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t3
+	MUL R8, R6, R19
+	UMULH R8, R6, R20
+	MUL R9, R5, R21
+	UMULH R9, R5, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R7, R11, R21
+	UMULH R7, R11, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R24
+	ADD R23, R24, R24
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// t4
+	MUL R8, R7, R19
+	UMULH R8, R7, R20
+	MUL R9, R6, R21
+	UMULH R9, R6, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	MUL R5, R5, R21
+	UMULH R5, R5, R22
+	ADDS R21, R19, R19
+	ADC R22, R20, R20
+	AND $0x7ffffffffffff, R19, R25
+	ADD R23, R25, R25
+	LSR $51, R19, R23
+	ORR R20<<13, R23, R23
+
+	// r0 += 19 * c, and carry through the limbs, matching SquareTimes.
+	MUL R17, R23, R23
+	ADD R23, R14, R3
+	LSR $51, R3, R23
+	AND $0x7ffffffffffff, R3, R3
+	ADD R23, R15, R4
+	LSR $51, R4, R23
+	AND $0x7ffffffffffff, R4, R4
+	ADD R23, R16, R5
+	LSR $51, R5, R23
+	AND $0x7ffffffffffff, R5, R5
+	ADD R23, R24, R6
+	LSR $51, R6, R23
+	AND $0x7ffffffffffff, R6, R6
+	ADD R23, R25, R7
+	LSR $51, R7, R23
+	AND $0x7ffffffffffff, R7, R7
+	MUL R17, R23, R23
+	ADD R23, R3, R3
+
+	SUBS $1, R26, R26
+	BNE loop
+
+done:
+	MOVD out+0(FP), R0
+	STP (R3, R4), 0(R0)
+	STP (R5, R6), 16(R0)
+	MOVD R7, 32(R0)
+	RET
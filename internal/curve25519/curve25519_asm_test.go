@@ -0,0 +1,169 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build amd64,!noasm arm64,go1.13,!noasm
+// +build !force32bit
+
+package curve25519
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+)
+
+func requireAsm(t *testing.T) {
+	if !useAsm {
+		t.Skip("assembly not supported")
+	}
+}
+
+func randomBignum(t *testing.T, out *Bignum25519, bits uint) {
+	var b [40]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatal(err)
+	}
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint64(b[i*8:]) >> (64 - bits)
+	}
+}
+
+func testAsm(t *testing.T, a, b *Bignum25519) {
+	var expected, actual Bignum25519
+
+	defer func() {
+		useAsm = true
+	}()
+
+	useAsm = false
+	Mul(&expected, a, b)
+	useAsm = true
+	Mul(&actual, a, b)
+	if expected != actual {
+		t.Fatalf("Mul(%v, %v): %v (expected: %v)", a, b, actual, expected)
+	}
+
+	useAsm = false
+	Square(&expected, a)
+	useAsm = true
+	Square(&actual, a)
+	if expected != actual {
+		t.Fatalf("Square(%v): %v (expected: %v)", a, actual, expected)
+	}
+
+	for count := 0; count < 4; count++ {
+		useAsm = false
+		SquareTimes(&expected, a, count)
+		useAsm = true
+		SquareTimes(&actual, a, count)
+		if expected != actual {
+			t.Fatalf("SquareTimes(%v, %d): %v (expected: %v)", a, count, actual, expected)
+		}
+	}
+
+	// The output may alias the inputs.
+	useAsm = false
+	Mul(&expected, a, b)
+	useAsm = true
+	actual = *a
+	Mul(&actual, &actual, b)
+	if expected != actual {
+		t.Fatalf("Mul(out, out, %v): %v (expected: %v)", b, actual, expected)
+	}
+	useAsm = false
+	SquareTimes(&expected, a, 5)
+	useAsm = true
+	actual = *a
+	SquareTimes(&actual, &actual, 5)
+	if expected != actual {
+		t.Fatalf("SquareTimes(out, out, 5): %v (expected: %v)", actual, expected)
+	}
+}
+
+func TestAsm(t *testing.T) {
+	requireAsm(t)
+
+	var a, b Bignum25519
+	for _, bits := range []uint{51, 52, 54} {
+		for iter := 0; iter < 1000; iter++ {
+			randomBignum(t, &a, bits)
+			randomBignum(t, &b, bits)
+			testAsm(t, &a, &b)
+		}
+	}
+
+	// Unreduced limbs, as produced by AddAfterBasic and SubAfterBasic.
+	Add(&a, &maxBignum, &maxBignum)
+	AddAfterBasic(&b, &a, &maxBignum)
+	testAsm(t, &a, &b)
+	testAsm(t, &b, &b)
+	testAsm(t, &maxBignum, &maxBignum)
+}
+
+func BenchmarkMul(b *testing.B) {
+	benchmarkAsm(b, func(r, a *Bignum25519) {
+		Mul(r, a, a)
+	})
+}
+
+func BenchmarkSquare(b *testing.B) {
+	benchmarkAsm(b, func(r, a *Bignum25519) {
+		Square(r, a)
+	})
+}
+
+func BenchmarkSquareTimes(b *testing.B) {
+	benchmarkAsm(b, func(r, a *Bignum25519) {
+		SquareTimes(r, a, 50)
+	})
+}
+
+func benchmarkAsm(b *testing.B, fn func(r, a *Bignum25519)) {
+	var r Bignum25519
+	a := maxBignum
+
+	hasAsm := useAsm
+	defer func() {
+		useAsm = hasAsm
+	}()
+
+	b.Run("Portable", func(b *testing.B) {
+		useAsm = false
+		for i := 0; i < b.N; i++ {
+			fn(&r, &a)
+		}
+	})
+	if hasAsm {
+		b.Run("Asm", func(b *testing.B) {
+			useAsm = true
+			for i := 0; i < b.N; i++ {
+				fn(&r, &a)
+			}
+		})
+	}
+}
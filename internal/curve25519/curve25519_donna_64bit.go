@@ -187,6 +187,10 @@ func Neg(out, a *Bignum25519) {
 // out = a * b
 func Mul(out, in2, in *Bignum25519) {
 	// curve25519_mul(bignum25519 out, const bignum25519 in2, const bignum25519 in)
+	if useAsm {
+		mulAsm(out, in2, in)
+		return
+	}
 
 	// Note: This should be inlined where possible, but the Go compiler
 	// thinks it is too complicated.
@@ -315,6 +319,10 @@ func Mul(out, in2, in *Bignum25519) {
 // out = in^(2 * count)
 func SquareTimes(out, in *Bignum25519, count int) {
 	// curve25519_square_times(bignum25519 out, const bignum25519 in, uint64_t count)
+	if useAsm {
+		squareTimesAsm(out, in, count)
+		return
+	}
 	var (
 		mul_lo, mul_hi, carry                                                uint64
 		t0_lo, t0_hi, t1_lo, t1_hi, t2_lo, t2_hi, t3_lo, t3_hi, t4_lo, t4_hi uint64
@@ -418,6 +426,10 @@ func SquareTimes(out, in *Bignum25519, count int) {
 
 func Square(out, in *Bignum25519) {
 	// curve25519_square(bignum25519 out, const bignum25519 in)
+	if useAsm {
+		squareAsm(out, in)
+		return
+	}
 	var (
 		mul_lo, mul_hi, carry                                                uint64
 		t0_lo, t0_hi, t1_lo, t1_hi, t2_lo, t2_hi, t3_lo, t3_hi, t4_lo, t4_hi uint64
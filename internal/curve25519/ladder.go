@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package curve25519
+
+// The Montgomery ladder is not part of upstream, which delegates X25519 to
+// a separate curve25519-donna implementation.
+
+// a24 = (486662 - 2) / 4 = 121665
+var a24 = Bignum25519{121665}
+
+// ScalarMult sets out to the product scalar * point, where out and point
+// are the u-coordinates of Montgomery curve points, as specified in
+// RFC 7748, Section 5.  The scalar is not clamped, and must be clamped by
+// the caller if required, though its most significant bit (bit 255) is
+// ignored.  The most significant bit of point is ignored, and
+// non-canonical values are accepted.
+//
+// This routine is constant time.
+func ScalarMult(out, scalar, point *[32]byte) {
+	var (
+		x1, x2, z2, x3, z3    Bignum25519
+		a, aa, b, bb, e, c, d Bignum25519
+		da, cb, t0            Bignum25519
+		swap                  uint64
+	)
+
+	Expand(&x1, point[:])
+	x2[0] = 1
+	Copy(&x3, &x1)
+	z3[0] = 1
+
+	for t := 254; t >= 0; t-- {
+		kT := uint64(scalar[t>>3]>>(uint(t)&7)) & 1
+		swap ^= kT
+		SwapConditional(&x2, &x3, swap)
+		SwapConditional(&z2, &z3, swap)
+		swap = kT
+
+		Add(&a, &x2, &z2)  // A = x_2 + z_2
+		Square(&aa, &a)    // AA = A^2
+		Sub(&b, &x2, &z2)  // B = x_2 - z_2
+		Square(&bb, &b)    // BB = B^2
+		Sub(&e, &aa, &bb)  // E = AA - BB
+		Add(&c, &x3, &z3)  // C = x_3 + z_3
+		Sub(&d, &x3, &z3)  // D = x_3 - z_3
+		Mul(&da, &d, &a)   // DA = D * A
+		Mul(&cb, &c, &b)   // CB = C * B
+		Add(&t0, &da, &cb) // x_3 = (DA + CB)^2
+		Square(&x3, &t0)
+		Sub(&t0, &da, &cb) // z_3 = x_1 * (DA - CB)^2
+		Square(&t0, &t0)
+		Mul(&z3, &x1, &t0)
+		Mul(&x2, &aa, &bb) // x_2 = AA * BB
+		Mul(&t0, &a24, &e) // z_2 = E * (AA + a24 * E)
+		Add(&t0, &aa, &t0)
+		Mul(&z2, &e, &t0)
+	}
+
+	SwapConditional(&x2, &x3, swap)
+	SwapConditional(&z2, &z3, swap)
+
+	// x_2 * z_2^(p - 2)
+	Recip(&z2, &z2)
+	Mul(&x2, &x2, &z2)
+	Contract(out[:], &x2)
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build amd64,!noasm
+// +build !force32bit
+
+package ge25519
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"golang.org/x/sys/cpu"
+
+	"github.com/oasisprotocol/ed25519/internal/curve25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+// The AVX2 backend computes on four field elements at once, using the
+// parallel formulas for extended twisted Edwards coordinates from
+// "Twisted Edwards Curves Revisited" by Hisil, Wong, Carter, and Dawson,
+// in the same manner as curve25519-dalek's AVX2 backend.  A point is a
+// single vectorized field element, with X, Y, Z and T in the four lanes,
+// so that each of the doubling and the addition formulas take two
+// vectorized multiplications (or a squaring and a multiplication).
+
+// useAVX2 is true iff the AVX2 backend is used for variable time
+// scalar multiplication.
+var useAVX2 = cpu.X86.HasAVX2
+
+// fe4 is four field elements in radix 2^25.5, with limb i of the elements
+// in the four 64-bit lanes of fe4[i].  Unless noted otherwise, the even
+// limbs are less than 2^26, and the odd limbs are less than 2^25 + 2^13.
+type fe4 [10][4]uint64
+
+// ge4 is a group element in extended coordinates, (X, Y, Z, T).
+type ge4 fe4
+
+// cached4 is a group element as used by additions, (Y-X, Y+X, 2Z, 2dT).
+type cached4 fe4
+
+//go:noescape
+func fe4Mul(r, a, b *fe4)
+
+//go:noescape
+func fe4Square(r, a *fe4)
+
+//go:noescape
+func ge4Double(r, p *ge4)
+
+//go:noescape
+func ge4AddCached(r, p *ge4, q *cached4)
+
+//go:noescape
+func ge4SubCached(r, p *ge4, q *cached4)
+
+//go:noescape
+func ge4ToCached(r *cached4, p *ge4)
+
+// fe4Offsets are the bit offsets of the limbs.
+var fe4Offsets = [11]uint{0, 26, 51, 77, 102, 128, 153, 179, 204, 230, 255}
+
+var (
+	// ge4Identity is the identity point (neutral element).
+	ge4Identity ge4
+
+	// cachedSlidingMultiples is nielsSlidingMultiples in the cached form.
+	cachedSlidingMultiples [len(nielsSlidingMultiples)]cached4
+)
+
+// setLane sets lane of f to a.
+func (f *fe4) setLane(lane int, a *curve25519.Bignum25519) {
+	var (
+		b [32]byte
+		w [4]uint64
+	)
+
+	curve25519.Contract(b[:], a)
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	for i := range f {
+		lo, width := fe4Offsets[i], fe4Offsets[i+1]-fe4Offsets[i]
+		v := w[lo/64] >> (lo % 64)
+		if lo%64+width > 64 {
+			v |= w[lo/64+1] << (64 - lo%64)
+		}
+		f[i][lane] = v & (1<<width - 1)
+	}
+}
+
+// getLane sets a to lane of f.
+func (f *fe4) getLane(a *curve25519.Bignum25519, lane int) {
+	var (
+		h [10]uint64
+		w [4]uint64
+		b [32]byte
+	)
+
+	for i := range h {
+		h[i] = f[i][lane]
+	}
+
+	// Carry until every limb fits in its width, which leaves a value
+	// less than 2^255 (but not necessarily less than p).  The third pass
+	// is only required if the second carries all the way through.
+	for pass := 0; pass < 3; pass++ {
+		for i := range h {
+			width := fe4Offsets[i+1] - fe4Offsets[i]
+			c := h[i] >> width
+			h[i] &= 1<<width - 1
+			if i < len(h)-1 {
+				h[i+1] += c
+			} else {
+				h[0] += 19 * c
+			}
+		}
+	}
+
+	for i := range h {
+		lo := fe4Offsets[i]
+		w[lo/64] |= h[i] << (lo % 64)
+		if lo%64+fe4Offsets[i+1]-lo > 64 {
+			w[lo/64+1] |= h[i] >> (64 - lo%64)
+		}
+	}
+	for i := range w {
+		binary.LittleEndian.PutUint64(b[8*i:], w[i])
+	}
+	curve25519.Expand(a, b[:])
+}
+
+func (r *ge4) set(p *Ge25519) {
+	f := (*fe4)(r)
+	f.setLane(0, &p.x)
+	f.setLane(1, &p.y)
+	f.setLane(2, &p.z)
+	f.setLane(3, &p.t)
+}
+
+func (r *ge4) get(p *Ge25519) {
+	f := (*fe4)(r)
+	f.getLane(&p.x, 0)
+	f.getLane(&p.y, 1)
+	f.getLane(&p.z, 2)
+	f.getLane(&p.t, 3)
+}
+
+// computes [s1]p1 + [s2]basepoint, with the AVX2 backend
+func doubleScalarmultVartimeAVX2(r, p1 *Ge25519, s1, s2 *modm.Bignum256) {
+	var (
+		slide1, slide2 [256]int8
+		pre1           [s1TableSize]cached4
+		p, d1          ge4
+		t              cached4
+		i              int
+	)
+
+	modm.ContractSlidingWindow(&slide1, s1, s1SWindowSize)
+	modm.ContractSlidingWindow(&slide2, s2, s2SWindowSize)
+
+	// pre1[i] = [2i+1]p1
+	p.set(p1)
+	ge4ToCached(&pre1[0], &p)
+	ge4Double(&d1, &p)
+	ge4ToCached(&t, &d1)
+	for i = 0; i < s1TableSize-1; i++ {
+		ge4AddCached(&p, &p, &t)
+		ge4ToCached(&pre1[i+1], &p)
+	}
+
+	p = ge4Identity
+
+	i = 255
+	for (i >= 0) && (slide1[i]|slide2[i]) == 0 {
+		i--
+	}
+
+	for ; i >= 0; i-- {
+		ge4Double(&p, &p)
+
+		if s := slide1[i]; s > 0 {
+			ge4AddCached(&p, &p, &pre1[s/2])
+		} else if s < 0 {
+			ge4SubCached(&p, &p, &pre1[-s/2])
+		}
+
+		if s := slide2[i]; s > 0 {
+			ge4AddCached(&p, &p, &cachedSlidingMultiples[s/2])
+		} else if s < 0 {
+			ge4SubCached(&p, &p, &cachedSlidingMultiples[-s/2])
+		}
+	}
+
+	p.get(r)
+}
+
+// multiScratch is the working storage of multiScalarmultVartimeAVX2, which
+// at around 330 KiB is too large for the stack, and is reused instead of
+// being allocated on every call.
+type multiScratch struct {
+	slides [MaxVectorMultiScalarmultPoints][256]int8
+	pre    [MaxVectorMultiScalarmultPoints][s1TableSize]cached4
+}
+
+var multiScratchPool = sync.Pool{
+	New: func() interface{} {
+		return new(multiScratch)
+	},
+}
+
+// multiScalarmultVartimeAVX2 computes sum([scalars[i]]points[i]) with
+// Straus' method, sharing the doublings between all of the sliding windows.
+// At most MaxVectorMultiScalarmultPoints points are supported.
+func multiScalarmultVartimeAVX2(r *Ge25519, points []Ge25519, scalars []modm.Bignum256) {
+	scratch := multiScratchPool.Get().(*multiScratch)
+	defer multiScratchPool.Put(scratch)
+
+	var (
+		slides = scratch.slides[:len(points)]
+		pre    = scratch.pre[:len(points)]
+		p, d   ge4
+		t      cached4
+		i      int
+	)
+
+	for j := range points {
+		modm.ContractSlidingWindow(&slides[j], &scalars[j], s1SWindowSize)
+
+		// pre[j][i] = [2i+1]points[j]
+		p.set(&points[j])
+		ge4ToCached(&pre[j][0], &p)
+		ge4Double(&d, &p)
+		ge4ToCached(&t, &d)
+		for i = 0; i < s1TableSize-1; i++ {
+			ge4AddCached(&p, &p, &t)
+			ge4ToCached(&pre[j][i+1], &p)
+		}
+	}
+
+	p = ge4Identity
+
+	for i = 255; i >= 0; i-- {
+		var nonzero int8
+		for j := range slides {
+			nonzero |= slides[j][i]
+		}
+		if nonzero != 0 {
+			break
+		}
+	}
+
+	for ; i >= 0; i-- {
+		ge4Double(&p, &p)
+
+		for j := range slides {
+			if s := slides[j][i]; s > 0 {
+				ge4AddCached(&p, &p, &pre[j][s/2])
+			} else if s < 0 {
+				ge4SubCached(&p, &p, &pre[j][-s/2])
+			}
+		}
+	}
+
+	p.get(r)
+}
+
+func init() {
+	var (
+		zero, two curve25519.Bignum25519
+		one       = curve25519.Bignum25519{1}
+	)
+	two[0] = 2
+
+	ge4Identity.set(&Ge25519{x: zero, y: one, z: one, t: zero})
+
+	for i := range nielsSlidingMultiples {
+		n, f := &nielsSlidingMultiples[i], (*fe4)(&cachedSlidingMultiples[i])
+		f.setLane(0, &n.ysubx)
+		f.setLane(1, &n.xaddy)
+		f.setLane(2, &two)
+		f.setLane(3, &n.t2d)
+	}
+}
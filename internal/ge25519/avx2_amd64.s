@@ -0,0 +1,858 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build amd64,!noasm
+// +build !force32bit
+
+#include "textflag.h"
+
+// The field elements are in radix 2^25.5, with limb i of the four elements
+// in the 64-bit lanes of the i-th 32 byte vector, so that each VPMULUDQ
+// computes the same partial product for all four elements.  See the
+// comments on fe4 in avx2_amd64.go for the limb bounds.
+
+// 19, 2^26 - 1 and 2^25 - 1, used for reduction.
+DATA ·fe4Nineteen<>+0x000(SB)/8, $0x13
+DATA ·fe4Nineteen<>+0x008(SB)/8, $0x13
+DATA ·fe4Nineteen<>+0x010(SB)/8, $0x13
+DATA ·fe4Nineteen<>+0x018(SB)/8, $0x13
+GLOBL ·fe4Nineteen<>(SB), (NOPTR+RODATA), $32
+
+DATA ·fe4Mask26<>+0x000(SB)/8, $0x3ffffff
+DATA ·fe4Mask26<>+0x008(SB)/8, $0x3ffffff
+DATA ·fe4Mask26<>+0x010(SB)/8, $0x3ffffff
+DATA ·fe4Mask26<>+0x018(SB)/8, $0x3ffffff
+GLOBL ·fe4Mask26<>(SB), (NOPTR+RODATA), $32
+
+DATA ·fe4Mask25<>+0x000(SB)/8, $0x1ffffff
+DATA ·fe4Mask25<>+0x008(SB)/8, $0x1ffffff
+DATA ·fe4Mask25<>+0x010(SB)/8, $0x1ffffff
+DATA ·fe4Mask25<>+0x018(SB)/8, $0x1ffffff
+GLOBL ·fe4Mask25<>(SB), (NOPTR+RODATA), $32
+
+// 2p and 4p, added before subtracting to keep the limbs positive.
+DATA ·fe4TwoP<>+0x000(SB)/8, $0x7ffffda
+DATA ·fe4TwoP<>+0x008(SB)/8, $0x7ffffda
+DATA ·fe4TwoP<>+0x010(SB)/8, $0x7ffffda
+DATA ·fe4TwoP<>+0x018(SB)/8, $0x7ffffda
+DATA ·fe4TwoP<>+0x020(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x028(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x030(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x038(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x040(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x048(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x050(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x058(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x060(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x068(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x070(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x078(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x080(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x088(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x090(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x098(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x0a0(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0a8(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0b0(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0b8(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0c0(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x0c8(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x0d0(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x0d8(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x0e0(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0e8(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0f0(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x0f8(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x100(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x108(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x110(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x118(SB)/8, $0x7fffffe
+DATA ·fe4TwoP<>+0x120(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x128(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x130(SB)/8, $0x3fffffe
+DATA ·fe4TwoP<>+0x138(SB)/8, $0x3fffffe
+GLOBL ·fe4TwoP<>(SB), (NOPTR+RODATA), $320
+
+DATA ·fe4FourP<>+0x000(SB)/8, $0xfffffb4
+DATA ·fe4FourP<>+0x008(SB)/8, $0xfffffb4
+DATA ·fe4FourP<>+0x010(SB)/8, $0xfffffb4
+DATA ·fe4FourP<>+0x018(SB)/8, $0xfffffb4
+DATA ·fe4FourP<>+0x020(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x028(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x030(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x038(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x040(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x048(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x050(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x058(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x060(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x068(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x070(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x078(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x080(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x088(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x090(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x098(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x0a0(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0a8(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0b0(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0b8(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0c0(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x0c8(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x0d0(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x0d8(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x0e0(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0e8(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0f0(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x0f8(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x100(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x108(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x110(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x118(SB)/8, $0xffffffc
+DATA ·fe4FourP<>+0x120(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x128(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x130(SB)/8, $0x7fffffc
+DATA ·fe4FourP<>+0x138(SB)/8, $0x7fffffc
+GLOBL ·fe4FourP<>(SB), (NOPTR+RODATA), $320
+
+// (1, 1, 2, 2d), to convert (Y-X, Y+X, Z, T) to (Y-X, Y+X, 2Z, 2dT).
+DATA ·fe4CachedK<>+0x000(SB)/8, $0x1
+DATA ·fe4CachedK<>+0x008(SB)/8, $0x1
+DATA ·fe4CachedK<>+0x010(SB)/8, $0x2
+DATA ·fe4CachedK<>+0x018(SB)/8, $0x2b2f159
+DATA ·fe4CachedK<>+0x020(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x028(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x030(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x038(SB)/8, $0x1a6e509
+DATA ·fe4CachedK<>+0x040(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x048(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x050(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x058(SB)/8, $0x22add7a
+DATA ·fe4CachedK<>+0x060(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x068(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x070(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x078(SB)/8, $0xd4141d
+DATA ·fe4CachedK<>+0x080(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x088(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x090(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x098(SB)/8, $0x38052
+DATA ·fe4CachedK<>+0x0a0(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0a8(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0b0(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0b8(SB)/8, $0xf3d130
+DATA ·fe4CachedK<>+0x0c0(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0c8(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0d0(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0d8(SB)/8, $0x3407977
+DATA ·fe4CachedK<>+0x0e0(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0e8(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0f0(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x0f8(SB)/8, $0x19ce331
+DATA ·fe4CachedK<>+0x100(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x108(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x110(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x118(SB)/8, $0x1c56dff
+DATA ·fe4CachedK<>+0x120(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x128(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x130(SB)/8, $0x0
+DATA ·fe4CachedK<>+0x138(SB)/8, $0x901b67
+GLOBL ·fe4CachedK<>(SB), (NOPTR+RODATA), $320
+
+// FE4_REDUCE carries the 64-bit limbs in Y0..Y9, which must be less than
+// 2^63, so that the even limbs are less than 2^26 and the odd limbs are
+// less than 2^25 + 2^13.  Clobbers Y10..Y15.
+#define FE4_REDUCE \
+	VMOVDQU ·fe4Mask26<>(SB), Y14; \
+	VMOVDQU ·fe4Mask25<>(SB), Y15; \
+	VPSRLQ $26, Y0, Y12; \
+	VPAND Y14, Y0, Y0; \
+	VPADDQ Y12, Y1, Y1; \
+	VPSRLQ $26, Y4, Y12; \
+	VPAND Y14, Y4, Y4; \
+	VPADDQ Y12, Y5, Y5; \
+	VPSRLQ $25, Y1, Y12; \
+	VPAND Y15, Y1, Y1; \
+	VPADDQ Y12, Y2, Y2; \
+	VPSRLQ $25, Y5, Y12; \
+	VPAND Y15, Y5, Y5; \
+	VPADDQ Y12, Y6, Y6; \
+	VPSRLQ $26, Y2, Y12; \
+	VPAND Y14, Y2, Y2; \
+	VPADDQ Y12, Y3, Y3; \
+	VPSRLQ $26, Y6, Y12; \
+	VPAND Y14, Y6, Y6; \
+	VPADDQ Y12, Y7, Y7; \
+	VPSRLQ $25, Y3, Y12; \
+	VPAND Y15, Y3, Y3; \
+	VPADDQ Y12, Y4, Y4; \
+	VPSRLQ $25, Y7, Y12; \
+	VPAND Y15, Y7, Y7; \
+	VPADDQ Y12, Y8, Y8; \
+	VPSRLQ $26, Y4, Y12; \
+	VPAND Y14, Y4, Y4; \
+	VPADDQ Y12, Y5, Y5; \
+	VPSRLQ $26, Y8, Y12; \
+	VPAND Y14, Y8, Y8; \
+	VPADDQ Y12, Y9, Y9; \
+	VPSRLQ $25, Y9, Y12; \
+	VPAND Y15, Y9, Y9; \
+	VPSLLQ $1, Y12, Y10; \
+	VPSLLQ $4, Y12, Y11; \
+	VPADDQ Y10, Y12, Y12; \
+	VPADDQ Y11, Y12, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPSRLQ $26, Y0, Y12; \
+	VPAND Y14, Y0, Y0; \
+	VPADDQ Y12, Y1, Y1
+
+// FE4_LOAD and FE4_STORE move a vectorized field element to/from Y0..Y9.
+#define FE4_LOAD(a) \
+	VMOVDQU 0(a), Y0; \
+	VMOVDQU 32(a), Y1; \
+	VMOVDQU 64(a), Y2; \
+	VMOVDQU 96(a), Y3; \
+	VMOVDQU 128(a), Y4; \
+	VMOVDQU 160(a), Y5; \
+	VMOVDQU 192(a), Y6; \
+	VMOVDQU 224(a), Y7; \
+	VMOVDQU 256(a), Y8; \
+	VMOVDQU 288(a), Y9
+
+#define FE4_STORE(r) \
+	VMOVDQU Y0, 0(r); \
+	VMOVDQU Y1, 32(r); \
+	VMOVDQU Y2, 64(r); \
+	VMOVDQU Y3, 96(r); \
+	VMOVDQU Y4, 128(r); \
+	VMOVDQU Y5, 160(r); \
+	VMOVDQU Y6, 192(r); \
+	VMOVDQU Y7, 224(r); \
+	VMOVDQU Y8, 256(r); \
+	VMOVDQU Y9, 288(r)
+
+// FE4_MUL sets r = a * b, lane-wise.  The limbs of a and b must be less
+// than 3 * 2^26.  r may alias a or b.  Uses 288 bytes of scratch space at
+// 0(SP), and clobbers Y0..Y15.
+#define FE4_MUL(a, b, r) \
+	VMOVDQU ·fe4Nineteen<>(SB), Y13; \
+	VPMULUDQ 32(b), Y13, Y12; \
+	VMOVDQU Y12, 0(SP); \
+	VPMULUDQ 64(b), Y13, Y12; \
+	VMOVDQU Y12, 32(SP); \
+	VPMULUDQ 96(b), Y13, Y12; \
+	VMOVDQU Y12, 64(SP); \
+	VPMULUDQ 128(b), Y13, Y12; \
+	VMOVDQU Y12, 96(SP); \
+	VPMULUDQ 160(b), Y13, Y12; \
+	VMOVDQU Y12, 128(SP); \
+	VPMULUDQ 192(b), Y13, Y12; \
+	VMOVDQU Y12, 160(SP); \
+	VPMULUDQ 224(b), Y13, Y12; \
+	VMOVDQU Y12, 192(SP); \
+	VPMULUDQ 256(b), Y13, Y12; \
+	VMOVDQU Y12, 224(SP); \
+	VPMULUDQ 288(b), Y13, Y12; \
+	VMOVDQU Y12, 256(SP); \
+	VMOVDQU 0(a), Y10; \
+	VPMULUDQ 0(b), Y10, Y0; \
+	VPMULUDQ 32(b), Y10, Y1; \
+	VPMULUDQ 64(b), Y10, Y2; \
+	VPMULUDQ 96(b), Y10, Y3; \
+	VPMULUDQ 128(b), Y10, Y4; \
+	VPMULUDQ 160(b), Y10, Y5; \
+	VPMULUDQ 192(b), Y10, Y6; \
+	VPMULUDQ 224(b), Y10, Y7; \
+	VPMULUDQ 256(b), Y10, Y8; \
+	VPMULUDQ 288(b), Y10, Y9; \
+	VMOVDQU 32(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 32(b), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 96(b), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 128(b), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 160(b), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 192(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 224(b), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 256(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 256(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VMOVDQU 64(a), Y10; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 32(b), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 96(b), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 128(b), Y10, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 160(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 192(b), Y10, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 224(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 256(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VMOVDQU 96(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 32(b), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 96(b), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 128(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 160(b), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 192(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 192(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 256(SP), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VMOVDQU 128(a), Y10; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 32(b), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 96(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 128(b), Y10, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 160(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 160(SP), Y10, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 192(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 256(SP), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VMOVDQU 160(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 32(b), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 96(b), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 128(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 160(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 192(SP), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 256(SP), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VMOVDQU 192(a), Y10; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 32(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 96(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 96(SP), Y10, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 128(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 160(SP), Y10, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 192(SP), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 256(SP), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VMOVDQU 224(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 32(b), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 64(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 64(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 96(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 160(SP), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 192(SP), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 256(SP), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VMOVDQU 256(a), Y10; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 32(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 32(SP), Y10, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 64(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 96(SP), Y10, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 128(SP), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 160(SP), Y10, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 192(SP), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 256(SP), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VMOVDQU 288(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 0(b), Y10, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 0(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 32(SP), Y10, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 64(SP), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 96(SP), Y10, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 160(SP), Y10, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 192(SP), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 224(SP), Y10, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 256(SP), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	FE4_REDUCE; \
+	FE4_STORE(r)
+
+// FE4_SQUARE sets r = a^2, lane-wise.  The limbs of a must be less than
+// 2^27 + 2^20.  r may alias a.  Uses 160 bytes of scratch space at 0(SP),
+// and clobbers Y0..Y15.
+#define FE4_SQUARE(a, r) \
+	VMOVDQU ·fe4Nineteen<>(SB), Y13; \
+	VPMULUDQ 160(a), Y13, Y12; \
+	VMOVDQU Y12, 0(SP); \
+	VPMULUDQ 192(a), Y13, Y12; \
+	VMOVDQU Y12, 32(SP); \
+	VPMULUDQ 224(a), Y13, Y12; \
+	VMOVDQU Y12, 64(SP); \
+	VPMULUDQ 256(a), Y13, Y12; \
+	VMOVDQU Y12, 96(SP); \
+	VPMULUDQ 288(a), Y13, Y12; \
+	VMOVDQU Y12, 128(SP); \
+	VMOVDQU 0(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 0(a), Y10, Y0; \
+	VPMULUDQ 32(a), Y11, Y1; \
+	VPMULUDQ 64(a), Y11, Y2; \
+	VPMULUDQ 96(a), Y11, Y3; \
+	VPMULUDQ 128(a), Y11, Y4; \
+	VPMULUDQ 160(a), Y11, Y5; \
+	VPMULUDQ 192(a), Y11, Y6; \
+	VPMULUDQ 224(a), Y11, Y7; \
+	VPMULUDQ 256(a), Y11, Y8; \
+	VPMULUDQ 288(a), Y11, Y9; \
+	VMOVDQU 32(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPADDQ Y11, Y11, Y13; \
+	VPMULUDQ 32(a), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 64(a), Y11, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 96(a), Y13, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 128(a), Y11, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 160(a), Y13, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 192(a), Y11, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 224(a), Y13, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 256(a), Y11, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 128(SP), Y13, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VMOVDQU 64(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 64(a), Y10, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 96(a), Y11, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 128(a), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 160(a), Y11, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 192(a), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 224(a), Y11, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 96(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VMOVDQU 96(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPADDQ Y11, Y11, Y13; \
+	VPMULUDQ 96(a), Y11, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 128(a), Y11, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VPMULUDQ 160(a), Y13, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 192(a), Y11, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 64(SP), Y13, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 96(SP), Y11, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 128(SP), Y13, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VMOVDQU 128(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 128(a), Y10, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	VPMULUDQ 160(a), Y11, Y12; \
+	VPADDQ Y12, Y9, Y9; \
+	VPMULUDQ 32(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 64(SP), Y11, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 96(SP), Y11, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VMOVDQU 160(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPADDQ Y11, Y11, Y13; \
+	VPMULUDQ 0(SP), Y11, Y12; \
+	VPADDQ Y12, Y0, Y0; \
+	VPMULUDQ 32(SP), Y11, Y12; \
+	VPADDQ Y12, Y1, Y1; \
+	VPMULUDQ 64(SP), Y13, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 96(SP), Y11, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 128(SP), Y13, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VMOVDQU 192(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 32(SP), Y10, Y12; \
+	VPADDQ Y12, Y2, Y2; \
+	VPMULUDQ 64(SP), Y11, Y12; \
+	VPADDQ Y12, Y3, Y3; \
+	VPMULUDQ 96(SP), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VMOVDQU 224(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPADDQ Y11, Y11, Y13; \
+	VPMULUDQ 64(SP), Y11, Y12; \
+	VPADDQ Y12, Y4, Y4; \
+	VPMULUDQ 96(SP), Y11, Y12; \
+	VPADDQ Y12, Y5, Y5; \
+	VPMULUDQ 128(SP), Y13, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VMOVDQU 256(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 96(SP), Y10, Y12; \
+	VPADDQ Y12, Y6, Y6; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y7, Y7; \
+	VMOVDQU 288(a), Y10; \
+	VPADDQ Y10, Y10, Y11; \
+	VPMULUDQ 128(SP), Y11, Y12; \
+	VPADDQ Y12, Y8, Y8; \
+	FE4_REDUCE; \
+	FE4_STORE(r)
+
+// DOUBLE_PRE sets limb off of (BX) to (X, Y, Z, X+Y), given (X, Y, Z, T)
+// at (SI).  Y15 must be zero.
+#define DOUBLE_PRE(off) \
+	VPERMQ $0x24, off(SI), Y0; \
+	VPERMQ $0x55, off(SI), Y1; \
+	VPBLENDD $0xc0, Y1, Y15, Y1; \
+	VPADDQ Y1, Y0, Y0; \
+	VMOVDQU Y0, off(BX)
+
+// DOUBLE_MID sets limb off of (BX) from (A, B, Z^2, S) to
+// (E, F, G, H) = (S-A-B, B-A-2Z^2, B-A, -A-B), where A = X^2, B = Y^2 and
+// S = (X+Y)^2.  The result is not reduced.  Y15 must be zero.
+#define DOUBLE_MID(off) \
+	VMOVDQU off(BX), Y0; \
+	VPERMQ $0x17, Y0, Y1; \
+	VPBLENDD $0xc0, Y15, Y1, Y1; \
+	VPERMQ $0x69, Y0, Y2; \
+	VPBLENDD $0x30, Y15, Y2, Y2; \
+	VPBLENDD $0x0c, Y2, Y15, Y3; \
+	VPADDQ Y3, Y2, Y2; \
+	VPERMQ $0x00, Y0, Y3; \
+	VPADDQ Y3, Y2, Y2; \
+	VPADDQ ·fe4FourP<>+off(SB), Y1, Y1; \
+	VPSUBQ Y2, Y1, Y1; \
+	VMOVDQU Y1, off(BX)
+
+// DOUBLE_OUT stores the operands of the final multiplication of the
+// doubling and the addition, (E, G, F, E) to (BX) and (F, H, G, H) to (CX),
+// given limb n of (E, F, G, H).
+#define DOUBLE_OUT(n, off) \
+	VPERMQ $0x18, n, Y12; \
+	VMOVDQU Y12, off(BX); \
+	VPERMQ $0xed, n, n; \
+	VMOVDQU n, off(CX)
+
+// ADD_PRE sets limb off of (BX) to (Y-X, Y+X, Z, T) (with xb = 0x0c and
+// nb = 0x03), or to (Y+X, Y-X, Z, T) (with xb = 0x03 and nb = 0x0c), given
+// (X, Y, Z, T) at (SI).  Y15 must be zero.
+#define ADD_PRE(off, xb, nb) \
+	VPERMQ $0xe5, off(SI), Y0; \
+	VPERMQ $0x00, off(SI), Y1; \
+	VMOVDQU ·fe4TwoP<>+off(SB), Y2; \
+	VPSUBQ Y1, Y2, Y2; \
+	VPBLENDD $xb, Y1, Y15, Y3; \
+	VPBLENDD $nb, Y2, Y3, Y3; \
+	VPADDQ Y3, Y0, Y0; \
+	VMOVDQU Y0, off(BX)
+
+// ADD_MID computes limb off of (E, F, G, H) from the product at (CX), and
+// stores the operands of the final multiplication like DOUBLE_OUT.  For
+// an addition the product is (A, B, D, C), and E = B-A, F = D-C, G = D+C
+// and H = B+A.  For a subtraction the product is (B, A, D, C), and F and G
+// are swapped, as the T coordinate of the cached point is negated.
+#define ADD_MID(off, p1, p2, bl) \
+	VPERMQ $p1, off(CX), Y0; \
+	VPERMQ $p2, off(CX), Y1; \
+	VPADDQ Y1, Y0, Y2; \
+	VPADDQ ·fe4TwoP<>+off(SB), Y0, Y0; \
+	VPSUBQ Y1, Y0, Y0; \
+	VPBLENDD $bl, Y2, Y0, Y0; \
+	VPERMQ $0x18, Y0, Y1; \
+	VMOVDQU Y1, off(BX); \
+	VPERMQ $0xed, Y0, Y0; \
+	VMOVDQU Y0, off(CX)
+
+// func fe4Mul(r, a, b *fe4)
+TEXT ·fe4Mul(SB), NOSPLIT, $288-24
+	MOVQ r+0(FP), DI
+	MOVQ a+8(FP), SI
+	MOVQ b+16(FP), DX
+	FE4_MUL(SI, DX, DI)
+	VZEROUPPER
+	RET
+
+// func fe4Square(r, a *fe4)
+TEXT ·fe4Square(SB), NOSPLIT, $160-16
+	MOVQ r+0(FP), DI
+	MOVQ a+8(FP), SI
+	FE4_SQUARE(SI, DI)
+	VZEROUPPER
+	RET
+
+// func ge4Double(r, p *ge4)
+TEXT ·ge4Double(SB), 0, $928-16
+	MOVQ r+0(FP), DI
+	MOVQ p+8(FP), SI
+	LEAQ 288(SP), BX
+	LEAQ 608(SP), CX
+	VPXOR Y15, Y15, Y15
+	DOUBLE_PRE(0)
+	DOUBLE_PRE(32)
+	DOUBLE_PRE(64)
+	DOUBLE_PRE(96)
+	DOUBLE_PRE(128)
+	DOUBLE_PRE(160)
+	DOUBLE_PRE(192)
+	DOUBLE_PRE(224)
+	DOUBLE_PRE(256)
+	DOUBLE_PRE(288)
+
+	// (A, B, Z^2, S) = (X^2, Y^2, Z^2, (X+Y)^2)
+	FE4_SQUARE(BX, BX)
+
+	VPXOR Y15, Y15, Y15
+	DOUBLE_MID(0)
+	DOUBLE_MID(32)
+	DOUBLE_MID(64)
+	DOUBLE_MID(96)
+	DOUBLE_MID(128)
+	DOUBLE_MID(160)
+	DOUBLE_MID(192)
+	DOUBLE_MID(224)
+	DOUBLE_MID(256)
+	DOUBLE_MID(288)
+	FE4_LOAD(BX)
+	FE4_REDUCE
+	DOUBLE_OUT(Y0, 0)
+	DOUBLE_OUT(Y1, 32)
+	DOUBLE_OUT(Y2, 64)
+	DOUBLE_OUT(Y3, 96)
+	DOUBLE_OUT(Y4, 128)
+	DOUBLE_OUT(Y5, 160)
+	DOUBLE_OUT(Y6, 192)
+	DOUBLE_OUT(Y7, 224)
+	DOUBLE_OUT(Y8, 256)
+	DOUBLE_OUT(Y9, 288)
+
+	// (X3, Y3, Z3, T3) = (E*F, G*H, F*G, E*H)
+	FE4_MUL(BX, CX, DI)
+	VZEROUPPER
+	RET
+
+// func ge4AddCached(r, p *ge4, q *cached4)
+TEXT ·ge4AddCached(SB), 0, $928-24
+	MOVQ r+0(FP), DI
+	MOVQ p+8(FP), SI
+	MOVQ q+16(FP), DX
+	LEAQ 288(SP), BX
+	LEAQ 608(SP), CX
+	VPXOR Y15, Y15, Y15
+	ADD_PRE(0, 0x0c, 0x03)
+	ADD_PRE(32, 0x0c, 0x03)
+	ADD_PRE(64, 0x0c, 0x03)
+	ADD_PRE(96, 0x0c, 0x03)
+	ADD_PRE(128, 0x0c, 0x03)
+	ADD_PRE(160, 0x0c, 0x03)
+	ADD_PRE(192, 0x0c, 0x03)
+	ADD_PRE(224, 0x0c, 0x03)
+	ADD_PRE(256, 0x0c, 0x03)
+	ADD_PRE(288, 0x0c, 0x03)
+
+	// (A, B, D, C) = (Y-X, Y+X, Z, T) * (Y2-X2, Y2+X2, 2Z2, 2dT2)
+	FE4_MUL(BX, DX, CX)
+	ADD_MID(0, 0x69, 0x3c, 0xf0)
+	ADD_MID(32, 0x69, 0x3c, 0xf0)
+	ADD_MID(64, 0x69, 0x3c, 0xf0)
+	ADD_MID(96, 0x69, 0x3c, 0xf0)
+	ADD_MID(128, 0x69, 0x3c, 0xf0)
+	ADD_MID(160, 0x69, 0x3c, 0xf0)
+	ADD_MID(192, 0x69, 0x3c, 0xf0)
+	ADD_MID(224, 0x69, 0x3c, 0xf0)
+	ADD_MID(256, 0x69, 0x3c, 0xf0)
+	ADD_MID(288, 0x69, 0x3c, 0xf0)
+
+	// (X3, Y3, Z3, T3) = (E*F, G*H, F*G, E*H)
+	FE4_MUL(BX, CX, DI)
+	VZEROUPPER
+	RET
+
+// func ge4SubCached(r, p *ge4, q *cached4)
+TEXT ·ge4SubCached(SB), 0, $928-24
+	MOVQ r+0(FP), DI
+	MOVQ p+8(FP), SI
+	MOVQ q+16(FP), DX
+	LEAQ 288(SP), BX
+	LEAQ 608(SP), CX
+	VPXOR Y15, Y15, Y15
+	ADD_PRE(0, 0x03, 0x0c)
+	ADD_PRE(32, 0x03, 0x0c)
+	ADD_PRE(64, 0x03, 0x0c)
+	ADD_PRE(96, 0x03, 0x0c)
+	ADD_PRE(128, 0x03, 0x0c)
+	ADD_PRE(160, 0x03, 0x0c)
+	ADD_PRE(192, 0x03, 0x0c)
+	ADD_PRE(224, 0x03, 0x0c)
+	ADD_PRE(256, 0x03, 0x0c)
+	ADD_PRE(288, 0x03, 0x0c)
+
+	// (B, A, D, C) = (Y+X, Y-X, Z, T) * (Y2-X2, Y2+X2, 2Z2, 2dT2)
+	FE4_MUL(BX, DX, CX)
+	ADD_MID(0, 0x28, 0x7d, 0xcc)
+	ADD_MID(32, 0x28, 0x7d, 0xcc)
+	ADD_MID(64, 0x28, 0x7d, 0xcc)
+	ADD_MID(96, 0x28, 0x7d, 0xcc)
+	ADD_MID(128, 0x28, 0x7d, 0xcc)
+	ADD_MID(160, 0x28, 0x7d, 0xcc)
+	ADD_MID(192, 0x28, 0x7d, 0xcc)
+	ADD_MID(224, 0x28, 0x7d, 0xcc)
+	ADD_MID(256, 0x28, 0x7d, 0xcc)
+	ADD_MID(288, 0x28, 0x7d, 0xcc)
+
+	// (X3, Y3, Z3, T3) = (E*F, G*H, F*G, E*H)
+	FE4_MUL(BX, CX, DI)
+	VZEROUPPER
+	RET
+
+// func ge4ToCached(r *cached4, p *ge4)
+TEXT ·ge4ToCached(SB), 0, $608-16
+	MOVQ r+0(FP), DI
+	MOVQ p+8(FP), SI
+	LEAQ 288(SP), BX
+	LEAQ ·fe4CachedK<>(SB), DX
+	VPXOR Y15, Y15, Y15
+	ADD_PRE(0, 0x0c, 0x03)
+	ADD_PRE(32, 0x0c, 0x03)
+	ADD_PRE(64, 0x0c, 0x03)
+	ADD_PRE(96, 0x0c, 0x03)
+	ADD_PRE(128, 0x0c, 0x03)
+	ADD_PRE(160, 0x0c, 0x03)
+	ADD_PRE(192, 0x0c, 0x03)
+	ADD_PRE(224, 0x0c, 0x03)
+	ADD_PRE(256, 0x0c, 0x03)
+	ADD_PRE(288, 0x0c, 0x03)
+
+	// (Y-X, Y+X, 2Z, 2dT) = (Y-X, Y+X, Z, T) * (1, 1, 2, 2d)
+	FE4_MUL(BX, DX, DI)
+	VZEROUPPER
+	RET
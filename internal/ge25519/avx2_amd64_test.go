@@ -0,0 +1,385 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build amd64,!noasm
+// +build !force32bit
+
+package ge25519
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519/internal/curve25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+var fieldPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+func requireAVX2(t *testing.T) {
+	if !useAVX2 {
+		t.Skip("AVX2 not supported")
+	}
+}
+
+func (f *fe4) laneBig(lane int) *big.Int {
+	v := new(big.Int)
+	for i := len(f) - 1; i >= 0; i-- {
+		v.Lsh(v, fe4Offsets[i+1]-fe4Offsets[i])
+		v.Add(v, new(big.Int).SetUint64(f[i][lane]))
+	}
+	return v.Mod(v, fieldPrime)
+}
+
+func randomFe4(t *testing.T, f *fe4, bound uint64) {
+	var b [8]byte
+	for i := range f {
+		for j := range f[i] {
+			if _, err := rand.Read(b[:]); err != nil {
+				t.Fatal(err)
+			}
+			v := uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 | uint64(b[4])<<32
+			f[i][j] = v % bound
+		}
+	}
+}
+
+func checkReduced(t *testing.T, f *fe4) {
+	for i := range f {
+		bound := uint64(1) << 26
+		if i%2 == 1 {
+			bound = 1<<25 + 1<<13
+		}
+		for j := range f[i] {
+			if f[i][j] >= bound {
+				t.Fatalf("limb %d of lane %d not reduced: %x", i, j, f[i][j])
+			}
+		}
+	}
+}
+
+func TestFe4Arithmetic(t *testing.T) {
+	requireAVX2(t)
+
+	for _, tc := range []struct {
+		name              string
+		mulBound, sqBound uint64
+	}{
+		{"Reduced", 1 << 25, 1 << 25},
+		{"Max", 3 << 26, 1<<27 + 1<<20},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for iter := 0; iter < 1000; iter++ {
+				var a, b, r fe4
+				randomFe4(t, &a, tc.mulBound)
+				randomFe4(t, &b, tc.mulBound)
+				if iter == 0 {
+					for i := range a {
+						for j := range a[i] {
+							a[i][j], b[i][j] = tc.mulBound-1, tc.mulBound-1
+						}
+					}
+				}
+
+				fe4Mul(&r, &a, &b)
+				checkReduced(t, &r)
+				for lane := 0; lane < 4; lane++ {
+					expected := new(big.Int).Mul(a.laneBig(lane), b.laneBig(lane))
+					expected.Mod(expected, fieldPrime)
+					if r.laneBig(lane).Cmp(expected) != 0 {
+						t.Fatalf("fe4Mul: lane %d mismatch", lane)
+					}
+				}
+
+				randomFe4(t, &a, tc.sqBound)
+				if iter == 0 {
+					for i := range a {
+						for j := range a[i] {
+							a[i][j] = tc.sqBound - 1
+						}
+					}
+				}
+				fe4Square(&r, &a)
+				checkReduced(t, &r)
+				for lane := 0; lane < 4; lane++ {
+					expected := new(big.Int).Mul(a.laneBig(lane), a.laneBig(lane))
+					expected.Mod(expected, fieldPrime)
+					if r.laneBig(lane).Cmp(expected) != 0 {
+						t.Fatalf("fe4Square: lane %d mismatch", lane)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFe4Lanes(t *testing.T) {
+	var (
+		f    fe4
+		a, b curve25519.Bignum25519
+		buf  [32]byte
+		out  [32]byte
+	)
+
+	for iter := 0; iter < 1000; iter++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatal(err)
+		}
+		curve25519.Expand(&a, buf[:])
+		for lane := 0; lane < 4; lane++ {
+			f.setLane(lane, &a)
+			f.getLane(&b, lane)
+			curve25519.Contract(buf[:], &a)
+			curve25519.Contract(out[:], &b)
+			if buf != out {
+				t.Fatalf("lane %d round trip mismatch", lane)
+			}
+		}
+	}
+
+	// Unreduced lanes, including ones that carry through every limb.
+	var maxLimbs, overflow fe4
+	for i := range maxLimbs {
+		for j := range maxLimbs[i] {
+			maxLimbs[i][j] = uint64(1)<<(fe4Offsets[i+1]-fe4Offsets[i]) - 1
+		}
+	}
+	overflow = maxLimbs
+	overflow[0][0]++
+	overflow[0][1] += 19
+	overflow[0][2] = (1 << 26) - 20
+	for _, f := range []*fe4{&maxLimbs, &overflow} {
+		for lane := 0; lane < 4; lane++ {
+			f.getLane(&b, lane)
+			curve25519.Contract(out[:], &b)
+			expected := f.laneBig(lane).Bytes()
+			for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+				expected[i], expected[j] = expected[j], expected[i]
+			}
+			var e [32]byte
+			copy(e[:], expected)
+			if out != e {
+				t.Fatalf("getLane(%d): got %x, expected %x", lane, out, e)
+			}
+		}
+	}
+}
+
+func randomPoint(t *testing.T, p *Ge25519) {
+	var (
+		b [64]byte
+		s modm.Bignum256
+	)
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatal(err)
+	}
+	modm.Expand(&s, b[:])
+	ScalarmultBaseNiels(p, &NielsBaseMultiples, &s)
+}
+
+func TestGe4(t *testing.T) {
+	requireAVX2(t)
+
+	for iter := 0; iter < 100; iter++ {
+		var (
+			p, q, expected, actual Ge25519
+			p4, q4, r4             ge4
+			q4c                    cached4
+		)
+		randomPoint(t, &p)
+		randomPoint(t, &q)
+		p4.set(&p)
+		q4.set(&q)
+		ge4ToCached(&q4c, &q4)
+
+		Double(&expected, &p)
+		ge4Double(&r4, &p4)
+		r4.get(&actual)
+		if !EqualVartime(&expected, &actual) {
+			t.Fatalf("ge4Double mismatch")
+		}
+
+		Add(&expected, &p, &q)
+		ge4AddCached(&r4, &p4, &q4c)
+		r4.get(&actual)
+		if !EqualVartime(&expected, &actual) {
+			t.Fatalf("ge4AddCached mismatch")
+		}
+
+		Sub(&expected, &p, &q)
+		ge4SubCached(&r4, &p4, &q4c)
+		r4.get(&actual)
+		if !EqualVartime(&expected, &actual) {
+			t.Fatalf("ge4SubCached mismatch")
+		}
+	}
+}
+
+func testDoubleScalarmultVartime(t *testing.T, p *Ge25519, s1, s2 *modm.Bignum256) {
+	var expected, actual, e, a Ge25519
+
+	useAVX2 = false
+	DoubleScalarmultVartime(&expected, p, s1, s2)
+	useAVX2 = true
+	DoubleScalarmultVartime(&actual, p, s1, s2)
+
+	ProjectiveToExtended(&e, &expected)
+	ProjectiveToExtended(&a, &actual)
+	if !EqualVartime(&e, &a) {
+		t.Fatalf("DoubleScalarmultVartime mismatch")
+	}
+}
+
+func TestDoubleScalarmultVartimeAVX2(t *testing.T) {
+	requireAVX2(t)
+	defer func() {
+		useAVX2 = true
+	}()
+
+	var (
+		p            Ge25519
+		s1, s2, zero modm.Bignum256
+		b1, b2       [64]byte
+	)
+	for iter := 0; iter < 100; iter++ {
+		randomPoint(t, &p)
+		if _, err := rand.Read(b1[:]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rand.Read(b2[:]); err != nil {
+			t.Fatal(err)
+		}
+		modm.Expand(&s1, b1[:])
+		modm.Expand(&s2, b2[:])
+
+		testDoubleScalarmultVartime(t, &p, &s1, &s2)
+		testDoubleScalarmultVartime(t, &p, &s1, &zero)
+		testDoubleScalarmultVartime(t, &p, &zero, &s2)
+	}
+	testDoubleScalarmultVartime(t, &p, &zero, &zero)
+	testDoubleScalarmultVartime(t, &p, &orderMinusOne, &orderMinusOne)
+}
+
+func TestVectorMultiScalarmultVartime(t *testing.T) {
+	requireAVX2(t)
+	defer func() {
+		useAVX2 = true
+	}()
+
+	const n = 9
+
+	var (
+		points   [n]Ge25519
+		scalars  [n]modm.Bignum256
+		b        [64]byte
+		zero     modm.Bignum256
+		expected Ge25519
+		actual   Ge25519
+	)
+	for iter := 0; iter < 20; iter++ {
+		for i := range points {
+			randomPoint(t, &points[i])
+			if _, err := rand.Read(b[:]); err != nil {
+				t.Fatal(err)
+			}
+			if i%2 == 1 {
+				// Batch verification uses 128 bit scalars for R.
+				modm.Expand(&scalars[i], b[:16])
+			} else {
+				modm.Expand(&scalars[i], b[:])
+			}
+		}
+		scalars[n-1] = zero
+		if iter == 0 {
+			scalars[0] = orderMinusOne
+		}
+
+		useAVX2 = false
+		expected.Reset()
+		expected.y[0] = 1
+		expected.z[0] = 1
+		for i := range points {
+			var tmp, term Ge25519
+			DoubleScalarmultVartime(&tmp, &points[i], &scalars[i], &zero)
+			ProjectiveToExtended(&term, &tmp)
+			Add(&expected, &expected, &term)
+		}
+		if VectorMultiScalarmultVartime(&actual, points[:], scalars[:]) {
+			t.Fatalf("VectorMultiScalarmultVartime succeeded without AVX2")
+		}
+
+		useAVX2 = true
+		if !VectorMultiScalarmultVartime(&actual, points[:], scalars[:]) {
+			t.Fatalf("VectorMultiScalarmultVartime failed with AVX2")
+		}
+		if !EqualVartime(&expected, &actual) {
+			t.Fatalf("VectorMultiScalarmultVartime mismatch")
+		}
+	}
+
+	var (
+		tooManyPoints  [MaxVectorMultiScalarmultPoints + 1]Ge25519
+		tooManyScalars [MaxVectorMultiScalarmultPoints + 1]modm.Bignum256
+	)
+	if VectorMultiScalarmultVartime(&actual, tooManyPoints[:], tooManyScalars[:]) {
+		t.Fatalf("VectorMultiScalarmultVartime accepted too many points")
+	}
+}
+
+func BenchmarkDoubleScalarmultVartime(b *testing.B) {
+	var (
+		p, r   Ge25519
+		s1, s2 modm.Bignum256
+		buf    [64]byte
+	)
+	_, _ = rand.Read(buf[:])
+	modm.Expand(&s1, buf[:])
+	modm.Expand(&s2, buf[:])
+	ScalarmultBaseNiels(&p, &NielsBaseMultiples, &s1)
+
+	hasAVX2 := useAVX2
+	defer func() {
+		useAVX2 = hasAVX2
+	}()
+
+	b.Run("Portable", func(b *testing.B) {
+		useAVX2 = false
+		for i := 0; i < b.N; i++ {
+			DoubleScalarmultVartime(&r, &p, &s1, &s2)
+		}
+	})
+	if hasAVX2 {
+		b.Run("AVX2", func(b *testing.B) {
+			useAVX2 = true
+			for i := 0; i < b.N; i++ {
+				DoubleScalarmultVartime(&r, &p, &s1, &s2)
+			}
+		})
+	}
+}
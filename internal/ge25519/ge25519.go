@@ -381,6 +381,11 @@ const (
 // computes [s1]p1 + [s2]basepoint
 func DoubleScalarmultVartime(r, p1 *Ge25519, s1, s2 *modm.Bignum256) {
 	// ge25519_double_scalarmult_vartime(ge25519 *r, const ge25519 *p1, const bignum256modm s1, const bignum256modm s2)
+	if useAVX2 {
+		doubleScalarmultVartimeAVX2(r, p1, s1, s2)
+		return
+	}
+
 	var (
 		slide1, slide2 [256]int8
 		pre1           [s1TableSize]ge25519pniels
@@ -432,6 +437,21 @@ func DoubleScalarmultVartime(r, p1 *Ge25519, s1, s2 *modm.Bignum256) {
 	}
 }
 
+// MaxVectorMultiScalarmultPoints is the maximum number of points supported
+// by VectorMultiScalarmultVartime, the size of a full batch verification.
+const MaxVectorMultiScalarmultPoints = 129
+
+// computes sum([scalars[i]]points[i]) with the vectorized backend if it is
+// available and there are at most MaxVectorMultiScalarmultPoints points,
+// returning false (and leaving r untouched) otherwise
+func VectorMultiScalarmultVartime(r *Ge25519, points []Ge25519, scalars []modm.Bignum256) bool {
+	if !useAVX2 || len(points) > MaxVectorMultiScalarmultPoints {
+		return false
+	}
+	multiScalarmultVartimeAVX2(r, points, scalars)
+	return true
+}
+
 // computes [s]basepoint
 func ScalarmultBaseNiels(r *Ge25519, basepointTable *[256][96]byte, s *modm.Bignum256) {
 	// ge25519_scalarmult_base_niels(ge25519 *r, const uint8_t basepoint_table[256][96], const bignum256modm s)
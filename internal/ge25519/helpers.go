@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ge25519
+
+import (
+	"crypto/subtle"
+
+	"github.com/oasisprotocol/ed25519/internal/curve25519"
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+// The routines in this file are not part of upstream, and exist to
+// support protocols built on top of the group (threshold signatures,
+// key blinding, etc).
+
+// orderMinusOne is the group order minus one, l - 1.
+var orderMinusOne modm.Bignum256
+
+// SetIdentity sets r to the identity point (neutral element).
+func SetIdentity(r *Ge25519) {
+	r.Reset()
+	r.y[0] = 1
+	r.z[0] = 1
+}
+
+// Neg sets r = -p.
+func Neg(r, p *Ge25519) {
+	curve25519.Neg(&r.x, &p.x)
+	curve25519.Copy(&r.y, &p.y)
+	curve25519.Copy(&r.z, &p.z)
+	curve25519.Neg(&r.t, &p.t)
+}
+
+// Sub sets r = p - q.
+func Sub(r, p, q *Ge25519) {
+	var (
+		t1 ge25519pniels
+		t2 ge25519p1p1
+	)
+
+	fullToPniels(&t1, q)
+	geSub(&t2, p, &t1)
+	p1p1ToFull(r, &t2)
+}
+
+// ScalarmultVartime sets r = [s]p, in variable time.
+func ScalarmultVartime(r, p *Ge25519, s *modm.Bignum256) {
+	var (
+		zero modm.Bignum256
+		rp   Ge25519
+	)
+
+	DoubleScalarmultVartime(&rp, p, s, &zero)
+	ProjectiveToExtended(r, &rp)
+}
+
+// Scalarmult sets r = [s]p, in constant time.
+func Scalarmult(r, p *Ge25519, s *modm.Bignum256) {
+	var (
+		b   [64]int8
+		pre [8]ge25519pniels
+		t   ge25519pniels
+		tp  ge25519p1p1
+	)
+
+	modm.ContractWindow4(&b, s)
+
+	// pre[i] = [i+1]p
+	fullToPniels(&pre[0], p)
+	for i := 0; i < len(pre)-1; i++ {
+		pnielsAdd(&pre[i+1], p, &pre[i])
+	}
+
+	SetIdentity(r)
+	for i := 63; i >= 0; i-- {
+		if i != 63 {
+			doublePartial(r, r)
+			doublePartial(r, r)
+			doublePartial(r, r)
+			Double(r, r)
+		}
+
+		scalarmultChoosePniels(&t, &pre, b[i])
+		pnielsAddP1P1Vartime(&tp, r, &t, 0) // Constant time when signbit is 0.
+		p1p1ToFull(r, &tp)
+	}
+
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// scalarmultChoosePniels sets t = [b]p, in constant time, given a table of
+// [1]p ... [8]p.
+func scalarmultChoosePniels(t *ge25519pniels, pre *[8]ge25519pniels, b int8) {
+	var (
+		neg  curve25519.Bignum25519
+		sign = uint32(uint8(b) >> 7)
+		mask = ^(sign - 1)
+		u    = (uint32(b) + mask) ^ mask
+	)
+
+	// Initialize to the neutral element.
+	t.ysubx.Reset()
+	t.xaddy.Reset()
+	t.z.Reset()
+	t.t2d.Reset()
+	t.ysubx[0] = 1
+	t.xaddy[0] = 1
+	t.z[0] = 1
+
+	for i := range pre {
+		flag := uint64(subtle.ConstantTimeEq(int32(u), int32(i+1)))
+
+		tmp := pre[i]
+		curve25519.SwapConditional(&t.ysubx, &tmp.ysubx, flag)
+		curve25519.SwapConditional(&t.xaddy, &tmp.xaddy, flag)
+		curve25519.SwapConditional(&t.z, &tmp.z, flag)
+		curve25519.SwapConditional(&t.t2d, &tmp.t2d, flag)
+	}
+
+	// adjust for sign
+	curve25519.SwapConditional(&t.ysubx, &t.xaddy, uint64(sign))
+	curve25519.Neg(&neg, &t.t2d)
+	curve25519.SwapConditional(&t.t2d, &neg, uint64(sign))
+}
+
+// EqualVartime returns true iff p == q.
+func EqualVartime(p, q *Ge25519) bool {
+	var (
+		t1, t2   curve25519.Bignum25519
+		t1b, t2b [32]byte
+		xOk, yOk int
+	)
+
+	// X1*Z2 == X2*Z1
+	curve25519.Mul(&t1, &p.x, &q.z)
+	curve25519.Mul(&t2, &q.x, &p.z)
+	curve25519.Contract(t1b[:], &t1)
+	curve25519.Contract(t2b[:], &t2)
+	xOk = subtle.ConstantTimeCompare(t1b[:], t2b[:])
+
+	// Y1*Z2 == Y2*Z1
+	curve25519.Mul(&t1, &p.y, &q.z)
+	curve25519.Mul(&t2, &q.y, &p.z)
+	curve25519.Contract(t1b[:], &t1)
+	curve25519.Contract(t2b[:], &t2)
+	yOk = subtle.ConstantTimeCompare(t1b[:], t2b[:])
+
+	return xOk&yOk == 1
+}
+
+// IsTorsionFreeVartime returns true iff p is in the prime order subgroup.
+func IsTorsionFreeVartime(p *Ge25519) bool {
+	// [l]p = [l-1]p + p
+	var t Ge25519
+	ScalarmultVartime(&t, p, &orderMinusOne)
+	Add(&t, &t, p)
+
+	return IsNeutralVartime(&t)
+}
+
+func init() {
+	var one modm.Bignum256
+	modm.SetUint64(&one, 1)
+	modm.Neg(&orderMinusOne, &one)
+}
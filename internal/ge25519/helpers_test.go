@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ge25519
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/oasisprotocol/ed25519/internal/modm"
+)
+
+func TestScalarmult(t *testing.T) {
+	var (
+		wide            [64]byte
+		s               modm.Bignum256
+		p, ct, vt, base Ge25519
+		ctB, vtB        [32]byte
+	)
+
+	for i := 0; i < 64; i++ {
+		if _, err := rand.Read(wide[:]); err != nil {
+			t.Fatalf("rand.Read: %v", err)
+		}
+		modm.Expand(&s, wide[:])
+		ScalarmultBaseNiels(&p, &NielsBaseMultiples, &s)
+
+		if _, err := rand.Read(wide[:]); err != nil {
+			t.Fatalf("rand.Read: %v", err)
+		}
+		modm.Expand(&s, wide[:])
+		if i == 0 {
+			s.Reset()
+		}
+
+		Scalarmult(&ct, &p, &s)
+		ScalarmultVartime(&vt, &p, &s)
+		Pack(ctB[:], &ct)
+		Pack(vtB[:], &vt)
+		if ctB != vtB {
+			t.Fatalf("Scalarmult mismatch: %x != %x", ctB, vtB)
+		}
+	}
+
+	// [s]B == Scalarmult(B, s)
+	var one modm.Bignum256
+	modm.SetUint64(&one, 1)
+	ScalarmultBaseNiels(&base, &NielsBaseMultiples, &one)
+	Scalarmult(&ct, &base, &s)
+	ScalarmultBaseNiels(&vt, &NielsBaseMultiples, &s)
+	Pack(ctB[:], &ct)
+	Pack(vtB[:], &vt)
+	if ctB != vtB {
+		t.Fatalf("Scalarmult(B) mismatch: %x != %x", ctB, vtB)
+	}
+}
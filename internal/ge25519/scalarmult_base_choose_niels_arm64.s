@@ -0,0 +1,419 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build arm64,go1.13,!noasm
+// +build !force32bit
+
+#include "textflag.h"
+
+// func scalarmultBaseChooseNielsARM64(u uint64, table *byte, t *ge25519niels, sign uint64)
+TEXT ·scalarmultBaseChooseNielsARM64(SB),NOSPLIT,$0-32
+	MOVD u+0(FP), R21
+	MOVD table+8(FP), R19
+	MOVD t+16(FP), R20
+	MOVD sign+24(FP), R22
+
+	// ysubx+xaddy+t2d, packed in R0-R3, R4-R7 and R8-R11
+
+	// 0
+	CMP $0, R21
+	CSET EQ, R0
+	MOVD ZR, R1
+	MOVD ZR, R2
+	MOVD ZR, R3
+	MOVD R0, R4
+	MOVD ZR, R5
+	MOVD ZR, R6
+	MOVD ZR, R7
+	MOVD ZR, R8
+	MOVD ZR, R9
+	MOVD ZR, R10
+	MOVD ZR, R11
+
+	// 1
+	CMP $1, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 2
+	CMP $2, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 3
+	CMP $3, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 4
+	CMP $4, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 5
+	CMP $5, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 6
+	CMP $6, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 7
+	CMP $7, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+	ADD $96, R19, R19
+
+	// 8
+	CMP $8, R21
+	CSETM EQ, R23
+	LDP 0(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R0, R0
+	ORR R25, R1, R1
+	LDP 16(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R2, R2
+	ORR R25, R3, R3
+	LDP 32(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R4, R4
+	ORR R25, R5, R5
+	LDP 48(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R6, R6
+	ORR R25, R7, R7
+	LDP 64(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R8, R8
+	ORR R25, R9, R9
+	LDP 80(R19), (R24, R25)
+	AND R23, R24, R24
+	AND R23, R25, R25
+	ORR R24, R10, R10
+	ORR R25, R11, R11
+
+	// conditionally swap ysubx and xaddy
+	CMP $0, R22
+	CSEL NE, R4, R0, R24
+	CSEL NE, R0, R4, R4
+	MOVD R24, R0
+	CSEL NE, R5, R1, R24
+	CSEL NE, R1, R5, R5
+	MOVD R24, R1
+	CSEL NE, R6, R2, R24
+	CSEL NE, R2, R6, R6
+	MOVD R24, R2
+	CSEL NE, R7, R3, R24
+	CSEL NE, R3, R7, R7
+	MOVD R24, R3
+
+	// store ysubx
+	AND $0x7ffffffffffff, R0, R12
+	LSR $51, R0, R13
+	ORR R1<<13, R13, R13
+	AND $0x7ffffffffffff, R13, R13
+	LSR $38, R1, R14
+	ORR R2<<26, R14, R14
+	AND $0x7ffffffffffff, R14, R14
+	LSR $25, R2, R15
+	ORR R3<<39, R15, R15
+	AND $0x7ffffffffffff, R15, R15
+	LSR $12, R3, R16
+	AND $0x7ffffffffffff, R16, R16
+	STP (R12, R13), 0(R20)
+	STP (R14, R15), 16(R20)
+	MOVD R16, 32(R20)
+
+	// store xaddy
+	AND $0x7ffffffffffff, R4, R12
+	LSR $51, R4, R13
+	ORR R5<<13, R13, R13
+	AND $0x7ffffffffffff, R13, R13
+	LSR $38, R5, R14
+	ORR R6<<26, R14, R14
+	AND $0x7ffffffffffff, R14, R14
+	LSR $25, R6, R15
+	ORR R7<<39, R15, R15
+	AND $0x7ffffffffffff, R15, R15
+	LSR $12, R7, R16
+	AND $0x7ffffffffffff, R16, R16
+	STP (R12, R13), 40(R20)
+	STP (R14, R15), 56(R20)
+	MOVD R16, 72(R20)
+
+	// extract t2d
+	AND $0x7ffffffffffff, R8, R12
+	LSR $51, R8, R13
+	ORR R9<<13, R13, R13
+	AND $0x7ffffffffffff, R13, R13
+	LSR $38, R9, R14
+	ORR R10<<26, R14, R14
+	AND $0x7ffffffffffff, R14, R14
+	LSR $25, R10, R15
+	ORR R11<<39, R15, R15
+	AND $0x7ffffffffffff, R15, R15
+	LSR $12, R11, R16
+	AND $0x7ffffffffffff, R16, R16
+
+	// conditionally negate t2d
+	MOVD $0xfffffffffffda, R24
+	MOVD $0xffffffffffffe, R25
+	SUB R12, R24, R24
+	CSEL NE, R24, R12, R12
+	SUB R13, R25, R24
+	CSEL NE, R24, R13, R13
+	SUB R14, R25, R24
+	CSEL NE, R24, R14, R14
+	SUB R15, R25, R24
+	CSEL NE, R24, R15, R15
+	SUB R16, R25, R24
+	CSEL NE, R24, R16, R16
+
+	// store t2d
+	STP (R12, R13), 80(R20)
+	STP (R14, R15), 96(R20)
+	MOVD R16, 112(R20)
+
+	RET
@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build amd64,!noasm arm64,go1.13,!noasm
+// +build !force32bit
+
+package ge25519
+
+import (
+	"testing"
+
+	"github.com/oasisprotocol/ed25519/internal/curve25519"
+)
+
+func TestScalarmultBaseChooseNiels(t *testing.T) {
+	var e, a [32]byte
+	equal := func(expected, actual *curve25519.Bignum25519) bool {
+		curve25519.Contract(e[:], expected)
+		curve25519.Contract(a[:], actual)
+		return e == a
+	}
+
+	for pos := 0; pos < 32; pos++ {
+		for b := -8; b <= 8; b++ {
+			var expected, actual ge25519niels
+			scalarmultBaseChooseNielsGeneric(&expected, &NielsBaseMultiples, pos, int8(b))
+			scalarmultBaseChooseNiels(&actual, &NielsBaseMultiples, pos, int8(b))
+
+			if !equal(&expected.ysubx, &actual.ysubx) {
+				t.Fatalf("pos %d, b %d: ysubx mismatch", pos, b)
+			}
+			if !equal(&expected.xaddy, &actual.xaddy) {
+				t.Fatalf("pos %d, b %d: xaddy mismatch", pos, b)
+			}
+			if !equal(&expected.t2d, &actual.t2d) {
+				t.Fatalf("pos %d, b %d: t2d mismatch", pos, b)
+			}
+		}
+	}
+}
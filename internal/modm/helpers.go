@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package modm
+
+import "crypto/subtle"
+
+// The routines in this file are not part of upstream, and are built on
+// top of the backend specific primitives so that they work unmodified
+// with both the 32 and 64 bit implementations.
+
+var (
+	// mBytes is m in little-endian form.
+	mBytes = [32]byte{
+		0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+		0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+	}
+
+	// mMinusOne is m - 1 in little-endian form.
+	mMinusOne = [32]byte{
+		0xec, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+		0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+	}
+
+	// mMinusTwo is m - 2 in little-endian form.
+	mMinusTwo = [32]byte{
+		0xeb, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+		0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x10,
+	}
+
+	negOne Bignum256
+)
+
+// SetUint64 sets r = v.
+func SetUint64(r *Bignum256, v uint64) {
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(v >> (8 * uint(i)))
+	}
+	Expand(r, b[:])
+}
+
+// Neg sets r = -x mod m.
+func Neg(r, x *Bignum256) {
+	Mul(r, x, &negOne)
+}
+
+// Sub sets r = x - y mod m.
+func Sub(r, x, y *Bignum256) {
+	var t Bignum256
+	Neg(&t, y)
+	Add(r, x, &t)
+}
+
+// Invert sets r = x^-1 mod m, by computing x^(m-2).  The exponent is
+// public, so the routine is constant time with respect to x.  If x is
+// zero, r will be set to zero.
+func Invert(r, x *Bignum256) {
+	var t, acc Bignum256
+
+	t = *x
+	acc.Reset()
+	acc[0] = 1
+	for i := 0; i < 253; i++ {
+		if (mMinusTwo[i/8]>>uint(i%8))&1 == 1 {
+			Mul(&acc, &acc, &t)
+		}
+		Mul(&t, &t, &t)
+	}
+
+	*r = acc
+	t.Reset()
+}
+
+// Equal returns true iff a == b, in constant time.  Both a and b must
+// be fully reduced.
+func Equal(a, b *Bignum256) bool {
+	var aBytes, bBytes [32]byte
+	Contract(aBytes[:], a)
+	Contract(bBytes[:], b)
+
+	return subtle.ConstantTimeCompare(aBytes[:], bBytes[:]) == 1
+}
+
+// IsCanonical returns true iff the 32 byte little-endian scalar s is
+// less than m, in constant time.
+func IsCanonical(s []byte) bool {
+	_ = s[31]
+
+	// s < m iff s - m borrows.
+	var borrow uint32
+	for i := 0; i < 32; i++ {
+		borrow = (uint32(s[i]) - uint32(mBytes[i]) - borrow) >> 31
+	}
+
+	return borrow == 1
+}
+
+func init() {
+	ExpandRaw(&negOne, mMinusOne[:])
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build go1.13
+
+package ed25519
+
+import (
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"io"
+)
+
+// The conversions between this package's key types and the standard
+// library's share the underlying storage, and do not validate the key
+// lengths.  As golang.org/x/crypto/ed25519's key types are aliases of the
+// standard library's for Go 1.13 and later, they can be used with the same
+// conversions.
+
+// FromStdPublicKey converts a crypto/ed25519 public key to a PublicKey.
+func FromStdPublicKey(publicKey stded25519.PublicKey) PublicKey {
+	return PublicKey(publicKey)
+}
+
+// ToStdPublicKey converts a PublicKey to a crypto/ed25519 public key.
+func ToStdPublicKey(publicKey PublicKey) stded25519.PublicKey {
+	return stded25519.PublicKey(publicKey)
+}
+
+// FromStdPrivateKey converts a crypto/ed25519 private key to a PrivateKey.
+func FromStdPrivateKey(privateKey stded25519.PrivateKey) PrivateKey {
+	return PrivateKey(privateKey)
+}
+
+// ToStdPrivateKey converts a PrivateKey to a crypto/ed25519 private key.
+func ToStdPrivateKey(privateKey PrivateKey) stded25519.PrivateKey {
+	return stded25519.PrivateKey(privateKey)
+}
+
+// NewStdSigner returns a crypto.Signer that signs with privateKey, but
+// returns a crypto/ed25519 public key from Public, so that it can be used
+// with packages that only recognize the standard library's key types, such
+// as crypto/x509 (for certificates and CSRs) and crypto/tls.
+func NewStdSigner(privateKey PrivateKey) crypto.Signer {
+	return &stdSigner{privateKey}
+}
+
+type stdSigner struct {
+	privateKey PrivateKey
+}
+
+func (s *stdSigner) Public() crypto.PublicKey {
+	return ToStdPublicKey(s.privateKey.Public().(PublicKey))
+}
+
+func (s *stdSigner) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.privateKey.Sign(rand, message, opts)
+}
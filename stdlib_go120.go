@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build go1.20
+
+package ed25519
+
+import (
+	"crypto"
+	stded25519 "crypto/ed25519"
+)
+
+// FromStdOptions converts crypto/ed25519 signing options to Options, for use
+// with VerifyWithOptions.  A nil opts is treated as plain Ed25519.
+func FromStdOptions(opts *stded25519.Options) *Options {
+	if opts == nil {
+		return &Options{}
+	}
+	return &Options{
+		Hash:    opts.Hash,
+		Context: opts.Context,
+	}
+}
+
+// VerifyWithStdOptions reports whether sig is a valid Ed25519 signature by
+// publicKey, with the variant (Ed25519, Ed25519ctx, Ed25519ph) selected by
+// the crypto/ed25519 options' Hash and Context.  It will panic under the
+// same conditions as VerifyWithOptions.
+func VerifyWithStdOptions(publicKey PublicKey, message, sig []byte, opts *stded25519.Options) bool {
+	return VerifyWithOptions(publicKey, message, sig, FromStdOptions(opts))
+}
+
+func fromStdSignerOpts(opts crypto.SignerOpts) *Options {
+	if o, ok := opts.(*stded25519.Options); ok {
+		return FromStdOptions(o)
+	}
+	return nil
+}
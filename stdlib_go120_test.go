@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build go1.20
+
+package ed25519
+
+import (
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"crypto/sha512"
+	"testing"
+)
+
+func TestStdOptions(t *testing.T) {
+	publicKey, privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	stdPublicKey := ToStdPublicKey(publicKey)
+
+	msg := []byte("test message")
+	msgHash := sha512.Sum512(msg)
+	for _, v := range []struct {
+		name string
+		msg  []byte
+		opts *stded25519.Options
+	}{
+		{"Ed25519", msg, &stded25519.Options{}},
+		{"Ed25519ctx", msg, &stded25519.Options{Context: "test context"}},
+		{"Ed25519ph", msgHash[:], &stded25519.Options{Hash: crypto.SHA512}},
+		{"Ed25519phctx", msgHash[:], &stded25519.Options{Hash: crypto.SHA512, Context: "test context"}},
+	} {
+		sig, err := privateKey.Sign(nil, v.msg, v.opts)
+		if err != nil {
+			t.Fatalf("Sign(%s): %v", v.name, err)
+		}
+		if err = stded25519.VerifyWithOptions(stdPublicKey, v.msg, sig, v.opts); err != nil {
+			t.Fatalf("crypto/ed25519.VerifyWithOptions(%s): %v", v.name, err)
+		}
+		if !VerifyWithOptions(publicKey, v.msg, sig, FromStdOptions(v.opts)) {
+			t.Fatalf("VerifyWithOptions(%s): failed to verify signature", v.name)
+		}
+		if !VerifyWithStdOptions(publicKey, v.msg, sig, v.opts) {
+			t.Fatalf("VerifyWithStdOptions(%s): failed to verify signature", v.name)
+		}
+		if VerifyWithStdOptions(publicKey, v.msg, sig, &stded25519.Options{Hash: v.opts.Hash, Context: "other context"}) {
+			t.Fatalf("VerifyWithStdOptions(%s): accepted signature with a different context", v.name)
+		}
+
+		stdSig, err := ToStdPrivateKey(privateKey).Sign(nil, v.msg, v.opts)
+		if err != nil {
+			t.Fatalf("crypto/ed25519.Sign(%s): %v", v.name, err)
+		}
+		if string(stdSig) != string(sig) {
+			t.Fatalf("Sign(%s): signature mismatch", v.name)
+		}
+	}
+
+	sig := Sign(privateKey, msg)
+	if o := FromStdOptions(nil); o == nil || o.Hash != 0 || o.Context != "" {
+		t.Fatalf("FromStdOptions(nil): unexpected options: %+v", o)
+	}
+	if !VerifyWithStdOptions(publicKey, msg, sig, nil) {
+		t.Fatalf("VerifyWithStdOptions(nil): failed to verify signature")
+	}
+
+	if _, err = privateKey.Sign(nil, msg, &stded25519.Options{Context: string(make([]byte, ContextMaxSize+1))}); err == nil {
+		t.Fatalf("Sign: accepted an oversized context")
+	}
+	if _, err = privateKey.Sign(nil, msg, &stded25519.Options{Hash: crypto.SHA512}); err == nil {
+		t.Fatalf("Sign: accepted an unhashed Ed25519ph message")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Oasis Labs Inc.  All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//   * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Oasis Labs Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build go1.13
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto"
+	stded25519 "crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	xed25519 "golang.org/x/crypto/ed25519"
+)
+
+func TestStdConversions(t *testing.T) {
+	publicKey, privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	stdPrivateKey := ToStdPrivateKey(privateKey)
+	stdPublicKey := ToStdPublicKey(publicKey)
+	if &stdPrivateKey[0] != &privateKey[0] || &stdPublicKey[0] != &publicKey[0] {
+		t.Fatalf("ToStd*: conversion copied the key")
+	}
+	if !stdPublicKey.Equal(stdPrivateKey.Public()) {
+		t.Fatalf("ToStdPrivateKey: public key mismatch")
+	}
+
+	msg := []byte("test message")
+	sig := Sign(privateKey, msg)
+	if !stded25519.Verify(stdPublicKey, msg, sig) {
+		t.Fatalf("crypto/ed25519.Verify: failed to verify signature")
+	}
+	if !bytes.Equal(stded25519.Sign(stdPrivateKey, msg), sig) {
+		t.Fatalf("crypto/ed25519.Sign: signature mismatch")
+	}
+
+	// golang.org/x/crypto/ed25519 keys are the same type.
+	var xPublicKey xed25519.PublicKey = stdPublicKey
+	var xPrivateKey xed25519.PrivateKey = stdPrivateKey
+	if !FromStdPublicKey(xPublicKey).Equal(publicKey) || !FromStdPrivateKey(xPrivateKey).Equal(privateKey) {
+		t.Fatalf("FromStd*: key mismatch")
+	}
+	if &FromStdPrivateKey(xPrivateKey)[0] != &privateKey[0] {
+		t.Fatalf("FromStdPrivateKey: conversion copied the key")
+	}
+}
+
+func TestStdSigner(t *testing.T) {
+	_, privateKey, err := GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	signer := NewStdSigner(privateKey)
+	if _, ok := signer.Public().(stded25519.PublicKey); !ok {
+		t.Fatalf("Public: unexpected type: %T", signer.Public())
+	}
+
+	// Signing is done by this package, so the options are honored.
+	msg := []byte("test message")
+	opts := &Options{Context: "test context"}
+	sig, err := signer.Sign(nil, msg, opts)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !VerifyWithOptions(privateKey.Public().(PublicKey), msg, sig, opts) {
+		t.Fatalf("Sign: failed to verify Ed25519ctx signature")
+	}
+
+	// Self-signed certificate.
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(nil, template, template, signer.Public(), signer)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate: %v", err)
+	}
+	if err = cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatalf("CheckSignatureFrom: %v", err)
+	}
+	certPublicKey, ok := cert.PublicKey.(stded25519.PublicKey)
+	if !ok || !FromStdPublicKey(certPublicKey).Equal(privateKey.Public()) {
+		t.Fatalf("x509.ParseCertificate: public key mismatch")
+	}
+
+	// TLS handshake, with the server authenticating with the signer.
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	server := tls.Server(serverConn, &tls.Config{
+		Certificates: []tls.Certificate{
+			{
+				Certificate: [][]byte{der},
+				PrivateKey:  signer,
+			},
+		},
+	})
+	client := tls.Client(clientConn, &tls.Config{
+		RootCAs:    roots,
+		ServerName: "localhost",
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Handshake()
+	}()
+	if err = client.Handshake(); err != nil {
+		t.Fatalf("client.Handshake: %v", err)
+	}
+	if err = <-errCh; err != nil {
+		t.Fatalf("server.Handshake: %v", err)
+	}
+	if state := client.ConnectionState(); !state.HandshakeComplete || len(state.PeerCertificates) != 1 {
+		t.Fatalf("client.ConnectionState: unexpected state")
+	}
+
+	if _, err = signer.Sign(nil, msg, crypto.SHA256); err == nil {
+		t.Fatalf("Sign: accepted SHA-256")
+	}
+}